@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"github.com/azaliaz/bookly/book-service/internal/blob"
+	"github.com/azaliaz/bookly/book-service/internal/cache"
 	"github.com/azaliaz/bookly/book-service/internal/config"
 	"github.com/azaliaz/bookly/book-service/internal/logger"
 	"github.com/azaliaz/bookly/book-service/internal/server"
@@ -34,16 +36,52 @@ func main() {
 
 	log.Debug().Any("cfg", cfg).Send()
 	var stor server.Storage
-
-	if err = storage.Migrations(cfg.DBDsn, cfg.MigratePath); err != nil {
-		log.Fatal().Err(err).Msg("migrations failed")
-	}
-	stor, err = storage.NewDB(context.TODO(), cfg.DBDsn)
-	if err != nil {
-		log.Error().Err(err).Msg("connecting to data base failed")
+	switch cfg.StorageBackend {
+	case storage.BackendPostgres:
+		stor, err = storage.Connect(ctx, cfg.DBDsn, cfg.MigratePath, cfg.DBConnectTimeout)
+		if err != nil {
+			log.Fatal().Err(err).Msg("connecting to data base failed")
+		}
+	case storage.BackendMemory:
+		log.Warn().Msg("in-memory storage is used, data will be lost on restart")
 		stor = storage.New()
 	}
+	log.Info().Str("backend", cfg.StorageBackend).Msg("storage ready")
+	switch cfg.CacheBackend {
+	case cache.BackendMemory:
+		stor = server.NewCachedStorage(stor, cache.NewLRU(cfg.CacheSize), cfg.CacheTTL)
+	case cache.BackendRedis:
+		redis, err := cache.NewRedis(cfg.RedisURL)
+		if err != nil {
+			log.Fatal().Err(err).Msg("storage cache config failed")
+		}
+		if err = redis.Ping(ctx); err != nil {
+			log.Error().Err(err).Msg("connecting to redis failed")
+		}
+		defer redis.Close()
+		stor = server.NewCachedStorage(stor, redis, cfg.CacheTTL)
+	}
 	serv := server.New(*cfg, stor)
+	if cfg.BlobBackend == blob.BackendS3 {
+		serv.Blobs, err = blob.NewS3(blob.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+		})
+		if err != nil {
+			log.Fatal().Err(err).Msg("blob storage config failed")
+		}
+	}
+	if cfg.BackfillCovers {
+		report, err := serv.BackfillCovers(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Any("report", report).Msg("covers backfill failed")
+		}
+		log.Info().Any("report", report).Msg("covers backfill finished")
+		return
+	}
 	group, gCtx := errgroup.WithContext(ctx)
 	group.Go(func() error {
 		return serv.Run(gCtx)
@@ -0,0 +1,80 @@
+// Package blob хранит файлы книг (обложки, PDF) по ключам вида covers/<uuid>.jpg
+// на локальном диске или в S3-совместимом хранилище.
+package blob
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+
+	// URLPrefix - путь, по которому book-service отдает объекты клиентам.
+	URLPrefix = "/uploads/"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Object - содержимое объекта; Body закрывает вызывающий. Body поддерживает Seek,
+// поэтому объект можно отдать через http.ServeContent с поддержкой Range.
+type Object struct {
+	Body        io.ReadSeekCloser
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// ValidKey проверяет, что ключ - относительный путь без "." и "..".
+func ValidKey(key string) bool {
+	return key != "" && fs.ValidPath(key) && key != "."
+}
+
+// ContentType определяет тип объекта по расширению ключа.
+func ContentType(key string) string {
+	if ct := mime.TypeByExtension(path.Ext(key)); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// URL возвращает путь, по которому объект с ключом key отдается клиентам.
+func URL(key string) string {
+	if key == "" {
+		return ""
+	}
+	return URLPrefix + escapeKey(key, url.PathEscape)
+}
+
+// KeyFromURL достает ключ объекта из ссылки, выданной URL (относительной или абсолютной).
+// Для внешних ссылок возвращает false.
+func KeyFromURL(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	key, ok := strings.CutPrefix(u.Path, URLPrefix)
+	if !ok || !ValidKey(key) {
+		return "", false
+	}
+	return key, true
+}
+
+// escapeKey экранирует сегменты ключа функцией escape, сохраняя разделители "/".
+func escapeKey(key string, escape func(string) string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = escape(s)
+	}
+	return strings.Join(segments, "/")
+}
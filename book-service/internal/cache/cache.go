@@ -0,0 +1,24 @@
+// Package cache хранит закешированные ответы хранилища книг в памяти процесса (LRU
+// с TTL) или в Redis, общем для всех реплик.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	BackendNone   = "none"
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// Backend - хранилище значений по ключу со сроком жизни. Счетчики, которые
+// увеличивает Incr, не вытесняются и не истекают; Get возвращает их десятичной строкой.
+type Backend interface {
+	// Get возвращает значение ключа; ok = false, если ключа нет или он истек.
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Incr увеличивает счетчик key на единицу и возвращает новое значение.
+	Incr(ctx context.Context, key string) (int64, error)
+}
@@ -0,0 +1,266 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+)
+
+const FormatONIX = "onix"
+
+// exportColumns - колонки CSV-экспорта; файл можно загрузить обратно через импорт.
+var exportColumns = []string{ //nolint:gochecknoglobals // неизменяемый
+	"bid", "lable", "author", "desc", "age", "genre", "rating", "cover_url", "pdf_url", "epub_url", "language", "isbn",
+	"publisher", "work_id",
+}
+
+// Writer пишет книги в выходной поток по одной, не накапливая каталог в памяти.
+type Writer interface {
+	Write(models.Book) error
+	Close() error
+}
+
+// Export описывает формат выгрузки для HTTP-ответа.
+type Export struct {
+	Format      string
+	ContentType string
+	Extension   string
+}
+
+// LookupExport возвращает описание формата csv, jsonl или onix.
+func LookupExport(format string) (Export, error) {
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		return Export{FormatCSV, "text/csv; charset=utf-8", "csv"}, nil
+	case FormatJSONL, "ndjson":
+		return Export{FormatJSONL, "application/x-ndjson", "jsonl"}, nil
+	case FormatONIX:
+		return Export{FormatONIX, "application/xml; charset=utf-8", "xml"}, nil
+	default:
+		return Export{}, ErrUnknownFormat
+	}
+}
+
+// NewWriter создает Writer для формата и сразу пишет заголовок файла, если он нужен.
+func NewWriter(w io.Writer, export Export) (Writer, error) {
+	switch export.Format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatJSONL:
+		return &jsonlWriter{enc: json.NewEncoder(w)}, nil
+	case FormatONIX:
+		return newONIXWriter(w, time.Now())
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w)}
+	return cw, cw.w.Write(exportColumns)
+}
+
+func (cw *csvWriter) Write(book models.Book) error {
+	return cw.w.Write([]string{
+		book.BID, book.Lable, book.Author, book.Desc, strconv.Itoa(book.Age),
+		book.Genre, strconv.Itoa(book.Rating), book.CoverURL, book.PDFURL,
+		book.EPUBURL, book.Language, book.ISBN, book.Publisher, book.WorkID,
+	})
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+type jsonlWriter struct {
+	enc *json.Encoder
+}
+
+func (jw *jsonlWriter) Write(book models.Book) error {
+	return jw.enc.Encode(book)
+}
+
+func (jw *jsonlWriter) Close() error {
+	return nil
+}
+
+// onixWriter пишет ONIX for Books 3.0 (reference tags): заголовок сообщения
+// и по одному <Product> на книгу.
+type onixWriter struct {
+	w   io.Writer
+	enc *xml.Encoder
+}
+
+const onixSender = "bookly"
+
+func newONIXWriter(w io.Writer, sent time.Time) (*onixWriter, error) {
+	_, err := fmt.Fprintf(w, `%s<ONIXMessage release="3.0" xmlns="http://ns.editeur.org/onix/3.0/reference">
+<Header><Sender><SenderName>%s</SenderName></Sender><SentDateTime>%s</SentDateTime></Header>
+`, xml.Header, onixSender, sent.UTC().Format("20060102T1504Z"))
+	if err != nil {
+		return nil, err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return &onixWriter{w: w, enc: enc}, nil
+}
+
+func (ow *onixWriter) Write(book models.Book) error {
+	if err := ow.enc.Encode(onixProduct(book)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(ow.w, "\n")
+	return err
+}
+
+func (ow *onixWriter) Close() error {
+	if err := ow.enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(ow.w, "</ONIXMessage>\n")
+	return err
+}
+
+// Коды из списков ONIX: 01 - собственный идентификатор, 02/15 - ISBN-10/ISBN-13,
+// 03 - подтвержденная запись, ED - загружаемая электронная книга, E107 - PDF,
+// E101 - EPUB, A01 - автор, 20 - ключевые слова,
+// 03 - описание, 01 - обложка, 01 - издатель и дата публикации, 05 - формат даты YYYY.
+type (
+	onixProductXML struct {
+		XMLName           xml.Name         `xml:"Product"`
+		RecordReference   string           `xml:"RecordReference"`
+		NotificationType  string           `xml:"NotificationType"`
+		ProductIdentifier []onixIdentifier `xml:"ProductIdentifier"`
+		DescriptiveDetail onixDescriptive  `xml:"DescriptiveDetail"`
+		CollateralDetail  *onixCollateral  `xml:"CollateralDetail,omitempty"`
+		PublishingDetail  onixPublishing   `xml:"PublishingDetail"`
+	}
+	onixIdentifier struct {
+		ProductIDType string `xml:"ProductIDType"`
+		IDTypeName    string `xml:"IDTypeName,omitempty"`
+		IDValue       string `xml:"IDValue"`
+	}
+	onixDescriptive struct {
+		ProductComposition string            `xml:"ProductComposition"`
+		ProductForm        string            `xml:"ProductForm"`
+		ProductFormDetail  []string          `xml:"ProductFormDetail,omitempty"`
+		TitleDetail        onixTitleDetail   `xml:"TitleDetail"`
+		Contributor        []onixContributor `xml:"Contributor"`
+		Subject            []onixSubject     `xml:"Subject"`
+	}
+	onixTitleDetail struct {
+		TitleType    string `xml:"TitleType"`
+		TitleElement struct {
+			TitleElementLevel string `xml:"TitleElementLevel"`
+			TitleText         string `xml:"TitleText"`
+		} `xml:"TitleElement"`
+	}
+	onixContributor struct {
+		SequenceNumber  int    `xml:"SequenceNumber"`
+		ContributorRole string `xml:"ContributorRole"`
+		PersonName      string `xml:"PersonName"`
+	}
+	onixSubject struct {
+		SubjectSchemeIdentifier string `xml:"SubjectSchemeIdentifier"`
+		SubjectHeadingText      string `xml:"SubjectHeadingText"`
+	}
+	onixCollateral struct {
+		TextContent        []onixTextContent `xml:"TextContent"`
+		SupportingResource []onixResource    `xml:"SupportingResource"`
+	}
+	onixTextContent struct {
+		TextType        string `xml:"TextType"`
+		ContentAudience string `xml:"ContentAudience"`
+		Text            string `xml:"Text"`
+	}
+	onixResource struct {
+		ResourceContentType string `xml:"ResourceContentType"`
+		ContentAudience     string `xml:"ContentAudience"`
+		ResourceMode        string `xml:"ResourceMode"`
+		ResourceVersion     struct {
+			ResourceForm string `xml:"ResourceForm"`
+			ResourceLink string `xml:"ResourceLink"`
+		} `xml:"ResourceVersion"`
+	}
+	onixPublishing struct {
+		Publisher      *onixPublisher `xml:"Publisher,omitempty"`
+		PublishingDate *onixDate      `xml:"PublishingDate,omitempty"`
+	}
+	onixPublisher struct {
+		PublishingRole string `xml:"PublishingRole"`
+		PublisherName  string `xml:"PublisherName"`
+	}
+	onixDate struct {
+		PublishingDateRole string `xml:"PublishingDateRole"`
+		Date               struct {
+			Format string `xml:"dateformat,attr"`
+			Value  string `xml:",chardata"`
+		} `xml:"Date"`
+	}
+)
+
+func onixProduct(book models.Book) onixProductXML {
+	p := onixProductXML{
+		RecordReference:   "bookly-" + book.BID,
+		NotificationType:  "03",
+		ProductIdentifier: []onixIdentifier{{ProductIDType: "01", IDTypeName: onixSender, IDValue: book.BID}},
+	}
+	// ISBN в каталоге всегда ISBN-13
+	if book.ISBN != "" {
+		p.ProductIdentifier = append(p.ProductIdentifier, onixIdentifier{ProductIDType: "15", IDValue: book.ISBN})
+	}
+
+	d := &p.DescriptiveDetail
+	d.ProductComposition = "00"
+	d.ProductForm = "ED"
+	if book.PDFURL != "" {
+		d.ProductFormDetail = append(d.ProductFormDetail, "E107")
+	}
+	if book.EPUBURL != "" {
+		d.ProductFormDetail = append(d.ProductFormDetail, "E101")
+	}
+	d.TitleDetail.TitleType = "01"
+	d.TitleDetail.TitleElement.TitleElementLevel = "01"
+	d.TitleDetail.TitleElement.TitleText = book.Lable
+	d.Contributor = []onixContributor{{SequenceNumber: 1, ContributorRole: "A01", PersonName: book.Author}}
+	if book.Genre != "" {
+		d.Subject = []onixSubject{{SubjectSchemeIdentifier: "20", SubjectHeadingText: book.Genre}}
+	}
+
+	collateral := &onixCollateral{}
+	if book.Desc != "" {
+		collateral.TextContent = []onixTextContent{{TextType: "03", ContentAudience: "00", Text: book.Desc}}
+	}
+	if book.CoverURL != "" {
+		r := onixResource{ResourceContentType: "01", ContentAudience: "00", ResourceMode: "03"}
+		r.ResourceVersion.ResourceForm = "02"
+		r.ResourceVersion.ResourceLink = book.CoverURL
+		collateral.SupportingResource = []onixResource{r}
+	}
+	if len(collateral.TextContent) > 0 || len(collateral.SupportingResource) > 0 {
+		p.CollateralDetail = collateral
+	}
+
+	if book.Publisher != "" {
+		p.PublishingDetail.Publisher = &onixPublisher{PublishingRole: "01", PublisherName: book.Publisher}
+	}
+	if book.Age > 0 {
+		date := &onixDate{PublishingDateRole: "01"}
+		date.Date.Format = "05"
+		date.Date.Value = strconv.Itoa(book.Age)
+		p.PublishingDetail.PublishingDate = date
+	}
+	return p
+}
@@ -0,0 +1,207 @@
+// Package catalog читает и пишет каталог книг во внешних форматах для импорта и экспорта.
+package catalog
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azaliaz/bookly/book-service/internal/blob"
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/isbn"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+
+	maxJSONLine = 1 << 20
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown format, expected csv or jsonl")
+	ErrMissingColumn = errors.New("missing required column")
+)
+
+// requiredCSVColumns - обязательные колонки заголовка; названия совпадают с JSON-полями models.Book.
+var requiredCSVColumns = []string{"lable", "author", "desc", "age"} //nolint:gochecknoglobals // неизменяемый
+
+// Row - одна запись файла импорта. Line - номер строки в исходном файле.
+type Row struct {
+	Line int
+	Book models.Book
+	Err  error
+}
+
+// DetectFormat выбирает формат по явному параметру, затем по Content-Type и расширению файла.
+func DetectFormat(format, contentType, filename string) (string, error) {
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		switch mediaType {
+		case "text/csv", "application/csv":
+			format = FormatCSV
+		case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+			format = FormatJSONL
+		default:
+			format = strings.TrimPrefix(filepath.Ext(filename), ".")
+		}
+	}
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatJSONL, "ndjson":
+		return FormatJSONL, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+// ReadRows разбирает файл целиком. Ошибки отдельных строк возвращаются в Row.Err,
+// а ошибка функции означает, что файл нельзя разобрать вообще.
+func ReadRows(r io.Reader, format string) ([]Row, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(r)
+	case FormatJSONL:
+		return readJSONL(r)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+func readCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))] = i
+	}
+	for _, name := range requiredCSVColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingColumn, name)
+		}
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// после ошибки разбора чтение продолжается со следующей строки, а ошибка
+			// самого источника (обрыв, превышение размера) повторялась бы бесконечно
+			var perr *csv.ParseError
+			if !errors.As(err, &perr) {
+				return nil, fmt.Errorf("read csv: %w", err)
+			}
+			rows = append(rows, Row{Line: perr.Line, Err: err})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) != len(header) {
+			rows = append(rows, Row{Line: line, Err: fmt.Errorf("expected %d fields, got %d", len(header), len(record))})
+			continue
+		}
+		book, err := csvBook(record, columns)
+		rows = append(rows, Row{Line: line, Book: book, Err: err})
+	}
+	return rows, nil
+}
+
+func csvBook(record []string, columns map[string]int) (models.Book, error) {
+	value := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	book := models.Book{
+		Lable:     value("lable"),
+		Author:    value("author"),
+		Desc:      value("desc"),
+		Genre:     value("genre"),
+		CoverURL:  value("cover_url"),
+		PDFURL:    value("pdf_url"),
+		EPUBURL:   value("epub_url"),
+		Language:  value("language"),
+		ISBN:      value("isbn"),
+		Publisher: value("publisher"),
+		WorkID:    value("work_id"),
+	}
+	var err error
+	if book.Age, err = strconv.Atoi(value("age")); err != nil {
+		return models.Book{}, errors.New("invalid age value")
+	}
+	if v := value("rating"); v != "" {
+		if book.Rating, err = strconv.Atoi(v); err != nil {
+			return models.Book{}, errors.New("invalid rating value")
+		}
+	}
+	return normalize(book)
+}
+
+func readJSONL(r io.Reader) ([]Row, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLine)
+
+	var rows []Row
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var book models.Book
+		if err := json.Unmarshal([]byte(text), &book); err != nil {
+			rows = append(rows, Row{Line: line, Err: fmt.Errorf("invalid json: %w", err)})
+			continue
+		}
+		book, err := normalize(book)
+		rows = append(rows, Row{Line: line, Book: book, Err: err})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read jsonl: %w", err)
+	}
+	return rows, nil
+}
+
+// normalize убирает служебные поля, которые нельзя задать импортом, ставит жанр по
+// умолчанию и приводит ISBN к ISBN-13, чтобы импорт находил дубликаты по изданию.
+func normalize(book models.Book) (models.Book, error) {
+	book.BID, book.Version, book.Rank, book.Highlights = "", 0, 0, nil
+	book.Popularity, book.Editions = 0, nil
+	book.CreatedAt = time.Time{}
+	// ссылки из выгрузки превращаются обратно в ключи BlobStore, внешние ссылки отбрасываются
+	book.CoverKey, _ = blob.KeyFromURL(book.CoverURL)
+	book.PDFKey, _ = blob.KeyFromURL(book.PDFURL)
+	book.EPUBKey, _ = blob.KeyFromURL(book.EPUBURL)
+	book.CoverURL, book.PDFURL, book.EPUBURL = "", "", ""
+	book.CoverURLs, book.Formats = nil, nil
+	// сведения о файлах сервер извлекает сам при их загрузке
+	book.PDFPages, book.PDFTitle, book.PDFAuthor, book.PDFSize, book.PDFHash = 0, "", "", 0, ""
+	book.EPUBSize, book.EPUBHash = 0, ""
+	if book.Genre == "" {
+		book.Genre = consts.DefaultGenre
+	}
+	var err error
+	if book.ISBN, err = isbn.Normalize(book.ISBN); err != nil {
+		return models.Book{}, err
+	}
+	book.ISBN10 = ""
+	return book, nil
+}
@@ -0,0 +1,132 @@
+// Package covers нормализует обложки книг: декодирует JPEG/PNG/GIF/WebP, поворачивает
+// по EXIF Orientation и пересохраняет в JPEG нескольких размеров. Метаданные исходного
+// файла (EXIF, ICC-профиль, комментарии) при перекодировании отбрасываются.
+package covers
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+)
+
+const (
+	Thumbnail = "thumbnail"
+	Card      = "card"
+	Full      = "full"
+
+	ext         = ".jpg"
+	jpegQuality = 85
+)
+
+// Variant - размер обложки: изображение вписывается в Width x Height без увеличения.
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+}
+
+var Variants = []Variant{
+	{Name: Thumbnail, Width: 160, Height: 240},
+	{Name: Card, Width: 400, Height: 600},
+	{Name: Full, Width: 1200, Height: 1800},
+}
+
+var (
+	ErrNotImage = errors.New("cover is not a JPEG, PNG, GIF or WebP image")
+	ErrTooLarge = errors.New("cover image is too large")
+)
+
+// Image - вариант обложки, закодированный в JPEG.
+type Image struct {
+	Variant string
+	Data    []byte
+}
+
+// Process декодирует обложку из r и возвращает ее варианты в порядке Variants.
+func Process(r io.Reader) ([]Image, error) {
+	data, err := io.ReadAll(io.LimitReader(r, consts.MaxCoverSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > consts.MaxCoverSize {
+		return nil, ErrTooLarge
+	}
+
+	// размеры проверяются до декодирования, чтобы маленький файл не развернулся
+	// в гигабайты пикселей
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrNotImage
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > consts.MaxCoverPixels {
+		return nil, ErrTooLarge
+	}
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrNotImage
+	}
+	if format == "jpeg" {
+		src = orient(src, exifOrientation(data))
+	}
+
+	images := make([]Image, 0, len(Variants))
+	for _, v := range Variants {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, fit(src, v.Width, v.Height), &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		images = append(images, Image{Variant: v.Name, Data: buf.Bytes()})
+	}
+	return images, nil
+}
+
+// Key возвращает ключ варианта обложки с базой base (например, covers/<uuid>).
+func Key(base, variant string) string {
+	return base + "/" + variant + ext
+}
+
+// Keys возвращает ключи всех вариантов по ключу полного варианта, который хранится
+// в Book.CoverKey. Для обложек, загруженных до нарезки на варианты, возвращает nil.
+func Keys(coverKey string) map[string]string {
+	base, ok := strings.CutSuffix(coverKey, "/"+Full+ext)
+	if !ok || base == "" {
+		return nil
+	}
+	keys := make(map[string]string, len(Variants))
+	for _, v := range Variants {
+		keys[v.Name] = Key(base, v.Name)
+	}
+	return keys
+}
+
+// fit уменьшает src так, чтобы оно вписалось в maxW x maxH, и кладет на белый фон:
+// в JPEG нет прозрачности.
+func fit(src image.Image, maxW, maxH int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > maxW {
+		w, h = maxW, max(1, h*maxW/w)
+	}
+	if h > maxH {
+		w, h = max(1, w*maxH/h), maxH
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	if w == b.Dx() && h == b.Dy() {
+		draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Over)
+	} else {
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+	}
+	return dst
+}
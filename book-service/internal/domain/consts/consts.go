@@ -2,4 +2,69 @@ package consts
 
 import "time"
 
-const DBCtxTimeout = 5 * time.Second
\ No newline at end of file
+const (
+	DBCtxTimeout       = 5 * time.Second
+	DBBulkCtxTimeout   = time.Minute
+	DBExportCtxTimeout = 10 * time.Minute
+
+	UsersCtxTimeout   = 5 * time.Second
+	BasicAuthCacheTTL = 5 * time.Minute
+
+	PDFLinkTTL = 15 * time.Minute
+
+	DefaultTrashRetention = 30 * 24 * time.Hour
+	TrashPurgeInterval    = time.Hour
+
+	// CatalogMaxAge - сколько браузер и CDN держат публичные ответы каталога, не
+	// переспрашивая сервер; потом ответ проверяется по ETag или Last-Modified.
+	CatalogMaxAge = time.Minute
+
+	DefaultCacheSize = 1000
+	DefaultCacheTTL  = 30 * time.Second
+	CacheCtxTimeout  = 200 * time.Millisecond
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+
+	DefaultSuggestLimit = 10
+	MaxSuggestLimit     = 25
+
+	MaxImportSize = 32 << 20
+
+	MaxCoverSize   = 20 << 20
+	MaxCoverPixels = 40_000_000
+
+	MaxPDFSize  = 200 << 20
+	MaxEPUBSize = 200 << 20
+
+	FacetTopAuthors = 10
+	FacetYearBucket = 10
+
+	DefaultTagCloudLimit = 50
+	MaxTagCloudLimit     = 200
+
+	MinTagName = 2
+	MaxTagName = 64
+)
+
+const DefaultGenre = "Без жанра"
+
+// Форматы файлов книги.
+const (
+	FormatPDF  = "pdf"
+	FormatEPUB = "epub"
+)
+
+// Роли автора в книге.
+const (
+	RoleAuthor      = "author"
+	RoleTranslator  = "translator"
+	RoleIllustrator = "illustrator"
+)
+
+const (
+	SuggestKindTitle  = "title"
+	SuggestKindAuthor = "author"
+)
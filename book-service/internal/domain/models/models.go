@@ -1,5 +1,10 @@
 package models
 
+import (
+	"reflect"
+	"time"
+)
+
 type Book struct {
 	BID      string `json:"bid,omitempty"`
 	Lable    string `json:"lable" validate:"required,min=3"`
@@ -10,4 +15,431 @@ type Book struct {
 	Rating   int    `json:"rating"`
 	CoverURL string `json:"cover_url,omitempty"`
 	PDFURL   string `json:"pdf_url,omitempty"`
+	Version  int    `json:"version"`
+
+	// CoverURLs - ссылки на размерные варианты обложки (thumbnail, card, full);
+	// у обложек, загруженных до нарезки на варианты, пусто.
+	CoverURLs map[string]string `json:"cover_urls,omitempty"`
+
+	// EPUBURL ведет туда же, куда PDFURL, но для EPUB; Formats перечисляет все
+	// загруженные форматы книги.
+	EPUBURL string       `json:"epub_url,omitempty"`
+	Formats []BookFormat `json:"formats,omitempty"`
+
+	// CoverKey, PDFKey и EPUBKey - ключи файлов в BlobStore; ссылки на них сервер
+	// строит перед ответом и в хранилище не сохраняет.
+	CoverKey string `json:"-"`
+	PDFKey   string `json:"-"`
+	EPUBKey  string `json:"-"`
+
+	// Сведения о PDF, которые сервер извлекает из файла при загрузке; PDFHash - SHA-256
+	// содержимого, по нему отклоняются повторные загрузки одного и того же файла.
+	PDFPages  int    `json:"pdf_pages,omitempty"`
+	PDFTitle  string `json:"pdf_title,omitempty"`
+	PDFAuthor string `json:"pdf_author,omitempty"`
+	PDFSize   int64  `json:"pdf_size,omitempty"`
+	PDFHash   string `json:"pdf_hash,omitempty"`
+
+	// Сведения об EPUB и метаданные из его OPF-пакета. Language и ISBN берутся из
+	// EPUB, только если они еще не заданы. ISBN хранится как ISBN-13 и уникален в
+	// каталоге; ISBN10 только выводится для изданий с префиксом 978.
+	EPUBSize int64  `json:"epub_size,omitempty"`
+	EPUBHash string `json:"epub_hash,omitempty"`
+	Language string `json:"language,omitempty"`
+	ISBN     string `json:"isbn,omitempty"`
+	ISBN10   string `json:"isbn10,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	// UpdatedAt сдвигается при любом изменении того, что видно в карточке: книги,
+	// ее авторов, жанров, серий и тегов, соседей по серии, других изданий и
+	// популярности произведения; по нему сервер отдает Last-Modified.
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Книга каталога - издание произведения WorkID: перевод или выпуск другого
+	// издательства со своими файлами. Год издания хранится в Age. Popularity -
+	// скачивания всех изданий произведения; Editions - остальные его издания,
+	// заполняется только в карточке книги.
+	WorkID     string    `json:"work_id,omitempty"`
+	Publisher  string    `json:"publisher,omitempty"`
+	Popularity int       `json:"popularity"`
+	Editions   []Edition `json:"editions,omitempty"`
+
+	// Authors - связанные с книгой авторы, переводчики и иллюстраторы; заполняется
+	// только в карточке книги. Author остается строкой для вывода и поиска.
+	Authors []BookAuthor `json:"authors,omitempty"`
+
+	// Genres - жанры книги из дерева жанров; заполняется только в карточке книги.
+	// Genre остается строкой основного жанра для вывода и фасетов.
+	Genres []BookGenre `json:"genres,omitempty"`
+
+	// Series - серии, в которые входит книга, с соседними книгами; заполняется
+	// только в карточке книги.
+	Series []BookSeries `json:"series,omitempty"`
+
+	// Tags - одобренные теги книги; заполняется только в карточке книги.
+	Tags []BookTag `json:"tags,omitempty"`
+
+	// DeletedAt и DeletedBy заданы у книг в корзине: когда и каким администратором
+	// книга удалена. Такие книги не видны в каталоге до восстановления.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy string     `json:"deleted_by,omitempty"`
+
+	// Rank и Highlights заполняются только в результатах полнотекстового поиска.
+	Rank       float64           `json:"rank,omitempty"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// BookFormat - загруженный файл книги в одном формате; URL ведет на выдачу
+// подписанной ссылки, как PDFURL.
+type BookFormat struct {
+	Format string `json:"format"`
+	URL    string `json:"url"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+// BookPatch - изменения книги; nil означает, что поле не меняется.
+type BookPatch struct {
+	Lable     *string `json:"lable"`
+	Author    *string `json:"author"`
+	Desc      *string `json:"desc"`
+	Age       *int    `json:"age"`
+	Genre     *string `json:"genre"`
+	Rating    *int    `json:"rating"`
+	Language  *string `json:"language"`
+	ISBN      *string `json:"isbn"`
+	Publisher *string `json:"publisher"`
+	CoverKey  *string `json:"-"`
+	PDFKey    *string `json:"-"`
+	EPUBKey   *string `json:"-"`
+}
+
+// Apply возвращает копию книги с примененными изменениями.
+func (p BookPatch) Apply(book Book) Book {
+	setIf(&book.Lable, p.Lable)
+	setIf(&book.Author, p.Author)
+	setIf(&book.Desc, p.Desc)
+	setIf(&book.Age, p.Age)
+	setIf(&book.Genre, p.Genre)
+	setIf(&book.Rating, p.Rating)
+	setIf(&book.Language, p.Language)
+	setIf(&book.ISBN, p.ISBN)
+	setIf(&book.Publisher, p.Publisher)
+	setIf(&book.CoverKey, p.CoverKey)
+	setIf(&book.PDFKey, p.PDFKey)
+	setIf(&book.EPUBKey, p.EPUBKey)
+	return book
+}
+
+func setIf[T any](dst *T, src *T) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+// Download - скачивание файла книги пользователем по подписанной ссылке.
+type Download struct {
+	BID          string    `json:"bid"`
+	UID          string    `json:"uid"`
+	Format       string    `json:"format"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+}
+
+// SaveStatus - результат сохранения одной книги в SaveBooks.
+type SaveStatus struct {
+	BID       string
+	Duplicate bool
+}
+
+// Page задает размер страницы и непрозрачный курсор, полученный из предыдущего ответа.
+type Page struct {
+	Limit  int
+	Cursor string
+}
+
+// BookFilter описывает параметры поиска, сортировки и пагинации списка книг.
+// Genres - id или названия жанров (русские или английские, без учета регистра);
+// книга подходит, если у нее есть один из этих жанров или их поджанр.
+// AuthorID отбирает книги, связанные с автором (в роли AuthorRole, если она задана).
+// Tags - названия тегов без учета регистра; при TagsAll книга должна иметь все
+// теги, иначе хотя бы один. Учитываются только одобренные теги.
+type BookFilter struct {
+	Search     string
+	Genres     []string
+	Author     string
+	AuthorID   string
+	AuthorRole string
+	Year       string
+	Tags       []string
+	TagsAll    bool
+	SortBy     string
+	Ascending  bool
+	Page
+}
+
+type BooksPage struct {
+	Books      []Book      `json:"books"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Total      int         `json:"total"`
+	Facets     *BookFacets `json:"facets,omitempty"`
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// BookFacets - количество книг по значениям фильтров. Годы сгруппированы
+// по десятилетиям, значение вида "1990-1999" можно передать в фильтр year.
+type BookFacets struct {
+	Genres  []FacetCount `json:"genres"`
+	Years   []FacetCount `json:"years"`
+	Authors []FacetCount `json:"authors"`
+}
+
+// Suggestion - вариант автодополнения: название книги или имя автора.
+type Suggestion struct {
+	Kind  string  `json:"kind"`
+	Text  string  `json:"text"`
+	BID   string  `json:"bid,omitempty"`
+	Score float64 `json:"score"`
+}
+
+// ImportRow - строка файла импорта в отчете.
+type ImportRow struct {
+	Line   int    `json:"line"`
+	BID    string `json:"bid,omitempty"`
+	Lable  string `json:"lable,omitempty"`
+	Author string `json:"author,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport - итог импорта. В режиме dry_run книги не сохраняются,
+// а прошедшие проверку строки перечисляются в Valid.
+type ImportReport struct {
+	DryRun     bool        `json:"dry_run"`
+	Total      int         `json:"total"`
+	Valid      []ImportRow `json:"valid,omitempty"`
+	Inserted   []ImportRow `json:"inserted"`
+	Duplicates []ImportRow `json:"duplicates"`
+	Rejected   []ImportRow `json:"rejected"`
+}
+
+// Author - автор, переводчик или иллюстратор. AltNames - другие написания имени
+// ("Л. Толстой"), по ним автор находится при поиске и при добавлении книги.
+// Годы жизни 0, если неизвестны.
+type Author struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name" validate:"required,min=2"`
+	AltNames  []string          `json:"alt_names,omitempty"`
+	Bio       string            `json:"bio,omitempty"`
+	BirthYear int               `json:"birth_year,omitempty"`
+	DeathYear int               `json:"death_year,omitempty"`
+	PhotoURL  string            `json:"photo_url,omitempty"`
+	PhotoURLs map[string]string `json:"photo_urls,omitempty"`
+	BookCount int               `json:"book_count"`
+	CreatedAt time.Time         `json:"created_at"`
+
+	// PhotoKey - ключ полного варианта фотографии в BlobStore, как CoverKey у книги.
+	PhotoKey string `json:"-"`
+}
+
+// BookAuthor - связь книги с автором в одной из ролей consts.Role*.
+type BookAuthor struct {
+	AuthorID string `json:"author_id"`
+	Name     string `json:"name,omitempty"`
+	Role     string `json:"role"`
+}
+
+type AuthorsPage struct {
+	Authors    []Author `json:"authors"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+	Total      int      `json:"total"`
+}
+
+// Genre - узел дерева жанров. ParentID пуст у корневых жанров; Children заполняется
+// только при выдаче дерева. BookCount считает книги, привязанные к самому жанру.
+type Genre struct {
+	ID        string    `json:"id"`
+	ParentID  string    `json:"parent_id,omitempty"`
+	NameRU    string    `json:"name_ru" validate:"required,min=2"`
+	NameEN    string    `json:"name_en,omitempty"`
+	BookCount int       `json:"book_count"`
+	CreatedAt time.Time `json:"created_at"`
+	Children  []Genre   `json:"children,omitempty"`
+}
+
+// BookGenre - жанр в карточке книги.
+type BookGenre struct {
+	GenreID string `json:"id"`
+	NameRU  string `json:"name_ru"`
+	NameEN  string `json:"name_en,omitempty"`
+}
+
+// Series - серия книг. Books заполняется только в карточке серии, в порядке чтения.
+type Series struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name" validate:"required,min=2"`
+	Desc      string       `json:"desc,omitempty"`
+	BookCount int          `json:"book_count"`
+	CreatedAt time.Time    `json:"created_at"`
+	Books     []SeriesBook `json:"books,omitempty"`
+}
+
+// SeriesBook - книга на месте Position в серии. Position может быть дробной:
+// повесть между второй и третьей книгами получает 2.5. URL ведет на карточку книги.
+type SeriesBook struct {
+	BID      string  `json:"bid"`
+	Lable    string  `json:"lable"`
+	Author   string  `json:"author,omitempty"`
+	Position float64 `json:"position"`
+	URL      string  `json:"url,omitempty"`
+}
+
+// BookSeries - место книги в серии. Prev и Next - соседние книги в порядке чтения,
+// их нет у первой и последней книги.
+type BookSeries struct {
+	SeriesID string      `json:"series_id"`
+	Name     string      `json:"name,omitempty"`
+	Position float64     `json:"position"`
+	Prev     *SeriesBook `json:"prev,omitempty"`
+	Next     *SeriesBook `json:"next,omitempty"`
+}
+
+// Work - произведение: все переводы и издания одной книги. Отзывы и популярность
+// считаются по произведению, скачивания и корзина - по изданиям.
+type Work struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	Author     string    `json:"author"`
+	Popularity int       `json:"popularity"`
+	CreatedAt  time.Time `json:"created_at"`
+	Editions   []Edition `json:"editions"`
+}
+
+// Edition - издание произведения. Year - год издания (Age книги), URL ведет на
+// карточку книги.
+type Edition struct {
+	BID       string `json:"bid"`
+	Lable     string `json:"lable"`
+	Language  string `json:"language,omitempty"`
+	Publisher string `json:"publisher,omitempty"`
+	Year      int    `json:"year,omitempty"`
+	ISBN      string `json:"isbn,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// Статусы тега книги: теги администратора сразу одобрены, предложенные
+// пользователями ждут модерации и до одобрения не видны в карточке и фильтрах.
+const (
+	TagApproved = "approved"
+	TagPending  = "pending"
+)
+
+// Tag - тег каталога. BookCount - число книг с одобренным тегом, заполняется в
+// облаке тегов.
+type Tag struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	BookCount int    `json:"book_count"`
+}
+
+// BookTag - тег в карточке книги.
+type BookTag struct {
+	TagID string `json:"id"`
+	Name  string `json:"name"`
+}
+
+// TagSuggestion - тег, предложенный пользователем SuggestedBy и ожидающий модерации.
+type TagSuggestion struct {
+	BID         string    `json:"bid"`
+	Lable       string    `json:"lable"`
+	TagID       string    `json:"tag_id"`
+	Name        string    `json:"name"`
+	SuggestedBy string    `json:"suggested_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Действия в истории изменений книги.
+const (
+	HistoryCreate  = "create"
+	HistoryUpdate  = "update"
+	HistoryDelete  = "delete"
+	HistoryRestore = "restore"
+	HistoryRevert  = "revert"
+	HistoryPurge   = "purge"
+)
+
+// BookSnapshot - хранимые поля книги в ревизии истории. В отличие от Book в JSON
+// попадают ключи файлов, чтобы откат возвращал и прежние обложку и файлы.
+type BookSnapshot struct {
+	Lable     string `json:"lable"`
+	Author    string `json:"author"`
+	Desc      string `json:"desc"`
+	Age       int    `json:"age"`
+	Genre     string `json:"genre"`
+	Rating    int    `json:"rating"`
+	Language  string `json:"language"`
+	ISBN      string `json:"isbn"`
+	Publisher string `json:"publisher"`
+	CoverKey  string `json:"cover_key"`
+	PDFKey    string `json:"pdf_key"`
+	PDFPages  int    `json:"pdf_pages"`
+	PDFTitle  string `json:"pdf_title"`
+	PDFAuthor string `json:"pdf_author"`
+	PDFSize   int64  `json:"pdf_size"`
+	PDFHash   string `json:"pdf_hash"`
+	EPUBKey   string `json:"epub_key"`
+	EPUBSize  int64  `json:"epub_size"`
+	EPUBHash  string `json:"epub_hash"`
+}
+
+func NewBookSnapshot(book Book) BookSnapshot {
+	return BookSnapshot{
+		Lable: book.Lable, Author: book.Author, Desc: book.Desc, Age: book.Age, Genre: book.Genre,
+		Rating: book.Rating, Language: book.Language, ISBN: book.ISBN, Publisher: book.Publisher,
+		CoverKey: book.CoverKey, PDFKey: book.PDFKey, PDFPages: book.PDFPages, PDFTitle: book.PDFTitle,
+		PDFAuthor: book.PDFAuthor, PDFSize: book.PDFSize, PDFHash: book.PDFHash,
+		EPUBKey: book.EPUBKey, EPUBSize: book.EPUBSize, EPUBHash: book.EPUBHash,
+	}
+}
+
+// Apply возвращает книгу с полями из снимка; связи, версия и произведение не меняются.
+func (s BookSnapshot) Apply(book Book) Book {
+	book.Lable, book.Author, book.Desc, book.Age, book.Genre = s.Lable, s.Author, s.Desc, s.Age, s.Genre
+	book.Rating, book.Language, book.ISBN, book.Publisher = s.Rating, s.Language, s.ISBN, s.Publisher
+	book.CoverKey, book.PDFKey, book.PDFPages, book.PDFTitle = s.CoverKey, s.PDFKey, s.PDFPages, s.PDFTitle
+	book.PDFAuthor, book.PDFSize, book.PDFHash = s.PDFAuthor, s.PDFSize, s.PDFHash
+	book.EPUBKey, book.EPUBSize, book.EPUBHash = s.EPUBKey, s.EPUBSize, s.EPUBHash
+	return book
+}
+
+// Diff возвращает изменившиеся поля по их именам в JSON.
+func (s BookSnapshot) Diff(next BookSnapshot) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+	prev, cur := reflect.ValueOf(s), reflect.ValueOf(next)
+	for i := range prev.NumField() {
+		if old, value := prev.Field(i).Interface(), cur.Field(i).Interface(); old != value {
+			changes[prev.Type().Field(i).Tag.Get("json")] = FieldChange{Old: old, New: value}
+		}
+	}
+	return changes
+}
+
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// BookRevision - неизменяемая запись истории книги: кто (Actor, uid из JWT) и
+// когда изменил книгу, какие поля изменились и снимок книги после изменения.
+// RevertedTo - ревизия, к которой откатили книгу действием revert.
+type BookRevision struct {
+	BID        string                 `json:"bid"`
+	Revision   int                    `json:"revision"`
+	Action     string                 `json:"action"`
+	Actor      string                 `json:"actor"`
+	RevertedTo int                    `json:"reverted_to,omitempty"`
+	Changes    map[string]FieldChange `json:"changes,omitempty"`
+	Snapshot   BookSnapshot           `json:"snapshot"`
+	ChangedAt  time.Time              `json:"changed_at"`
 }
@@ -0,0 +1,285 @@
+// Package epubmeta проверяет загруженные EPUB и извлекает из OPF-пакета сведения для
+// карточки книги: название, авторов, язык, ISBN и встроенную обложку.
+//
+// EPUB - zip-архив с файлом mimetype, META-INF/container.xml и OPF-пакетом, путь к
+// которому указан в container.xml. Из архива читаются только эти файлы и обложка,
+// каждый с ограничением размера, чтобы zip-бомба не съела память.
+package epubmeta
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/isbn"
+)
+
+const (
+	mediaType  = "application/epub+zip"
+	maxXMLSize = 4 << 20
+)
+
+var (
+	ErrNotEPUB   = errors.New("file is not an EPUB")
+	ErrMalformed = errors.New("EPUB structure is damaged")
+	ErrTooLarge  = errors.New("EPUB file is too large")
+)
+
+// Info - сведения об EPUB. Поля метаданных пусты, если их нет в OPF; Cover пуст,
+// если обложка не найдена, не является растровым изображением или слишком большая.
+type Info struct {
+	Title    string
+	Authors  []string
+	Language string
+	ISBN     string
+	Cover    []byte
+	Size     int64
+	SHA256   string
+}
+
+type container struct {
+	Rootfiles []rootfile `xml:"rootfiles>rootfile"`
+}
+
+type rootfile struct {
+	FullPath  string `xml:"full-path,attr"`
+	MediaType string `xml:"media-type,attr"`
+}
+
+// opfPackage - нужная часть OPF. Элементы dc:* сопоставляются по локальному имени,
+// поэтому подходят и EPUB 2, и EPUB 3.
+type opfPackage struct {
+	Metadata struct {
+		Titles      []string     `xml:"title"`
+		Creators    []creator    `xml:"creator"`
+		Languages   []string     `xml:"language"`
+		Identifiers []identifier `xml:"identifier"`
+		Metas       []meta       `xml:"meta"`
+	} `xml:"metadata"`
+	Items []item `xml:"manifest>item"`
+}
+
+type creator struct {
+	ID   string `xml:"id,attr"`
+	Role string `xml:"role,attr"`
+	Name string `xml:",chardata"`
+}
+
+type identifier struct {
+	Scheme string `xml:"scheme,attr"`
+	Value  string `xml:",chardata"`
+}
+
+type meta struct {
+	Name     string `xml:"name,attr"`
+	Content  string `xml:"content,attr"`
+	Property string `xml:"property,attr"`
+	Refines  string `xml:"refines,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type item struct {
+	ID         string `xml:"id,attr"`
+	Href       string `xml:"href,attr"`
+	MediaType  string `xml:"media-type,attr"`
+	Properties string `xml:"properties,attr"`
+}
+
+// Inspect проверяет, что r - EPUB с читаемым OPF-пакетом, и возвращает его сведения.
+func Inspect(r io.ReaderAt, size int64) (Info, error) {
+	if size > consts.MaxEPUBSize {
+		return Info{}, ErrTooLarge
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return Info{}, ErrNotEPUB
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	mimetype, err := readFile(files, "mimetype", int64(len(mediaType))+2)
+	if err != nil || strings.TrimSpace(string(mimetype)) != mediaType {
+		return Info{}, ErrNotEPUB
+	}
+
+	info, err := inspect(files)
+	if err != nil {
+		return Info{}, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(r, 0, size)); err != nil {
+		return Info{}, err
+	}
+	info.Size = size
+	info.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return info, nil
+}
+
+func inspect(files map[string]*zip.File) (Info, error) {
+	data, err := readFile(files, "META-INF/container.xml", maxXMLSize)
+	if err != nil {
+		return Info{}, fmt.Errorf("container: %v", err)
+	}
+	var c container
+	if err := xml.Unmarshal(data, &c); err != nil {
+		return Info{}, fmt.Errorf("container: %v", err)
+	}
+	i := slices.IndexFunc(c.Rootfiles, func(rf rootfile) bool {
+		return rf.FullPath != "" && (rf.MediaType == "" || rf.MediaType == "application/oebps-package+xml")
+	})
+	if i < 0 {
+		return Info{}, fmt.Errorf("container has no package rootfile")
+	}
+	opfPath := c.Rootfiles[i].FullPath
+
+	data, err = readFile(files, opfPath, maxXMLSize)
+	if err != nil {
+		return Info{}, fmt.Errorf("package: %v", err)
+	}
+	var pkg opfPackage
+	if err := xml.Unmarshal(data, &pkg); err != nil {
+		return Info{}, fmt.Errorf("package: %v", err)
+	}
+
+	md := pkg.Metadata
+	info := Info{
+		Title:    first(md.Titles),
+		Language: first(md.Languages),
+		Authors:  authors(md.Creators, md.Metas),
+		ISBN:     findISBN(md.Identifiers),
+	}
+	if it, ok := coverItem(pkg); ok {
+		name, err := url.PathUnescape(it.Href)
+		if err == nil {
+			name = path.Join(path.Dir(opfPath), name)
+			// обложка необязательна: без нее книга загружается с файлом cover из формы
+			if cover, err := readFile(files, name, consts.MaxCoverSize); err == nil {
+				info.Cover = cover
+			}
+		}
+	}
+	return info, nil
+}
+
+// readFile читает файл архива name, если он не больше limit байт после распаковки.
+func readFile(files map[string]*zip.File, name string, limit int64) ([]byte, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("%s not found", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is too large", name)
+	}
+	return data, nil
+}
+
+func first(values []string) string {
+	for _, v := range values {
+		if v = clean(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// authors возвращает создателей с ролью aut или без роли. Роль задается атрибутом
+// opf:role (EPUB 2) или уточняющим meta property="role" (EPUB 3). Если авторов
+// среди них нет, возвращаются все создатели.
+func authors(creators []creator, metas []meta) []string {
+	roles := make(map[string]string)
+	for _, m := range metas {
+		if m.Property == "role" && strings.HasPrefix(m.Refines, "#") {
+			roles[m.Refines[1:]] = clean(m.Value)
+		}
+	}
+	var all, aut []string
+	for _, c := range creators {
+		name := clean(c.Name)
+		if name == "" {
+			continue
+		}
+		all = append(all, name)
+		role := c.Role
+		if c.ID != "" && roles[c.ID] != "" {
+			role = roles[c.ID]
+		}
+		if role == "" || role == "aut" {
+			aut = append(aut, name)
+		}
+	}
+	if len(aut) == 0 {
+		return all
+	}
+	return aut
+}
+
+// findISBN ищет ISBN среди dc:identifier: с атрибутом opf:scheme="ISBN" или в виде
+// urn:isbn:. Возвращается первый ISBN с верной контрольной цифрой в виде ISBN-13.
+func findISBN(ids []identifier) string {
+	for _, id := range ids {
+		v := clean(id.Value)
+		isURN := len(v) > 9 && strings.EqualFold(v[:9], "urn:isbn:")
+		if !isURN && !strings.EqualFold(id.Scheme, "isbn") {
+			continue
+		}
+		if n, err := isbn.Normalize(v); err == nil && n != "" {
+			return n
+		}
+	}
+	return ""
+}
+
+// coverItem находит обложку в манифесте: по properties="cover-image" (EPUB 3), по
+// <meta name="cover"> (EPUB 2) или, если ни того ни другого нет, по "cover" в id
+// или пути изображения. SVG не подходит: обложка перекодируется в JPEG.
+func coverItem(pkg opfPackage) (item, bool) {
+	images := slices.DeleteFunc(slices.Clone(pkg.Items), func(it item) bool {
+		return !strings.HasPrefix(it.MediaType, "image/") || it.MediaType == "image/svg+xml"
+	})
+	for _, it := range images {
+		if slices.Contains(strings.Fields(it.Properties), "cover-image") {
+			return it, true
+		}
+	}
+	for _, m := range pkg.Metadata.Metas {
+		if m.Name != "cover" {
+			continue
+		}
+		for _, it := range images {
+			if it.ID == m.Content {
+				return it, true
+			}
+		}
+	}
+	for _, it := range images {
+		if strings.Contains(strings.ToLower(it.ID+" "+it.Href), "cover") {
+			return it, true
+		}
+	}
+	return item{}, false
+}
@@ -0,0 +1,97 @@
+// Package isbn проверяет ISBN и приводит их к единому виду: книги хранятся с
+// ISBN-13 из одних цифр, поэтому одно издание, записанное как ISBN-10 или с
+// дефисами, находится по любому из написаний.
+package isbn
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalid = errors.New("invalid ISBN")
+
+// Normalize убирает префикс "ISBN", "urn:isbn:", дефисы и пробелы, проверяет
+// контрольную цифру и возвращает ISBN-13. ISBN-10 переводится в ISBN-13 с
+// префиксом 978. Пустая строка остается пустой.
+func Normalize(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 9 && strings.EqualFold(s[:9], "urn:isbn:") {
+		s = s[9:]
+	} else if len(s) >= 4 && strings.EqualFold(s[:4], "isbn") {
+		s = strings.TrimLeft(s[4:], ":- ")
+	}
+	s = strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(s))
+
+	switch {
+	case s == "":
+		return "", nil
+	case len(s) == 10 && valid10(s):
+		body := "978" + s[:9]
+		return body + string(check13(body)), nil
+	case len(s) == 13 && digits(s) && check13(s[:12]) == s[12]:
+		return s, nil
+	}
+	return "", ErrInvalid
+}
+
+// To10 возвращает ISBN-10 для нормализованного ISBN-13 с префиксом 978; у ISBN-13
+// с префиксом 979 аналога в ISBN-10 нет, для них возвращается пустая строка.
+func To10(isbn13 string) string {
+	if len(isbn13) != 13 || !strings.HasPrefix(isbn13, "978") {
+		return ""
+	}
+	body := isbn13[3:12]
+	sum := 0
+	for i, c := range body {
+		sum += (10 - i) * int(c-'0')
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return body + "X"
+	}
+	return body + string(rune('0'+check))
+}
+
+func valid10(s string) bool {
+	sum := 0
+	for i, c := range s {
+		var d int
+		switch {
+		case c >= '0' && c <= '9':
+			d = int(c - '0')
+		case c == 'X' && i == 9:
+			d = 10
+		default:
+			return false
+		}
+		sum += (10 - i) * d
+	}
+	return sum%11 == 0
+}
+
+// check13 возвращает контрольную цифру для первых 12 цифр ISBN-13.
+func check13(body string) byte {
+	sum := 0
+	for i, c := range body {
+		d := int(c - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func digits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,203 @@
+// Package opds описывает Atom-фиды OPDS 1.2 и OpenSearch description для e-reader приложений.
+package opds
+
+import (
+	"encoding/xml"
+	"io"
+	"mime"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+)
+
+const (
+	NavigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	AcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	OpenSearchType  = "application/opensearchdescription+xml"
+
+	RelStart       = "start"
+	RelSelf        = "self"
+	RelUp          = "up"
+	RelNext        = "next"
+	RelSearch      = "search"
+	RelSubsection  = "subsection"
+	RelAcquisition = "http://opds-spec.org/acquisition"
+	RelImage       = "http://opds-spec.org/image"
+	RelThumbnail   = "http://opds-spec.org/image/thumbnail"
+	RelSortNew     = "http://opds-spec.org/sort/new"
+	RelSortPopular = "http://opds-spec.org/sort/popular"
+)
+
+const (
+	atomNS       = "http://www.w3.org/2005/Atom"
+	dcNS         = "http://purl.org/dc/terms/"
+	opdsNS       = "http://opds-spec.org/2010/catalog"
+	openSearchNS = "http://a9.com/-/spec/opensearch/1.1/"
+)
+
+type Feed struct {
+	XMLName         xml.Name `xml:"feed"`
+	Xmlns           string   `xml:"xmlns,attr"`
+	XmlnsDC         string   `xml:"xmlns:dc,attr"`
+	XmlnsOPDS       string   `xml:"xmlns:opds,attr"`
+	XmlnsOpenSearch string   `xml:"xmlns:opensearch,attr"`
+
+	ID           string  `xml:"id"`
+	Title        string  `xml:"title"`
+	Updated      string  `xml:"updated"`
+	Author       *Person `xml:"author,omitempty"`
+	TotalResults int     `xml:"opensearch:totalResults,omitempty"`
+	ItemsPerPage int     `xml:"opensearch:itemsPerPage,omitempty"`
+	Links        []Link  `xml:"link"`
+	Entries      []Entry `xml:"entry"`
+}
+
+type Entry struct {
+	ID         string     `xml:"id"`
+	Title      string     `xml:"title"`
+	Updated    string     `xml:"updated"`
+	Authors    []Person   `xml:"author"`
+	Issued     string     `xml:"dc:issued,omitempty"`
+	Language   string     `xml:"dc:language,omitempty"`
+	Identifier string     `xml:"dc:identifier,omitempty"`
+	Categories []Category `xml:"category"`
+	Summary    *Text      `xml:"summary,omitempty"`
+	Content    *Text      `xml:"content,omitempty"`
+	Links      []Link     `xml:"link"`
+}
+
+type Person struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type Category struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr,omitempty"`
+}
+
+type Text struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type Link struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+// NewFeed создает пустой фид с объявленными пространствами имен OPDS.
+func NewFeed(id, title string, updated time.Time) *Feed {
+	return &Feed{
+		Xmlns:           atomNS,
+		XmlnsDC:         dcNS,
+		XmlnsOPDS:       opdsNS,
+		XmlnsOpenSearch: openSearchNS,
+		ID:              id,
+		Title:           title,
+		Updated:         timestamp(updated),
+		Author:          &Person{Name: "bookly"},
+	}
+}
+
+// NavigationEntry - пункт навигационного фида, ведущий в другой фид.
+func NavigationEntry(id, title, text, rel, href, feedType string, updated time.Time) Entry {
+	return Entry{
+		ID:      id,
+		Title:   title,
+		Updated: timestamp(updated),
+		Content: &Text{Type: "text", Value: text},
+		Links:   []Link{{Rel: rel, Href: href, Type: feedType}},
+	}
+}
+
+// BookEntry - книга в фиде получения. Ссылки на обложку, ее миниатюру и файлы книги
+// (acquisitions, по одной на формат) должны быть абсолютными; пустая ссылка на
+// обложку не попадает в запись.
+func BookEntry(book models.Book, coverURL, thumbnailURL string, acquisitions []Link) Entry {
+	entry := Entry{
+		ID:      BookID(book.BID),
+		Title:   book.Lable,
+		Updated: timestamp(book.CreatedAt),
+		Authors: []Person{{Name: book.Author}},
+		Summary: &Text{Type: "text", Value: book.Desc},
+	}
+	if book.Age > 0 {
+		entry.Issued = strconv.Itoa(book.Age)
+	}
+	entry.Language = book.Language
+	if book.ISBN != "" {
+		entry.Identifier = "urn:isbn:" + book.ISBN
+	}
+	if book.Genre != "" {
+		entry.Categories = []Category{{Term: book.Genre, Label: book.Genre}}
+	}
+	if coverURL != "" {
+		entry.Links = append(entry.Links, Link{Rel: RelImage, Href: coverURL, Type: mime.TypeByExtension(path.Ext(coverURL))})
+	}
+	if thumbnailURL != "" {
+		entry.Links = append(entry.Links, Link{Rel: RelThumbnail, Href: thumbnailURL, Type: mime.TypeByExtension(path.Ext(thumbnailURL))})
+	}
+	for _, link := range acquisitions {
+		link.Rel = RelAcquisition
+		entry.Links = append(entry.Links, link)
+	}
+	return entry
+}
+
+func BookID(bid string) string {
+	return "urn:bookly:book:" + bid
+}
+
+type OpenSearchDescription struct {
+	XMLName        xml.Name        `xml:"OpenSearchDescription"`
+	Xmlns          string          `xml:"xmlns,attr"`
+	ShortName      string          `xml:"ShortName"`
+	Description    string          `xml:"Description"`
+	InputEncoding  string          `xml:"InputEncoding"`
+	OutputEncoding string          `xml:"OutputEncoding"`
+	URLs           []OpenSearchURL `xml:"Url"`
+}
+
+type OpenSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// NewOpenSearchDescription описывает поиск по каталогу; {searchTerms} подставляет клиент.
+func NewOpenSearchDescription(urls ...OpenSearchURL) *OpenSearchDescription {
+	return &OpenSearchDescription{
+		Xmlns:          openSearchNS,
+		ShortName:      "bookly",
+		Description:    "Поиск книг bookly",
+		InputEncoding:  "UTF-8",
+		OutputEncoding: "UTF-8",
+		URLs:           urls,
+	}
+}
+
+// Write пишет документ с XML-заголовком.
+func Write(w io.Writer, doc any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// timestamp форматирует время для atom:updated; у книг из старых записей его может не быть.
+func timestamp(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,136 @@
+package pdfmeta
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+// maxStreamSize ограничивает распакованный поток: служебным потокам (xref, объекты)
+// больше не нужно, а zip-бомба не должна съесть память.
+const maxStreamSize = 64 << 20
+
+// maxPredictorColumns ограничивает ширину строки PNG-предсказания: в потоках xref
+// строка - несколько байт, а огромный /Columns не должен приводить к огромному буферу.
+const maxPredictorColumns = 1 << 16
+
+// readStream читает и распаковывает данные потока. Поддерживается только FlateDecode:
+// им сжаты потоки перекрестных ссылок и объектов во всех распространенных генераторах.
+func (d *document) readStream(s stream) ([]byte, error) {
+	length, err := d.resolveInt(s.dict["Length"])
+	// length сравнивается с остатком файла: s.offset+length переполняется на огромных /Length
+	if err != nil || length < 0 || s.offset < 0 || s.offset > d.size || length > d.size-s.offset {
+		return nil, fmt.Errorf("bad stream length")
+	}
+	raw := make([]byte, length)
+	if _, err := d.r.ReadAt(raw, s.offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	filter, err := d.resolve(s.dict["Filter"])
+	if err != nil {
+		return nil, err
+	}
+	if arr, ok := filter.(array); ok && len(arr) == 1 {
+		filter = arr[0]
+	}
+	switch filter {
+	case nil:
+		return raw, nil
+	case name("FlateDecode"):
+	default:
+		return nil, fmt.Errorf("unsupported stream filter %v", filter)
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(zr, maxStreamSize+1))
+	if err != nil && len(data) == 0 {
+		return nil, err
+	}
+	if len(data) > maxStreamSize {
+		return nil, fmt.Errorf("stream too large")
+	}
+
+	params, err := d.resolve(s.dict["DecodeParms"])
+	if err != nil {
+		return nil, err
+	}
+	if arr, ok := params.(array); ok && len(arr) == 1 {
+		params, _ = d.resolve(arr[0])
+	}
+	if p, ok := params.(dict); ok {
+		predictor, _ := d.resolveInt(p["Predictor"])
+		if predictor >= 10 {
+			columns, _ := d.resolveInt(p["Columns"])
+			return unpredictPNG(data, int(min(max(columns, 1), maxPredictorColumns+1)))
+		}
+		if predictor > 1 {
+			return nil, fmt.Errorf("unsupported predictor %d", predictor)
+		}
+	}
+	return data, nil
+}
+
+// unpredictPNG снимает PNG-предсказание: каждая строка из columns байт предваряется
+// байтом типа фильтра. Потоки xref пишутся с одним байтом на пиксель.
+func unpredictPNG(data []byte, columns int) ([]byte, error) {
+	if columns < 1 || columns > maxPredictorColumns {
+		return nil, fmt.Errorf("bad predictor columns %d", columns)
+	}
+	rowLen := columns + 1
+	if len(data)%rowLen != 0 {
+		return nil, fmt.Errorf("bad predictor row length")
+	}
+	out := make([]byte, 0, len(data)/rowLen*columns)
+	prev := make([]byte, columns)
+	for i := 0; i < len(data); i += rowLen {
+		kind, row := data[i], append([]byte(nil), data[i+1:i+rowLen]...)
+		for j := range row {
+			var left, upLeft byte
+			if j > 0 {
+				left, upLeft = row[j-1], prev[j-1]
+			}
+			up := prev[j]
+			switch kind {
+			case 0:
+			case 1:
+				row[j] += left
+			case 2:
+				row[j] += up
+			case 3:
+				row[j] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[j] += paeth(left, up, upLeft)
+			default:
+				return nil, fmt.Errorf("bad predictor %d", kind)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	default:
+		return c
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,409 @@
+package pdfmeta
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Объекты PDF: nil, bool, int64, float64, []byte (строка), name, array, dict, ref, stream.
+type (
+	name  string
+	array []any
+	dict  map[name]any
+	ref   struct{ num, gen int }
+
+	// keyword - слово вне объекта: obj, endobj, stream, xref, trailer и т.п.
+	keyword string
+)
+
+// stream - словарь потока и смещение его данных в файле.
+type stream struct {
+	dict   dict
+	offset int64
+}
+
+const (
+	chunkSize = 4096
+	maxDepth  = 64
+)
+
+// lexer читает объекты PDF с произвольного смещения r, подгружая файл кусками.
+type lexer struct {
+	r    io.ReaderAt
+	size int64
+	pos  int64
+	buf  []byte
+	off  int64
+	err  error
+}
+
+func newLexer(r io.ReaderAt, size, pos int64) *lexer {
+	return &lexer{r: r, size: size, pos: pos}
+}
+
+func (l *lexer) peek() (byte, bool) {
+	if l.pos < 0 || l.pos >= l.size || l.err != nil {
+		return 0, false
+	}
+	if l.pos < l.off || l.pos >= l.off+int64(len(l.buf)) {
+		buf := make([]byte, min(chunkSize, l.size-l.pos))
+		if _, err := l.r.ReadAt(buf, l.pos); err != nil && err != io.EOF {
+			l.err = err
+			return 0, false
+		}
+		l.buf, l.off = buf, l.pos
+	}
+	return l.buf[l.pos-l.off], true
+}
+
+func (l *lexer) next() (byte, bool) {
+	c, ok := l.peek()
+	if ok {
+		l.pos++
+	}
+	return c, ok
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isDelim(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+func isRegular(c byte) bool {
+	return !isSpace(c) && !isDelim(c)
+}
+
+// skipSpace пропускает пробелы и комментарии.
+func (l *lexer) skipSpace() {
+	for {
+		c, ok := l.peek()
+		switch {
+		case !ok:
+			return
+		case isSpace(c):
+			l.pos++
+		case c == '%':
+			for c != '\n' && c != '\r' && ok {
+				c, ok = l.next()
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) word() string {
+	start := l.pos
+	for {
+		c, ok := l.peek()
+		if !ok || !isRegular(c) {
+			break
+		}
+		l.pos++
+	}
+	return l.slice(start)
+}
+
+// slice возвращает байты файла от start до текущей позиции; слова короткие,
+// поэтому обычно они уже в буфере.
+func (l *lexer) slice(start int64) string {
+	if start >= l.off && l.pos <= l.off+int64(len(l.buf)) {
+		return string(l.buf[start-l.off : l.pos-l.off])
+	}
+	buf := make([]byte, l.pos-start)
+	if _, err := l.r.ReadAt(buf, start); err != nil && err != io.EOF {
+		l.err = err
+	}
+	return string(buf)
+}
+
+func (l *lexer) failf(format string, args ...any) error {
+	if l.err != nil {
+		return l.err
+	}
+	return fmt.Errorf("offset %d: "+format, append([]any{l.pos}, args...)...)
+}
+
+// readObject читает следующий объект или ключевое слово. Ссылка "N G R" собирается
+// из трех лексем, поэтому после целого числа читается вперед.
+func (l *lexer) readObject(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, l.failf("objects nested too deep")
+	}
+	l.skipSpace()
+	c, ok := l.peek()
+	if !ok {
+		return nil, l.failf("unexpected end of file")
+	}
+	switch {
+	case c == '/':
+		l.pos++
+		return l.readName()
+	case c == '(':
+		l.pos++
+		return l.readLiteral()
+	case c == '<':
+		l.pos++
+		if c, _ := l.peek(); c == '<' {
+			l.pos++
+			return l.readDict(depth)
+		}
+		return l.readHex()
+	case c == '[':
+		l.pos++
+		return l.readArray(depth)
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return l.readNumber()
+	case isRegular(c):
+		switch w := l.word(); w {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		default:
+			return keyword(w), nil
+		}
+	default:
+		l.pos++
+		return keyword([]byte{c}), nil
+	}
+}
+
+func (l *lexer) readNumber() (any, error) {
+	w := l.word()
+	if n, err := strconv.ParseInt(w, 10, 64); err == nil {
+		if n >= 0 {
+			if r, ok := l.tryRef(n); ok {
+				return r, nil
+			}
+		}
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(w, 64)
+	if err != nil {
+		return nil, l.failf("bad number %q", w)
+	}
+	return f, nil
+}
+
+// tryRef проверяет, не продолжается ли число num как ссылка "num gen R".
+func (l *lexer) tryRef(num int64) (ref, bool) {
+	save := l.pos
+	l.skipSpace()
+	if gen, err := strconv.Atoi(l.word()); err == nil && gen >= 0 {
+		l.skipSpace()
+		if l.word() == "R" {
+			return ref{int(num), gen}, true
+		}
+	}
+	l.pos = save
+	return ref{}, false
+}
+
+func (l *lexer) readName() (name, error) {
+	var b []byte
+	for {
+		c, ok := l.peek()
+		if !ok || !isRegular(c) {
+			return name(b), nil
+		}
+		l.pos++
+		if c == '#' {
+			h1, _ := l.next()
+			h2, _ := l.next()
+			v, err := hex.DecodeString(string([]byte{h1, h2}))
+			if err != nil {
+				return "", l.failf("bad name escape")
+			}
+			c = v[0]
+		}
+		b = append(b, c)
+	}
+}
+
+func (l *lexer) readLiteral() ([]byte, error) {
+	var b []byte
+	depth := 1
+	for {
+		c, ok := l.next()
+		if !ok {
+			return nil, l.failf("unterminated string")
+		}
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return b, nil
+			}
+		case '\r':
+			// конец строки внутри строки всегда читается как \n
+			if c, _ := l.peek(); c == '\n' {
+				l.pos++
+			}
+			c = '\n'
+		case '\\':
+			c, ok = l.next()
+			if !ok {
+				return nil, l.failf("unterminated string")
+			}
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r', '\n':
+				// перенос строки, экранированный обратной косой чертой, пропускается
+				if n, _ := l.peek(); c == '\r' && n == '\n' {
+					l.pos++
+				}
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					v := c - '0'
+					for i := 0; i < 2; i++ {
+						d, ok := l.peek()
+						if !ok || d < '0' || d > '7' {
+							break
+						}
+						l.pos++
+						v = v*8 + d - '0'
+					}
+					c = v
+				}
+			}
+		}
+		b = append(b, c)
+	}
+}
+
+func (l *lexer) readHex() ([]byte, error) {
+	var digits []byte
+	for {
+		c, ok := l.next()
+		if !ok {
+			return nil, l.failf("unterminated hex string")
+		}
+		if c == '>' {
+			break
+		}
+		if !isSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b, err := hex.DecodeString(string(digits))
+	if err != nil {
+		return nil, l.failf("bad hex string")
+	}
+	return b, nil
+}
+
+func (l *lexer) readArray(depth int) (array, error) {
+	var arr array
+	for {
+		obj, err := l.readObject(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if obj == keyword("]") {
+			return arr, nil
+		}
+		if _, ok := obj.(keyword); ok {
+			return nil, l.failf("unexpected %q in array", obj)
+		}
+		arr = append(arr, obj)
+	}
+}
+
+func (l *lexer) readDict(depth int) (dict, error) {
+	d := make(dict)
+	for {
+		l.skipSpace()
+		if c, _ := l.peek(); c == '>' {
+			l.pos++
+			if c, _ := l.next(); c != '>' {
+				return nil, l.failf("bad dictionary end")
+			}
+			return d, nil
+		}
+		key, err := l.readObject(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		k, ok := key.(name)
+		if !ok {
+			return nil, l.failf("dictionary key is not a name")
+		}
+		value, err := l.readObject(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := value.(keyword); ok {
+			return nil, l.failf("unexpected %q in dictionary", value)
+		}
+		d[k] = value
+	}
+}
+
+// readIndirect читает "num gen obj <объект>" и, если за словарем идет поток,
+// возвращает stream со смещением его данных.
+func (l *lexer) readIndirect() (int, any, error) {
+	num, err := l.readObject(0)
+	if err != nil {
+		return 0, nil, err
+	}
+	n, ok := num.(int64)
+	if !ok {
+		return 0, nil, l.failf("object number expected")
+	}
+	if gen, err := l.readObject(0); err != nil {
+		return 0, nil, err
+	} else if _, ok := gen.(int64); !ok {
+		return 0, nil, l.failf("generation number expected")
+	}
+	if kw, err := l.readObject(0); err != nil {
+		return 0, nil, err
+	} else if kw != keyword("obj") {
+		return 0, nil, l.failf("obj keyword expected")
+	}
+
+	obj, err := l.readObject(0)
+	if err != nil {
+		return 0, nil, err
+	}
+	d, ok := obj.(dict)
+	if !ok {
+		return int(n), obj, nil
+	}
+	save := l.pos
+	l.skipSpace()
+	if l.word() != "stream" {
+		l.pos = save
+		return int(n), d, nil
+	}
+	// после stream идет CRLF или LF, затем данные
+	if c, _ := l.peek(); c == '\r' {
+		l.pos++
+	}
+	if c, _ := l.peek(); c == '\n' {
+		l.pos++
+	}
+	return int(n), stream{dict: d, offset: l.pos}, nil
+}
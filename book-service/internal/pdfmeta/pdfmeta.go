@@ -0,0 +1,469 @@
+// Package pdfmeta проверяет загруженные PDF и извлекает из них сведения для карточки
+// книги: число страниц, название и автора из словаря Info и SHA-256 содержимого.
+//
+// Разбирается ровно столько структуры, сколько нужно: заголовок, таблицы или потоки
+// перекрестных ссылок (включая /Prev и потоки объектов), каталог и дерево страниц.
+// Файл читается через io.ReaderAt и целиком в память не загружается.
+package pdfmeta
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+)
+
+const (
+	headerSize = 1024
+	tailSize   = 2048
+	maxXrefs   = 64
+)
+
+var (
+	ErrNotPDF    = errors.New("file is not a PDF")
+	ErrMalformed = errors.New("PDF structure is damaged")
+	ErrTooLarge  = errors.New("PDF file is too large")
+)
+
+// Info - сведения о PDF. Title и Author пусты, если их нет в словаре Info
+// или документ зашифрован.
+type Info struct {
+	Pages  int
+	Title  string
+	Author string
+	Size   int64
+	SHA256 string
+}
+
+// xrefEntry - положение объекта: смещение в файле или номер потока объектов и индекс в нем.
+type xrefEntry struct {
+	offset   int64
+	objStm   int
+	index    int
+	inStream bool
+}
+
+type document struct {
+	r       io.ReaderAt
+	size    int64
+	xref    map[int]xrefEntry
+	trailer dict
+	objStms map[int]*objStm
+	depth   int
+}
+
+// objStm - распакованный поток объектов: смещения объектов отсчитываются от first.
+type objStm struct {
+	data    []byte
+	first   int64
+	offsets []int64
+}
+
+// Inspect проверяет, что r - PDF с целой структурой, и возвращает его сведения.
+func Inspect(r io.ReaderAt, size int64) (Info, error) {
+	if size > consts.MaxPDFSize {
+		return Info{}, ErrTooLarge
+	}
+	header := make([]byte, min(headerSize, size))
+	if _, err := r.ReadAt(header, 0); err != nil && err != io.EOF {
+		return Info{}, err
+	}
+	if !bytes.HasPrefix(header, []byte("%PDF-")) || len(header) < 8 || header[5] < '1' || header[5] > '2' {
+		return Info{}, ErrNotPDF
+	}
+
+	d := &document{r: r, size: size, xref: make(map[int]xrefEntry), objStms: make(map[int]*objStm)}
+	info, err := d.inspect()
+	if err != nil {
+		return Info{}, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(r, 0, size)); err != nil {
+		return Info{}, err
+	}
+	info.Size = size
+	info.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return info, nil
+}
+
+func (d *document) inspect() (Info, error) {
+	start, err := d.startXref()
+	if err != nil {
+		return Info{}, err
+	}
+	if err := d.loadXrefs(start); err != nil {
+		return Info{}, err
+	}
+
+	root, err := d.resolveDict(d.trailer["Root"])
+	if err != nil {
+		return Info{}, fmt.Errorf("catalog: %v", err)
+	}
+	pages, err := d.resolveDict(root["Pages"])
+	if err != nil {
+		return Info{}, fmt.Errorf("page tree: %v", err)
+	}
+	count, err := d.resolveInt(pages["Count"])
+	if err != nil || count < 1 {
+		return Info{}, fmt.Errorf("document has no pages")
+	}
+	info := Info{Pages: int(count)}
+
+	// строки зашифрованного документа без ключа не прочитать
+	if _, encrypted := d.trailer["Encrypt"]; encrypted {
+		return info, nil
+	}
+	if meta, err := d.resolveDict(d.trailer["Info"]); err == nil {
+		info.Title = d.text(meta["Title"])
+		info.Author = d.text(meta["Author"])
+	}
+	return info, nil
+}
+
+// startXref находит смещение последней секции перекрестных ссылок по startxref в конце файла.
+func (d *document) startXref() (int64, error) {
+	n := min(tailSize, d.size)
+	tail := make([]byte, n)
+	if _, err := d.r.ReadAt(tail, d.size-n); err != nil && err != io.EOF {
+		return 0, err
+	}
+	i := bytes.LastIndex(tail, []byte("startxref"))
+	if i < 0 || !bytes.Contains(tail[i:], []byte("%%EOF")) {
+		return 0, fmt.Errorf("startxref or %%%%EOF not found")
+	}
+	fields := bytes.Fields(tail[i+len("startxref"):])
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("bad startxref")
+	}
+	offset, err := strconv.ParseInt(string(fields[0]), 10, 64)
+	if err != nil || offset <= 0 || offset >= d.size {
+		return 0, fmt.Errorf("bad startxref")
+	}
+	return offset, nil
+}
+
+// loadXrefs читает цепочку секций от последней к первой. Запись из более новой
+// секции перекрывает старую, поэтому уже известные номера не перезаписываются.
+func (d *document) loadXrefs(offset int64) error {
+	seen := make(map[int64]bool)
+	pending := []int64{offset}
+	for len(pending) > 0 {
+		offset, pending = pending[0], pending[1:]
+		if seen[offset] {
+			continue
+		}
+		if seen[offset] = true; len(seen) > maxXrefs {
+			return fmt.Errorf("too many xref sections")
+		}
+
+		trailer, err := d.loadXref(offset)
+		if err != nil {
+			return err
+		}
+		if d.trailer == nil {
+			d.trailer = trailer
+		}
+		// в гибридных файлах таблица дополняется потоком из /XRefStm
+		for _, key := range []name{"XRefStm", "Prev"} {
+			if next, ok := trailer[key].(int64); ok {
+				pending = append(pending, next)
+			}
+		}
+	}
+	if d.trailer["Root"] == nil {
+		return fmt.Errorf("trailer has no /Root")
+	}
+	return nil
+}
+
+func (d *document) loadXref(offset int64) (dict, error) {
+	l := newLexer(d.r, d.size, offset)
+	l.skipSpace()
+	save := l.pos
+	if l.word() == "xref" {
+		return d.loadXrefTable(l)
+	}
+	l.pos = save
+
+	_, obj, err := l.readIndirect()
+	if err != nil {
+		return nil, err
+	}
+	s, ok := obj.(stream)
+	if !ok || s.dict["Type"] != name("XRef") {
+		return nil, fmt.Errorf("offset %d: xref section expected", offset)
+	}
+	return s.dict, d.loadXrefStream(s)
+}
+
+// loadXrefTable читает классическую таблицу: подсекции "start count" из записей
+// "offset gen n|f" и словарь trailer после них.
+func (d *document) loadXrefTable(l *lexer) (dict, error) {
+	for {
+		obj, err := l.readObject(0)
+		if err != nil {
+			return nil, err
+		}
+		if obj == keyword("trailer") {
+			obj, err := l.readObject(0)
+			if err != nil {
+				return nil, err
+			}
+			trailer, ok := obj.(dict)
+			if !ok {
+				return nil, l.failf("trailer dictionary expected")
+			}
+			return trailer, nil
+		}
+		start, ok1 := obj.(int64)
+		countObj, err := l.readObject(0)
+		if err != nil {
+			return nil, err
+		}
+		count, ok2 := countObj.(int64)
+		if !ok1 || !ok2 || start < 0 || count < 0 {
+			return nil, l.failf("bad xref subsection")
+		}
+		for i := int64(0); i < count; i++ {
+			offset, _ := l.readObject(0)
+			gen, _ := l.readObject(0)
+			kind, err := l.readObject(0)
+			if err != nil {
+				return nil, err
+			}
+			off, ok1 := offset.(int64)
+			_, ok2 := gen.(int64)
+			if !ok1 || !ok2 || (kind != keyword("n") && kind != keyword("f")) {
+				return nil, l.failf("bad xref entry")
+			}
+			num := int(start + i)
+			if _, known := d.xref[num]; !known && kind == keyword("n") {
+				d.xref[num] = xrefEntry{offset: off}
+			}
+		}
+	}
+}
+
+// loadXrefStream читает поток перекрестных ссылок: строки по /W байт на поле,
+// номера объектов - по парам /Index.
+func (d *document) loadXrefStream(s stream) error {
+	data, err := d.readStream(s)
+	if err != nil {
+		return err
+	}
+	widthsObj, _ := s.dict["W"].(array)
+	if len(widthsObj) != 3 {
+		return fmt.Errorf("bad xref stream /W")
+	}
+	var widths [3]int
+	rowLen := 0
+	for i, w := range widthsObj {
+		n, ok := w.(int64)
+		if !ok || n < 0 || n > 8 {
+			return fmt.Errorf("bad xref stream /W")
+		}
+		widths[i] = int(n)
+		rowLen += int(n)
+	}
+	if rowLen == 0 {
+		return fmt.Errorf("bad xref stream /W")
+	}
+
+	index, _ := s.dict["Index"].(array)
+	if index == nil {
+		size, _ := s.dict["Size"].(int64)
+		index = array{int64(0), size}
+	}
+	pos := 0
+	for i := 0; i+1 < len(index); i += 2 {
+		start, ok1 := index[i].(int64)
+		count, ok2 := index[i+1].(int64)
+		if !ok1 || !ok2 || start < 0 || count < 0 {
+			return fmt.Errorf("bad xref stream /Index")
+		}
+		for j := int64(0); j < count; j++ {
+			if pos+rowLen > len(data) {
+				return fmt.Errorf("xref stream is truncated")
+			}
+			var fields [3]int64
+			for k, w := range widths {
+				for _, b := range data[pos : pos+w] {
+					fields[k] = fields[k]<<8 | int64(b)
+				}
+				pos += w
+			}
+			if widths[0] == 0 {
+				fields[0] = 1 // тип по умолчанию - обычный объект
+			}
+			num := int(start + j)
+			if _, known := d.xref[num]; known {
+				continue
+			}
+			switch fields[0] {
+			case 1:
+				d.xref[num] = xrefEntry{offset: fields[1]}
+			case 2:
+				d.xref[num] = xrefEntry{objStm: int(fields[1]), index: int(fields[2]), inStream: true}
+			}
+		}
+	}
+	return nil
+}
+
+// object возвращает объект по номеру: из файла или из потока объектов.
+func (d *document) object(num int) (any, error) {
+	entry, ok := d.xref[num]
+	if !ok {
+		return nil, nil // по спецификации ссылка на отсутствующий объект - это null
+	}
+	if !entry.inStream {
+		got, obj, err := newLexer(d.r, d.size, entry.offset).readIndirect()
+		if err != nil {
+			return nil, err
+		}
+		if got != num {
+			return nil, fmt.Errorf("object %d: xref points to object %d", num, got)
+		}
+		return obj, nil
+	}
+
+	stm, err := d.objStm(entry.objStm)
+	if err != nil {
+		return nil, err
+	}
+	if entry.index < 0 || entry.index >= len(stm.offsets) {
+		return nil, fmt.Errorf("object %d: bad object stream index", num)
+	}
+	data := bytes.NewReader(stm.data)
+	return newLexer(data, data.Size(), stm.first+stm.offsets[entry.index]).readObject(0)
+}
+
+func (d *document) objStm(num int) (*objStm, error) {
+	if stm, ok := d.objStms[num]; ok {
+		return stm, nil
+	}
+	obj, err := d.resolve(ref{num: num})
+	if err != nil {
+		return nil, err
+	}
+	s, ok := obj.(stream)
+	if !ok || s.dict["Type"] != name("ObjStm") {
+		return nil, fmt.Errorf("object %d is not an object stream", num)
+	}
+	data, err := d.readStream(s)
+	if err != nil {
+		return nil, err
+	}
+	n, err1 := d.resolveInt(s.dict["N"])
+	first, err2 := d.resolveInt(s.dict["First"])
+	if err1 != nil || err2 != nil || n < 0 || first < 0 || first > int64(len(data)) {
+		return nil, fmt.Errorf("object %d: bad object stream header", num)
+	}
+
+	// заголовок потока - пары "номер смещение"
+	stm := &objStm{data: data, first: first}
+	l := newLexer(bytes.NewReader(data), first, 0)
+	for i := int64(0); i < n; i++ {
+		_, err1 := l.readObject(0)
+		offset, err2 := l.readObject(0)
+		off, ok := offset.(int64)
+		if err1 != nil || err2 != nil || !ok || first+off > int64(len(data)) {
+			return nil, fmt.Errorf("object %d: bad object stream header", num)
+		}
+		stm.offsets = append(stm.offsets, off)
+	}
+	d.objStms[num] = stm
+	return stm, nil
+}
+
+// resolve разыменовывает ссылки; глубина ограничена, чтобы циклы не зациклили разбор.
+func (d *document) resolve(obj any) (any, error) {
+	for i := 0; ; i++ {
+		r, ok := obj.(ref)
+		if !ok {
+			return obj, nil
+		}
+		if i > maxDepth || d.depth > maxDepth {
+			return nil, fmt.Errorf("reference chain too long")
+		}
+		d.depth++
+		var err error
+		obj, err = d.object(r.num)
+		d.depth--
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (d *document) resolveDict(obj any) (dict, error) {
+	obj, err := d.resolve(obj)
+	if err != nil {
+		return nil, err
+	}
+	switch v := obj.(type) {
+	case dict:
+		return v, nil
+	case stream:
+		return v.dict, nil
+	}
+	return nil, fmt.Errorf("dictionary expected")
+}
+
+func (d *document) resolveInt(obj any) (int64, error) {
+	obj, err := d.resolve(obj)
+	if err != nil {
+		return 0, err
+	}
+	switch v := obj.(type) {
+	case int64:
+		return v, nil
+	case float64:
+		return int64(v), nil
+	}
+	return 0, fmt.Errorf("integer expected")
+}
+
+// text декодирует текстовую строку PDF: UTF-16BE или UTF-8 с BOM, иначе PDFDocEncoding,
+// который для печатных символов совпадает с Latin-1.
+func (d *document) text(obj any) string {
+	obj, err := d.resolve(obj)
+	if err != nil {
+		return ""
+	}
+	b, ok := obj.([]byte)
+	if !ok {
+		return ""
+	}
+	var s string
+	switch {
+	case bytes.HasPrefix(b, []byte{0xFE, 0xFF}):
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		s = string(utf16.Decode(units))
+	case bytes.HasPrefix(b, []byte{0xEF, 0xBB, 0xBF}):
+		s = strings.ToValidUTF8(string(b[3:]), "")
+	default:
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		s = string(runes)
+	}
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r < ' ' {
+			return -1
+		}
+		return r
+	}, s))
+}
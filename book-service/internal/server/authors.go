@@ -0,0 +1,312 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+var errInvalidLifeYears = errors.New("death_year must not be less than birth_year")
+
+// authorRoles - допустимые роли в PUT /books/:id/authors.
+var authorRoles = []string{consts.RoleAuthor, consts.RoleTranslator, consts.RoleIllustrator}
+
+// ListAuthors (GET /authors?q=&limit=&cursor=) возвращает страницу авторов по имени;
+// q ищется в имени и других написаниях.
+func (s *Server) ListAuthors(ctx *gin.Context) {
+	page, err := pageFromQuery(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	authors, err := s.Storage.ListAuthors(strings.TrimSpace(ctx.Query("q")), page)
+	if err != nil {
+		if errors.Is(err, storerrros.ErrInvalidCursor) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if authors.Authors == nil {
+		authors.Authors = []models.Author{}
+	}
+	for i := range authors.Authors {
+		authors.Authors[i] = withAuthorURLs(authors.Authors[i])
+	}
+	ctx.JSON(http.StatusOK, authors)
+}
+
+// AuthorInfo (GET /authors/:id) возвращает карточку автора.
+func (s *Server) AuthorInfo(ctx *gin.Context) {
+	author, ok := s.findAuthor(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, withAuthorURLs(author))
+}
+
+// AuthorBooks (GET /authors/:id/books?role=&sort_by=&ascending=&limit=&cursor=) возвращает
+// книги автора; role оставляет только книги, где автор в этой роли. У существующего
+// автора без книг возвращается пустая страница.
+func (s *Server) AuthorBooks(ctx *gin.Context) {
+	page, err := pageFromQuery(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	role := ctx.Query("role")
+	if role != "" && !slices.Contains(authorRoles, role) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid role: " + role})
+		return
+	}
+	author, ok := s.findAuthor(ctx)
+	if !ok {
+		return
+	}
+
+	books, err := s.Storage.GetBooksWithFilters(models.BookFilter{
+		AuthorID:   author.ID,
+		AuthorRole: role,
+		SortBy:     ctx.DefaultQuery("sort_by", "created"),
+		Ascending:  ctx.DefaultQuery("ascending", "false") == "true",
+		Page:       page,
+	})
+	if err != nil {
+		if errors.Is(err, storerrros.ErrEmptyBooksList) {
+			ctx.JSON(http.StatusOK, models.BooksPage{Books: []models.Book{}})
+			return
+		}
+		if errors.Is(err, storerrros.ErrInvalidCursor) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, withPageURLs(books))
+}
+
+// AddAuthor (POST /authors) создает автора из JSON или multipart-формы; в форме можно
+// передать фотографию в поле photo.
+func (s *Server) AddAuthor(ctx *gin.Context) {
+	log := logger.Get()
+
+	author, err := authorFromRequest(ctx)
+	if err == nil {
+		err = s.validAuthor(author)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if hasUpload(ctx, "photo") {
+		if author.PhotoKey, err = s.saveImage(ctx, "photo", photoPrefix); err != nil {
+			writeUploadError(ctx, err)
+			return
+		}
+	}
+
+	saved, err := s.Storage.SaveAuthor(author)
+	if err != nil {
+		s.removeUpload(author.PhotoKey)
+		log.Error().Err(err).Msg("save author failed")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusCreated, withAuthorURLs(saved))
+}
+
+// UpdateAuthor (PUT /authors/:id) заменяет сведения об авторе. Фотография меняется,
+// только если в форме пришло поле photo.
+func (s *Server) UpdateAuthor(ctx *gin.Context) {
+	log := logger.Get()
+
+	author, err := authorFromRequest(ctx)
+	if err == nil {
+		err = s.validAuthor(author)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	current, ok := s.findAuthor(ctx)
+	if !ok {
+		return
+	}
+	author.ID = current.ID
+	author.PhotoKey = current.PhotoKey
+
+	if hasUpload(ctx, "photo") {
+		if author.PhotoKey, err = s.saveImage(ctx, "photo", photoPrefix); err != nil {
+			writeUploadError(ctx, err)
+			return
+		}
+	}
+
+	updated, err := s.Storage.UpdateAuthor(author)
+	if err != nil {
+		if author.PhotoKey != current.PhotoKey {
+			s.removeUpload(author.PhotoKey)
+		}
+		if errors.Is(err, storerrros.ErrAuthorNoExist) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Error().Err(err).Msg("update author failed")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if updated.PhotoKey != current.PhotoKey {
+		s.removeUpload(current.PhotoKey)
+	}
+	ctx.JSON(http.StatusOK, withAuthorURLs(updated))
+}
+
+// RemoveAuthor (DELETE /authors/:id) удаляет автора и его фотографию. Книги автора
+// остаются в каталоге, пропадают только связи с ними.
+func (s *Server) RemoveAuthor(ctx *gin.Context) {
+	log := logger.Get()
+
+	author, ok := s.findAuthor(ctx)
+	if !ok {
+		return
+	}
+	if err := s.Storage.DeleteAuthor(author.ID); err != nil {
+		if errors.Is(err, storerrros.ErrAuthorNoExist) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Error().Err(err).Msg("failed to delete author")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete author"})
+		return
+	}
+	s.removeUpload(author.PhotoKey)
+	ctx.JSON(http.StatusOK, gin.H{"message": "author deleted"})
+}
+
+// SetBookAuthors (PUT /books/:id/authors) заменяет авторов книги списком
+// [{"author_id": ..., "role": ...}] в порядке вывода; роль по умолчанию - author.
+func (s *Server) SetBookAuthors(ctx *gin.Context) {
+	log := logger.Get()
+
+	var authors []models.BookAuthor
+	if err := ctx.ShouldBindJSON(&authors); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	for i := range authors {
+		if authors[i].AuthorID == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "missing author_id"})
+			return
+		}
+		if authors[i].Role == "" {
+			authors[i].Role = consts.RoleAuthor
+		}
+		if !slices.Contains(authorRoles, authors[i].Role) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid role: " + authors[i].Role})
+			return
+		}
+	}
+
+	bid := ctx.Param("id")
+	if err := s.Storage.SetBookAuthors(bid, authors); err != nil {
+		switch {
+		case errors.Is(err, storerrros.ErrBookNoExist), errors.Is(err, storerrros.ErrAuthorNoExist):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			log.Error().Err(err).Msg("failed to set book authors")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	book, err := s.Storage.GetBook(bid)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, withURLs(book))
+}
+
+// findAuthor загружает автора из параметра :id; при ошибке ответ уже записан.
+func (s *Server) findAuthor(ctx *gin.Context) (models.Author, bool) {
+	author, err := s.Storage.GetAuthor(ctx.Param("id"))
+	if err != nil {
+		if errors.Is(err, storerrros.ErrAuthorNoExist) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return models.Author{}, false
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return models.Author{}, false
+	}
+	return author, true
+}
+
+func (s *Server) validAuthor(author models.Author) error {
+	if err := s.valid.Struct(author); err != nil {
+		return err
+	}
+	if author.BirthYear < 0 || author.DeathYear < 0 {
+		return errors.New("invalid life years")
+	}
+	if author.BirthYear != 0 && author.DeathYear != 0 && author.DeathYear < author.BirthYear {
+		return errInvalidLifeYears
+	}
+	return nil
+}
+
+// authorFromRequest читает автора из multipart-формы (alt_names можно передать
+// несколько раз) или JSON. Пробелы в именах схлопываются, повторы написаний убираются.
+func authorFromRequest(ctx *gin.Context) (models.Author, error) {
+	var author models.Author
+
+	if ctx.ContentType() == gin.MIMEMultipartPOSTForm {
+		if err := ctx.Request.ParseMultipartForm(10 << 20); err != nil {
+			return models.Author{}, errors.New("failed to parse multipart form")
+		}
+		values := ctx.Request.MultipartForm.Value
+		author.Name = ctx.Request.FormValue("name")
+		author.AltNames = values["alt_names"]
+		author.Bio = ctx.Request.FormValue("bio")
+		for _, f := range []struct {
+			name string
+			dst  *int
+		}{{"birth_year", &author.BirthYear}, {"death_year", &author.DeathYear}} {
+			v := ctx.Request.FormValue(f.name)
+			if v == "" {
+				continue
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return models.Author{}, errors.New("invalid " + f.name + " value")
+			}
+			*f.dst = n
+		}
+	} else if err := ctx.ShouldBindJSON(&author); err != nil {
+		return models.Author{}, errors.New("invalid request body")
+	}
+
+	author.Name = strings.Join(strings.Fields(author.Name), " ")
+	altNames := make([]string, 0, len(author.AltNames))
+	for _, name := range author.AltNames {
+		name = strings.Join(strings.Fields(name), " ")
+		if name != "" && !strings.EqualFold(name, author.Name) && !slices.Contains(altNames, name) {
+			altNames = append(altNames, name)
+		}
+	}
+	author.AltNames = altNames
+	author.ID, author.PhotoKey, author.PhotoURL, author.PhotoURLs, author.BookCount = "", "", "", nil, 0
+	return author, nil
+}
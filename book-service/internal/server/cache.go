@@ -0,0 +1,120 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+)
+
+const jsonContentType = "application/json; charset=utf-8"
+
+// Cache-Control для публичных маршрутов каталога и для маршрутов с авторизацией:
+// ответы с JWT могут зависеть от пользователя и не должны оседать в общих кешах.
+var (
+	publicCacheControl  = "public, max-age=" + strconv.Itoa(int(consts.CatalogMaxAge.Seconds()))
+	privateCacheControl = "private, no-cache"
+)
+
+// contentETag возвращает сильный ETag тела ответа: он совпадает только у
+// побайтно одинаковых ответов.
+func contentETag(prefix string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + prefix + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// bookETag - ETag карточки книги. Перед хешем стоит версия книги, чтобы тот же
+// ETag можно было передать в If-Match при изменении, см. ifMatchVersion.
+func bookETag(version int, body []byte) string {
+	return contentETag(strconv.Itoa(version)+"-", body)
+}
+
+// renderBook сериализует карточку книги и возвращает ее вместе с ETag.
+func renderBook(book models.Book) ([]byte, string, error) {
+	body, err := json.Marshal(withURLs(book))
+	if err != nil {
+		return nil, "", err
+	}
+	return body, bookETag(book.Version, body), nil
+}
+
+// writeBook отдает карточку книги после изменения: с ETag для следующего If-Match,
+// но без проверки условных заголовков.
+func writeBook(ctx *gin.Context, book models.Book) {
+	body, etag, err := renderBook(book)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.Header("ETag", etag)
+	ctx.Data(http.StatusOK, jsonContentType, body)
+}
+
+// writeCachedBook отдает карточку книги на GET с ETag и Last-Modified по UpdatedAt.
+func writeCachedBook(ctx *gin.Context, book models.Book) {
+	body, etag, err := renderBook(book)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	writeCached(ctx, etag, book.UpdatedAt, body)
+}
+
+// writeCachedJSON отдает JSON-ответ на GET с ETag по содержимому. Last-Modified у
+// списков нет: удаление книги со страницы не сдвигает ни одну дату на ней, и
+// If-Modified-Since вернул бы устаревший список.
+func writeCachedJSON(ctx *gin.Context, obj any) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	writeCached(ctx, contentETag("", body), time.Time{}, body)
+}
+
+// writeCached ставит заголовки кеширования и отвечает 304 без тела, если клиент
+// прислал совпадающий If-None-Match или, без него, If-Modified-Since не раньше
+// modified. Cache-Control, уже заданный маршрутом, не меняется.
+func writeCached(ctx *gin.Context, etag string, modified time.Time, body []byte) {
+	ctx.Header("ETag", etag)
+	if !modified.IsZero() {
+		ctx.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if ctx.Writer.Header().Get("Cache-Control") == "" {
+		ctx.Header("Cache-Control", publicCacheControl)
+	}
+
+	if notModified(ctx.Request, etag, modified) {
+		ctx.Status(http.StatusNotModified)
+		ctx.Writer.WriteHeaderNow()
+		return
+	}
+	ctx.Data(http.StatusOK, jsonContentType, body)
+}
+
+// notModified проверяет условные заголовки по RFC 9110: If-None-Match сравнивается
+// слабым сравнением и отменяет If-Modified-Since.
+func notModified(req *http.Request, etag string, modified time.Time) bool {
+	if match := req.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil || modified.IsZero() {
+		return false
+	}
+	return !modified.Truncate(time.Second).After(since)
+}
@@ -0,0 +1,285 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/azaliaz/bookly/book-service/internal/cache"
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+)
+
+// cacheGenerationKey - счетчик поколения кеша. Он входит во все ключи, поэтому
+// любое изменение каталога делает недействительными сразу все записи, в том числе
+// у других реплик с общим Redis.
+const cacheGenerationKey = "bookly:books:generation"
+
+// CacheStats - счетчики read-through кеша хранилища. Errors - обращения к кешу,
+// завершившиеся ошибкой: такие чтения идут напрямую в хранилище.
+type CacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	Errors uint64 `json:"errors"`
+}
+
+// CachedStorage - read-through кеш поверх Storage для GetBook, GetBooks и
+// GetBooksWithFilters. Все методы, меняющие книги и их связи, сбрасывают кеш.
+// Скачивания (популярность) и рейтинг, который меняют другие сервисы, кеш не
+// сбрасывают: они обновятся не позже чем через ttl.
+type CachedStorage struct {
+	Storage
+	backend cache.Backend
+	ttl     time.Duration
+
+	hits, misses, errors atomic.Uint64
+}
+
+func NewCachedStorage(stor Storage, backend cache.Backend, ttl time.Duration) *CachedStorage {
+	return &CachedStorage{Storage: stor, backend: backend, ttl: ttl}
+}
+
+func (c *CachedStorage) CacheStats() CacheStats {
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), Errors: c.errors.Load()}
+}
+
+// StorageCacheStats (GET /cache/stats) возвращает счетчики кеша хранилища или 404,
+// если кеш выключен.
+func (s *Server) StorageCacheStats(ctx *gin.Context) {
+	cached, ok := s.Storage.(*CachedStorage)
+	if !ok {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "storage cache is disabled"})
+		return
+	}
+	ctx.JSON(http.StatusOK, cached.CacheStats())
+}
+
+func (c *CachedStorage) GetBook(bid string) (models.Book, error) {
+	return cached(c, "book", bid, func() (models.Book, error) { return c.Storage.GetBook(bid) })
+}
+
+func (c *CachedStorage) GetBooks(page models.Page) (models.BooksPage, error) {
+	if page.Limit <= 0 {
+		page.Limit = consts.DefaultPageLimit
+	}
+	return cached(c, "page", queryKey(page), func() (models.BooksPage, error) { return c.Storage.GetBooks(page) })
+}
+
+func (c *CachedStorage) GetBooksWithFilters(filter models.BookFilter) (models.BooksPage, error) {
+	filter = normalizeFilter(filter)
+	return cached(c, "filter", queryKey(filter), func() (models.BooksPage, error) {
+		return c.Storage.GetBooksWithFilters(filter)
+	})
+}
+
+// cached возвращает значение из кеша или загружает его через load и кладет в кеш.
+// Ошибки load не кешируются; если недоступен сам кеш, чтение идет в хранилище.
+func cached[T any](c *CachedStorage, kind, key string, load func() (T, error)) (T, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.CacheCtxTimeout)
+	defer cancel()
+
+	generation, err := c.generation(ctx)
+	if err != nil {
+		c.errors.Add(1)
+		log.Warn().Err(err).Msg("storage cache is unavailable")
+		return load()
+	}
+	key = "bookly:books:" + generation + ":" + kind + ":" + key
+
+	var value T
+	data, ok, err := c.backend.Get(ctx, key)
+	if err == nil && ok && gob.NewDecoder(bytes.NewReader(data)).Decode(&value) == nil {
+		c.hits.Add(1)
+		return value, nil
+	}
+	if err != nil {
+		c.errors.Add(1)
+		log.Warn().Err(err).Str("key", key).Msg("failed to read storage cache")
+	}
+	c.misses.Add(1)
+
+	if value, err = load(); err != nil {
+		return value, err
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		log.Error().Err(err).Str("key", key).Msg("failed to encode cache value")
+		return value, nil
+	}
+	if err := c.backend.Set(ctx, key, buf.Bytes(), c.ttl); err != nil {
+		c.errors.Add(1)
+		log.Warn().Err(err).Str("key", key).Msg("failed to write storage cache")
+	}
+	return value, nil
+}
+
+// generation возвращает текущее поколение кеша; пока каталог не менялся, его нет.
+func (c *CachedStorage) generation(ctx context.Context) (string, error) {
+	data, ok, err := c.backend.Get(ctx, cacheGenerationKey)
+	if err != nil || !ok {
+		return "0", err
+	}
+	return string(data), nil
+}
+
+// invalidate сбрасывает кеш, начиная новое поколение. Если кеш недоступен,
+// устаревшие записи истекут через ttl.
+func (c *CachedStorage) invalidate() {
+	ctx, cancel := context.WithTimeout(context.Background(), consts.CacheCtxTimeout)
+	defer cancel()
+	if _, err := c.backend.Incr(ctx, cacheGenerationKey); err != nil {
+		c.errors.Add(1)
+		log := logger.Get()
+		log.Error().Err(err).Msg("failed to invalidate storage cache")
+	}
+}
+
+// queryKey - короткий ключ кеша для параметров запроса.
+func queryKey(query any) string {
+	data, _ := json.Marshal(query)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// normalizeFilter приводит фильтр к виду, в котором одинаковые по смыслу запросы
+// дают один ключ кеша: регистр поиска, жанров и тегов и порядок жанров и тегов
+// на выдачу не влияют.
+func normalizeFilter(filter models.BookFilter) models.BookFilter {
+	filter.Search = strings.ToLower(filter.Search)
+	filter.Genres = normalizeValues(filter.Genres)
+	filter.Tags = normalizeValues(filter.Tags)
+	if len(filter.Tags) == 0 {
+		filter.TagsAll = false
+	}
+	if filter.AuthorID == "" {
+		filter.AuthorRole = ""
+	}
+	filter.Year = strings.TrimSpace(filter.Year)
+	if filter.Limit <= 0 {
+		filter.Limit = consts.DefaultPageLimit
+	}
+	return filter
+}
+
+func normalizeValues(values []string) []string {
+	var result []string
+	for _, v := range values {
+		if v = strings.ToLower(strings.Join(strings.Fields(v), " ")); v != "" {
+			result = append(result, v)
+		}
+	}
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+func (c *CachedStorage) SaveBook(book models.Book, actor string) error {
+	defer c.invalidate()
+	return c.Storage.SaveBook(book, actor)
+}
+
+func (c *CachedStorage) SaveBooks(books []models.Book, actor string) ([]models.SaveStatus, error) {
+	defer c.invalidate()
+	return c.Storage.SaveBooks(books, actor)
+}
+
+func (c *CachedStorage) DeleteBook(bid string, uid string) error {
+	defer c.invalidate()
+	return c.Storage.DeleteBook(bid, uid)
+}
+
+func (c *CachedStorage) RestoreBook(bid string, uid string) error {
+	defer c.invalidate()
+	return c.Storage.RestoreBook(bid, uid)
+}
+
+func (c *CachedStorage) UpdateBook(book models.Book, version int, actor string) (models.Book, error) {
+	defer c.invalidate()
+	return c.Storage.UpdateBook(book, version, actor)
+}
+
+func (c *CachedStorage) RevertBook(bid string, revision int, actor string) (models.Book, error) {
+	defer c.invalidate()
+	return c.Storage.RevertBook(bid, revision, actor)
+}
+
+func (c *CachedStorage) UpdateAuthor(author models.Author) (models.Author, error) {
+	defer c.invalidate()
+	return c.Storage.UpdateAuthor(author)
+}
+
+func (c *CachedStorage) DeleteAuthor(id string) error {
+	defer c.invalidate()
+	return c.Storage.DeleteAuthor(id)
+}
+
+func (c *CachedStorage) SetBookAuthors(bid string, authors []models.BookAuthor) error {
+	defer c.invalidate()
+	return c.Storage.SetBookAuthors(bid, authors)
+}
+
+func (c *CachedStorage) UpdateGenre(genre models.Genre) (models.Genre, error) {
+	defer c.invalidate()
+	return c.Storage.UpdateGenre(genre)
+}
+
+func (c *CachedStorage) DeleteGenre(id string) error {
+	defer c.invalidate()
+	return c.Storage.DeleteGenre(id)
+}
+
+func (c *CachedStorage) SetBookGenres(bid string, ids []string) error {
+	defer c.invalidate()
+	return c.Storage.SetBookGenres(bid, ids)
+}
+
+func (c *CachedStorage) UpdateSeries(series models.Series) (models.Series, error) {
+	defer c.invalidate()
+	return c.Storage.UpdateSeries(series)
+}
+
+func (c *CachedStorage) DeleteSeries(id string) error {
+	defer c.invalidate()
+	return c.Storage.DeleteSeries(id)
+}
+
+func (c *CachedStorage) SetBookSeries(bid string, entries []models.BookSeries) error {
+	defer c.invalidate()
+	return c.Storage.SetBookSeries(bid, entries)
+}
+
+func (c *CachedStorage) SetBookWork(bid string, workID string) error {
+	defer c.invalidate()
+	return c.Storage.SetBookWork(bid, workID)
+}
+
+func (c *CachedStorage) DeleteTag(id string) error {
+	defer c.invalidate()
+	return c.Storage.DeleteTag(id)
+}
+
+func (c *CachedStorage) SetBookTags(bid string, names []string) error {
+	defer c.invalidate()
+	return c.Storage.SetBookTags(bid, names)
+}
+
+func (c *CachedStorage) ApproveBookTag(bid, tagID string) error {
+	defer c.invalidate()
+	return c.Storage.ApproveBookTag(bid, tagID)
+}
+
+func (c *CachedStorage) RemoveBookTag(bid, tagID string) error {
+	defer c.invalidate()
+	return c.Storage.RemoveBookTag(bid, tagID)
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/azaliaz/bookly/book-service/internal/blob"
+	"github.com/azaliaz/bookly/book-service/internal/covers"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+// BackfillReport - итог BackfillCovers.
+type BackfillReport struct {
+	Processed int `json:"processed"`
+	Skipped   int `json:"skipped"`
+	Failed    int `json:"failed"`
+}
+
+// backfillActor - автор ревизий, которые пишет BackfillCovers.
+const backfillActor = "backfill"
+
+// BackfillCovers нарезает на варианты обложки, загруженные до появления cover_urls:
+// сохраняет варианты и переключает на них CoverKey. Исходный файл остается - на него
+// ссылается история книги, и откат к ней вернет рабочую обложку; файл удалится вместе
+// с книгой при очистке корзины. Книги, измененные или удаленные во время обработки,
+// пропускаются - их подхватит повторный запуск. Обложки, которые не удалось
+// обработать, остаются как есть.
+func (s *Server) BackfillCovers(ctx context.Context) (BackfillReport, error) {
+	log := logger.Get()
+	var report BackfillReport
+
+	// сначала собираем книги, а обновляем после: во время обхода хранилище может
+	// держать соединение или блокировку
+	var pending []models.Book
+	err := s.Storage.StreamBooks(models.BookFilter{}, func(book models.Book) error {
+		if book.CoverKey != "" && covers.Keys(book.CoverKey) == nil {
+			pending = append(pending, book)
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		return report, err
+	}
+
+	for _, book := range pending {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		err := s.backfillCover(ctx, book)
+		switch {
+		case err == nil:
+			report.Processed++
+		case errors.Is(err, storerrros.ErrVersionConflict), errors.Is(err, storerrros.ErrBookNoExist),
+			errors.Is(err, blob.ErrNotFound):
+			log.Warn().Err(err).Str("bid", book.BID).Str("key", book.CoverKey).Msg("cover backfill skipped")
+			report.Skipped++
+		default:
+			log.Error().Err(err).Str("bid", book.BID).Str("key", book.CoverKey).Msg("cover backfill failed")
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+func (s *Server) backfillCover(ctx context.Context, book models.Book) error {
+	obj, err := s.Blobs.Get(ctx, book.CoverKey)
+	if err != nil {
+		return err
+	}
+	images, err := covers.Process(obj.Body)
+	obj.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	key, err := s.putImages(ctx, coverPrefix, images)
+	if err != nil {
+		return err
+	}
+	book.CoverKey = key
+	if _, err := s.Storage.UpdateBook(book, book.Version, backfillActor); err != nil {
+		s.removeUpload(key)
+		return err
+	}
+	return nil
+}
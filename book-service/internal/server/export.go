@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/azaliaz/bookly/book-service/internal/catalog"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+)
+
+// ExportBooks (GET /books/export?format=csv|jsonl|onix) выгружает каталог потоком.
+// Поддерживаются те же фильтры, что и у /books/search; ссылки на обложки и PDF
+// в выгрузке абсолютные.
+func (s *Server) ExportBooks(ctx *gin.Context) {
+	log := logger.Get()
+
+	export, err := catalog.LookupExport(ctx.DefaultQuery("format", catalog.FormatCSV))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.Header("Content-Type", export.ContentType)
+	ctx.Header("Content-Disposition", `attachment; filename="books.`+export.Extension+`"`)
+	ctx.Status(http.StatusOK)
+
+	writer, err := catalog.NewWriter(ctx.Writer, export)
+	if err != nil {
+		log.Error().Err(err).Msg("export books failed")
+		return
+	}
+
+	base := s.publicURL
+	count := 0
+	err = s.Storage.StreamBooks(filterFromQuery(ctx), func(book models.Book) error {
+		book = withURLs(book)
+		book.CoverURL = absoluteURL(base, book.CoverURL)
+		for variant, u := range book.CoverURLs {
+			book.CoverURLs[variant] = absoluteURL(base, u)
+		}
+		book.PDFURL = absoluteURL(base, book.PDFURL)
+		book.EPUBURL = absoluteURL(base, book.EPUBURL)
+		for i, f := range book.Formats {
+			book.Formats[i].URL = absoluteURL(base, f.URL)
+		}
+		count++
+		return writer.Write(book)
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		// статус уже отправлен, клиент увидит оборванный файл
+		log.Error().Err(err).Int("written", count).Msg("export books failed")
+		return
+	}
+	log.Info().Int("books", count).Msg("books exported")
+}
+
+func absoluteURL(base, path string) string {
+	if path == "" || strings.Contains(path, "://") {
+		return path
+	}
+	return base + path
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+var (
+	errInvalidSignature = errors.New("invalid download signature")
+	errLinkExpired      = errors.New("download link expired")
+)
+
+// bookFile описывает скачиваемый формат книги: префикс ключей в BlobStore, тип
+// содержимого и поле книги с ключом файла.
+type bookFile struct {
+	format      string
+	prefix      string
+	contentType string
+	key         func(models.Book) string
+}
+
+// bookFiles - поддерживаемые форматы файлов книги.
+var bookFiles = []bookFile{
+	{consts.FormatPDF, pdfPrefix, "application/pdf", func(b models.Book) string { return b.PDFKey }},
+	{consts.FormatEPUB, epubPrefix, "application/epub+zip", func(b models.Book) string { return b.EPUBKey }},
+}
+
+func fileFormat(format string) bookFile {
+	for _, f := range bookFiles {
+		if f.format == format {
+			return f
+		}
+	}
+	panic("unknown book format " + format)
+}
+
+// PDFLink (GET /books/:id/pdf) выдает авторизованному пользователю ссылку на PDF,
+// подписанную HMAC и действующую consts.PDFLinkTTL. Ссылка привязана к пользователю,
+// чтобы скачивания по ней записывались на него.
+func (s *Server) PDFLink(ctx *gin.Context) {
+	s.fileLink(ctx, consts.FormatPDF)
+}
+
+// EPUBLink (GET /books/:id/epub) - то же, что PDFLink, для EPUB.
+func (s *Server) EPUBLink(ctx *gin.Context) {
+	s.fileLink(ctx, consts.FormatEPUB)
+}
+
+// OPDSDownloadPDF (GET /opds/books/:id/pdf) перенаправляет OPDS-клиент на свежую
+// подписанную ссылку: ссылки в фиде не должны истекать, пока клиент листает каталог.
+func (s *Server) OPDSDownloadPDF(ctx *gin.Context) {
+	s.opdsDownload(ctx, consts.FormatPDF)
+}
+
+// OPDSDownloadEPUB (GET /opds/books/:id/epub) - то же, что OPDSDownloadPDF, для EPUB.
+func (s *Server) OPDSDownloadEPUB(ctx *gin.Context) {
+	s.opdsDownload(ctx, consts.FormatEPUB)
+}
+
+// DownloadPDF (GET /books/:id/pdf/download?uid=&expires=&signature=) отдает PDF по
+// подписанной ссылке. Поддерживаются Range-запросы, чтобы читалки в браузере могли
+// подгружать документ частями.
+func (s *Server) DownloadPDF(ctx *gin.Context) {
+	s.downloadFile(ctx, consts.FormatPDF)
+}
+
+// DownloadEPUB (GET /books/:id/epub/download?uid=&expires=&signature=) отдает EPUB
+// по подписанной ссылке.
+func (s *Server) DownloadEPUB(ctx *gin.Context) {
+	s.downloadFile(ctx, consts.FormatEPUB)
+}
+
+func (s *Server) fileLink(ctx *gin.Context, format string) {
+	book, ok := s.fileBook(ctx, format)
+	if !ok {
+		return
+	}
+	expires := time.Now().Add(consts.PDFLinkTTL)
+	ctx.JSON(http.StatusOK, gin.H{
+		"url":        s.signedFileURL(format, book.BID, ctx.GetString("uid"), expires),
+		"expires_at": expires.UTC(),
+	})
+}
+
+func (s *Server) opdsDownload(ctx *gin.Context, format string) {
+	expires := time.Now().Add(consts.PDFLinkTTL)
+	ctx.Redirect(http.StatusFound, s.signedFileURL(format, ctx.Param("id"), ctx.GetString("uid"), expires))
+}
+
+func (s *Server) downloadFile(ctx *gin.Context, format string) {
+	log := logger.Get()
+	bid, uid := ctx.Param("id"), ctx.Query("uid")
+	if err := s.verifyFileSignature(format, bid, uid, ctx.Query("expires"), ctx.Query("signature"), time.Now()); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	book, ok := s.fileBook(ctx, format)
+	if !ok {
+		return
+	}
+
+	// читалка запрашивает документ десятками диапазонов; скачиванием считается
+	// только запрос с начала файла
+	if ctx.Request.Method == http.MethodGet && startsAtZero(ctx.GetHeader("Range")) {
+		log.Info().Str("bid", bid).Str("uid", uid).Str("format", format).Msg("book file downloaded")
+		download := models.Download{BID: bid, UID: uid, Format: format, DownloadedAt: time.Now().UTC()}
+		if err := s.Storage.LogDownload(download); err != nil {
+			log.Error().Err(err).Str("bid", bid).Str("uid", uid).Msg("failed to log download")
+		}
+	}
+
+	file := fileFormat(format)
+	s.serveBlob(ctx, file.key(book), map[string]string{
+		"Content-Type":        file.contentType,
+		"Content-Disposition": mime.FormatMediaType("inline", map[string]string{"filename": book.Lable + "." + format}),
+		"Cache-Control":       "private, max-age=" + strconv.Itoa(int(consts.PDFLinkTTL.Seconds())),
+	})
+}
+
+// fileBook загружает книгу из параметра :id и проверяет, что у нее есть файл формата format.
+func (s *Server) fileBook(ctx *gin.Context, format string) (models.Book, bool) {
+	book, err := s.Storage.GetBook(ctx.Param("id"))
+	if err != nil {
+		if errors.Is(err, storerrros.ErrBookNoExist) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return models.Book{}, false
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return models.Book{}, false
+	}
+	if fileFormat(format).key(book) == "" {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "book has no " + format})
+		return models.Book{}, false
+	}
+	return book, true
+}
+
+func (s *Server) signedFileURL(format, bid, uid string, expires time.Time) string {
+	exp := expires.Unix()
+	query := url.Values{
+		"uid":       {uid},
+		"expires":   {strconv.FormatInt(exp, 10)},
+		"signature": {s.fileSignature(format, bid, uid, exp)},
+	}
+	return "/books/" + url.PathEscape(bid) + "/" + format + "/download?" + query.Encode()
+}
+
+func (s *Server) verifyFileSignature(format, bid, uid, expires, signature string, now time.Time) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || uid == "" {
+		return errInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.fileSignature(format, bid, uid, exp))) {
+		return errInvalidSignature
+	}
+	if now.Unix() > exp {
+		return errLinkExpired
+	}
+	return nil
+}
+
+// fileSignature подписывает формат вместе с книгой, чтобы ссылкой на PDF нельзя было
+// скачать EPUB. Для PDF формат в подпись не входит: так остаются действительными
+// ссылки, выданные до появления EPUB.
+func (s *Server) fileSignature(format, bid, uid string, expires int64) string {
+	key := s.downloadKey
+	if len(key) == 0 {
+		key = []byte(SecretKey)
+	}
+	msg := bid + "\n" + uid + "\n" + strconv.FormatInt(expires, 10)
+	if format != consts.FormatPDF {
+		msg += "\n" + format
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msg))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func startsAtZero(rangeHeader string) bool {
+	return rangeHeader == "" || strings.HasPrefix(strings.TrimSpace(rangeHeader), "bytes=0-")
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+// ListGenres (GET /genres) возвращает дерево жанров: корневые жанры с вложенными
+// поджанрами, на каждом уровне по русскому названию.
+func (s *Server) ListGenres(ctx *gin.Context) {
+	genres, err := s.Storage.GetGenres()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"genres": genreTree(genres, "")})
+}
+
+// GenreInfo (GET /genres/:id) возвращает жанр с его поддеревом.
+func (s *Server) GenreInfo(ctx *gin.Context) {
+	genres, err := s.Storage.GetGenres()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	id := ctx.Param("id")
+	for _, genre := range genres {
+		if genre.ID == id {
+			genre.Children = genreTree(genres, id)
+			ctx.JSON(http.StatusOK, genre)
+			return
+		}
+	}
+	ctx.JSON(http.StatusNotFound, gin.H{"error": storerrros.ErrGenreNoExist.Error()})
+}
+
+// AddGenre (POST /genres) создает жанр; parent_id делает его поджанром.
+func (s *Server) AddGenre(ctx *gin.Context) {
+	log := logger.Get()
+
+	genre, err := s.genreFromRequest(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	saved, err := s.Storage.SaveGenre(genre)
+	if err != nil {
+		if errors.Is(err, storerrros.ErrGenreNoExist) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "parent " + err.Error()})
+			return
+		}
+		log.Error().Err(err).Msg("save genre failed")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusCreated, saved)
+}
+
+// UpdateGenre (PUT /genres/:id) заменяет названия и родителя жанра; пустой parent_id
+// делает жанр корневым.
+func (s *Server) UpdateGenre(ctx *gin.Context) {
+	log := logger.Get()
+
+	genre, err := s.genreFromRequest(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	genre.ID = ctx.Param("id")
+
+	updated, err := s.Storage.UpdateGenre(genre)
+	if err != nil {
+		switch {
+		case errors.Is(err, storerrros.ErrGenreNoExist):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, storerrros.ErrGenreCycle):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Error().Err(err).Msg("update genre failed")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	ctx.JSON(http.StatusOK, updated)
+}
+
+// RemoveGenre (DELETE /genres/:id) удаляет жанр без поджанров. Книги остаются в
+// каталоге, пропадают только их связи с жанром.
+func (s *Server) RemoveGenre(ctx *gin.Context) {
+	log := logger.Get()
+
+	if err := s.Storage.DeleteGenre(ctx.Param("id")); err != nil {
+		switch {
+		case errors.Is(err, storerrros.ErrGenreNoExist):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, storerrros.ErrGenreHasChildren):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Error().Err(err).Msg("failed to delete genre")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete genre"})
+		}
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "genre deleted"})
+}
+
+// SetBookGenres (PUT /books/:id/genres) заменяет жанры книги списком id жанров.
+func (s *Server) SetBookGenres(ctx *gin.Context) {
+	log := logger.Get()
+
+	var ids []string
+	if err := ctx.ShouldBindJSON(&ids); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	bid := ctx.Param("id")
+	if err := s.Storage.SetBookGenres(bid, ids); err != nil {
+		switch {
+		case errors.Is(err, storerrros.ErrBookNoExist), errors.Is(err, storerrros.ErrGenreNoExist):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			log.Error().Err(err).Msg("failed to set book genres")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	book, err := s.Storage.GetBook(bid)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, withURLs(book))
+}
+
+func (s *Server) genreFromRequest(ctx *gin.Context) (models.Genre, error) {
+	var genre models.Genre
+	if err := ctx.ShouldBindJSON(&genre); err != nil {
+		return models.Genre{}, errors.New("invalid request body")
+	}
+	genre.NameRU = strings.Join(strings.Fields(genre.NameRU), " ")
+	genre.NameEN = strings.Join(strings.Fields(genre.NameEN), " ")
+	genre.ParentID = strings.TrimSpace(genre.ParentID)
+	genre.ID, genre.BookCount, genre.Children = "", 0, nil
+	if err := s.valid.Struct(genre); err != nil {
+		return models.Genre{}, err
+	}
+	return genre, nil
+}
+
+// genreTree собирает из плоского списка поджанры parent; порядок списка сохраняется.
+func genreTree(genres []models.Genre, parent string) []models.Genre {
+	children := []models.Genre{}
+	for _, genre := range genres {
+		if genre.ParentID == parent {
+			genre.Children = genreTree(genres, genre.ID)
+			children = append(children, genre)
+		}
+	}
+	return children
+}
@@ -2,54 +2,131 @@ package server
 
 import (
 	// "context"
+	"bytes"
+	"cmp"
 	"errors"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
-	"io"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
+	"strings"
 
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
 	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/epubmeta"
+	"github.com/azaliaz/bookly/book-service/internal/isbn"
 	"github.com/azaliaz/bookly/book-service/internal/logger"
+	"github.com/azaliaz/bookly/book-service/internal/pdfmeta"
 	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
 )
 
+var errInvalidLimit = errors.New("limit must be a positive integer")
+
+// pageFromQuery читает параметры limit и cursor; limit больше максимального урезается.
+func pageFromQuery(ctx *gin.Context) (models.Page, error) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(consts.DefaultPageLimit)))
+	if err != nil || limit < 1 {
+		return models.Page{}, errInvalidLimit
+	}
+	return models.Page{
+		Limit:  min(limit, consts.MaxPageLimit),
+		Cursor: ctx.Query("cursor"),
+	}, nil
+}
+
+// filterFromQuery читает параметры поиска и сортировки /books/search.
+func filterFromQuery(ctx *gin.Context) models.BookFilter {
+	return models.BookFilter{
+		Search:    ctx.DefaultQuery("search", ""),
+		Genres:    ctx.QueryArray("genre"),
+		Author:    ctx.Query("author"),
+		Year:      ctx.DefaultQuery("year", ""),
+		Tags:      ctx.QueryArray("tag"),
+		TagsAll:   ctx.Query("tag_mode") == "all",
+		SortBy:    ctx.DefaultQuery("sort_by", "rating"),
+		Ascending: ctx.DefaultQuery("ascending", "true") == "true",
+	}
+}
+
 func (s *Server) AllBooksWithSearch(ctx *gin.Context) {
-	searchQuery := ctx.DefaultQuery("search", "")
-	genres := ctx.QueryArray("genre")
-	yearFilter := ctx.DefaultQuery("year", "")
-	sortBy := ctx.DefaultQuery("sort_by", "rating")
-	ascending := ctx.DefaultQuery("ascending", "true") == "true"
+	page, err := pageFromQuery(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter := filterFromQuery(ctx)
+	filter.Page = page
 
-	books, err := s.Storage.GetBooksWithFilters(searchQuery, genres, yearFilter, sortBy, ascending)
+	books, err := s.Storage.GetBooksWithFilters(filter)
 	if err != nil {
 		if errors.Is(err, storerrros.ErrEmptyBooksList) {
 			ctx.String(http.StatusNotFound, err.Error())
 			return
 		}
+		if errors.Is(err, storerrros.ErrInvalidCursor) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, books)
+	// facets=true добавляет к выдаче счетчики для боковой панели фильтров
+	if ctx.Query("facets") == "true" {
+		facets, err := s.Storage.GetBookFacets(filter)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		books.Facets = &facets
+	}
+
+	writeCachedJSON(ctx, withPageURLs(books))
+}
+
+// SuggestBooks возвращает подсказки по названиям и авторам для строки поиска.
+// В отличие от поиска пустой результат не считается ошибкой.
+func (s *Server) SuggestBooks(ctx *gin.Context) {
+	query := strings.TrimSpace(ctx.Query("q"))
+	if query == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "missing query"})
+		return
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(consts.DefaultSuggestLimit)))
+	if err != nil || limit < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": errInvalidLimit.Error()})
+		return
+	}
+
+	suggestions, err := s.Storage.SuggestBooks(query, min(limit, consts.MaxSuggestLimit))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if suggestions == nil {
+		suggestions = []models.Suggestion{}
+	}
+	ctx.JSON(http.StatusOK, gin.H{"query": query, "suggestions": suggestions})
 }
 
-// получение списка всех книг из хранилища и возврат их клиенту в формате JSON.
+// получение страницы списка книг из хранилища и возврат ее клиенту в формате JSON.
 func (s *Server) AllBooks(ctx *gin.Context) {
+	page, err := pageFromQuery(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	books, err := s.Storage.GetBooks() //достает список книг из бд
-	if err != nil {                    //если произошла ошибка
-		if errors.Is(err, storerrros.ErrEmptyBooksList) {
-			ctx.String(http.StatusNotFound, err.Error())
+	books, err := s.Storage.GetBooks(page) //достает страницу книг из бд
+	if err != nil {                        //если произошла ошибка
+		if errors.Is(err, storerrros.ErrInvalidCursor) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, books)
+	writeCachedJSON(ctx, withPageURLs(books))
 }
 
 func (s *Server) BookInfo(ctx *gin.Context) {
@@ -63,7 +140,27 @@ func (s *Server) BookInfo(ctx *gin.Context) {
 		ctx.String(http.StatusInternalServerError, err.Error())
 		return
 	}
-	ctx.JSON(http.StatusOK, book)
+	writeCachedBook(ctx, book)
+}
+
+// BookByISBN (GET /books/isbn/:isbn) ищет книгу по ISBN-10 или ISBN-13 в любом
+// написании: с дефисами, пробелами или префиксом "ISBN".
+func (s *Server) BookByISBN(ctx *gin.Context) {
+	isbn13, err := isbn.Normalize(ctx.Param("isbn"))
+	if err != nil || isbn13 == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": isbn.ErrInvalid.Error()})
+		return
+	}
+	book, err := s.Storage.GetBookByISBN(isbn13)
+	if err != nil {
+		if errors.Is(err, storerrros.ErrBookNoExist) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	writeCachedBook(ctx, book)
 }
 
 func (s *Server) AddBook(ctx *gin.Context) {
@@ -82,7 +179,8 @@ func (s *Server) AddBook(ctx *gin.Context) {
 	}
 
 	form := ctx.Request.MultipartForm
-	requiredFields := []string{"lable", "author", "desc", "genre", "age"}
+	// lable и author можно не передавать, если они есть в метаданных EPUB или PDF
+	requiredFields := []string{"desc", "genre", "age"}
 	for _, field := range requiredFields {
 		if len(form.Value[field]) == 0 || form.Value[field][0] == "" {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": "missing or empty field: " + field})
@@ -96,12 +194,23 @@ func (s *Server) AddBook(ctx *gin.Context) {
 		return
 	}
 
+	// ISBN хранится как ISBN-13: так издание находится по любому написанию номера
+	isbn13, err := isbn.Normalize(ctx.Request.FormValue("isbn"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	book := models.Book{
-		Lable:  form.Value["lable"][0],
-		Author: form.Value["author"][0],
-		Desc:   form.Value["desc"][0],
-		Genre:  form.Value["genre"][0],
-		Age:    age,
+		Lable:     ctx.Request.FormValue("lable"),
+		Author:    ctx.Request.FormValue("author"),
+		Desc:      form.Value["desc"][0],
+		Genre:     form.Value["genre"][0],
+		Age:       age,
+		Language:  ctx.Request.FormValue("language"),
+		ISBN:      isbn13,
+		Publisher: ctx.Request.FormValue("publisher"),
+		WorkID:    ctx.Request.FormValue("work_id"),
 	}
 
 	age, _ = strconv.Atoi(form.Value["age"][0])
@@ -119,65 +228,96 @@ func (s *Server) AddBook(ctx *gin.Context) {
 		book.Rating = 0
 	}
 
-	coverFile, coverHeader, err := ctx.Request.FormFile("cover")
-	if err != nil {
-		log.Error().Err(err).Msg("failed to get cover file")
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "failed to get cover file"})
+	// книгу можно загрузить в PDF, в EPUB или сразу в обоих форматах
+	hasPDF, hasEPUB := hasUpload(ctx, "pdf"), hasUpload(ctx, "epub")
+	if !hasPDF && !hasEPUB {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "failed to get pdf or epub file"})
 		return
 	}
-	defer coverFile.Close()
-
-	coverPath := "uploads/covers/" + uuid.New().String() + "_" + coverHeader.Filename
-	if err := os.MkdirAll(filepath.Dir(coverPath), os.ModePerm); err != nil {
-		log.Error().Err(err).Msg("failed to create cover directory")
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create cover directory"})
-		return
+	var pdf pdfmeta.Info
+	if hasPDF {
+		if pdf, err = inspectPDF(ctx); err != nil {
+			writeUploadError(ctx, err)
+			return
+		}
+		applyPDFInfo(&book, pdf)
 	}
-
-	out, err := os.Create(coverPath)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to create cover file")
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create cover file"})
-		return
+	var epub epubmeta.Info
+	if hasEPUB {
+		if epub, err = inspectEPUB(ctx); err != nil {
+			writeUploadError(ctx, err)
+			return
+		}
+		applyEPUBInfo(&book, epub)
 	}
-	defer out.Close()
-	if _, err := io.Copy(out, coverFile); err != nil {
-		log.Error().Err(err).Msg("failed to write cover file")
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write cover file"})
+	// метаданные OPF обычно точнее словаря Info в PDF
+	book.Lable = cmp.Or(book.Lable, epub.Title, pdf.Title)
+	book.Author = cmp.Or(book.Author, strings.Join(epub.Authors, ", "), pdf.Author)
+	switch {
+	case book.Lable == "":
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "missing or empty field: lable"})
 		return
-	}
-	book.CoverURL = "/" + coverPath
-
-	pdfFile, pdfHeader, err := ctx.Request.FormFile("pdf")
-	if err != nil {
-		log.Error().Err(err).Msg("failed to get pdf file")
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "failed to get pdf file"})
+	case book.Author == "":
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "missing or empty field: author"})
 		return
 	}
-	defer pdfFile.Close()
-
-	pdfPath := "uploads/pdfs/" + uuid.New().String() + "_" + pdfHeader.Filename
-	if err := os.MkdirAll(filepath.Dir(pdfPath), os.ModePerm); err != nil {
-		log.Error().Err(err).Msg("failed to create pdf directory")
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create pdf directory"})
+	for _, hash := range []string{pdf.SHA256, epub.SHA256} {
+		if hash == "" {
+			continue
+		}
+		if err := s.checkDuplicateFile(hash, ""); err != nil {
+			writeUploadError(ctx, err)
+			return
+		}
+	}
+	if err := s.checkDuplicateISBN(book.ISBN, ""); err != nil {
+		writeUploadError(ctx, err)
 		return
 	}
 
-	out, err = os.Create(pdfPath)
+	// без файла cover берется обложка, встроенная в EPUB
+	if hasUpload(ctx, "cover") || len(epub.Cover) == 0 {
+		book.CoverKey, err = s.saveImage(ctx, "cover", coverPrefix)
+	} else {
+		book.CoverKey, err = s.storeImage(ctx.Request.Context(), bytes.NewReader(epub.Cover), "cover", coverPrefix)
+	}
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create pdf file")
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create pdf file"})
+		writeUploadError(ctx, err)
 		return
 	}
-	defer out.Close()
-	if _, err := io.Copy(out, pdfFile); err != nil {
-		log.Error().Err(err).Msg("failed to write pdf file")
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write pdf file"})
-		return
+	uploaded := []string{book.CoverKey}
+	removeUploaded := func() {
+		for _, key := range uploaded {
+			s.removeUpload(key)
+		}
+	}
+	if hasPDF {
+		if book.PDFKey, err = s.saveFile(ctx, consts.FormatPDF); err != nil {
+			removeUploaded()
+			writeUploadError(ctx, err)
+			return
+		}
+		uploaded = append(uploaded, book.PDFKey)
+	}
+	if hasEPUB {
+		if book.EPUBKey, err = s.saveFile(ctx, consts.FormatEPUB); err != nil {
+			removeUploaded()
+			writeUploadError(ctx, err)
+			return
+		}
+		uploaded = append(uploaded, book.EPUBKey)
 	}
-	book.PDFURL = "/" + pdfPath
 
-	if err := s.Storage.SaveBook(book); err != nil {
+	if err := s.Storage.SaveBook(book, ctx.GetString("uid")); err != nil {
+		removeUploaded()
+		if errors.Is(err, storerrros.ErrDuplicateFile) || errors.Is(err, storerrros.ErrDuplicateISBN) {
+			writeUploadError(ctx, err)
+			return
+		}
+		if errors.Is(err, storerrros.ErrWorkNoExist) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		log.Error().Err(err).Msg("save book failed")
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -186,6 +326,8 @@ func (s *Server) AddBook(ctx *gin.Context) {
 	ctx.String(http.StatusOK, "book %s %s was added", book.Author, book.Lable)
 }
 
+// RemoveBook (DELETE /books/remove/:id) переносит книгу в корзину; окончательно
+// она удаляется после срока хранения корзины, см. trash.go.
 func (s *Server) RemoveBook(ctx *gin.Context) {
 	log := logger.Get()
 
@@ -202,8 +344,8 @@ func (s *Server) RemoveBook(ctx *gin.Context) {
 		return
 	}
 
-	if err := s.Storage.DeleteBook(id); err != nil {
-		if err.Error() == "book not found" {
+	if err := s.Storage.DeleteBook(id, ctx.GetString("uid")); err != nil {
+		if errors.Is(err, storerrros.ErrBookNoExist) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "book not found"})
 			return
 		}
@@ -211,6 +353,12 @@ func (s *Server) RemoveBook(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete book"})
 		return
 	}
+	s.wakeDeleter()
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "book deleted"})
 }
+
+// Status (GET /status) сообщает, на каком хранилище работает сервис.
+func (s *Server) Status(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok", "storage": s.storageBackend})
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+// BookHistory (GET /books/:id/history) возвращает ревизии книги, начиная с последней:
+// кто и когда ее менял и какие поля изменились. История доступна и для книг в корзине.
+func (s *Server) BookHistory(ctx *gin.Context) {
+	bid := ctx.Param("id")
+	revisions, err := s.Storage.GetBookHistory(bid)
+	if err != nil {
+		if errors.Is(err, storerrros.ErrBookNoExist) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "book not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"bid": bid, "revisions": revisions})
+}
+
+// RevertBook (POST /books/:id/history/:revision/revert) возвращает поля книги к
+// состоянию ревизии revision. Откат сам записывается в историю новой ревизией.
+func (s *Server) RevertBook(ctx *gin.Context) {
+	log := logger.Get()
+
+	revision, err := strconv.Atoi(ctx.Param("revision"))
+	if err != nil || revision < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision"})
+		return
+	}
+
+	book, err := s.Storage.RevertBook(ctx.Param("id"), revision, ctx.GetString("uid"))
+	if err != nil {
+		switch {
+		case errors.Is(err, storerrros.ErrBookNoExist):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "book not found"})
+		case errors.Is(err, storerrros.ErrRevisionNoExist):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, storerrros.ErrDuplicateFile), errors.Is(err, storerrros.ErrDuplicateISBN):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Error().Err(err).Msg("failed to revert book")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revert book"})
+		}
+		return
+	}
+
+	writeBook(ctx, book)
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+
+	"github.com/azaliaz/bookly/book-service/internal/catalog"
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+// ImportBooks (POST /books/import) загружает каталог из CSV или JSON Lines.
+// Файл передается телом запроса или полем file multipart-формы, формат задается
+// параметром format, Content-Type или расширением файла. С dry_run=true книги
+// только проверяются.
+func (s *Server) ImportBooks(ctx *gin.Context) {
+	log := logger.Get()
+
+	if _, exist := ctx.Get("uid"); !exist {
+		log.Error().Msg("user ID not found")
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, consts.MaxImportSize)
+	body, filename, contentType, err := importSource(ctx)
+	if err != nil {
+		ctx.JSON(importErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer body.Close()
+
+	format, err := catalog.DetectFormat(ctx.Query("format"), contentType, filename)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rows, err := catalog.ReadRows(body, format)
+	if err != nil {
+		ctx.JSON(importErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	report := models.ImportReport{
+		DryRun:     ctx.Query("dry_run") == "true",
+		Total:      len(rows),
+		Inserted:   []models.ImportRow{},
+		Duplicates: []models.ImportRow{},
+		Rejected:   []models.ImportRow{},
+	}
+	var valid []catalog.Row
+	for _, row := range rows {
+		if row.Err == nil {
+			row.Err = validationError(s.valid.Struct(row.Book))
+		}
+		if row.Err != nil {
+			report.Rejected = append(report.Rejected, importRow(row, row.Err.Error()))
+			continue
+		}
+		valid = append(valid, row)
+	}
+
+	if report.DryRun {
+		report.Valid = []models.ImportRow{}
+		for _, row := range valid {
+			report.Valid = append(report.Valid, importRow(row, ""))
+		}
+		ctx.JSON(http.StatusOK, report)
+		return
+	}
+
+	if len(valid) > 0 {
+		books := make([]models.Book, len(valid))
+		for i, row := range valid {
+			books[i] = row.Book
+		}
+		statuses, err := s.Storage.SaveBooks(books, ctx.GetString("uid"))
+		if errors.Is(err, storerrros.ErrWorkNoExist) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("import books failed")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for i, status := range statuses {
+			row := importRow(valid[i], "")
+			row.BID = status.BID
+			if status.Duplicate {
+				report.Duplicates = append(report.Duplicates, row)
+			} else {
+				report.Inserted = append(report.Inserted, row)
+			}
+		}
+	}
+	log.Info().Int("inserted", len(report.Inserted)).Int("duplicates", len(report.Duplicates)).
+		Int("rejected", len(report.Rejected)).Msg("books imported")
+	ctx.JSON(http.StatusOK, report)
+}
+
+// importSource возвращает содержимое файла импорта, его имя и Content-Type.
+func importSource(ctx *gin.Context) (io.ReadCloser, string, string, error) {
+	if ctx.ContentType() != gin.MIMEMultipartPOSTForm {
+		return ctx.Request.Body, "", ctx.GetHeader("Content-Type"), nil
+	}
+	file, header, err := ctx.Request.FormFile("file")
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to get import file: %w", err)
+	}
+	return file, header.Filename, header.Header.Get("Content-Type"), nil
+}
+
+// importErrorStatus - 413 для файла больше MaxImportSize, иначе 400.
+func importErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+func importRow(row catalog.Row, errMsg string) models.ImportRow {
+	return models.ImportRow{Line: row.Line, Lable: row.Book.Lable, Author: row.Book.Author, Error: errMsg}
+}
+
+// validationError превращает ошибки валидатора в короткое сообщение вида "lable: min".
+func validationError(err error) error {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	msgs := make([]string, 0, len(verrs))
+	for _, fe := range verrs {
+		msgs = append(msgs, strings.ToLower(fe.Field())+": "+fe.Tag())
+	}
+	return errors.New("invalid fields: " + strings.Join(msgs, ", "))
+}
@@ -5,8 +5,12 @@
 package mocks
 
 import (
+	context "context"
+	io "io"
 	reflect "reflect"
+	time "time"
 
+	blob "github.com/azaliaz/bookly/book-service/internal/blob"
 	models "github.com/azaliaz/bookly/book-service/internal/domain/models"
 	gomock "github.com/golang/mock/gomock"
 )
@@ -34,18 +38,118 @@ func (m *MockStorage) EXPECT() *MockStorageMockRecorder {
 	return m.recorder
 }
 
+// ApproveBookTag mocks base method.
+func (m *MockStorage) ApproveBookTag(bid, tagID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveBookTag", bid, tagID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApproveBookTag indicates an expected call of ApproveBookTag.
+func (mr *MockStorageMockRecorder) ApproveBookTag(bid, tagID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveBookTag", reflect.TypeOf((*MockStorage)(nil).ApproveBookTag), bid, tagID)
+}
+
+// DeleteAuthor mocks base method.
+func (m *MockStorage) DeleteAuthor(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAuthor", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAuthor indicates an expected call of DeleteAuthor.
+func (mr *MockStorageMockRecorder) DeleteAuthor(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAuthor", reflect.TypeOf((*MockStorage)(nil).DeleteAuthor), id)
+}
+
 // DeleteBook mocks base method.
-func (m *MockStorage) DeleteBook(arg0 string) error {
+func (m *MockStorage) DeleteBook(bid, uid string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteBook", arg0)
+	ret := m.ctrl.Call(m, "DeleteBook", bid, uid)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteBook indicates an expected call of DeleteBook.
-func (mr *MockStorageMockRecorder) DeleteBook(arg0 interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) DeleteBook(bid, uid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBook", reflect.TypeOf((*MockStorage)(nil).DeleteBook), bid, uid)
+}
+
+// DeleteGenre mocks base method.
+func (m *MockStorage) DeleteGenre(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteGenre", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteGenre indicates an expected call of DeleteGenre.
+func (mr *MockStorageMockRecorder) DeleteGenre(id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBook", reflect.TypeOf((*MockStorage)(nil).DeleteBook), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteGenre", reflect.TypeOf((*MockStorage)(nil).DeleteGenre), id)
+}
+
+// DeleteSeries mocks base method.
+func (m *MockStorage) DeleteSeries(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSeries", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSeries indicates an expected call of DeleteSeries.
+func (mr *MockStorageMockRecorder) DeleteSeries(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSeries", reflect.TypeOf((*MockStorage)(nil).DeleteSeries), id)
+}
+
+// DeleteTag mocks base method.
+func (m *MockStorage) DeleteTag(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTag", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTag indicates an expected call of DeleteTag.
+func (mr *MockStorageMockRecorder) DeleteTag(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTag", reflect.TypeOf((*MockStorage)(nil).DeleteTag), id)
+}
+
+// GetAuthor mocks base method.
+func (m *MockStorage) GetAuthor(id string) (models.Author, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuthor", id)
+	ret0, _ := ret[0].(models.Author)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuthor indicates an expected call of GetAuthor.
+func (mr *MockStorageMockRecorder) GetAuthor(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthor", reflect.TypeOf((*MockStorage)(nil).GetAuthor), id)
+}
+
+// GetAuthors mocks base method.
+func (m *MockStorage) GetAuthors() ([]models.FacetCount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuthors")
+	ret0, _ := ret[0].([]models.FacetCount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuthors indicates an expected call of GetAuthors.
+func (mr *MockStorageMockRecorder) GetAuthors() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthors", reflect.TypeOf((*MockStorage)(nil).GetAuthors))
 }
 
 // GetBook mocks base method.
@@ -63,60 +167,621 @@ func (mr *MockStorageMockRecorder) GetBook(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBook", reflect.TypeOf((*MockStorage)(nil).GetBook), arg0)
 }
 
+// GetBookByFileHash mocks base method.
+func (m *MockStorage) GetBookByFileHash(hash string) (models.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBookByFileHash", hash)
+	ret0, _ := ret[0].(models.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBookByFileHash indicates an expected call of GetBookByFileHash.
+func (mr *MockStorageMockRecorder) GetBookByFileHash(hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBookByFileHash", reflect.TypeOf((*MockStorage)(nil).GetBookByFileHash), hash)
+}
+
+// GetBookByISBN mocks base method.
+func (m *MockStorage) GetBookByISBN(isbn string) (models.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBookByISBN", isbn)
+	ret0, _ := ret[0].(models.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBookByISBN indicates an expected call of GetBookByISBN.
+func (mr *MockStorageMockRecorder) GetBookByISBN(isbn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBookByISBN", reflect.TypeOf((*MockStorage)(nil).GetBookByISBN), isbn)
+}
+
+// GetBookFacets mocks base method.
+func (m *MockStorage) GetBookFacets(arg0 models.BookFilter) (models.BookFacets, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBookFacets", arg0)
+	ret0, _ := ret[0].(models.BookFacets)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBookFacets indicates an expected call of GetBookFacets.
+func (mr *MockStorageMockRecorder) GetBookFacets(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBookFacets", reflect.TypeOf((*MockStorage)(nil).GetBookFacets), arg0)
+}
+
+// GetBookHistory mocks base method.
+func (m *MockStorage) GetBookHistory(bid string) ([]models.BookRevision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBookHistory", bid)
+	ret0, _ := ret[0].([]models.BookRevision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBookHistory indicates an expected call of GetBookHistory.
+func (mr *MockStorageMockRecorder) GetBookHistory(bid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBookHistory", reflect.TypeOf((*MockStorage)(nil).GetBookHistory), bid)
+}
+
 // GetBooks mocks base method.
-func (m *MockStorage) GetBooks() ([]models.Book, error) {
+func (m *MockStorage) GetBooks(arg0 models.Page) (models.BooksPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetBooks")
-	ret0, _ := ret[0].([]models.Book)
+	ret := m.ctrl.Call(m, "GetBooks", arg0)
+	ret0, _ := ret[0].(models.BooksPage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetBooks indicates an expected call of GetBooks.
-func (mr *MockStorageMockRecorder) GetBooks() *gomock.Call {
+func (mr *MockStorageMockRecorder) GetBooks(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBooks", reflect.TypeOf((*MockStorage)(nil).GetBooks))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBooks", reflect.TypeOf((*MockStorage)(nil).GetBooks), arg0)
 }
 
 // GetBooksWithFilters mocks base method.
-func (m *MockStorage) GetBooksWithFilters(searchTerm string, genres []string, year, sortBy string, ascending bool) ([]models.Book, error) {
+func (m *MockStorage) GetBooksWithFilters(arg0 models.BookFilter) (models.BooksPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetBooksWithFilters", searchTerm, genres, year, sortBy, ascending)
-	ret0, _ := ret[0].([]models.Book)
+	ret := m.ctrl.Call(m, "GetBooksWithFilters", arg0)
+	ret0, _ := ret[0].(models.BooksPage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetBooksWithFilters indicates an expected call of GetBooksWithFilters.
-func (mr *MockStorageMockRecorder) GetBooksWithFilters(searchTerm, genres, year, sortBy, ascending interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) GetBooksWithFilters(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBooksWithFilters", reflect.TypeOf((*MockStorage)(nil).GetBooksWithFilters), arg0)
+}
+
+// GetGenres mocks base method.
+func (m *MockStorage) GetGenres() ([]models.Genre, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGenres")
+	ret0, _ := ret[0].([]models.Genre)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGenres indicates an expected call of GetGenres.
+func (mr *MockStorageMockRecorder) GetGenres() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGenres", reflect.TypeOf((*MockStorage)(nil).GetGenres))
+}
+
+// GetSeries mocks base method.
+func (m *MockStorage) GetSeries(id string) (models.Series, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSeries", id)
+	ret0, _ := ret[0].(models.Series)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSeries indicates an expected call of GetSeries.
+func (mr *MockStorageMockRecorder) GetSeries(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSeries", reflect.TypeOf((*MockStorage)(nil).GetSeries), id)
+}
+
+// GetTagSuggestions mocks base method.
+func (m *MockStorage) GetTagSuggestions() ([]models.TagSuggestion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTagSuggestions")
+	ret0, _ := ret[0].([]models.TagSuggestion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTagSuggestions indicates an expected call of GetTagSuggestions.
+func (mr *MockStorageMockRecorder) GetTagSuggestions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTagSuggestions", reflect.TypeOf((*MockStorage)(nil).GetTagSuggestions))
+}
+
+// GetTags mocks base method.
+func (m *MockStorage) GetTags(limit int) ([]models.Tag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTags", limit)
+	ret0, _ := ret[0].([]models.Tag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTags indicates an expected call of GetTags.
+func (mr *MockStorageMockRecorder) GetTags(limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTags", reflect.TypeOf((*MockStorage)(nil).GetTags), limit)
+}
+
+// GetTrash mocks base method.
+func (m *MockStorage) GetTrash() ([]models.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTrash")
+	ret0, _ := ret[0].([]models.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTrash indicates an expected call of GetTrash.
+func (mr *MockStorageMockRecorder) GetTrash() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTrash", reflect.TypeOf((*MockStorage)(nil).GetTrash))
+}
+
+// GetWork mocks base method.
+func (m *MockStorage) GetWork(id string) (models.Work, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWork", id)
+	ret0, _ := ret[0].(models.Work)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWork indicates an expected call of GetWork.
+func (mr *MockStorageMockRecorder) GetWork(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWork", reflect.TypeOf((*MockStorage)(nil).GetWork), id)
+}
+
+// ListAuthors mocks base method.
+func (m *MockStorage) ListAuthors(query string, page models.Page) (models.AuthorsPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuthors", query, page)
+	ret0, _ := ret[0].(models.AuthorsPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuthors indicates an expected call of ListAuthors.
+func (mr *MockStorageMockRecorder) ListAuthors(query, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuthors", reflect.TypeOf((*MockStorage)(nil).ListAuthors), query, page)
+}
+
+// LogDownload mocks base method.
+func (m *MockStorage) LogDownload(arg0 models.Download) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LogDownload", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LogDownload indicates an expected call of LogDownload.
+func (mr *MockStorageMockRecorder) LogDownload(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogDownload", reflect.TypeOf((*MockStorage)(nil).LogDownload), arg0)
+}
+
+// PurgeBooks mocks base method.
+func (m *MockStorage) PurgeBooks(before time.Time) ([]models.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeBooks", before)
+	ret0, _ := ret[0].([]models.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeBooks indicates an expected call of PurgeBooks.
+func (mr *MockStorageMockRecorder) PurgeBooks(before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeBooks", reflect.TypeOf((*MockStorage)(nil).PurgeBooks), before)
+}
+
+// RemoveBookTag mocks base method.
+func (m *MockStorage) RemoveBookTag(bid, tagID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveBookTag", bid, tagID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveBookTag indicates an expected call of RemoveBookTag.
+func (mr *MockStorageMockRecorder) RemoveBookTag(bid, tagID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveBookTag", reflect.TypeOf((*MockStorage)(nil).RemoveBookTag), bid, tagID)
+}
+
+// RestoreBook mocks base method.
+func (m *MockStorage) RestoreBook(bid, uid string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreBook", bid, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreBook indicates an expected call of RestoreBook.
+func (mr *MockStorageMockRecorder) RestoreBook(bid, uid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreBook", reflect.TypeOf((*MockStorage)(nil).RestoreBook), bid, uid)
+}
+
+// RevertBook mocks base method.
+func (m *MockStorage) RevertBook(bid string, revision int, actor string) (models.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevertBook", bid, revision, actor)
+	ret0, _ := ret[0].(models.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevertBook indicates an expected call of RevertBook.
+func (mr *MockStorageMockRecorder) RevertBook(bid, revision, actor interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevertBook", reflect.TypeOf((*MockStorage)(nil).RevertBook), bid, revision, actor)
+}
+
+// SaveAuthor mocks base method.
+func (m *MockStorage) SaveAuthor(arg0 models.Author) (models.Author, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAuthor", arg0)
+	ret0, _ := ret[0].(models.Author)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveAuthor indicates an expected call of SaveAuthor.
+func (mr *MockStorageMockRecorder) SaveAuthor(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBooksWithFilters", reflect.TypeOf((*MockStorage)(nil).GetBooksWithFilters), searchTerm, genres, year, sortBy, ascending)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAuthor", reflect.TypeOf((*MockStorage)(nil).SaveAuthor), arg0)
 }
 
 // SaveBook mocks base method.
-func (m *MockStorage) SaveBook(arg0 models.Book) error {
+func (m *MockStorage) SaveBook(book models.Book, actor string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveBook", arg0)
+	ret := m.ctrl.Call(m, "SaveBook", book, actor)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SaveBook indicates an expected call of SaveBook.
-func (mr *MockStorageMockRecorder) SaveBook(arg0 interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) SaveBook(book, actor interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveBook", reflect.TypeOf((*MockStorage)(nil).SaveBook), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveBook", reflect.TypeOf((*MockStorage)(nil).SaveBook), book, actor)
 }
 
 // SaveBooks mocks base method.
-func (m *MockStorage) SaveBooks(arg0 []models.Book) error {
+func (m *MockStorage) SaveBooks(books []models.Book, actor string) ([]models.SaveStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveBooks", books, actor)
+	ret0, _ := ret[0].([]models.SaveStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveBooks indicates an expected call of SaveBooks.
+func (mr *MockStorageMockRecorder) SaveBooks(books, actor interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveBooks", reflect.TypeOf((*MockStorage)(nil).SaveBooks), books, actor)
+}
+
+// SaveGenre mocks base method.
+func (m *MockStorage) SaveGenre(arg0 models.Genre) (models.Genre, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveBooks", arg0)
+	ret := m.ctrl.Call(m, "SaveGenre", arg0)
+	ret0, _ := ret[0].(models.Genre)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveGenre indicates an expected call of SaveGenre.
+func (mr *MockStorageMockRecorder) SaveGenre(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveGenre", reflect.TypeOf((*MockStorage)(nil).SaveGenre), arg0)
+}
+
+// SaveSeries mocks base method.
+func (m *MockStorage) SaveSeries(arg0 models.Series) (models.Series, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveSeries", arg0)
+	ret0, _ := ret[0].(models.Series)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveSeries indicates an expected call of SaveSeries.
+func (mr *MockStorageMockRecorder) SaveSeries(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSeries", reflect.TypeOf((*MockStorage)(nil).SaveSeries), arg0)
+}
+
+// SetBookAuthors mocks base method.
+func (m *MockStorage) SetBookAuthors(bid string, authors []models.BookAuthor) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetBookAuthors", bid, authors)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SaveBooks indicates an expected call of SaveBooks.
-func (mr *MockStorageMockRecorder) SaveBooks(arg0 interface{}) *gomock.Call {
+// SetBookAuthors indicates an expected call of SetBookAuthors.
+func (mr *MockStorageMockRecorder) SetBookAuthors(bid, authors interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBookAuthors", reflect.TypeOf((*MockStorage)(nil).SetBookAuthors), bid, authors)
+}
+
+// SetBookGenres mocks base method.
+func (m *MockStorage) SetBookGenres(bid string, ids []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetBookGenres", bid, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetBookGenres indicates an expected call of SetBookGenres.
+func (mr *MockStorageMockRecorder) SetBookGenres(bid, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBookGenres", reflect.TypeOf((*MockStorage)(nil).SetBookGenres), bid, ids)
+}
+
+// SetBookSeries mocks base method.
+func (m *MockStorage) SetBookSeries(bid string, entries []models.BookSeries) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetBookSeries", bid, entries)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetBookSeries indicates an expected call of SetBookSeries.
+func (mr *MockStorageMockRecorder) SetBookSeries(bid, entries interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBookSeries", reflect.TypeOf((*MockStorage)(nil).SetBookSeries), bid, entries)
+}
+
+// SetBookTags mocks base method.
+func (m *MockStorage) SetBookTags(bid string, names []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetBookTags", bid, names)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetBookTags indicates an expected call of SetBookTags.
+func (mr *MockStorageMockRecorder) SetBookTags(bid, names interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBookTags", reflect.TypeOf((*MockStorage)(nil).SetBookTags), bid, names)
+}
+
+// SetBookWork mocks base method.
+func (m *MockStorage) SetBookWork(bid, workID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetBookWork", bid, workID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetBookWork indicates an expected call of SetBookWork.
+func (mr *MockStorageMockRecorder) SetBookWork(bid, workID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBookWork", reflect.TypeOf((*MockStorage)(nil).SetBookWork), bid, workID)
+}
+
+// StreamBooks mocks base method.
+func (m *MockStorage) StreamBooks(filter models.BookFilter, fn func(models.Book) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamBooks", filter, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamBooks indicates an expected call of StreamBooks.
+func (mr *MockStorageMockRecorder) StreamBooks(filter, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamBooks", reflect.TypeOf((*MockStorage)(nil).StreamBooks), filter, fn)
+}
+
+// SuggestBookTag mocks base method.
+func (m *MockStorage) SuggestBookTag(bid, uid, name string) (models.TagSuggestion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SuggestBookTag", bid, uid, name)
+	ret0, _ := ret[0].(models.TagSuggestion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SuggestBookTag indicates an expected call of SuggestBookTag.
+func (mr *MockStorageMockRecorder) SuggestBookTag(bid, uid, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SuggestBookTag", reflect.TypeOf((*MockStorage)(nil).SuggestBookTag), bid, uid, name)
+}
+
+// SuggestBooks mocks base method.
+func (m *MockStorage) SuggestBooks(query string, limit int) ([]models.Suggestion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SuggestBooks", query, limit)
+	ret0, _ := ret[0].([]models.Suggestion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SuggestBooks indicates an expected call of SuggestBooks.
+func (mr *MockStorageMockRecorder) SuggestBooks(query, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SuggestBooks", reflect.TypeOf((*MockStorage)(nil).SuggestBooks), query, limit)
+}
+
+// UpdateAuthor mocks base method.
+func (m *MockStorage) UpdateAuthor(arg0 models.Author) (models.Author, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAuthor", arg0)
+	ret0, _ := ret[0].(models.Author)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAuthor indicates an expected call of UpdateAuthor.
+func (mr *MockStorageMockRecorder) UpdateAuthor(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAuthor", reflect.TypeOf((*MockStorage)(nil).UpdateAuthor), arg0)
+}
+
+// UpdateBook mocks base method.
+func (m *MockStorage) UpdateBook(book models.Book, version int, actor string) (models.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateBook", book, version, actor)
+	ret0, _ := ret[0].(models.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateBook indicates an expected call of UpdateBook.
+func (mr *MockStorageMockRecorder) UpdateBook(book, version, actor interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBook", reflect.TypeOf((*MockStorage)(nil).UpdateBook), book, version, actor)
+}
+
+// UpdateGenre mocks base method.
+func (m *MockStorage) UpdateGenre(arg0 models.Genre) (models.Genre, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateGenre", arg0)
+	ret0, _ := ret[0].(models.Genre)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateGenre indicates an expected call of UpdateGenre.
+func (mr *MockStorageMockRecorder) UpdateGenre(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateGenre", reflect.TypeOf((*MockStorage)(nil).UpdateGenre), arg0)
+}
+
+// UpdateSeries mocks base method.
+func (m *MockStorage) UpdateSeries(arg0 models.Series) (models.Series, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSeries", arg0)
+	ret0, _ := ret[0].(models.Series)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateSeries indicates an expected call of UpdateSeries.
+func (mr *MockStorageMockRecorder) UpdateSeries(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSeries", reflect.TypeOf((*MockStorage)(nil).UpdateSeries), arg0)
+}
+
+// MockBlobStore is a mock of BlobStore interface.
+type MockBlobStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockBlobStoreMockRecorder
+}
+
+// MockBlobStoreMockRecorder is the mock recorder for MockBlobStore.
+type MockBlobStoreMockRecorder struct {
+	mock *MockBlobStore
+}
+
+// NewMockBlobStore creates a new mock instance.
+func NewMockBlobStore(ctrl *gomock.Controller) *MockBlobStore {
+	mock := &MockBlobStore{ctrl: ctrl}
+	mock.recorder = &MockBlobStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBlobStore) EXPECT() *MockBlobStoreMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockBlobStore) Delete(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockBlobStoreMockRecorder) Delete(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockBlobStore)(nil).Delete), ctx, key)
+}
+
+// Get mocks base method.
+func (m *MockBlobStore) Get(ctx context.Context, key string) (blob.Object, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, key)
+	ret0, _ := ret[0].(blob.Object)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockBlobStoreMockRecorder) Get(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockBlobStore)(nil).Get), ctx, key)
+}
+
+// Put mocks base method.
+func (m *MockBlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Put", ctx, key, r, size, contentType)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Put indicates an expected call of Put.
+func (mr *MockBlobStoreMockRecorder) Put(ctx, key, r, size, contentType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockBlobStore)(nil).Put), ctx, key, r, size, contentType)
+}
+
+// MockUserAuth is a mock of UserAuth interface.
+type MockUserAuth struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserAuthMockRecorder
+}
+
+// MockUserAuthMockRecorder is the mock recorder for MockUserAuth.
+type MockUserAuthMockRecorder struct {
+	mock *MockUserAuth
+}
+
+// NewMockUserAuth creates a new mock instance.
+func NewMockUserAuth(ctrl *gomock.Controller) *MockUserAuth {
+	mock := &MockUserAuth{ctrl: ctrl}
+	mock.recorder = &MockUserAuthMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserAuth) EXPECT() *MockUserAuthMockRecorder {
+	return m.recorder
+}
+
+// Login mocks base method.
+func (m *MockUserAuth) Login(email, pass string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Login", email, pass)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Login indicates an expected call of Login.
+func (mr *MockUserAuthMockRecorder) Login(email, pass interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveBooks", reflect.TypeOf((*MockStorage)(nil).SaveBooks), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockUserAuth)(nil).Login), email, pass)
 }
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/azaliaz/bookly/book-service/internal/covers"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	"github.com/azaliaz/bookly/book-service/internal/opds"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+// OPDSRoot (GET /opds) - корневой навигационный фид каталога для e-reader приложений.
+func (s *Server) OPDSRoot(ctx *gin.Context) {
+	base := s.publicURL
+	now := time.Now()
+	feed := opds.NewFeed("urn:bookly:opds", "bookly", now)
+	feed.Links = opdsLinks(base, ctx.Request.URL, opds.NavigationType)
+	feed.Entries = []opds.Entry{
+		opds.NavigationEntry("urn:bookly:opds:new", "Новинки", "Недавно добавленные книги",
+			opds.RelSortNew, base+"/opds/new", opds.AcquisitionType, now),
+		opds.NavigationEntry("urn:bookly:opds:popular", "Популярное", "Самые скачиваемые книги",
+			opds.RelSortPopular, base+"/opds/popular", opds.AcquisitionType, now),
+		opds.NavigationEntry("urn:bookly:opds:genres", "Жанры", "Книги по жанрам",
+			opds.RelSubsection, base+"/opds/genres", opds.NavigationType, now),
+		opds.NavigationEntry("urn:bookly:opds:authors", "Авторы", "Книги по авторам",
+			opds.RelSubsection, base+"/opds/authors", opds.NavigationType, now),
+	}
+	writeOPDS(ctx, opds.NavigationType, feed)
+}
+
+func (s *Server) OPDSNew(ctx *gin.Context) {
+	s.opdsAcquisition(ctx, "urn:bookly:opds:new", "Новинки",
+		models.BookFilter{SortBy: "created_at", Ascending: false})
+}
+
+func (s *Server) OPDSPopular(ctx *gin.Context) {
+	s.opdsAcquisition(ctx, "urn:bookly:opds:popular", "Популярное",
+		models.BookFilter{SortBy: "popularity", Ascending: false})
+}
+
+// OPDSGenres (GET /opds/genres) перечисляет жанры с числом книг.
+func (s *Server) OPDSGenres(ctx *gin.Context) {
+	facets, err := s.Storage.GetBookFacets(models.BookFilter{})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	s.opdsNavigation(ctx, "urn:bookly:opds:genres", "Жанры", "/opds/genres/", facets.Genres)
+}
+
+func (s *Server) OPDSGenre(ctx *gin.Context) {
+	genre := ctx.Param("genre")
+	s.opdsAcquisition(ctx, "urn:bookly:opds:genre:"+genre, "Жанр: "+genre,
+		models.BookFilter{Genres: []string{genre}, SortBy: "lable", Ascending: true})
+}
+
+// OPDSAuthors (GET /opds/authors) перечисляет всех авторов по алфавиту.
+func (s *Server) OPDSAuthors(ctx *gin.Context) {
+	authors, err := s.Storage.GetAuthors()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	s.opdsNavigation(ctx, "urn:bookly:opds:authors", "Авторы", "/opds/authors/", authors)
+}
+
+func (s *Server) OPDSAuthor(ctx *gin.Context) {
+	author := ctx.Param("author")
+	s.opdsAcquisition(ctx, "urn:bookly:opds:author:"+author, "Автор: "+author,
+		models.BookFilter{Author: author, SortBy: "lable", Ascending: true})
+}
+
+// OPDSSearch (GET /opds/search?q=) - результаты поиска по релевантности, как /books/search.
+func (s *Server) OPDSSearch(ctx *gin.Context) {
+	query := strings.TrimSpace(ctx.Query("q"))
+	if query == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "missing query"})
+		return
+	}
+	s.opdsAcquisition(ctx, "urn:bookly:opds:search:"+query, "Поиск: "+query,
+		models.BookFilter{Search: query, SortBy: "relevance"})
+}
+
+// OPDSSearchDescription (GET /opds/search.xml) - OpenSearch description для поиска
+// из e-reader приложения и для JSON-поиска /books/search.
+func (s *Server) OPDSSearchDescription(ctx *gin.Context) {
+	base := s.publicURL
+	writeOPDS(ctx, opds.OpenSearchType, opds.NewOpenSearchDescription(
+		opds.OpenSearchURL{Type: opds.AcquisitionType, Template: base + "/opds/search?q={searchTerms}"},
+		opds.OpenSearchURL{Type: "application/json", Template: base + "/books/search?search={searchTerms}"},
+	))
+}
+
+// opdsNavigation отдает навигационный фид, в котором каждое значение ведет в фид
+// получения по адресу prefix + значение.
+func (s *Server) opdsNavigation(ctx *gin.Context, id, title, prefix string, values []models.FacetCount) {
+	base := s.publicURL
+	now := time.Now()
+	feed := opds.NewFeed(id, title, now)
+	feed.Links = opdsLinks(base, ctx.Request.URL, opds.NavigationType)
+	for _, v := range values {
+		if v.Value == "" {
+			continue
+		}
+		feed.Entries = append(feed.Entries, opds.NavigationEntry(id+":"+v.Value, v.Value,
+			fmt.Sprintf("Книг: %d", v.Count), opds.RelSubsection,
+			base+prefix+url.PathEscape(v.Value), opds.AcquisitionType, now))
+	}
+	writeOPDS(ctx, opds.NavigationType, feed)
+}
+
+// opdsAcquisition отдает страницу книг фильтра как фид получения; следующая
+// страница доступна по ссылке rel="next" с курсором.
+func (s *Server) opdsAcquisition(ctx *gin.Context, id, title string, filter models.BookFilter) {
+	page, err := pageFromQuery(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.Page = page
+
+	// пустая подборка - это пустой фид, а не ошибка
+	books, err := s.Storage.GetBooksWithFilters(filter)
+	if err != nil && !errors.Is(err, storerrros.ErrEmptyBooksList) {
+		if errors.Is(err, storerrros.ErrInvalidCursor) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	base := s.publicURL
+	feed := opds.NewFeed(id, title, time.Now())
+	feed.Links = opdsLinks(base, ctx.Request.URL, opds.AcquisitionType)
+	feed.TotalResults, feed.ItemsPerPage = books.Total, page.Limit
+	for _, book := range withPageURLs(books).Books {
+		// файлы скачиваются через /opds/books/:id/<формат>, который понимает Basic-авторизацию
+		var acquisitions []opds.Link
+		for _, f := range book.Formats {
+			acquisitions = append(acquisitions, opds.Link{
+				Href: base + "/opds/books/" + url.PathEscape(book.BID) + "/" + f.Format,
+				Type: fileFormat(f.Format).contentType,
+			})
+		}
+		thumbnailURL := cmp.Or(book.CoverURLs[covers.Thumbnail], book.CoverURL)
+		feed.Entries = append(feed.Entries, opds.BookEntry(book,
+			absoluteURL(base, book.CoverURL), absoluteURL(base, thumbnailURL), acquisitions))
+	}
+	if books.NextCursor != "" {
+		next := *ctx.Request.URL
+		query := next.Query()
+		query.Set("cursor", books.NextCursor)
+		next.RawQuery = query.Encode()
+		feed.Links = append(feed.Links, opds.Link{Rel: opds.RelNext, Href: base + next.RequestURI(), Type: opds.AcquisitionType})
+	}
+	writeOPDS(ctx, opds.AcquisitionType, feed)
+}
+
+// opdsLinks - ссылки, общие для всех фидов: self, start, up и поиск.
+func opdsLinks(base string, self *url.URL, feedType string) []opds.Link {
+	links := []opds.Link{
+		{Rel: opds.RelSelf, Href: base + self.RequestURI(), Type: feedType},
+		{Rel: opds.RelStart, Href: base + "/opds", Type: opds.NavigationType},
+	}
+	if self.Path != "/opds" {
+		links = append(links, opds.Link{Rel: opds.RelUp, Href: base + "/opds", Type: opds.NavigationType})
+	}
+	return append(links, opds.Link{Rel: opds.RelSearch, Href: base + "/opds/search.xml", Type: opds.OpenSearchType})
+}
+
+func writeOPDS(ctx *gin.Context, contentType string, doc any) {
+	log := logger.Get()
+	var buf bytes.Buffer
+	if err := opds.Write(&buf, doc); err != nil {
+		log.Error().Err(err).Msg("encode opds feed failed")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.Data(http.StatusOK, contentType, buf.Bytes())
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+var errInvalidPosition = errors.New("position must be a non-negative number with at most two decimals")
+
+// SeriesInfo (GET /series/:id) возвращает серию с книгами в порядке чтения.
+func (s *Server) SeriesInfo(ctx *gin.Context) {
+	series, err := s.Storage.GetSeries(ctx.Param("id"))
+	if err != nil {
+		if errors.Is(err, storerrros.ErrSeriesNoExist) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range series.Books {
+		series.Books[i].URL = bookURL(series.Books[i].BID)
+	}
+	ctx.JSON(http.StatusOK, series)
+}
+
+// AddSeries (POST /series) создает серию.
+func (s *Server) AddSeries(ctx *gin.Context) {
+	log := logger.Get()
+
+	series, err := s.seriesFromRequest(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	saved, err := s.Storage.SaveSeries(series)
+	if err != nil {
+		log.Error().Err(err).Msg("save series failed")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusCreated, saved)
+}
+
+// UpdateSeries (PUT /series/:id) заменяет название и описание серии.
+func (s *Server) UpdateSeries(ctx *gin.Context) {
+	log := logger.Get()
+
+	series, err := s.seriesFromRequest(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	series.ID = ctx.Param("id")
+
+	updated, err := s.Storage.UpdateSeries(series)
+	if err != nil {
+		if errors.Is(err, storerrros.ErrSeriesNoExist) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Error().Err(err).Msg("update series failed")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, updated)
+}
+
+// RemoveSeries (DELETE /series/:id) удаляет серию; книги остаются в каталоге.
+func (s *Server) RemoveSeries(ctx *gin.Context) {
+	log := logger.Get()
+
+	if err := s.Storage.DeleteSeries(ctx.Param("id")); err != nil {
+		if errors.Is(err, storerrros.ErrSeriesNoExist) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Error().Err(err).Msg("failed to delete series")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete series"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "series deleted"})
+}
+
+// BookSeries (GET /books/:id/series) возвращает серии книги с предыдущей и следующей
+// книгой в каждой - ответ на вопрос "что читать дальше".
+func (s *Server) BookSeries(ctx *gin.Context) {
+	book, err := s.Storage.GetBook(ctx.Param("id"))
+	if err != nil {
+		if errors.Is(err, storerrros.ErrBookNoExist) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"series": seriesWithURLs(book.Series)})
+}
+
+// SetBookSeries (PUT /books/:id/series) заменяет серии книги списком
+// [{"series_id": ..., "position": 2.5}].
+func (s *Server) SetBookSeries(ctx *gin.Context) {
+	log := logger.Get()
+
+	var entries []models.BookSeries
+	if err := ctx.ShouldBindJSON(&entries); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	for _, entry := range entries {
+		if entry.SeriesID == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "missing series_id"})
+			return
+		}
+		if !validPosition(entry.Position) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": errInvalidPosition.Error()})
+			return
+		}
+	}
+
+	bid := ctx.Param("id")
+	if err := s.Storage.SetBookSeries(bid, entries); err != nil {
+		switch {
+		case errors.Is(err, storerrros.ErrBookNoExist), errors.Is(err, storerrros.ErrSeriesNoExist):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			log.Error().Err(err).Msg("failed to set book series")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	s.BookSeries(ctx)
+}
+
+func (s *Server) seriesFromRequest(ctx *gin.Context) (models.Series, error) {
+	var series models.Series
+	if err := ctx.ShouldBindJSON(&series); err != nil {
+		return models.Series{}, errors.New("invalid request body")
+	}
+	series.Name = strings.Join(strings.Fields(series.Name), " ")
+	series.ID, series.BookCount, series.Books = "", 0, nil
+	if err := s.valid.Struct(series); err != nil {
+		return models.Series{}, err
+	}
+	return series, nil
+}
+
+// validPosition проверяет место в серии: в базе оно хранится как numeric(8, 2).
+func validPosition(position float64) bool {
+	return position >= 0 && position < 1e6 && math.Round(position*100)/100 == position
+}
+
+func bookURL(bid string) string {
+	return "/books/" + url.PathEscape(bid)
+}
+
+// seriesWithURLs заполняет ссылки на карточки соседних книг серии.
+func seriesWithURLs(series []models.BookSeries) []models.BookSeries {
+	if series == nil {
+		return []models.BookSeries{}
+	}
+	for _, entry := range series {
+		for _, neighbor := range []*models.SeriesBook{entry.Prev, entry.Next} {
+			if neighbor != nil {
+				neighbor.URL = bookURL(neighbor.BID)
+			}
+		}
+	}
+	return series
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
@@ -8,12 +9,17 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator"
 	"github.com/golang-jwt/jwt/v4"
+	"io"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/azaliaz/bookly/book-service/internal/blob"
 	"github.com/azaliaz/bookly/book-service/internal/config"
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
 	"github.com/azaliaz/bookly/book-service/internal/domain/models"
 	"github.com/azaliaz/bookly/book-service/internal/logger"
+	"github.com/azaliaz/bookly/book-service/internal/users"
 )
 
 //go:generate mockgen -source=server.go -destination=./mocks/service_mock.go -package=mocks
@@ -28,21 +34,86 @@ type Claims struct {
 }
 
 type Storage interface {
-	SaveBook(models.Book) error
-	SaveBooks([]models.Book) error
-	GetBooks() ([]models.Book, error)
+	SaveBook(book models.Book, actor string) error
+	SaveBooks(books []models.Book, actor string) ([]models.SaveStatus, error)
+	GetBooks(models.Page) (models.BooksPage, error)
 	GetBook(string) (models.Book, error)
-	DeleteBook(string) error
+	DeleteBook(bid string, uid string) error
+	GetTrash() ([]models.Book, error)
+	RestoreBook(bid string, uid string) error
+	PurgeBooks(before time.Time) ([]models.Book, error)
+	UpdateBook(book models.Book, version int, actor string) (models.Book, error)
+	GetBookByFileHash(hash string) (models.Book, error)
+	GetBookByISBN(isbn string) (models.Book, error)
 	//GetBooksWithSearchAndSort(searchTerm, genre, year, sortBy string, ascending bool) ([]models.Book, error)
-	GetBooksWithFilters(searchTerm string, genres []string, year string, sortBy string, ascending bool) ([]models.Book, error)
+	GetBooksWithFilters(models.BookFilter) (models.BooksPage, error)
+	SuggestBooks(query string, limit int) ([]models.Suggestion, error)
+	GetBookFacets(models.BookFilter) (models.BookFacets, error)
+	StreamBooks(filter models.BookFilter, fn func(models.Book) error) error
+	GetAuthors() ([]models.FacetCount, error)
+	LogDownload(models.Download) error
+	SaveAuthor(models.Author) (models.Author, error)
+	GetAuthor(id string) (models.Author, error)
+	ListAuthors(query string, page models.Page) (models.AuthorsPage, error)
+	UpdateAuthor(models.Author) (models.Author, error)
+	DeleteAuthor(id string) error
+	SetBookAuthors(bid string, authors []models.BookAuthor) error
+	SaveGenre(models.Genre) (models.Genre, error)
+	GetGenres() ([]models.Genre, error)
+	UpdateGenre(models.Genre) (models.Genre, error)
+	DeleteGenre(id string) error
+	SetBookGenres(bid string, ids []string) error
+	SaveSeries(models.Series) (models.Series, error)
+	GetSeries(id string) (models.Series, error)
+	UpdateSeries(models.Series) (models.Series, error)
+	DeleteSeries(id string) error
+	SetBookSeries(bid string, entries []models.BookSeries) error
+	GetWork(id string) (models.Work, error)
+	SetBookWork(bid string, workID string) error
+	GetTags(limit int) ([]models.Tag, error)
+	DeleteTag(id string) error
+	SetBookTags(bid string, names []string) error
+	SuggestBookTag(bid, uid, name string) (models.TagSuggestion, error)
+	GetTagSuggestions() ([]models.TagSuggestion, error)
+	ApproveBookTag(bid, tagID string) error
+	RemoveBookTag(bid, tagID string) error
+	GetBookHistory(bid string) ([]models.BookRevision, error)
+	RevertBook(bid string, revision int, actor string) (models.Book, error)
+}
+
+// BlobStore хранит файлы книг (обложки, PDF, EPUB) по ключам; реализации - в пакете blob.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (blob.Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// UserAuth проверяет логин и пароль в user-service и возвращает выданный им JWT.
+type UserAuth interface {
+	Login(email, pass string) (string, error)
 }
 
 type Server struct {
 	serv    *http.Server
 	valid   *validator.Validate
 	Storage Storage
+	Users   UserAuth
+	Blobs   BlobStore
 	delChan chan struct{}
 	ErrChan chan error
+
+	// downloadKey подписывает ссылки на файлы книг, см. files.go
+	downloadKey []byte
+
+	// trashRetention - срок хранения книг в корзине, см. trash.go
+	trashRetention time.Duration
+
+	// storageBackend - выбранный в конфиге бэкенд хранилища, см. Status
+	storageBackend string
+
+	// publicURL - внешний адрес сервиса для абсолютных ссылок в выгрузке и OPDS.
+	// Он берется из конфига, а не из Host и X-Forwarded-Proto, которые присылает клиент.
+	publicURL string
 }
 
 func New(cfg config.Config, stor Storage) *Server {
@@ -54,8 +125,18 @@ func New(cfg config.Config, stor Storage) *Server {
 		serv:    &server,
 		valid:   valid,
 		Storage: stor,
+		Users:   users.New(cfg.UsersAddr),
+		Blobs:   blob.NewLocal(cmp.Or(cfg.BlobDir, "uploads")),
 		delChan: make(chan struct{}, 10), //nolint:mnd //todo
 		ErrChan: make(chan error),
+
+		downloadKey: []byte(cmp.Or(cfg.DownloadSecret, SecretKey)),
+
+		trashRetention: cmp.Or(cfg.TrashRetention, consts.DefaultTrashRetention),
+
+		storageBackend: cfg.StorageBackend,
+
+		publicURL: strings.TrimSuffix(cmp.Or(cfg.PublicURL, "http://"+cfg.Addr), "/"),
 	}
 }
 
@@ -66,28 +147,105 @@ func (s *Server) ShutdownServer() error {
 func (s *Server) Run(ctx context.Context) error {
 	log := logger.Get()
 	router := gin.Default()
-	router.Static("/uploads", "./uploads") // Подключаем каталог uploads как статический
+	// автор или жанр в пути OPDS может содержать "/", закодированный как %2F: маршрут
+	// ищется по сырому пути, а значения параметров раскодируются
+	router.UseRawPath = true
+	router.UnescapePathValues = true
+	router.GET("/uploads/*key", s.DownloadBlob) // файлы книг отдаются из BlobStore
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "If-Match", "If-None-Match", "If-Modified-Since", "Range"},
+		ExposeHeaders:    []string{"Content-Length", "ETag", "Last-Modified", "Accept-Ranges", "Content-Range"},
 		AllowCredentials: true,
 		MaxAge:           12 * 3600,
 	}))
 	router.GET("/", func(ctx *gin.Context) { ctx.String(http.StatusOK, "Hello") })
+	router.GET("/status", s.Status)
 	books := router.Group("/books")
 	{
 		books.GET("/:id", s.BookInfo)
+		books.GET("/isbn/:isbn", s.BookByISBN)
+		books.GET("/:id/pdf", s.JWTAuthRoleMiddleware(), s.PDFLink)
+		books.GET("/:id/pdf/download", s.DownloadPDF)
+		books.GET("/:id/epub", s.JWTAuthRoleMiddleware(), s.EPUBLink)
+		books.GET("/:id/epub/download", s.DownloadEPUB)
+		books.PUT("/:id", s.JWTAuthRoleMiddleware("admin"), s.ReplaceBook)
+		books.PATCH("/:id", s.JWTAuthRoleMiddleware("admin"), s.PatchBook)
 		books.DELETE("/remove/:id", s.JWTAuthRoleMiddleware("admin"), s.RemoveBook)
 		books.GET("/", s.AllBooks)
 		books.GET("/search", s.AllBooksWithSearch)
+		books.GET("/suggest", s.SuggestBooks)
+		books.GET("/trash", s.JWTAuthRoleMiddleware("admin"), s.ListTrash)
+		books.POST("/trash/:id/restore", s.JWTAuthRoleMiddleware("admin"), s.RestoreBook)
+		books.POST("/import", s.JWTAuthRoleMiddleware("admin"), s.ImportBooks)
+		books.GET("/export", s.JWTAuthRoleMiddleware("admin"), s.ExportBooks)
+		books.PUT("/:id/authors", s.JWTAuthRoleMiddleware("admin"), s.SetBookAuthors)
+		books.PUT("/:id/genres", s.JWTAuthRoleMiddleware("admin"), s.SetBookGenres)
+		books.GET("/:id/series", s.BookSeries)
+		books.PUT("/:id/series", s.JWTAuthRoleMiddleware("admin"), s.SetBookSeries)
+		books.PUT("/:id/work", s.JWTAuthRoleMiddleware("admin"), s.SetBookWork)
+		books.GET("/:id/history", s.JWTAuthRoleMiddleware("admin"), s.BookHistory)
+		books.POST("/:id/history/:revision/revert", s.JWTAuthRoleMiddleware("admin"), s.RevertBook)
+		books.PUT("/:id/tags", s.JWTAuthRoleMiddleware("admin"), s.SetBookTags)
+		books.POST("/:id/tags", s.JWTAuthRoleMiddleware(), s.SuggestBookTag)
+		books.PUT("/:id/tags/:tag_id", s.JWTAuthRoleMiddleware("admin"), s.ApproveBookTag)
+		books.DELETE("/:id/tags/:tag_id", s.JWTAuthRoleMiddleware("admin"), s.RemoveBookTag)
+	}
+	series := router.Group("/series")
+	{
+		series.GET("/:id", s.SeriesInfo)
+		series.POST("", s.JWTAuthRoleMiddleware("admin"), s.AddSeries)
+		series.PUT("/:id", s.JWTAuthRoleMiddleware("admin"), s.UpdateSeries)
+		series.DELETE("/:id", s.JWTAuthRoleMiddleware("admin"), s.RemoveSeries)
+	}
+	works := router.Group("/works")
+	{
+		works.GET("/:id", s.WorkInfo)
+	}
+	tags := router.Group("/tags")
+	{
+		tags.GET("", s.TagCloud)
+		tags.GET("/suggestions", s.JWTAuthRoleMiddleware("admin"), s.TagSuggestions)
+		tags.DELETE("/:id", s.JWTAuthRoleMiddleware("admin"), s.RemoveTag)
+	}
+	router.GET("/cache/stats", s.JWTAuthRoleMiddleware("admin"), s.StorageCacheStats)
+	genres := router.Group("/genres")
+	{
+		genres.GET("", s.ListGenres)
+		genres.GET("/:id", s.GenreInfo)
+		genres.POST("", s.JWTAuthRoleMiddleware("admin"), s.AddGenre)
+		genres.PUT("/:id", s.JWTAuthRoleMiddleware("admin"), s.UpdateGenre)
+		genres.DELETE("/:id", s.JWTAuthRoleMiddleware("admin"), s.RemoveGenre)
+	}
+	authors := router.Group("/authors")
+	{
+		authors.GET("", s.ListAuthors)
+		authors.GET("/:id", s.AuthorInfo)
+		authors.GET("/:id/books", s.AuthorBooks)
+		authors.POST("", s.JWTAuthRoleMiddleware("admin"), s.AddAuthor)
+		authors.PUT("/:id", s.JWTAuthRoleMiddleware("admin"), s.UpdateAuthor)
+		authors.DELETE("/:id", s.JWTAuthRoleMiddleware("admin"), s.RemoveAuthor)
+	}
+	opds := router.Group("/opds", s.JWTOrBasicAuthMiddleware())
+	{
+		opds.GET("", s.OPDSRoot)
+		opds.GET("/new", s.OPDSNew)
+		opds.GET("/popular", s.OPDSPopular)
+		opds.GET("/genres", s.OPDSGenres)
+		opds.GET("/genres/:genre", s.OPDSGenre)
+		opds.GET("/authors", s.OPDSAuthors)
+		opds.GET("/authors/:author", s.OPDSAuthor)
+		opds.GET("/search", s.OPDSSearch)
+		opds.GET("/search.xml", s.OPDSSearchDescription)
+		opds.GET("/books/:id/pdf", s.OPDSDownloadPDF)
+		opds.GET("/books/:id/epub", s.OPDSDownloadEPUB)
 	}
 	router.POST("/add-book", s.JWTAuthRoleMiddleware("admin"), s.AddBook)
 
 	s.serv.Handler = router
 	log.Debug().Msg("start delete listener")
-	// go s.deleter(ctx)
+	go s.deleter(ctx)
 	log.Info().Str("host", s.serv.Addr).Msg("server started")
 	if err := s.serv.ListenAndServe(); err != nil {
 		return err
@@ -122,6 +280,7 @@ func (s *Server) JWTAuthMiddleware() gin.HandlerFunc {
 
 		ctx.Set("uid", UID)
 		ctx.Set("role", Role)
+		ctx.Header("Cache-Control", privateCacheControl)
 		ctx.Next()
 	}
 }
@@ -167,9 +326,58 @@ func (s *Server) JWTAuthRoleMiddleware(roles ...string) gin.HandlerFunc {
 		fmt.Printf("role: %s\n", Role)
 		ctx.Set("uid", UID)
 		ctx.Set("role", Role)
+		ctx.Header("Cache-Control", privateCacheControl)
+		ctx.Next()
+	}
+}
+
+// JWTOrBasicAuthMiddleware пускает по JWT, как JWTAuthMiddleware, или по HTTP Basic
+// с логином и паролем из user-service: OPDS-клиенты умеют только Basic.
+func (s *Server) JWTOrBasicAuthMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		log := logger.Get()
+		var token string
+		if email, pass, ok := ctx.Request.BasicAuth(); ok {
+			t, err := s.Users.Login(email, pass)
+			if err != nil {
+				if errors.Is(err, users.ErrInvalidCredentials) {
+					basicChallenge(ctx, "Invalid login or password")
+					return
+				}
+				log.Error().Err(err).Msg("basic auth failed")
+				ctx.String(http.StatusServiceUnavailable, "Authorization service unavailable")
+				ctx.Abort()
+				return
+			}
+			token = t
+		} else {
+			scheme, value, _ := strings.Cut(ctx.GetHeader("Authorization"), " ")
+			if scheme != "Bearer" || value == "" {
+				basicChallenge(ctx, "Authorization header is required")
+				return
+			}
+			token = value
+		}
+
+		UID, Role, err := validToken(token)
+		if err != nil {
+			basicChallenge(ctx, "Invalid token")
+			return
+		}
+		ctx.Set("uid", UID)
+		ctx.Set("role", Role)
+		ctx.Header("Cache-Control", privateCacheControl)
 		ctx.Next()
 	}
 }
+
+// basicChallenge отвечает 401 с WWW-Authenticate, чтобы клиент спросил логин и пароль.
+func basicChallenge(ctx *gin.Context, msg string) {
+	ctx.Header("WWW-Authenticate", `Basic realm="bookly", charset="UTF-8"`)
+	ctx.String(http.StatusUnauthorized, msg)
+	ctx.Abort()
+}
+
 func validToken(tokenStr string) (string, string, error) {
 	claims := &Claims{}
 	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+var errTagName = fmt.Errorf("tag name must be %d to %d characters", consts.MinTagName, consts.MaxTagName)
+
+// TagCloud (GET /tags) возвращает облако тегов: теги с числом одобренных книг,
+// начиная с самых частых.
+func (s *Server) TagCloud(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(consts.DefaultTagCloudLimit)))
+	if err != nil || limit < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": errInvalidLimit.Error()})
+		return
+	}
+	tags, err := s.Storage.GetTags(min(limit, consts.MaxTagCloudLimit))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// TagSuggestions (GET /tags/suggestions) возвращает теги, предложенные
+// пользователями и ожидающие модерации.
+func (s *Server) TagSuggestions(ctx *gin.Context) {
+	suggestions, err := s.Storage.GetTagSuggestions()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
+// RemoveTag (DELETE /tags/:id) удаляет тег со всех книг.
+func (s *Server) RemoveTag(ctx *gin.Context) {
+	log := logger.Get()
+
+	if err := s.Storage.DeleteTag(ctx.Param("id")); err != nil {
+		if errors.Is(err, storerrros.ErrTagNoExist) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Error().Err(err).Msg("failed to delete tag")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete tag"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "tag deleted"})
+}
+
+// SetBookTags (PUT /books/:id/tags) заменяет теги книги списком названий. Теги
+// администратора одобрены сразу, недостающие теги создаются.
+func (s *Server) SetBookTags(ctx *gin.Context) {
+	log := logger.Get()
+
+	var names []string
+	if err := ctx.ShouldBindJSON(&names); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	var tags []string
+	for _, name := range names {
+		name, err := tagName(name)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !containsFold(tags, name) {
+			tags = append(tags, name)
+		}
+	}
+
+	bid := ctx.Param("id")
+	if err := s.Storage.SetBookTags(bid, tags); err != nil {
+		if errors.Is(err, storerrros.ErrBookNoExist) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Error().Err(err).Msg("failed to set book tags")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	book, err := s.Storage.GetBook(bid)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, withURLs(book))
+}
+
+// SuggestBookTag (POST /books/:id/tags) принимает тег от пользователя; тег
+// появится у книги после одобрения администратором.
+func (s *Server) SuggestBookTag(ctx *gin.Context) {
+	log := logger.Get()
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	name, err := tagName(req.Name)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	suggestion, err := s.Storage.SuggestBookTag(ctx.Param("id"), ctx.GetString("uid"), name)
+	if err != nil {
+		switch {
+		case errors.Is(err, storerrros.ErrBookNoExist):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, storerrros.ErrTagExists):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Error().Err(err).Msg("failed to suggest book tag")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	ctx.JSON(http.StatusAccepted, suggestion)
+}
+
+// ApproveBookTag (PUT /books/:id/tags/:tag_id) одобряет предложенный тег книги.
+func (s *Server) ApproveBookTag(ctx *gin.Context) {
+	s.moderateBookTag(ctx, s.Storage.ApproveBookTag, "tag approved")
+}
+
+// RemoveBookTag (DELETE /books/:id/tags/:tag_id) снимает тег с книги или
+// отклоняет предложенный тег.
+func (s *Server) RemoveBookTag(ctx *gin.Context) {
+	s.moderateBookTag(ctx, s.Storage.RemoveBookTag, "tag removed")
+}
+
+func (s *Server) moderateBookTag(ctx *gin.Context, moderate func(bid, tagID string) error, message string) {
+	log := logger.Get()
+
+	if err := moderate(ctx.Param("id"), ctx.Param("tag_id")); err != nil {
+		if errors.Is(err, storerrros.ErrTagNoExist) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Error().Err(err).Msg("failed to moderate book tag")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// tagName схлопывает пробелы в названии тега и проверяет его длину.
+func tagName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if n := utf8.RuneCountInString(name); n < consts.MinTagName || n > consts.MaxTagName {
+		return "", errTagName
+	}
+	return name, nil
+}
+
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/azaliaz/bookly/book-service/internal/config"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/server"
+	"github.com/azaliaz/bookly/book-service/internal/server/mocks"
+	"github.com/azaliaz/bookly/book-service/internal/storage"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func authorsRouter(s *server.Server) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/authors", s.ListAuthors)
+	router.GET("/authors/:id", s.AuthorInfo)
+	router.GET("/authors/:id/books", s.AuthorBooks)
+	router.POST("/authors", s.JWTAuthRoleMiddleware("admin"), s.AddAuthor)
+	router.PUT("/authors/:id", s.JWTAuthRoleMiddleware("admin"), s.UpdateAuthor)
+	router.DELETE("/authors/:id", s.JWTAuthRoleMiddleware("admin"), s.RemoveAuthor)
+	router.GET("/books/:id", s.BookInfo)
+	router.PUT("/books/:id/authors", s.JWTAuthRoleMiddleware("admin"), s.SetBookAuthors)
+	return router
+}
+
+func TestServer_authors(t *testing.T) {
+	stor := storage.New()
+	s := server.New(config.Config{BlobDir: t.TempDir()}, stor)
+	router := authorsRouter(s)
+	admin := "Bearer " + testToken(t, "admin1", "admin")
+
+	do := func(method, target, contentType string, body *bytes.Buffer) *httptest.ResponseRecorder {
+		if body == nil {
+			body = new(bytes.Buffer)
+		}
+		req := httptest.NewRequest(method, target, body)
+		req.Header.Set("Authorization", admin)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	doJSON := func(method, target string, v interface{}) *httptest.ResponseRecorder {
+		raw, err := json.Marshal(v)
+		assert.NoError(t, err)
+		return do(method, target, "application/json", bytes.NewBuffer(raw))
+	}
+
+	assert.NoError(t, stor.SaveBook(models.Book{Lable: "Война и мир", Author: "Лев  Толстой", Age: 1869}, ""))
+	assert.NoError(t, stor.SaveBook(models.Book{Lable: "Анна Каренина", Author: "лев толстой", Age: 1877}, ""))
+	page, err := stor.GetBooksWithFilters(models.BookFilter{Search: "Война"})
+	assert.NoError(t, err)
+	war := page.Books[0].BID
+
+	var tolstoy models.Author
+	t.Run("books are linked to one author by normalized name", func(t *testing.T) {
+		w := do(http.MethodGet, "/authors?q=толст", "", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var got models.AuthorsPage
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		if assert.Len(t, got.Authors, 1) {
+			tolstoy = got.Authors[0]
+			assert.Equal(t, "Лев Толстой", tolstoy.Name)
+			assert.Equal(t, 2, tolstoy.BookCount)
+		}
+	})
+
+	var translator models.Author
+	t.Run("create with photo", func(t *testing.T) {
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		assert.NoError(t, writer.WriteField("name", "  Louise   Maude "))
+		assert.NoError(t, writer.WriteField("alt_names", "Луиза Мод"))
+		assert.NoError(t, writer.WriteField("alt_names", "louise maude"))
+		assert.NoError(t, writer.WriteField("birth_year", "1855"))
+		assert.NoError(t, writer.WriteField("death_year", "1939"))
+		part, err := writer.CreateFormFile("photo", "photo.png")
+		assert.NoError(t, err)
+		_, err = part.Write(testCover(t, 300, 400))
+		assert.NoError(t, err)
+		assert.NoError(t, writer.Close())
+
+		w := do(http.MethodPost, "/authors", writer.FormDataContentType(), body)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &translator))
+		assert.Equal(t, "Louise Maude", translator.Name)
+		assert.Equal(t, []string{"Луиза Мод"}, translator.AltNames)
+		assert.Equal(t, 1855, translator.BirthYear)
+		assert.True(t, strings.HasPrefix(translator.PhotoURL, "/uploads/photos/"))
+		assert.Len(t, translator.PhotoURLs, 3)
+	})
+
+	t.Run("search by alternative spelling", func(t *testing.T) {
+		w := do(http.MethodGet, "/authors?q=луиза", "", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), translator.ID)
+		assert.NotContains(t, w.Body.String(), tolstoy.ID)
+	})
+
+	t.Run("invalid life years", func(t *testing.T) {
+		w := doJSON(http.MethodPut, "/authors/"+translator.ID,
+			map[string]interface{}{"name": "Louise Maude", "birth_year": 1939, "death_year": 1855})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "death_year must not be less than birth_year")
+	})
+
+	t.Run("update keeps photo", func(t *testing.T) {
+		w := doJSON(http.MethodPut, "/authors/"+translator.ID,
+			map[string]interface{}{"name": "Louise Maude", "bio": "Translator of Tolstoy", "birth_year": 1855})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var got models.Author
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Equal(t, "Translator of Tolstoy", got.Bio)
+		assert.Zero(t, got.DeathYear)
+		assert.Equal(t, translator.PhotoURL, got.PhotoURL)
+	})
+
+	t.Run("set book authors with roles", func(t *testing.T) {
+		w := doJSON(http.MethodPut, "/books/"+war+"/authors", []models.BookAuthor{
+			{AuthorID: tolstoy.ID},
+			{AuthorID: translator.ID, Role: "translator"},
+		})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var book models.Book
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &book))
+		assert.Equal(t, []models.BookAuthor{
+			{AuthorID: tolstoy.ID, Name: "Лев Толстой", Role: "author"},
+			{AuthorID: translator.ID, Name: "Louise Maude", Role: "translator"},
+		}, book.Authors)
+	})
+
+	t.Run("reject unknown role and author", func(t *testing.T) {
+		w := doJSON(http.MethodPut, "/books/"+war+"/authors", []models.BookAuthor{{AuthorID: tolstoy.ID, Role: "editor"}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = doJSON(http.MethodPut, "/books/"+war+"/authors", []models.BookAuthor{{AuthorID: "missing"}})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), storerrros.ErrAuthorNoExist.Error())
+	})
+
+	t.Run("author books by role", func(t *testing.T) {
+		w := do(http.MethodGet, "/authors/"+tolstoy.ID+"/books?sort_by=age&ascending=true", "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var got models.BooksPage
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		if assert.Len(t, got.Books, 2) {
+			assert.Equal(t, "Война и мир", got.Books[0].Lable)
+		}
+
+		w = do(http.MethodGet, "/authors/"+translator.ID+"/books?role=translator", "", nil)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Len(t, got.Books, 1)
+
+		w = do(http.MethodGet, "/authors/"+translator.ID+"/books?role=illustrator", "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"books": [], "total": 0}`, w.Body.String())
+	})
+
+	t.Run("remove author keeps books", func(t *testing.T) {
+		w := do(http.MethodDelete, "/authors/"+translator.ID, "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = do(http.MethodGet, "/authors/"+translator.ID, "", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = do(http.MethodGet, "/authors/"+translator.ID+"/books", "", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		book, err := stor.GetBook(war)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.BookAuthor{{AuthorID: tolstoy.ID, Name: "Лев Толстой", Role: "author"}}, book.Authors)
+	})
+}
+
+func TestServer_authorsErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	s := server.New(config.Config{BlobDir: t.TempDir()}, mockStorage)
+	router := authorsRouter(s)
+
+	do := func(method, target, body, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if role != "" {
+			req.Header.Set("Authorization", "Bearer "+testToken(t, "u1", role))
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("create requires admin", func(t *testing.T) {
+		w := do(http.MethodPost, "/authors", `{"name": "Лев Толстой"}`, "user")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("create requires name", func(t *testing.T) {
+		w := do(http.MethodPost, "/authors", `{"name": " ", "bio": "x"}`, "admin")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		mockStorage.EXPECT().ListAuthors("", models.Page{Limit: 20, Cursor: "bad"}).
+			Return(models.AuthorsPage{}, storerrros.ErrInvalidCursor)
+
+		w := do(http.MethodGet, "/authors?cursor=bad", "", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("unknown book", func(t *testing.T) {
+		mockStorage.EXPECT().SetBookAuthors("b1", gomock.Any()).Return(storerrros.ErrBookNoExist)
+
+		w := do(http.MethodPut, "/books/b1/authors", `[{"author_id": "a1"}]`, "admin")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("invalid role filter", func(t *testing.T) {
+		w := do(http.MethodGet, "/authors/a1/books?role=editor", "", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
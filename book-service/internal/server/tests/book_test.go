@@ -2,12 +2,17 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/azaliaz/bookly/book-service/internal/blob"
+	"github.com/azaliaz/bookly/book-service/internal/covers"
 	"github.com/azaliaz/bookly/book-service/internal/domain/models"
 	"github.com/azaliaz/bookly/book-service/internal/server"
 	"github.com/azaliaz/bookly/book-service/internal/server/mocks"
@@ -27,39 +32,208 @@ func TestServer_allBooks(t *testing.T) {
 	mockStorage := mocks.NewMockStorage(ctrl)
 	s := &server.Server{Storage: mockStorage}
 
+	createCtx := func(target string) (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodGet, target, nil)
+		return ctx, w
+	}
+
 	t.Run("success", func(t *testing.T) {
 		books := []models.Book{{Lable: "Book1"}, {Lable: "Book2"}}
-		mockStorage.EXPECT().GetBooks().Return(books, nil)
+		mockStorage.EXPECT().GetBooks(models.Page{Limit: 20}).Return(models.BooksPage{Books: books, Total: 2}, nil)
 
-		w := httptest.NewRecorder()
-		ctx, _ := gin.CreateTestContext(w)
+		ctx, w := createCtx("/books/")
 
 		s.AllBooks(ctx)
 
 		assert.Equal(t, http.StatusOK, w.Code)
 		assert.Contains(t, w.Body.String(), "Book1")
 		assert.Contains(t, w.Body.String(), "Book2")
+		assert.Contains(t, w.Body.String(), `"total":2`)
 	})
 
-	t.Run("empty list error", func(t *testing.T) {
-		mockStorage.EXPECT().GetBooks().Return(nil, storerrros.ErrEmptyBooksList)
+	t.Run("next page", func(t *testing.T) {
+		page := models.BooksPage{Books: []models.Book{{Lable: "Book3"}}, NextCursor: "next", Total: 5}
+		mockStorage.EXPECT().GetBooks(models.Page{Limit: 1, Cursor: "abc"}).Return(page, nil)
 
-		w := httptest.NewRecorder()
-		ctx, _ := gin.CreateTestContext(w)
+		ctx, w := createCtx("/books/?limit=1&cursor=abc")
 
 		s.AllBooks(ctx)
 
-		assert.Equal(t, http.StatusNotFound, w.Code)
-		assert.Contains(t, w.Body.String(), storerrros.ErrEmptyBooksList.Error())
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"next_cursor":"next"`)
+	})
+
+	t.Run("limit is clamped", func(t *testing.T) {
+		mockStorage.EXPECT().GetBooks(models.Page{Limit: 100}).Return(models.BooksPage{}, nil)
+
+		ctx, w := createCtx("/books/?limit=1000")
+
+		s.AllBooks(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		ctx, w := createCtx("/books/?limit=-1")
+
+		s.AllBooks(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "limit")
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		mockStorage.EXPECT().GetBooks(gomock.Any()).Return(models.BooksPage{}, storerrros.ErrInvalidCursor)
+
+		ctx, w := createCtx("/books/?cursor=garbage")
+
+		s.AllBooks(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), storerrros.ErrInvalidCursor.Error())
+	})
+
+	t.Run("empty catalogue", func(t *testing.T) {
+		mockStorage.EXPECT().GetBooks(gomock.Any()).Return(models.BooksPage{Books: []models.Book{}}, nil)
+
+		ctx, w := createCtx("/books/")
+
+		s.AllBooks(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"books":[],"total":0}`, w.Body.String())
 	})
 
 	t.Run("internal error", func(t *testing.T) {
-		mockStorage.EXPECT().GetBooks().Return(nil, errors.New("db error"))
+		mockStorage.EXPECT().GetBooks(gomock.Any()).Return(models.BooksPage{}, errors.New("db error"))
+
+		ctx, w := createCtx("/books/")
+
+		s.AllBooks(ctx)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "db error")
+	})
+}
+
+func TestServer_allBooksWithSearch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	s := &server.Server{Storage: mockStorage}
+
+	t.Run("filters and page", func(t *testing.T) {
+		filter := models.BookFilter{
+			Search:    "war",
+			Genres:    []string{"novel"},
+			SortBy:    "lable",
+			Ascending: false,
+			Page:      models.Page{Limit: 5, Cursor: "abc"},
+		}
+		mockStorage.EXPECT().GetBooksWithFilters(filter).
+			Return(models.BooksPage{Books: []models.Book{{Lable: "War and Peace"}}, Total: 1}, nil)
 
 		w := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodGet,
+			"/books/search?search=war&genre=novel&sort_by=lable&ascending=false&limit=5&cursor=abc", nil)
 
-		s.AllBooks(ctx)
+		s.AllBooksWithSearch(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "War and Peace")
+	})
+
+	t.Run("with facets", func(t *testing.T) {
+		filter := models.BookFilter{Year: "1860-1869", SortBy: "rating", Ascending: true, Page: models.Page{Limit: 20}}
+		facets := models.BookFacets{
+			Genres:  []models.FacetCount{{Value: "Роман", Count: 3}},
+			Years:   []models.FacetCount{{Value: "1860-1869", Count: 2}},
+			Authors: []models.FacetCount{{Value: "Лев Толстой", Count: 2}},
+		}
+		mockStorage.EXPECT().GetBooksWithFilters(filter).Return(models.BooksPage{Books: []models.Book{{Lable: "War and Peace"}}, Total: 1}, nil)
+		mockStorage.EXPECT().GetBookFacets(filter).Return(facets, nil)
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/books/search?year=1860-1869&facets=true", nil)
+
+		s.AllBooksWithSearch(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"facets":{"genres":[{"value":"Роман","count":3}]`)
+		assert.Contains(t, w.Body.String(), "Лев Толстой")
+	})
+
+	t.Run("facets error", func(t *testing.T) {
+		mockStorage.EXPECT().GetBooksWithFilters(gomock.Any()).Return(models.BooksPage{Total: 1}, nil)
+		mockStorage.EXPECT().GetBookFacets(gomock.Any()).Return(models.BookFacets{}, errors.New("db error"))
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/books/search?facets=true", nil)
+
+		s.AllBooksWithSearch(ctx)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestServer_suggestBooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	s := &server.Server{Storage: mockStorage}
+
+	createCtx := func(target string) (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodGet, target, nil)
+		return ctx, w
+	}
+
+	t.Run("success", func(t *testing.T) {
+		suggestions := []models.Suggestion{{Kind: "author", Text: "Фёдор Достоевский", Score: 0.5}}
+		mockStorage.EXPECT().SuggestBooks("Dostoevsky", 10).Return(suggestions, nil)
+
+		ctx, w := createCtx("/books/suggest?q=Dostoevsky")
+
+		s.SuggestBooks(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Фёдор Достоевский")
+	})
+
+	t.Run("no matches is not an error", func(t *testing.T) {
+		mockStorage.EXPECT().SuggestBooks("zzz", 25).Return(nil, nil)
+
+		ctx, w := createCtx("/books/suggest?q=zzz&limit=100")
+
+		s.SuggestBooks(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"suggestions":[]`)
+	})
+
+	t.Run("missing query", func(t *testing.T) {
+		ctx, w := createCtx("/books/suggest?q=%20")
+
+		s.SuggestBooks(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "missing query")
+	})
+
+	t.Run("internal error", func(t *testing.T) {
+		mockStorage.EXPECT().SuggestBooks("war", 10).Return(nil, errors.New("db error"))
+
+		ctx, w := createCtx("/books/suggest?q=war")
+
+		s.SuggestBooks(ctx)
 
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 		assert.Contains(t, w.Body.String(), "db error")
@@ -79,6 +253,7 @@ func TestServer_bookInfo(t *testing.T) {
 
 		w := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/books/123", nil)
 		ctx.Params = gin.Params{{Key: "id", Value: "123"}}
 
 		s.BookInfo(ctx)
@@ -92,6 +267,7 @@ func TestServer_bookInfo(t *testing.T) {
 
 		w := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/books/123", nil)
 		ctx.Params = gin.Params{{Key: "id", Value: "123"}}
 
 		s.BookInfo(ctx)
@@ -105,6 +281,7 @@ func TestServer_bookInfo(t *testing.T) {
 
 		w := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/books/123", nil)
 		ctx.Params = gin.Params{{Key: "id", Value: "123"}}
 
 		s.BookInfo(ctx)
@@ -130,7 +307,7 @@ func TestServer_removeBook(t *testing.T) {
 	}
 
 	t.Run("success", func(t *testing.T) {
-		mockStorage.EXPECT().DeleteBook("book123").Return(nil).Times(1) // ← Явно указать, что ожидается один вызов
+		mockStorage.EXPECT().DeleteBook("book123", "user1").Return(nil).Times(1) // ← Явно указать, что ожидается один вызов
 
 		ctx, w := createCtxWithUID()
 		s.RemoveBook(ctx)
@@ -163,7 +340,7 @@ func TestServer_removeBook(t *testing.T) {
 	})
 
 	t.Run("not found error", func(t *testing.T) {
-		mockStorage.EXPECT().DeleteBook("book123").Return(errors.New("book not found"))
+		mockStorage.EXPECT().DeleteBook("book123", "user1").Return(storerrros.ErrBookNoExist)
 
 		ctx, w := createCtxWithUID()
 		s.RemoveBook(ctx)
@@ -173,7 +350,7 @@ func TestServer_removeBook(t *testing.T) {
 	})
 
 	t.Run("internal error", func(t *testing.T) {
-		mockStorage.EXPECT().DeleteBook("book123").Return(errors.New("some error"))
+		mockStorage.EXPECT().DeleteBook("book123", "user1").Return(errors.New("some error"))
 
 		ctx, w := createCtxWithUID()
 		s.RemoveBook(ctx)
@@ -188,7 +365,10 @@ func TestServer_addBook(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockStorage := mocks.NewMockStorage(ctrl)
-	s := &server.Server{Storage: mockStorage}
+	blobs := blob.NewLocal(t.TempDir())
+	s := &server.Server{Storage: mockStorage, Blobs: blobs}
+	cover := testCover(t, 800, 1200)
+	pdf := testPDF("", "", 2)
 
 	createMultipartRequest := func(t *testing.T, fields map[string]string, coverContent, pdfContent []byte) *http.Request {
 		body := new(bytes.Buffer)
@@ -224,7 +404,27 @@ func TestServer_addBook(t *testing.T) {
 	}
 
 	t.Run("success", func(t *testing.T) {
-		mockStorage.EXPECT().SaveBook(gomock.Any()).Return(nil)
+		mockStorage.EXPECT().GetBookByFileHash(gomock.Any()).Return(models.Book{}, storerrros.ErrBookNoExist)
+		mockStorage.EXPECT().SaveBook(gomock.Any(), gomock.Any()).DoAndReturn(func(book models.Book, _ string) error {
+			assert.True(t, strings.HasPrefix(book.CoverKey, "covers/"))
+			assert.True(t, strings.HasSuffix(book.CoverKey, "/full.jpg"))
+			for _, variant := range []string{"thumbnail", "card", "full"} {
+				key := strings.TrimSuffix(book.CoverKey, "full.jpg") + variant + ".jpg"
+				obj, err := blobs.Get(context.Background(), key)
+				if assert.NoError(t, err, variant) {
+					obj.Body.Close()
+				}
+			}
+			assert.True(t, strings.HasPrefix(book.PDFKey, "pdfs/"))
+
+			obj, err := blobs.Get(context.Background(), book.PDFKey)
+			assert.NoError(t, err)
+			defer obj.Body.Close()
+			content, _ := io.ReadAll(obj.Body)
+			assert.Equal(t, pdf, content)
+			assert.Equal(t, 2, book.PDFPages)
+			return nil
+		})
 
 		fields := map[string]string{
 			"lable":  "Test Book",
@@ -235,7 +435,7 @@ func TestServer_addBook(t *testing.T) {
 			"rating": "5",
 		}
 
-		req := createMultipartRequest(t, fields, []byte("fake cover"), []byte("fake pdf"))
+		req := createMultipartRequest(t, fields, cover, pdf)
 
 		w := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(w)
@@ -259,7 +459,7 @@ func TestServer_addBook(t *testing.T) {
 			"rating": "5",
 		}
 
-		req := createMultipartRequest(t, fields, []byte("fake cover"), []byte("fake pdf"))
+		req := createMultipartRequest(t, fields, cover, pdf)
 
 		w := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(w)
@@ -272,6 +472,7 @@ func TestServer_addBook(t *testing.T) {
 	})
 
 	t.Run("missing cover file", func(t *testing.T) {
+		mockStorage.EXPECT().GetBookByFileHash(gomock.Any()).Return(models.Book{}, storerrros.ErrBookNoExist)
 		fields := map[string]string{
 			"lable":  "Test Book",
 			"author": "Test Author",
@@ -281,7 +482,7 @@ func TestServer_addBook(t *testing.T) {
 			"rating": "5",
 		}
 
-		req := createMultipartRequest(t, fields, nil, []byte("fake pdf"))
+		req := createMultipartRequest(t, fields, nil, pdf)
 
 		w := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(w)
@@ -304,7 +505,7 @@ func TestServer_addBook(t *testing.T) {
 			"rating": "5",
 		}
 
-		req := createMultipartRequest(t, fields, []byte("fake cover"), nil)
+		req := createMultipartRequest(t, fields, cover, nil)
 
 		w := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(w)
@@ -314,11 +515,12 @@ func TestServer_addBook(t *testing.T) {
 		s.AddBook(ctx)
 
 		assert.Equal(t, http.StatusBadRequest, w.Code)
-		assert.Contains(t, w.Body.String(), "failed to get pdf file")
+		assert.Contains(t, w.Body.String(), "failed to get pdf or epub file")
 	})
 
 	t.Run("save book fails", func(t *testing.T) {
-		mockStorage.EXPECT().SaveBook(gomock.Any()).Return(errors.New("save failed"))
+		mockStorage.EXPECT().GetBookByFileHash(gomock.Any()).Return(models.Book{}, storerrros.ErrBookNoExist)
+		mockStorage.EXPECT().SaveBook(gomock.Any(), gomock.Any()).Return(errors.New("save failed"))
 
 		fields := map[string]string{
 			"lable":  "Test Book",
@@ -329,7 +531,7 @@ func TestServer_addBook(t *testing.T) {
 			"rating": "5",
 		}
 
-		req := createMultipartRequest(t, fields, []byte("fake cover"), []byte("fake pdf"))
+		req := createMultipartRequest(t, fields, cover, pdf)
 
 		w := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(w)
@@ -352,7 +554,7 @@ func TestServer_addBook(t *testing.T) {
 			"rating": "invalid",
 		}
 
-		req := createMultipartRequest(t, fields, []byte("fake cover"), []byte("fake pdf"))
+		req := createMultipartRequest(t, fields, cover, pdf)
 
 		w := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(w)
@@ -374,7 +576,7 @@ func TestServer_addBook(t *testing.T) {
 			"rating": "5",
 		}
 
-		req := createMultipartRequest(t, fields, []byte("fake cover"), []byte("fake pdf"))
+		req := createMultipartRequest(t, fields, cover, pdf)
 
 		w := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(w)
@@ -386,16 +588,40 @@ func TestServer_addBook(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 		assert.Contains(t, w.Body.String(), "missing or empty field: lable")
 	})
-	t.Run("failed to create cover directory", func(t *testing.T) {
+	t.Run("cover is not an image", func(t *testing.T) {
+		mockStorage.EXPECT().GetBookByFileHash(gomock.Any()).Return(models.Book{}, storerrros.ErrBookNoExist)
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = createMultipartRequest(t, map[string]string{
+			"lable":  "Test Book",
+			"author": "Test Author",
+			"desc":   "Test Description",
+			"genre":  "Test Genre",
+			"age":    "10",
+			"rating": "5",
+		}, []byte("fake cover"), pdf)
+		ctx.Set("uid", "user1")
+
+		s.AddBook(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), covers.ErrNotImage.Error())
+	})
+
+	t.Run("failed to save cover", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		mockStorage := mocks.NewMockStorage(ctrl)
-		s := &server.Server{Storage: mockStorage}
+		mockBlobs := mocks.NewMockBlobStore(ctrl)
+		s := &server.Server{Storage: mockStorage, Blobs: mockBlobs}
+		mockStorage.EXPECT().GetBookByFileHash(gomock.Any()).Return(models.Book{}, storerrros.ErrBookNoExist)
 
-		mockStorage.EXPECT().SaveBook(gomock.Any()).Return(nil)
+		mockBlobs.EXPECT().Put(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "image/jpeg").
+			Return(errors.New("disk full"))
 
-		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
 		ctx.Request = createMultipartRequest(t, map[string]string{
 			"lable":  "Test Book",
 			"author": "Test Author",
@@ -403,10 +629,13 @@ func TestServer_addBook(t *testing.T) {
 			"genre":  "Test Genre",
 			"age":    "10",
 			"rating": "5",
-		}, []byte("fake cover"), []byte("fake pdf"))
+		}, cover, pdf)
 
 		ctx.Set("uid", "user1")
 		s.AddBook(ctx)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to save cover file")
 	})
 
 }
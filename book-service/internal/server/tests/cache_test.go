@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azaliaz/bookly/book-service/internal/config"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/server"
+	"github.com/azaliaz/bookly/book-service/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_caching(t *testing.T) {
+	stor := storage.New()
+	s := server.New(config.Config{BlobDir: t.TempDir()}, stor)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/books/", s.AllBooks)
+	router.GET("/books/search", s.AllBooksWithSearch)
+	router.GET("/books/trash", s.JWTAuthRoleMiddleware("admin"), s.ListTrash)
+	router.GET("/books/:id", s.BookInfo)
+	router.PATCH("/books/:id", s.JWTAuthRoleMiddleware("admin"), s.PatchBook)
+	admin := "Bearer " + testToken(t, "admin1", "admin")
+
+	do := func(method, target string, headers map[string]string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	get := func(target string, headers map[string]string) *httptest.ResponseRecorder {
+		return do(http.MethodGet, target, headers, "")
+	}
+
+	_, err := stor.SaveBooks([]models.Book{
+		{Lable: "Обломов", Author: "Иван Гончаров", Age: 1859, ISBN: "9785170906307"},
+		{Lable: "Обрыв", Author: "Иван Гончаров", Age: 1869},
+	}, "")
+	assert.NoError(t, err)
+	book, err := stor.GetBookByISBN("9785170906307")
+	assert.NoError(t, err)
+	target := "/books/" + book.BID
+
+	t.Run("book info", func(t *testing.T) {
+		w := get(target, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		etag := w.Header().Get("ETag")
+		modified := w.Header().Get("Last-Modified")
+		assert.True(t, strings.HasPrefix(etag, `"1-`), etag)
+		assert.NotEmpty(t, modified)
+		assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+		assert.Contains(t, w.Body.String(), `"updated_at"`)
+
+		w = get(target, map[string]string{"If-None-Match": etag})
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.String())
+		assert.Equal(t, etag, w.Header().Get("ETag"))
+
+		assert.Equal(t, http.StatusNotModified, get(target, map[string]string{"If-None-Match": `"other", W/` + etag}).Code)
+		assert.Equal(t, http.StatusNotModified, get(target, map[string]string{"If-Modified-Since": modified}).Code)
+
+		before := book.UpdatedAt.Add(-time.Hour).Format(http.TimeFormat)
+		assert.Equal(t, http.StatusOK, get(target, map[string]string{"If-Modified-Since": before}).Code)
+		// If-None-Match важнее If-Modified-Since
+		assert.Equal(t, http.StatusOK, get(target, map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": modified}).Code)
+	})
+
+	t.Run("linked data changes etag", func(t *testing.T) {
+		etag := get(target, nil).Header().Get("ETag")
+		assert.NoError(t, stor.SetBookTags(book.BID, []string{"классика"}))
+
+		w := get(target, map[string]string{"If-None-Match": etag})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEqual(t, etag, w.Header().Get("ETag"))
+		assert.Contains(t, w.Body.String(), "классика")
+	})
+
+	t.Run("etag works as if-match", func(t *testing.T) {
+		etag := get(target, nil).Header().Get("ETag")
+		w := do(http.MethodPatch, target, map[string]string{
+			"Authorization": admin, "Content-Type": "application/json", "If-Match": etag,
+		}, `{"desc":"Роман о русском барине"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, strings.HasPrefix(w.Header().Get("ETag"), `"2-`), w.Header().Get("ETag"))
+
+		assert.Equal(t, http.StatusOK, get(target, map[string]string{"If-None-Match": etag}).Code)
+	})
+
+	t.Run("lists", func(t *testing.T) {
+		for _, list := range []string{"/books/", "/books/search?search=Гончаров"} {
+			w := get(list, nil)
+			assert.Equal(t, http.StatusOK, w.Code, list)
+			etag := w.Header().Get("ETag")
+			assert.NotEmpty(t, etag, list)
+			assert.Empty(t, w.Header().Get("Last-Modified"), list)
+			assert.Equal(t, http.StatusNotModified, get(list, map[string]string{"If-None-Match": etag}).Code, list)
+		}
+
+		etag := get("/books/", nil).Header().Get("ETag")
+		assert.NoError(t, stor.SaveBook(models.Book{Lable: "Фрегат «Паллада»", Author: "Иван Гончаров"}, ""))
+		assert.Equal(t, http.StatusOK, get("/books/", map[string]string{"If-None-Match": etag}).Code)
+	})
+
+	t.Run("authenticated routes are private", func(t *testing.T) {
+		w := get("/books/trash", map[string]string{"Authorization": admin})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+	})
+}
@@ -0,0 +1,251 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/azaliaz/bookly/book-service/internal/blob"
+	"github.com/azaliaz/bookly/book-service/internal/covers"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/server"
+	"github.com/azaliaz/bookly/book-service/internal/server/mocks"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+// testCover возвращает PNG-обложку размером w x h.
+func testCover(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, testImage(w, h)))
+	return buf.Bytes()
+}
+
+// exifJPEG возвращает JPEG с EXIF-сегментом, в котором задан тег Orientation.
+func exifJPEG(t *testing.T, w, h int, orientation uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, testImage(w, h), nil))
+
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = append(tiff, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)
+	app1 := append([]byte("Exif\x00\x00"), tiff...)
+
+	data := buf.Bytes()
+	out := append([]byte{}, data[:2]...)
+	out = append(out, 0xFF, 0xE1)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(app1)+2))
+	out = append(out, app1...)
+	return append(out, data[2:]...)
+}
+
+func decodeVariants(t *testing.T, images []covers.Image) map[string]image.Config {
+	t.Helper()
+	configs := make(map[string]image.Config)
+	for _, img := range images {
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(img.Data))
+		assert.NoError(t, err)
+		assert.Equal(t, "jpeg", format)
+		configs[img.Variant] = cfg
+	}
+	return configs
+}
+
+func TestCovers_process(t *testing.T) {
+	t.Run("resize png", func(t *testing.T) {
+		images, err := covers.Process(bytes.NewReader(testCover(t, 1000, 1500)))
+		assert.NoError(t, err)
+
+		configs := decodeVariants(t, images)
+		assert.Len(t, configs, 3)
+		assert.Equal(t, 160, configs[covers.Thumbnail].Width)
+		assert.Equal(t, 240, configs[covers.Thumbnail].Height)
+		assert.Equal(t, 400, configs[covers.Card].Width)
+		assert.Equal(t, 1000, configs[covers.Full].Width)
+	})
+
+	t.Run("small image is not upscaled", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, gif.Encode(&buf, testImage(100, 50), nil))
+
+		images, err := covers.Process(&buf)
+		assert.NoError(t, err)
+
+		for variant, cfg := range decodeVariants(t, images) {
+			assert.Equal(t, 100, cfg.Width, variant)
+			assert.Equal(t, 50, cfg.Height, variant)
+		}
+	})
+
+	t.Run("exif orientation applied and stripped", func(t *testing.T) {
+		images, err := covers.Process(bytes.NewReader(exifJPEG(t, 300, 200, 6)))
+		assert.NoError(t, err)
+
+		configs := decodeVariants(t, images)
+		assert.Equal(t, 200, configs[covers.Full].Width)
+		assert.Equal(t, 300, configs[covers.Full].Height)
+		for _, img := range images {
+			assert.False(t, bytes.Contains(img.Data, []byte("Exif")), img.Variant)
+		}
+	})
+
+	t.Run("not an image", func(t *testing.T) {
+		_, err := covers.Process(strings.NewReader("%PDF-1.7 not a cover"))
+		assert.ErrorIs(t, err, covers.ErrNotImage)
+	})
+
+	t.Run("too many pixels", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 8000, 6000))))
+
+		_, err := covers.Process(&buf)
+		assert.ErrorIs(t, err, covers.ErrTooLarge)
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		assert.Equal(t, map[string]string{
+			covers.Thumbnail: "covers/abc/thumbnail.jpg",
+			covers.Card:      "covers/abc/card.jpg",
+			covers.Full:      "covers/abc/full.jpg",
+		}, covers.Keys("covers/abc/full.jpg"))
+		assert.Nil(t, covers.Keys("covers/abc.jpg"))
+		assert.Nil(t, covers.Keys(""))
+	})
+}
+
+func TestServer_coverURLs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	s := &server.Server{Storage: mockStorage}
+
+	do := func(coverKey string) string {
+		mockStorage.EXPECT().GetBook("123").Return(models.Book{BID: "123", Lable: "Book1", CoverKey: coverKey}, nil)
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/books/123", nil)
+		ctx.Params = gin.Params{{Key: "id", Value: "123"}}
+		s.BookInfo(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+
+	body := do("covers/abc/full.jpg")
+	assert.Contains(t, body, `"cover_url":"/uploads/covers/abc/full.jpg"`)
+	assert.Contains(t, body, `"thumbnail":"/uploads/covers/abc/thumbnail.jpg"`)
+	assert.Contains(t, body, `"card":"/uploads/covers/abc/card.jpg"`)
+
+	body = do("covers/legacy.png")
+	assert.Contains(t, body, `"cover_url":"/uploads/covers/legacy.png"`)
+	assert.NotContains(t, body, "cover_urls")
+}
+
+func TestServer_backfillCovers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	mockStorage := mocks.NewMockStorage(ctrl)
+	blobs := blob.NewLocal(t.TempDir())
+	s := &server.Server{Storage: mockStorage, Blobs: blobs}
+
+	put := func(key string, data []byte) {
+		assert.NoError(t, blobs.Put(ctx, key, bytes.NewReader(data), int64(len(data)), blob.ContentType(key)))
+	}
+	put("covers/b1.png", testCover(t, 600, 900))
+	put("covers/b3.jpg", []byte("not an image"))
+	put("covers/b5.png", testCover(t, 60, 90))
+
+	books := []models.Book{
+		{BID: "b1", CoverKey: "covers/b1.png", Version: 2},
+		{BID: "b2", CoverKey: "covers/b2/full.jpg", Version: 1},
+		{BID: "b3", CoverKey: "covers/b3.jpg", Version: 1},
+		{BID: "b4", CoverKey: "covers/missing.jpg", Version: 1},
+		{BID: "b5", CoverKey: "covers/b5.png", Version: 7},
+		{BID: "b6", Version: 1},
+	}
+	mockStorage.EXPECT().StreamBooks(models.BookFilter{}, gomock.Any()).
+		DoAndReturn(func(_ models.BookFilter, fn func(models.Book) error) error {
+			for _, book := range books {
+				if err := fn(book); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	var b1Key, b5Key string
+	mockStorage.EXPECT().UpdateBook(gomock.Any(), 2, "backfill").DoAndReturn(func(b models.Book, _ int, _ string) (models.Book, error) {
+		assert.Equal(t, "b1", b.BID)
+		b1Key = b.CoverKey
+		b.Version++
+		return b, nil
+	})
+	mockStorage.EXPECT().UpdateBook(gomock.Any(), 7, "backfill").DoAndReturn(func(b models.Book, _ int, _ string) (models.Book, error) {
+		b5Key = b.CoverKey
+		return models.Book{}, storerrros.ErrVersionConflict
+	})
+
+	report, err := s.BackfillCovers(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, server.BackfillReport{Processed: 1, Skipped: 2, Failed: 1}, report)
+
+	// b1 переключена на варианты, исходный файл остается для отката по истории
+	assert.Len(t, covers.Keys(b1Key), 3)
+	for _, key := range covers.Keys(b1Key) {
+		obj, err := blobs.Get(ctx, key)
+		if assert.NoError(t, err, key) {
+			obj.Body.Close()
+		}
+	}
+	obj, err := blobs.Get(ctx, "covers/b1.png")
+	if assert.NoError(t, err) {
+		obj.Body.Close()
+	}
+
+	// b5 изменили во время обработки: варианты удалены, исходный файл на месте
+	for _, key := range covers.Keys(b5Key) {
+		_, err := blobs.Get(ctx, key)
+		assert.ErrorIs(t, err, blob.ErrNotFound, key)
+	}
+	obj, err = blobs.Get(ctx, "covers/b5.png")
+	if assert.NoError(t, err) {
+		obj.Body.Close()
+	}
+
+	t.Run("stream error", func(t *testing.T) {
+		mockStorage.EXPECT().StreamBooks(gomock.Any(), gomock.Any()).Return(errors.New("db error"))
+
+		_, err := s.BackfillCovers(ctx)
+		assert.EqualError(t, err, "db error")
+	})
+}
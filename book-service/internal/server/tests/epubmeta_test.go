@@ -0,0 +1,348 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/azaliaz/bookly/book-service/internal/blob"
+	"github.com/azaliaz/bookly/book-service/internal/covers"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/epubmeta"
+	"github.com/azaliaz/bookly/book-service/internal/server"
+	"github.com/azaliaz/bookly/book-service/internal/server/mocks"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const epubContainer = `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>`
+
+// epub3OPF - пакет EPUB 3: роли создателей заданы через refines, обложка - через
+// properties="cover-image".
+const epub3OPF = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="uid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="uid">urn:isbn:978-5-17-090630-7</dc:identifier>
+    <dc:title>  Война
+      и мир </dc:title>
+    <dc:creator id="c1">Лев Толстой</dc:creator>
+    <meta refines="#c1" property="role" scheme="marc:relators">aut</meta>
+    <dc:creator id="c2">Иван Иванов</dc:creator>
+    <meta refines="#c2" property="role" scheme="marc:relators">ill</meta>
+    <dc:language>ru</dc:language>
+  </metadata>
+  <manifest>
+    <item id="img1" href="images/cover%20art.png" media-type="image/png" properties="cover-image"/>
+    <item id="ch1" href="ch1.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+</package>`
+
+// epub2OPF - пакет EPUB 2: ISBN в opf:scheme, обложка через <meta name="cover">.
+const epub2OPF = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" xmlns:opf="http://www.idpf.org/2007/opf" version="2.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:title>Anna Karenina</dc:title>
+    <dc:creator opf:role="trl">Constance Garnett</dc:creator>
+    <dc:identifier opf:scheme="UUID">a1b2c3</dc:identifier>
+    <dc:identifier opf:scheme="ISBN">0-14-044913-2</dc:identifier>
+    <dc:language>en</dc:language>
+    <meta name="cover" content="cover-jpg"/>
+  </metadata>
+  <manifest>
+    <item id="cover-svg" href="cover.svg" media-type="image/svg+xml"/>
+    <item id="cover-jpg" href="../cover.jpg" media-type="image/jpeg"/>
+  </manifest>
+</package>`
+
+// testEPUB собирает EPUB из файлов name -> содержимое; mimetype пишется первым
+// без сжатия, как требует спецификация.
+func testEPUB(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("application/epub+zip"))
+	assert.NoError(t, err)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		assert.NoError(t, err)
+		_, err = w.Write(data)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func testEPUB3(t *testing.T, cover []byte) []byte {
+	t.Helper()
+	files := map[string][]byte{
+		"META-INF/container.xml": []byte(epubContainer),
+		"OEBPS/content.opf":      []byte(epub3OPF),
+		"OEBPS/ch1.xhtml":        []byte("<html/>"),
+	}
+	if cover != nil {
+		files["OEBPS/images/cover art.png"] = cover
+	}
+	return testEPUB(t, files)
+}
+
+func TestEPUBMeta_inspect(t *testing.T) {
+	inspect := func(data []byte) (epubmeta.Info, error) {
+		return epubmeta.Inspect(bytes.NewReader(data), int64(len(data)))
+	}
+
+	t.Run("epub 3", func(t *testing.T) {
+		cover := testCover(t, 30, 45)
+		data := testEPUB3(t, cover)
+
+		info, err := inspect(data)
+		assert.NoError(t, err)
+		assert.Equal(t, "Война и мир", info.Title)
+		assert.Equal(t, []string{"Лев Толстой"}, info.Authors)
+		assert.Equal(t, "ru", info.Language)
+		assert.Equal(t, "9785170906307", info.ISBN)
+		assert.Equal(t, cover, info.Cover)
+		assert.Equal(t, int64(len(data)), info.Size)
+		assert.Len(t, info.SHA256, 64)
+	})
+
+	t.Run("epub 2", func(t *testing.T) {
+		info, err := inspect(testEPUB(t, map[string][]byte{
+			"META-INF/container.xml": []byte(epubContainer),
+			"OEBPS/content.opf":      []byte(epub2OPF),
+			"cover.jpg":              []byte("jpeg"),
+		}))
+		assert.NoError(t, err)
+		assert.Equal(t, "Anna Karenina", info.Title)
+		// авторов с ролью aut нет - берутся все создатели
+		assert.Equal(t, []string{"Constance Garnett"}, info.Authors)
+		assert.Equal(t, "9780140449136", info.ISBN)
+		assert.Equal(t, []byte("jpeg"), info.Cover)
+	})
+
+	t.Run("missing cover file", func(t *testing.T) {
+		info, err := inspect(testEPUB3(t, nil))
+		assert.NoError(t, err)
+		assert.Empty(t, info.Cover)
+	})
+
+	t.Run("not a zip", func(t *testing.T) {
+		_, err := inspect([]byte("%PDF-1.7"))
+		assert.ErrorIs(t, err, epubmeta.ErrNotEPUB)
+	})
+
+	t.Run("zip without mimetype", func(t *testing.T) {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		_, err := zw.Create("word/document.xml")
+		assert.NoError(t, err)
+		assert.NoError(t, zw.Close())
+
+		_, err = inspect(buf.Bytes())
+		assert.ErrorIs(t, err, epubmeta.ErrNotEPUB)
+	})
+
+	t.Run("missing package", func(t *testing.T) {
+		_, err := inspect(testEPUB(t, map[string][]byte{"META-INF/container.xml": []byte(epubContainer)}))
+		assert.ErrorIs(t, err, epubmeta.ErrMalformed)
+	})
+}
+
+func TestServer_addBookEPUB(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	blobs := blob.NewLocal(t.TempDir())
+	s := &server.Server{Storage: mockStorage, Blobs: blobs}
+
+	do := func(files map[string][]byte) *httptest.ResponseRecorder {
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		for field, value := range map[string]string{"desc": "Роман-эпопея", "genre": "Роман", "age": "1869"} {
+			assert.NoError(t, writer.WriteField(field, value))
+		}
+		for field, content := range files {
+			part, err := writer.CreateFormFile(field, field+".bin")
+			assert.NoError(t, err)
+			_, err = part.Write(content)
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, writer.Close())
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/add-book", body)
+		ctx.Request.Header.Set("Content-Type", writer.FormDataContentType())
+		ctx.Set("uid", "admin1")
+		s.AddBook(ctx)
+		return w
+	}
+
+	t.Run("metadata and embedded cover", func(t *testing.T) {
+		epub := testEPUB3(t, testCover(t, 300, 450))
+		info, _ := epubmeta.Inspect(bytes.NewReader(epub), int64(len(epub)))
+
+		mockStorage.EXPECT().GetBookByFileHash(info.SHA256).Return(models.Book{}, storerrros.ErrBookNoExist)
+		mockStorage.EXPECT().GetBookByISBN("9785170906307").Return(models.Book{}, storerrros.ErrBookNoExist)
+		mockStorage.EXPECT().SaveBook(gomock.Any(), gomock.Any()).DoAndReturn(func(book models.Book, _ string) error {
+			assert.Equal(t, "Война и мир", book.Lable)
+			assert.Equal(t, "Лев Толстой", book.Author)
+			assert.Equal(t, "ru", book.Language)
+			assert.Equal(t, "9785170906307", book.ISBN)
+			assert.Equal(t, info.SHA256, book.EPUBHash)
+			assert.Equal(t, int64(len(epub)), book.EPUBSize)
+			assert.Empty(t, book.PDFKey)
+			assert.True(t, strings.HasPrefix(book.EPUBKey, "epubs/"))
+			assert.Len(t, covers.Keys(book.CoverKey), 3)
+
+			obj, err := blobs.Get(context.Background(), book.EPUBKey)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "application/epub+zip", obj.ContentType)
+				obj.Body.Close()
+			}
+			return nil
+		})
+
+		w := do(map[string][]byte{"epub": epub})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("pdf and epub", func(t *testing.T) {
+		mockStorage.EXPECT().GetBookByFileHash(gomock.Any()).Return(models.Book{}, storerrros.ErrBookNoExist).Times(2)
+		mockStorage.EXPECT().GetBookByISBN(gomock.Any()).Return(models.Book{}, storerrros.ErrBookNoExist)
+		mockStorage.EXPECT().SaveBook(gomock.Any(), gomock.Any()).DoAndReturn(func(book models.Book, _ string) error {
+			// метаданные EPUB важнее словаря Info в PDF
+			assert.Equal(t, "Война и мир", book.Lable)
+			assert.Equal(t, "War and Peace", book.PDFTitle)
+			assert.NotEmpty(t, book.PDFKey)
+			assert.NotEmpty(t, book.EPUBKey)
+			assert.Equal(t, 2, book.PDFPages)
+			return nil
+		})
+
+		w := do(map[string][]byte{
+			"pdf":   testPDF("War and Peace", "Leo Tolstoy", 2),
+			"epub":  testEPUB3(t, nil),
+			"cover": testCover(t, 40, 60),
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("same edition already in catalog", func(t *testing.T) {
+		mockStorage.EXPECT().GetBookByFileHash(gomock.Any()).Return(models.Book{}, storerrros.ErrBookNoExist)
+		mockStorage.EXPECT().GetBookByISBN("9785170906307").Return(models.Book{BID: "b1"}, nil)
+
+		w := do(map[string][]byte{"epub": testEPUB3(t, nil)})
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.JSONEq(t, `{"error": "book with the same ISBN already exists", "bid": "b1"}`, w.Body.String())
+	})
+
+	t.Run("no cover anywhere", func(t *testing.T) {
+		mockStorage.EXPECT().GetBookByFileHash(gomock.Any()).Return(models.Book{}, storerrros.ErrBookNoExist)
+		mockStorage.EXPECT().GetBookByISBN(gomock.Any()).Return(models.Book{}, storerrros.ErrBookNoExist)
+
+		w := do(map[string][]byte{"epub": testEPUB3(t, nil)})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to get cover file")
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		mockStorage.EXPECT().GetBookByFileHash(gomock.Any()).Return(models.Book{BID: "b1"}, nil)
+
+		w := do(map[string][]byte{"epub": testEPUB3(t, nil)})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), `"bid":"b1"`)
+	})
+
+	t.Run("not an epub", func(t *testing.T) {
+		w := do(map[string][]byte{"epub": []byte("<html>not a book</html>"), "cover": testCover(t, 40, 60)})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), epubmeta.ErrNotEPUB.Error())
+	})
+}
+
+func TestServer_epubDownload(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	store := blob.NewLocal(t.TempDir())
+	assert.NoError(t, store.Put(context.Background(), "epubs/b1.epub", strings.NewReader("PK epub"), 7, "application/epub+zip"))
+	s := &server.Server{Storage: mockStorage, Blobs: store}
+
+	book := models.Book{BID: "b1", Lable: "Война и мир", PDFKey: "pdfs/b1.pdf", EPUBKey: "epubs/b1.epub", EPUBSize: 7}
+
+	router := gin.New()
+	router.GET("/books/:id", s.BookInfo)
+	router.GET("/books/:id/epub", s.JWTAuthRoleMiddleware(), s.EPUBLink)
+	router.GET("/books/:id/pdf/download", s.DownloadPDF)
+	router.GET("/books/:id/epub/download", s.DownloadEPUB)
+
+	auth := http.Header{"Authorization": {"Bearer " + testToken(t, "u1", "user")}}
+	do := func(target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("formats", func(t *testing.T) {
+		mockStorage.EXPECT().GetBook("b1").Return(book, nil)
+		w := do("/books/b1", nil)
+
+		var resp models.Book
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "/books/b1/epub", resp.EPUBURL)
+		assert.Equal(t, []models.BookFormat{
+			{Format: "pdf", URL: "/books/b1/pdf"},
+			{Format: "epub", URL: "/books/b1/epub", Size: 7},
+		}, resp.Formats)
+	})
+
+	mockStorage.EXPECT().GetBook("b1").Return(book, nil)
+	w := do("/books/b1/epub", auth)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		URL string `json:"url"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, strings.HasPrefix(resp.URL, "/books/b1/epub/download?"))
+
+	t.Run("download", func(t *testing.T) {
+		mockStorage.EXPECT().GetBook("b1").Return(book, nil)
+		mockStorage.EXPECT().LogDownload(gomock.Any()).DoAndReturn(func(d models.Download) error {
+			assert.Equal(t, "epub", d.Format)
+			return nil
+		})
+		w := do(resp.URL, nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/epub+zip", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), ".epub")
+		assert.Equal(t, "PK epub", w.Body.String())
+	})
+
+	t.Run("epub signature does not open pdf", func(t *testing.T) {
+		w := do(strings.Replace(resp.URL, "/epub/", "/pdf/", 1), nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
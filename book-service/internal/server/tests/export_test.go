@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/azaliaz/bookly/book-service/internal/config"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/server"
+	"github.com/azaliaz/bookly/book-service/internal/server/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_exportBooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	s := server.New(config.Config{PublicURL: "https://books.example.com/"}, mockStorage)
+
+	books := []models.Book{
+		{BID: "b1", Lable: "War and Peace", Author: "Leo Tolstoy", Desc: "A novel", Age: 1869, Genre: "Роман", Rating: 5, CoverKey: "covers/b1.jpg"},
+		{BID: "b2", Lable: "Anna Karenina", Author: "Leo Tolstoy", Desc: "A novel", Age: 1877, Genre: "Роман", Rating: 4},
+	}
+	stream := func(filter models.BookFilter, fn func(models.Book) error) error {
+		for _, book := range books {
+			if err := fn(book); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	createCtx := func(target string) (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodGet, target, nil)
+		// адрес из запроса не должен попадать в ссылки выгрузки
+		ctx.Request.Host = "evil.example.com"
+		ctx.Request.Header.Set("X-Forwarded-Proto", "http")
+		ctx.Set("uid", "admin1")
+		return ctx, w
+	}
+
+	t.Run("csv export", func(t *testing.T) {
+		mockStorage.EXPECT().StreamBooks(gomock.Any(), gomock.Any()).DoAndReturn(stream)
+
+		ctx, w := createCtx("/books/export")
+		s.ExportBooks(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "books.csv")
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		assert.Len(t, lines, 3)
+		assert.True(t, strings.HasPrefix(lines[0], "bid,lable,author"))
+		assert.Contains(t, lines[1], "https://books.example.com/uploads/covers/b1.jpg")
+		assert.NotContains(t, w.Body.String(), "evil.example.com")
+	})
+
+	t.Run("jsonl export with filter", func(t *testing.T) {
+		mockStorage.EXPECT().StreamBooks(gomock.Any(), gomock.Any()).DoAndReturn(func(filter models.BookFilter, fn func(models.Book) error) error {
+			assert.Equal(t, []string{"Роман"}, filter.Genres)
+			return stream(filter, fn)
+		})
+
+		ctx, w := createCtx("/books/export?format=jsonl&genre=Роман")
+		s.ExportBooks(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		assert.Len(t, lines, 2)
+		var book models.Book
+		assert.NoError(t, json.Unmarshal([]byte(lines[1]), &book))
+		assert.Equal(t, "b2", book.BID)
+	})
+
+	t.Run("onix export", func(t *testing.T) {
+		mockStorage.EXPECT().StreamBooks(gomock.Any(), gomock.Any()).DoAndReturn(stream)
+
+		ctx, w := createCtx("/books/export?format=onix")
+		s.ExportBooks(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/xml")
+		body := w.Body.String()
+		assert.Contains(t, body, `<ONIXMessage release="3.0"`)
+		assert.Equal(t, 2, strings.Count(body, "<Product>"))
+		assert.Contains(t, body, "https://books.example.com/uploads/covers/b1.jpg")
+		assert.True(t, strings.HasSuffix(strings.TrimSpace(body), "</ONIXMessage>"))
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		ctx, w := createCtx("/books/export?format=xlsx")
+		s.ExportBooks(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("stream error", func(t *testing.T) {
+		mockStorage.EXPECT().StreamBooks(gomock.Any(), gomock.Any()).Return(errors.New("db down"))
+
+		ctx, w := createCtx("/books/export?format=jsonl")
+		s.ExportBooks(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+}
@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/azaliaz/bookly/book-service/internal/config"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/server"
+	"github.com/azaliaz/bookly/book-service/internal/storage"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_genres(t *testing.T) {
+	stor := storage.New()
+	s := server.New(config.Config{BlobDir: t.TempDir()}, stor)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/genres", s.ListGenres)
+	router.GET("/genres/:id", s.GenreInfo)
+	router.POST("/genres", s.JWTAuthRoleMiddleware("admin"), s.AddGenre)
+	router.PUT("/genres/:id", s.JWTAuthRoleMiddleware("admin"), s.UpdateGenre)
+	router.DELETE("/genres/:id", s.JWTAuthRoleMiddleware("admin"), s.RemoveGenre)
+	router.GET("/books/search", s.AllBooksWithSearch)
+	router.PUT("/books/:id/genres", s.JWTAuthRoleMiddleware("admin"), s.SetBookGenres)
+	admin := "Bearer " + testToken(t, "admin1", "admin")
+
+	do := func(method, target string, v interface{}) *httptest.ResponseRecorder {
+		body := new(bytes.Buffer)
+		if v != nil {
+			assert.NoError(t, json.NewEncoder(body).Encode(v))
+		}
+		req := httptest.NewRequest(method, target, body)
+		req.Header.Set("Authorization", admin)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	add := func(genre models.Genre) models.Genre {
+		w := do(http.MethodPost, "/genres", genre)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var got models.Genre
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		return got
+	}
+	search := func(query string) []string {
+		w := do(http.MethodGet, "/books/search?sort_by=lable&"+query, nil)
+		if w.Code == http.StatusNotFound {
+			return nil
+		}
+		var page models.BooksPage
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		var labels []string
+		for _, b := range page.Books {
+			labels = append(labels, b.Lable)
+		}
+		return labels
+	}
+
+	fantasy := add(models.Genre{NameRU: "Фэнтези", NameEN: "Fantasy"})
+	urban := add(models.Genre{NameRU: "Городское фэнтези", NameEN: "Urban fantasy", ParentID: fantasy.ID})
+	scifi := add(models.Genre{NameRU: "Фантастика", NameEN: "Science fiction"})
+
+	assert.NoError(t, stor.SaveBook(models.Book{Lable: "Хоббит", Author: "Толкин", Genre: "фэнтези"}, ""))
+	assert.NoError(t, stor.SaveBook(models.Book{Lable: "Дозоры", Author: "Лукьяненко", Genre: "Urban Fantasy"}, ""))
+	assert.NoError(t, stor.SaveBook(models.Book{Lable: "Солярис", Author: "Лем", Genre: "Фантастика; Роман"}, ""))
+	assert.NoError(t, stor.SaveBook(models.Book{Lable: "Без жанра", Author: "Аноним", Genre: "Без жанра"}, ""))
+
+	t.Run("tree", func(t *testing.T) {
+		w := do(http.MethodGet, "/genres", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var got struct {
+			Genres []models.Genre `json:"genres"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		var roots []string
+		for _, g := range got.Genres {
+			roots = append(roots, g.NameRU)
+			if g.ID == fantasy.ID && assert.Len(t, g.Children, 1) {
+				assert.Equal(t, urban.ID, g.Children[0].ID)
+				assert.Equal(t, 1, g.Children[0].BookCount)
+			}
+		}
+		// "Роман" из строки жанра книги стал новым корневым жанром
+		assert.Equal(t, []string{"Роман", "Фантастика", "Фэнтези"}, roots)
+	})
+
+	t.Run("parent genre includes subgenres", func(t *testing.T) {
+		assert.Equal(t, []string{"Дозоры", "Хоббит"}, search("genre=Fantasy"))
+		assert.Equal(t, []string{"Дозоры", "Хоббит"}, search("genre="+fantasy.ID))
+	})
+
+	t.Run("no substring matches", func(t *testing.T) {
+		assert.Equal(t, []string{"Дозоры"}, search("genre=urban+fantasy"))
+		assert.Nil(t, search("genre=fan"))
+	})
+
+	t.Run("book with several genres", func(t *testing.T) {
+		assert.Equal(t, []string{"Солярис"}, search("genre=роман"))
+		assert.Equal(t, []string{"Солярис"}, search("genre=Science+fiction"))
+	})
+
+	t.Run("set book genres", func(t *testing.T) {
+		page, err := stor.GetBooksWithFilters(models.BookFilter{Search: "Хоббит"})
+		assert.NoError(t, err)
+		bid := page.Books[0].BID
+
+		w := do(http.MethodPut, "/books/"+bid+"/genres", []string{scifi.ID, fantasy.ID})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var book models.Book
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &book))
+		assert.Equal(t, []models.BookGenre{
+			{GenreID: scifi.ID, NameRU: "Фантастика", NameEN: "Science fiction"},
+			{GenreID: fantasy.ID, NameRU: "Фэнтези", NameEN: "Fantasy"},
+		}, book.Genres)
+		assert.Equal(t, []string{"Солярис", "Хоббит"}, search("genre="+scifi.ID))
+
+		w = do(http.MethodPut, "/books/"+bid+"/genres", []string{"missing"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("move genre", func(t *testing.T) {
+		w := do(http.MethodPut, "/genres/"+fantasy.ID, models.Genre{NameRU: "Фэнтези", ParentID: urban.ID})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), storerrros.ErrGenreCycle.Error())
+
+		w = do(http.MethodPut, "/genres/"+fantasy.ID, models.Genre{NameRU: "Фэнтези", NameEN: "Fantasy", ParentID: scifi.ID})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"Дозоры", "Солярис", "Хоббит"}, search("genre=Science+fiction"))
+
+		w = do(http.MethodGet, "/genres/"+scifi.ID, nil)
+		var got models.Genre
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		if assert.Len(t, got.Children, 1) {
+			assert.Equal(t, urban.ID, got.Children[0].Children[0].ID)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		w := do(http.MethodDelete, "/genres/"+fantasy.ID, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = do(http.MethodDelete, "/genres/"+urban.ID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, search("genre=urban+fantasy"))
+
+		w = do(http.MethodGet, "/genres/"+urban.ID, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		w := do(http.MethodPost, "/genres", models.Genre{NameEN: "Horror"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = do(http.MethodPost, "/genres", models.Genre{NameRU: "Ужасы", ParentID: "missing"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
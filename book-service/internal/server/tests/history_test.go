@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/azaliaz/bookly/book-service/internal/config"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/server"
+	"github.com/azaliaz/bookly/book-service/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_history(t *testing.T) {
+	stor := storage.New()
+	s := server.New(config.Config{BlobDir: t.TempDir()}, stor)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/books/:id", s.BookInfo)
+	router.DELETE("/books/remove/:id", s.JWTAuthRoleMiddleware("admin"), s.RemoveBook)
+	router.POST("/books/trash/:id/restore", s.JWTAuthRoleMiddleware("admin"), s.RestoreBook)
+	router.GET("/books/:id/history", s.JWTAuthRoleMiddleware("admin"), s.BookHistory)
+	router.POST("/books/:id/history/:revision/revert", s.JWTAuthRoleMiddleware("admin"), s.RevertBook)
+
+	do := func(method, target, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	admin := testToken(t, "admin1", "admin")
+	history := func(bid string) []models.BookRevision {
+		w := do(http.MethodGet, "/books/"+bid+"/history", admin)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var res struct {
+			BID       string                `json:"bid"`
+			Revisions []models.BookRevision `json:"revisions"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, bid, res.BID)
+		return res.Revisions
+	}
+
+	assert.NoError(t, stor.SaveBook(models.Book{Lable: "Пикник на обочине", Author: "Стругацкие", Desc: "Повесть",
+		ISBN: "9785170906307", CoverKey: "covers/old/full.jpg"}, "editor1"))
+	book, err := stor.GetBookByISBN("9785170906307")
+	assert.NoError(t, err)
+	bid := book.BID
+
+	t.Run("create is recorded", func(t *testing.T) {
+		revisions := history(bid)
+		assert.Len(t, revisions, 1)
+		assert.Equal(t, 1, revisions[0].Revision)
+		assert.Equal(t, models.HistoryCreate, revisions[0].Action)
+		assert.Equal(t, "editor1", revisions[0].Actor)
+		assert.Equal(t, "Повесть", revisions[0].Snapshot.Desc)
+		assert.False(t, revisions[0].ChangedAt.IsZero())
+	})
+
+	t.Run("update records field diff", func(t *testing.T) {
+		book.Desc = "Фантастическая повесть"
+		book.CoverKey = "covers/new/full.jpg"
+		_, err := stor.UpdateBook(book, book.Version, "admin1")
+		assert.NoError(t, err)
+
+		revisions := history(bid)
+		assert.Len(t, revisions, 2)
+		update := revisions[0]
+		assert.Equal(t, models.HistoryUpdate, update.Action)
+		assert.Equal(t, "admin1", update.Actor)
+		assert.Len(t, update.Changes, 2)
+		assert.Equal(t, models.FieldChange{Old: "Повесть", New: "Фантастическая повесть"}, update.Changes["desc"])
+		assert.Equal(t, models.FieldChange{Old: "covers/old/full.jpg", New: "covers/new/full.jpg"}, update.Changes["cover_key"])
+	})
+
+	t.Run("delete and restore are recorded", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/books/remove/"+bid, admin).Code)
+		assert.Equal(t, http.StatusOK, do(http.MethodPost, "/books/trash/"+bid+"/restore", testToken(t, "admin2", "admin")).Code)
+
+		revisions := history(bid)
+		assert.Len(t, revisions, 4)
+		assert.Equal(t, models.HistoryDelete, revisions[1].Action)
+		assert.Equal(t, "admin1", revisions[1].Actor)
+		assert.Equal(t, models.HistoryRestore, revisions[0].Action)
+		assert.Equal(t, "admin2", revisions[0].Actor)
+		assert.Empty(t, revisions[0].Changes)
+	})
+
+	t.Run("revert", func(t *testing.T) {
+		w := do(http.MethodPost, "/books/"+bid+"/history/1/revert", admin)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, w.Header().Get("ETag"))
+		var reverted models.Book
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &reverted))
+		assert.Equal(t, "Повесть", reverted.Desc)
+		assert.Equal(t, "/uploads/covers/old/full.jpg", reverted.CoverURL)
+
+		revisions := history(bid)
+		assert.Len(t, revisions, 5)
+		assert.Equal(t, models.HistoryRevert, revisions[0].Action)
+		assert.Equal(t, 1, revisions[0].RevertedTo)
+		assert.Equal(t, models.FieldChange{Old: "Фантастическая повесть", New: "Повесть"}, revisions[0].Changes["desc"])
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/books/missing/history", admin).Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/books/"+bid+"/history/42/revert", admin).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/books/"+bid+"/history/abc/revert", admin).Code)
+		assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/books/"+bid+"/history", testToken(t, "user1", "user")).Code)
+	})
+}
@@ -0,0 +1,173 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/azaliaz/bookly/book-service/internal/catalog"
+	"github.com/azaliaz/bookly/book-service/internal/config"
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/server"
+	"github.com/azaliaz/bookly/book-service/internal/server/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const importCSV = `lable,author,desc,age,genre,rating
+War and Peace,Leo Tolstoy,A novel about war and peace,1869,Роман,5
+Anna Karenina,Leo Tolstoy,A novel about love and society,1877,,4
+X,Nobody,too short,1900,,0
+Resurrection,Leo Tolstoy,The last novel by Tolstoy,not-a-year,,0
+`
+
+func TestServer_importBooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	s := server.New(config.Config{}, mockStorage)
+
+	createCtx := func(target, contentType, body string) (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		ctx.Request.Header.Set("Content-Type", contentType)
+		ctx.Set("uid", "admin1")
+		return ctx, w
+	}
+	decode := func(t *testing.T, w *httptest.ResponseRecorder) models.ImportReport {
+		var report models.ImportReport
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return report
+	}
+
+	t.Run("csv import", func(t *testing.T) {
+		mockStorage.EXPECT().SaveBooks(gomock.Any(), gomock.Any()).DoAndReturn(func(books []models.Book, _ string) ([]models.SaveStatus, error) {
+			assert.Len(t, books, 2)
+			assert.Equal(t, "Без жанра", books[1].Genre)
+			return []models.SaveStatus{{BID: "b1"}, {BID: "b2", Duplicate: true}}, nil
+		})
+
+		ctx, w := createCtx("/books/import", "text/csv", importCSV)
+		s.ImportBooks(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		report := decode(t, w)
+		assert.Equal(t, 4, report.Total)
+		assert.Equal(t, []models.ImportRow{{Line: 2, BID: "b1", Lable: "War and Peace", Author: "Leo Tolstoy"}}, report.Inserted)
+		assert.Equal(t, "b2", report.Duplicates[0].BID)
+		assert.Len(t, report.Rejected, 2)
+		assert.Equal(t, 4, report.Rejected[0].Line)
+		assert.Contains(t, report.Rejected[0].Error, "lable: min")
+		assert.Equal(t, "invalid age value", report.Rejected[1].Error)
+	})
+
+	t.Run("dry run does not save", func(t *testing.T) {
+		ctx, w := createCtx("/books/import?dry_run=true", "text/csv", importCSV)
+		s.ImportBooks(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		report := decode(t, w)
+		assert.True(t, report.DryRun)
+		assert.Len(t, report.Valid, 2)
+		assert.Len(t, report.Rejected, 2)
+		assert.Empty(t, report.Inserted)
+	})
+
+	t.Run("jsonl file upload", func(t *testing.T) {
+		mockStorage.EXPECT().SaveBooks(gomock.Any(), gomock.Any()).Return([]models.SaveStatus{{BID: "b1"}}, nil)
+
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("file", "books.jsonl")
+		assert.NoError(t, err)
+		_, err = part.Write([]byte(`{"lable":"War and Peace","author":"Leo Tolstoy","desc":"A novel about war and peace","age":1869}
+
+{"lable":broken}
+`))
+		assert.NoError(t, err)
+		assert.NoError(t, writer.Close())
+
+		ctx, w := createCtx("/books/import", writer.FormDataContentType(), body.String())
+		s.ImportBooks(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		report := decode(t, w)
+		assert.Len(t, report.Inserted, 1)
+		assert.Equal(t, 3, report.Rejected[0].Line)
+		assert.Contains(t, report.Rejected[0].Error, "invalid json")
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		ctx, w := createCtx("/books/import", "application/xml", "<books/>")
+		s.ImportBooks(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "unknown format")
+	})
+
+	t.Run("missing csv column", func(t *testing.T) {
+		ctx, w := createCtx("/books/import?format=csv", "text/plain", "lable,author\nA,B\n")
+		s.ImportBooks(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "missing required column: desc")
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		row := "War and Peace,Leo Tolstoy,A novel about war and peace,1869,Роман,5\n"
+		body := "lable,author,desc,age,genre,rating\n" + strings.Repeat(row, consts.MaxImportSize/len(row)+1)
+		ctx, w := createCtx("/books/import", "text/csv", body)
+		s.ImportBooks(ctx)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+		var form bytes.Buffer
+		mw := multipart.NewWriter(&form)
+		part, _ := mw.CreateFormFile("file", "books.csv")
+		part.Write([]byte(body))
+		mw.Close()
+		ctx, w = createCtx("/books/import", mw.FormDataContentType(), form.String())
+		s.ImportBooks(ctx)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("save fails", func(t *testing.T) {
+		mockStorage.EXPECT().SaveBooks(gomock.Any(), gomock.Any()).Return(nil, errors.New("db error"))
+
+		ctx, w := createCtx("/books/import", "text/csv", importCSV)
+		s.ImportBooks(ctx)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+// TestCatalog_readRowsSourceError проверяет, что ошибка источника прерывает разбор:
+// csv.Reader возвращает ее на каждом следующем чтении.
+func TestCatalog_readRowsSourceError(t *testing.T) {
+	broken := errors.New("connection reset")
+	for _, format := range []string{catalog.FormatCSV, catalog.FormatJSONL} {
+		source := io.MultiReader(strings.NewReader(importCSV), iotest.ErrReader(broken))
+		done := make(chan error, 1)
+		go func() {
+			_, err := catalog.ReadRows(source, format)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			assert.ErrorIs(t, err, broken, format)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("%s: ReadRows did not return on a broken source", format)
+		}
+	}
+}
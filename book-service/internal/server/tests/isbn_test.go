@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/azaliaz/bookly/book-service/internal/config"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/isbn"
+	"github.com/azaliaz/bookly/book-service/internal/server"
+	"github.com/azaliaz/bookly/book-service/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestISBN_Normalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+		err  error
+	}{
+		{name: "isbn-13 with hyphens", in: "978-5-17-090630-7", want: "9785170906307"},
+		{name: "isbn-10 becomes isbn-13", in: "0-14-044913-2", want: "9780140449136"},
+		{name: "isbn-10 with X check digit", in: "080442957X", want: "9780804429573"},
+		{name: "prefix and spaces", in: "ISBN: 978 0 14 044913 6", want: "9780140449136"},
+		{name: "urn", in: "urn:isbn:9780140449136", want: "9780140449136"},
+		{name: "empty", in: "  ", want: ""},
+		{name: "wrong isbn-13 check digit", in: "9780140449137", err: isbn.ErrInvalid},
+		{name: "wrong isbn-10 check digit", in: "0140449133", err: isbn.ErrInvalid},
+		{name: "wrong length", in: "97801404491", err: isbn.ErrInvalid},
+		{name: "letters", in: "978014044913A", err: isbn.ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := isbn.Normalize(tt.in)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	assert.Equal(t, "0140449132", isbn.To10("9780140449136"))
+	assert.Equal(t, "080442957X", isbn.To10("9780804429573"))
+	assert.Empty(t, isbn.To10("9791032305690"))
+}
+
+func TestServer_isbnDuplicates(t *testing.T) {
+	stor := storage.New()
+
+	statuses, err := stor.SaveBooks([]models.Book{
+		{Lable: "Анна Каренина", Author: "Лев Толстой", ISBN: "9785170906307"},
+		// другое издание того же романа - отдельная книга
+		{Lable: "Анна Каренина", Author: "Лев Толстой", ISBN: "9780140449174"},
+		// то же издание с другим написанием названия
+		{Lable: "Анна Каренина (роман)", Author: "Толстой Л.", ISBN: "9785170906307"},
+		// книги без ISBN сравниваются по названию без регистра и пунктуации
+		{Lable: "Мастер и Маргарита", Author: "Михаил Булгаков"},
+		{Lable: "мастер и маргарита!", Author: "Михаил  Булгаков"},
+	}, "")
+
+	assert.NoError(t, err)
+	var duplicates []bool
+	for _, status := range statuses {
+		duplicates = append(duplicates, status.Duplicate)
+	}
+	assert.Equal(t, []bool{false, false, true, false, true}, duplicates)
+	assert.Equal(t, statuses[0].BID, statuses[2].BID)
+	assert.NotEqual(t, statuses[0].BID, statuses[1].BID)
+	assert.Equal(t, statuses[3].BID, statuses[4].BID)
+}
+
+func TestServer_bookByISBN(t *testing.T) {
+	stor := storage.New()
+	s := server.New(config.Config{BlobDir: t.TempDir()}, stor)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/books/isbn/:isbn", s.BookByISBN)
+
+	statuses, err := stor.SaveBooks([]models.Book{{Lable: "Преступление и наказание", Author: "Федор Достоевский", ISBN: "9780140449136"}}, "")
+	assert.NoError(t, err)
+	bid := statuses[0].BID
+
+	do := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	t.Run("any spelling", func(t *testing.T) {
+		for _, value := range []string{"9780140449136", "978-0-14-044913-6", "0140449132", "0-14-044913-2"} {
+			w := do("/books/isbn/" + value)
+
+			assert.Equal(t, http.StatusOK, w.Code, value)
+			var book models.Book
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &book))
+			assert.Equal(t, bid, book.BID)
+			assert.Equal(t, "9780140449136", book.ISBN)
+			assert.Equal(t, "0140449132", book.ISBN10)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		w := do("/books/isbn/9785170906307")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("invalid checksum", func(t *testing.T) {
+		w := do("/books/isbn/9780140449137")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), isbn.ErrInvalid.Error())
+	})
+}
@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azaliaz/bookly/book-service/internal/config"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/server"
+	"github.com/azaliaz/bookly/book-service/internal/server/mocks"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+	"github.com/azaliaz/bookly/book-service/internal/users"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func testToken(t *testing.T, uid, role string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, server.Claims{UserID: uid, Role: role}).
+		SignedString([]byte(server.SecretKey))
+	assert.NoError(t, err)
+	return token
+}
+
+func TestServer_opds(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	mockUsers := mocks.NewMockUserAuth(ctrl)
+	s := server.New(config.Config{PublicURL: "http://books.example.com"}, mockStorage)
+	s.Users = mockUsers
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.UseRawPath = true // как в Run
+	opds := router.Group("/opds", s.JWTOrBasicAuthMiddleware())
+	opds.GET("", s.OPDSRoot)
+	opds.GET("/new", s.OPDSNew)
+	opds.GET("/genres", s.OPDSGenres)
+	opds.GET("/authors", s.OPDSAuthors)
+	opds.GET("/authors/:author", s.OPDSAuthor)
+	opds.GET("/search", s.OPDSSearch)
+	opds.GET("/search.xml", s.OPDSSearchDescription)
+
+	token := testToken(t, "u1", "user")
+	do := func(target string, auth func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Host = "books.example.com"
+		if auth != nil {
+			auth(req)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	bearer := func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }
+
+	t.Run("root navigation feed", func(t *testing.T) {
+		w := do("/opds", bearer)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "kind=navigation")
+		body := w.Body.String()
+		assert.Contains(t, body, `rel="http://opds-spec.org/sort/new" href="http://books.example.com/opds/new"`)
+		assert.Contains(t, body, `rel="http://opds-spec.org/sort/popular"`)
+		assert.Contains(t, body, `href="http://books.example.com/opds/search.xml" type="application/opensearchdescription+xml"`)
+		assert.NotContains(t, body, `rel="up"`)
+	})
+
+	t.Run("newest acquisition feed", func(t *testing.T) {
+		mockStorage.EXPECT().GetBooksWithFilters(gomock.Any()).DoAndReturn(func(filter models.BookFilter) (models.BooksPage, error) {
+			assert.Equal(t, "created_at", filter.SortBy)
+			assert.False(t, filter.Ascending)
+			return models.BooksPage{
+				Books: []models.Book{{
+					BID: "b1", Lable: "War and Peace", Author: "Leo Tolstoy", Desc: "A novel", Age: 1869, Genre: "Роман",
+					CoverKey: "covers/b1.jpg", PDFKey: "pdfs/b1.pdf",
+					CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+				}},
+				NextCursor: "next-page",
+				Total:      2,
+			}, nil
+		})
+
+		w := do("/opds/new?limit=1", bearer)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "kind=acquisition")
+		body := w.Body.String()
+		assert.Contains(t, body, "<id>urn:bookly:book:b1</id>")
+		assert.Contains(t, body, "<updated>2024-05-01T10:00:00Z</updated>")
+		assert.Contains(t, body, `rel="http://opds-spec.org/acquisition" href="http://books.example.com/opds/books/b1/pdf" type="application/pdf"`)
+		assert.Contains(t, body, `rel="http://opds-spec.org/image" href="http://books.example.com/uploads/covers/b1.jpg" type="image/jpeg"`)
+		assert.Contains(t, body, `rel="next" href="http://books.example.com/opds/new?cursor=next-page&amp;limit=1"`)
+		assert.Contains(t, body, "<opensearch:totalResults>2</opensearch:totalResults>")
+	})
+
+	t.Run("genres navigation", func(t *testing.T) {
+		mockStorage.EXPECT().GetBookFacets(models.BookFilter{}).Return(models.BookFacets{
+			Genres: []models.FacetCount{{Value: "Роман", Count: 3}, {Value: "", Count: 1}},
+		}, nil)
+
+		w := do("/opds/genres", bearer)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		body := w.Body.String()
+		assert.Equal(t, 1, strings.Count(body, "<entry>"))
+		assert.Contains(t, body, "http://books.example.com/opds/genres/%D0%A0%D0%BE%D0%BC%D0%B0%D0%BD")
+		assert.Contains(t, body, "Книг: 3")
+	})
+
+	t.Run("author with slash", func(t *testing.T) {
+		mockStorage.EXPECT().GetAuthors().Return([]models.FacetCount{{Value: "AC/DC", Count: 2}}, nil)
+
+		body := do("/opds/authors", bearer).Body.String()
+		link := "http://books.example.com/opds/authors/AC%2FDC"
+		assert.Contains(t, body, link)
+
+		mockStorage.EXPECT().GetBooksWithFilters(gomock.Any()).DoAndReturn(func(filter models.BookFilter) (models.BooksPage, error) {
+			assert.Equal(t, "AC/DC", filter.Author)
+			return models.BooksPage{}, storerrros.ErrEmptyBooksList
+		})
+
+		w := do(link, bearer)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Автор: AC/DC")
+	})
+
+	t.Run("empty author feed", func(t *testing.T) {
+		mockStorage.EXPECT().GetBooksWithFilters(gomock.Any()).DoAndReturn(func(filter models.BookFilter) (models.BooksPage, error) {
+			assert.Equal(t, "Leo Tolstoy", filter.Author)
+			return models.BooksPage{}, storerrros.ErrEmptyBooksList
+		})
+
+		w := do("/opds/authors/Leo%20Tolstoy", bearer)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "<entry>")
+	})
+
+	t.Run("search without query", func(t *testing.T) {
+		w := do("/opds/search", bearer)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("opensearch description", func(t *testing.T) {
+		w := do("/opds/search.xml", bearer)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/opensearchdescription+xml", w.Header().Get("Content-Type"))
+		body := w.Body.String()
+		assert.Contains(t, body, `template="http://books.example.com/opds/search?q={searchTerms}"`)
+		assert.Contains(t, body, `template="http://books.example.com/books/search?search={searchTerms}"`)
+	})
+
+	t.Run("basic auth", func(t *testing.T) {
+		mockUsers.EXPECT().Login("reader@example.com", "secret123").Return(token, nil)
+
+		w := do("/opds", func(req *http.Request) { req.SetBasicAuth("reader@example.com", "secret123") })
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("basic auth invalid password", func(t *testing.T) {
+		mockUsers.EXPECT().Login("reader@example.com", "wrong").Return("", users.ErrInvalidCredentials)
+
+		w := do("/opds", func(req *http.Request) { req.SetBasicAuth("reader@example.com", "wrong") })
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Basic")
+	})
+
+	t.Run("user-service unavailable", func(t *testing.T) {
+		mockUsers.EXPECT().Login(gomock.Any(), gomock.Any()).Return("", errors.New("connection refused"))
+
+		w := do("/opds", func(req *http.Request) { req.SetBasicAuth("reader@example.com", "secret123") })
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+
+	t.Run("no credentials", func(t *testing.T) {
+		w := do("/opds", nil)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), `realm="bookly"`)
+	})
+}
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azaliaz/bookly/book-service/internal/blob"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/server"
+	"github.com/azaliaz/bookly/book-service/internal/server/mocks"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_pdfDownload(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	store := blob.NewLocal(t.TempDir())
+	assert.NoError(t, store.Put(context.Background(), "pdfs/b1.pdf", strings.NewReader("%PDF-1.4"), 8, "application/pdf"))
+	s := &server.Server{Storage: mockStorage, Blobs: store}
+
+	book := models.Book{BID: "b1", Lable: "Война и мир", PDFKey: "pdfs/b1.pdf"}
+
+	router := gin.New()
+	router.GET("/books/:id/pdf", s.JWTAuthRoleMiddleware(), s.PDFLink)
+	router.GET("/books/:id/pdf/download", s.DownloadPDF)
+	router.GET("/opds/books/:id/pdf", s.JWTOrBasicAuthMiddleware(), s.OPDSDownloadPDF)
+
+	token := testToken(t, "u1", "user")
+	do := func(target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	auth := http.Header{"Authorization": {"Bearer " + token}}
+	issue := func(t *testing.T) string {
+		mockStorage.EXPECT().GetBook("b1").Return(book, nil)
+		w := do("/books/b1/pdf", auth)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			URL string `json:"url"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.URL
+	}
+
+	t.Run("link requires auth", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, do("/books/b1/pdf", nil).Code)
+	})
+
+	t.Run("book without pdf", func(t *testing.T) {
+		mockStorage.EXPECT().GetBook("b2").Return(models.Book{BID: "b2"}, nil)
+		assert.Equal(t, http.StatusNotFound, do("/books/b2/pdf", auth).Code)
+	})
+
+	t.Run("missing book", func(t *testing.T) {
+		mockStorage.EXPECT().GetBook("b3").Return(models.Book{}, storerrros.ErrBookNoExist)
+		assert.Equal(t, http.StatusNotFound, do("/books/b3/pdf", auth).Code)
+	})
+
+	t.Run("download", func(t *testing.T) {
+		link := issue(t)
+		assert.True(t, strings.HasPrefix(link, "/books/b1/pdf/download?"))
+
+		mockStorage.EXPECT().GetBook("b1").Return(book, nil)
+		mockStorage.EXPECT().LogDownload(gomock.Any()).DoAndReturn(func(d models.Download) error {
+			assert.Equal(t, "b1", d.BID)
+			assert.Equal(t, "u1", d.UID)
+			return nil
+		})
+		w := do(link, nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+		assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "inline")
+		assert.Equal(t, "%PDF-1.4", w.Body.String())
+	})
+
+	t.Run("range request", func(t *testing.T) {
+		link := issue(t)
+
+		mockStorage.EXPECT().GetBook("b1").Return(book, nil)
+		w := do(link, http.Header{"Range": {"bytes=5-7"}})
+
+		assert.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Equal(t, "bytes 5-7/8", w.Header().Get("Content-Range"))
+		assert.Equal(t, "1.4", w.Body.String())
+	})
+
+	t.Run("tampered link", func(t *testing.T) {
+		link, err := url.Parse(issue(t))
+		assert.NoError(t, err)
+		query := link.Query()
+		query.Set("uid", "u2")
+		link.RawQuery = query.Encode()
+
+		w := do(link.String(), nil)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid download signature")
+	})
+
+	t.Run("expired link", func(t *testing.T) {
+		exp := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(server.SecretKey))
+		mac.Write([]byte("b1\nu1\n" + exp))
+		query := url.Values{
+			"uid":       {"u1"},
+			"expires":   {exp},
+			"signature": {base64.RawURLEncoding.EncodeToString(mac.Sum(nil))},
+		}
+
+		w := do("/books/b1/pdf/download?"+query.Encode(), nil)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "download link expired")
+	})
+
+	t.Run("opds redirect", func(t *testing.T) {
+		w := do("/opds/books/b1/pdf", auth)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		location := w.Header().Get("Location")
+		assert.True(t, strings.HasPrefix(location, "/books/b1/pdf/download?"))
+		assert.Contains(t, location, "uid=u1")
+	})
+}
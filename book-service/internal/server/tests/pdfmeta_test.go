@@ -0,0 +1,335 @@
+package tests
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/azaliaz/bookly/book-service/internal/blob"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/pdfmeta"
+	"github.com/azaliaz/bookly/book-service/internal/server"
+	"github.com/azaliaz/bookly/book-service/internal/server/mocks"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// pdfText кодирует строку для словаря Info как UTF-16BE с BOM.
+func pdfText(s string) string {
+	if s == "" {
+		return "()"
+	}
+	b := []byte{0xFE, 0xFF}
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u>>8), byte(u))
+	}
+	return "<" + hex.EncodeToString(b) + ">"
+}
+
+// pdfObjects возвращает объекты документа: каталог, дерево страниц, страницы и Info последним.
+func pdfObjects(title, author string, pages int) []string {
+	objects := []string{"<< /Type /Catalog /Pages 2 0 R >>", ""}
+	var kids []string
+	for i := 0; i < pages; i++ {
+		kids = append(kids, fmt.Sprintf("%d 0 R", len(objects)+1))
+		objects = append(objects, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>")
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pages)
+	return append(objects, fmt.Sprintf("<< /Title %s /Author %s /Producer (bookly \\(test\\)) >>", pdfText(title), pdfText(author)))
+}
+
+// testPDF собирает PDF с классической таблицей перекрестных ссылок.
+func testPDF(title, author string, pages int) []byte {
+	objects := pdfObjects(title, author, pages)
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(objects)+1, len(objects), xref)
+	return buf.Bytes()
+}
+
+func deflate(data []byte) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// compressedPDF собирает PDF 1.5: все объекты в потоке объектов, ссылки - в потоке
+// перекрестных ссылок с PNG-предсказанием, как пишут pdfTeX и большинство редакторов.
+func compressedPDF(title, author string, pages int) []byte {
+	objects := pdfObjects(title, author, pages)
+	var header, body bytes.Buffer
+	for i, obj := range objects {
+		fmt.Fprintf(&header, "%d %d ", i+1, body.Len())
+		body.WriteString(obj + "\n")
+	}
+	stmNum, xrefNum := len(objects)+1, len(objects)+2
+	stmData := deflate(append(header.Bytes(), body.Bytes()...))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.5\n")
+	stmOffset := buf.Len()
+	fmt.Fprintf(&buf, "%d 0 obj\n<< /Type /ObjStm /N %d /First %d /Filter /FlateDecode /Length %d >>\nstream\n",
+		stmNum, len(objects), header.Len(), len(stmData))
+	buf.Write(stmData)
+	buf.WriteString("\nendstream\nendobj\n")
+	xrefOffset := buf.Len()
+
+	// строки по 1+4+2 байта: тип, смещение или номер потока, поколение или индекс
+	var rows [][]byte
+	rows = append(rows, []byte{0, 0, 0, 0, 0, 0xFF, 0xFF})
+	for i := range objects {
+		rows = append(rows, []byte{2, 0, 0, 0, byte(stmNum), 0, byte(i)})
+	}
+	for _, off := range []int{stmOffset, xrefOffset} {
+		rows = append(rows, []byte{1, byte(off >> 24), byte(off >> 16), byte(off >> 8), byte(off), 0, 0})
+	}
+	var predicted []byte
+	prev := make([]byte, 7)
+	for _, row := range rows {
+		predicted = append(predicted, 2)
+		for j := range row {
+			predicted = append(predicted, row[j]-prev[j])
+		}
+		prev = row
+	}
+	xrefData := deflate(predicted)
+
+	fmt.Fprintf(&buf, "%d 0 obj\n<< /Type /XRef /Size %d /W [1 4 2] /Root 1 0 R /Info %d 0 R "+
+		"/Filter /FlateDecode /DecodeParms << /Predictor 12 /Columns 7 >> /Length %d >>\nstream\n",
+		xrefNum, xrefNum+1, len(objects), len(xrefData))
+	buf.Write(xrefData)
+	fmt.Fprintf(&buf, "\nendstream\nendobj\nstartxref\n%d\n%%%%EOF\n", xrefOffset)
+	return buf.Bytes()
+}
+
+// xrefStreamPDF собирает PDF из одного потока перекрестных ссылок со словарем dict
+// (без /Length, если он задан в dict) и данными data.
+func xrefStreamPDF(dict string, data []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.5\n")
+	offset := buf.Len()
+	if !strings.Contains(dict, "/Length") {
+		dict += fmt.Sprintf(" /Length %d", len(data))
+	}
+	fmt.Fprintf(&buf, "1 0 obj\n<< /Type /XRef /Size 2 /W [1 4 2] %s >>\nstream\n", dict)
+	buf.Write(data)
+	fmt.Fprintf(&buf, "\nendstream\nendobj\nstartxref\n%d\n%%%%EOF\n", offset)
+	return buf.Bytes()
+}
+
+func TestPDFMeta_inspect(t *testing.T) {
+	inspect := func(data []byte) (pdfmeta.Info, error) {
+		return pdfmeta.Inspect(bytes.NewReader(data), int64(len(data)))
+	}
+
+	t.Run("xref table", func(t *testing.T) {
+		data := testPDF("Война и мир", "Лев Толстой", 3)
+		info, err := inspect(data)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, info.Pages)
+		assert.Equal(t, "Война и мир", info.Title)
+		assert.Equal(t, "Лев Толстой", info.Author)
+		assert.Equal(t, int64(len(data)), info.Size)
+		assert.Len(t, info.SHA256, 64)
+	})
+
+	t.Run("xref and object streams", func(t *testing.T) {
+		info, err := inspect(compressedPDF("Anna Karenina", "Leo Tolstoy", 12))
+		assert.NoError(t, err)
+		assert.Equal(t, 12, info.Pages)
+		assert.Equal(t, "Anna Karenina", info.Title)
+		assert.Equal(t, "Leo Tolstoy", info.Author)
+	})
+
+	t.Run("same content same hash", func(t *testing.T) {
+		a, _ := inspect(testPDF("Title", "Author", 1))
+		b, _ := inspect(testPDF("Title", "Author", 1))
+		c, _ := inspect(testPDF("Title", "Author", 2))
+		assert.Equal(t, a.SHA256, b.SHA256)
+		assert.NotEqual(t, a.SHA256, c.SHA256)
+	})
+
+	t.Run("not a pdf", func(t *testing.T) {
+		_, err := inspect([]byte("fake pdf"))
+		assert.ErrorIs(t, err, pdfmeta.ErrNotPDF)
+
+		_, err = inspect(testCover(t, 10, 10))
+		assert.ErrorIs(t, err, pdfmeta.ErrNotPDF)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		data := testPDF("Title", "Author", 2)
+		_, err := inspect(data[:len(data)-40])
+		assert.ErrorIs(t, err, pdfmeta.ErrMalformed)
+	})
+
+	t.Run("broken xref offset", func(t *testing.T) {
+		data := testPDF("Title", "Author", 2)
+		i := bytes.LastIndex(data, []byte("startxref\n"))
+		broken := append(append([]byte{}, data[:i]...), []byte("startxref\n17\n%%EOF\n")...)
+		_, err := inspect(broken)
+		assert.ErrorIs(t, err, pdfmeta.ErrMalformed)
+	})
+
+	t.Run("no pages", func(t *testing.T) {
+		_, err := inspect(testPDF("Title", "Author", 0))
+		assert.ErrorIs(t, err, pdfmeta.ErrMalformed)
+	})
+
+	t.Run("huge stream length", func(t *testing.T) {
+		_, err := inspect(xrefStreamPDF("/Length 9223372036854775807", []byte("data")))
+		assert.ErrorIs(t, err, pdfmeta.ErrMalformed)
+	})
+
+	t.Run("huge predictor columns", func(t *testing.T) {
+		_, err := inspect(xrefStreamPDF("/Filter /FlateDecode /DecodeParms << /Predictor 12 /Columns 4611686018427387904 >>", deflate(nil)))
+		assert.ErrorIs(t, err, pdfmeta.ErrMalformed)
+	})
+}
+
+// FuzzPDFMeta_inspect проверяет, что Inspect на любом файле возвращает ошибку, а не
+// паникует: он разбирает загрузки администраторов.
+func FuzzPDFMeta_inspect(f *testing.F) {
+	f.Add(testPDF("Война и мир", "Лев Толстой", 3))
+	f.Add(compressedPDF("Anna Karenina", "Leo Tolstoy", 2))
+	f.Add(xrefStreamPDF("/Length 9223372036854775807", []byte("data")))
+	f.Add(xrefStreamPDF("/Filter /FlateDecode /DecodeParms << /Predictor 12 /Columns 7 >>", deflate([]byte{2, 1, 0, 0, 0, 9, 0, 0})))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		info, err := pdfmeta.Inspect(bytes.NewReader(data), int64(len(data)))
+		if err == nil && info.Pages < 1 {
+			t.Errorf("inspected pdf without pages: %+v", info)
+		}
+	})
+}
+
+func TestServer_addBookPDF(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	s := &server.Server{Storage: mockStorage, Blobs: blob.NewLocal(t.TempDir())}
+	cover := testCover(t, 40, 60)
+
+	do := func(fields map[string]string, pdf []byte) *httptest.ResponseRecorder {
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		for field, value := range fields {
+			assert.NoError(t, writer.WriteField(field, value))
+		}
+		for field, content := range map[string][]byte{"cover": cover, "pdf": pdf} {
+			part, err := writer.CreateFormFile(field, field+".bin")
+			assert.NoError(t, err)
+			_, err = part.Write(content)
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, writer.Close())
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/add-book", body)
+		ctx.Request.Header.Set("Content-Type", writer.FormDataContentType())
+		ctx.Set("uid", "admin1")
+		s.AddBook(ctx)
+		return w
+	}
+	fields := map[string]string{"desc": "Роман-эпопея", "genre": "Роман", "age": "1869"}
+
+	t.Run("fields from metadata", func(t *testing.T) {
+		pdf := testPDF("Война и мир", "Лев Толстой", 3)
+		info, _ := pdfmeta.Inspect(bytes.NewReader(pdf), int64(len(pdf)))
+
+		mockStorage.EXPECT().GetBookByFileHash(info.SHA256).Return(models.Book{}, storerrros.ErrBookNoExist)
+		mockStorage.EXPECT().SaveBook(gomock.Any(), gomock.Any()).DoAndReturn(func(book models.Book, _ string) error {
+			assert.Equal(t, "Война и мир", book.Lable)
+			assert.Equal(t, "Лев Толстой", book.Author)
+			assert.Equal(t, 3, book.PDFPages)
+			assert.Equal(t, "Война и мир", book.PDFTitle)
+			assert.Equal(t, int64(len(pdf)), book.PDFSize)
+			assert.Equal(t, info.SHA256, book.PDFHash)
+			return nil
+		})
+
+		w := do(fields, pdf)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("form fields win over metadata", func(t *testing.T) {
+		mockStorage.EXPECT().GetBookByFileHash(gomock.Any()).Return(models.Book{}, storerrros.ErrBookNoExist)
+		mockStorage.EXPECT().SaveBook(gomock.Any(), gomock.Any()).DoAndReturn(func(book models.Book, _ string) error {
+			assert.Equal(t, "War and Peace", book.Lable)
+			assert.Equal(t, "Лев Толстой", book.Author)
+			assert.Equal(t, "Война и мир", book.PDFTitle)
+			return nil
+		})
+
+		w := do(map[string]string{"lable": "War and Peace", "desc": "Роман-эпопея", "genre": "Роман", "age": "1869"},
+			testPDF("Война и мир", "Лев Толстой", 4))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("no author anywhere", func(t *testing.T) {
+		w := do(fields, testPDF("Война и мир", "", 3))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "missing or empty field: author")
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		mockStorage.EXPECT().GetBookByFileHash(gomock.Any()).Return(models.Book{BID: "b1"}, nil)
+
+		w := do(fields, testPDF("Война и мир", "Лев Толстой", 3))
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), storerrros.ErrDuplicateFile.Error())
+		assert.Contains(t, w.Body.String(), `"bid":"b1"`)
+	})
+
+	t.Run("duplicate race", func(t *testing.T) {
+		mockStorage.EXPECT().GetBookByFileHash(gomock.Any()).Return(models.Book{}, storerrros.ErrBookNoExist)
+		mockStorage.EXPECT().SaveBook(gomock.Any(), gomock.Any()).Return(storerrros.ErrDuplicateFile)
+
+		w := do(fields, testPDF("Война и мир", "Лев Толстой", 3))
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("not a pdf", func(t *testing.T) {
+		w := do(fields, []byte("<html>not a book</html>"))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), pdfmeta.ErrNotPDF.Error())
+	})
+
+	t.Run("damaged pdf", func(t *testing.T) {
+		pdf := testPDF("Война и мир", "Лев Толстой", 3)
+		w := do(fields, pdf[:len(pdf)/2])
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), pdfmeta.ErrMalformed.Error())
+	})
+
+	t.Run("hash lookup fails", func(t *testing.T) {
+		mockStorage.EXPECT().GetBookByFileHash(gomock.Any()).Return(models.Book{}, errors.New("db error"))
+
+		w := do(fields, testPDF("Война и мир", "Лев Толстой", 3))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/azaliaz/bookly/book-service/internal/config"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/server"
+	"github.com/azaliaz/bookly/book-service/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_series(t *testing.T) {
+	stor := storage.New()
+	s := server.New(config.Config{BlobDir: t.TempDir()}, stor)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/series/:id", s.SeriesInfo)
+	router.POST("/series", s.JWTAuthRoleMiddleware("admin"), s.AddSeries)
+	router.PUT("/series/:id", s.JWTAuthRoleMiddleware("admin"), s.UpdateSeries)
+	router.DELETE("/series/:id", s.JWTAuthRoleMiddleware("admin"), s.RemoveSeries)
+	router.GET("/books/:id", s.BookInfo)
+	router.GET("/books/:id/series", s.BookSeries)
+	router.PUT("/books/:id/series", s.JWTAuthRoleMiddleware("admin"), s.SetBookSeries)
+	admin := "Bearer " + testToken(t, "admin1", "admin")
+
+	do := func(method, target string, v interface{}) *httptest.ResponseRecorder {
+		body := new(bytes.Buffer)
+		if v != nil {
+			assert.NoError(t, json.NewEncoder(body).Encode(v))
+		}
+		req := httptest.NewRequest(method, target, body)
+		req.Header.Set("Authorization", admin)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	bids := make(map[string]string)
+	for _, lable := range []string{"Цвет волшебства", "Безумная звезда", "Мор, ученик Смерти", "Троллев мост"} {
+		assert.NoError(t, stor.SaveBook(models.Book{Lable: lable, Author: "Терри Пратчетт"}, ""))
+		page, err := stor.GetBooksWithFilters(models.BookFilter{Search: lable})
+		assert.NoError(t, err)
+		bids[lable] = page.Books[0].BID
+	}
+
+	w := do(http.MethodPost, "/series", models.Series{Name: " Плоский   мир ", Desc: "Цикл романов"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var discworld models.Series
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &discworld))
+	assert.Equal(t, "Плоский мир", discworld.Name)
+
+	place := func(lable string, position float64) *httptest.ResponseRecorder {
+		return do(http.MethodPut, "/books/"+bids[lable]+"/series",
+			[]models.BookSeries{{SeriesID: discworld.ID, Position: position}})
+	}
+	assert.Equal(t, http.StatusOK, place("Цвет волшебства", 1).Code)
+	assert.Equal(t, http.StatusOK, place("Безумная звезда", 2).Code)
+	assert.Equal(t, http.StatusOK, place("Мор, ученик Смерти", 4).Code)
+
+	t.Run("fractional position between books", func(t *testing.T) {
+		w := place("Троллев мост", 2.5)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var got struct {
+			Series []models.BookSeries `json:"series"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		if assert.Len(t, got.Series, 1) {
+			entry := got.Series[0]
+			assert.Equal(t, 2.5, entry.Position)
+			assert.Equal(t, "Безумная звезда", entry.Prev.Lable)
+			assert.Equal(t, "/books/"+bids["Безумная звезда"], entry.Prev.URL)
+			assert.Equal(t, "Мор, ученик Смерти", entry.Next.Lable)
+			assert.Equal(t, 4.0, entry.Next.Position)
+		}
+	})
+
+	t.Run("series in reading order", func(t *testing.T) {
+		w := do(http.MethodGet, "/series/"+discworld.ID, nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var got models.Series
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		var order []string
+		for _, b := range got.Books {
+			order = append(order, b.Lable)
+		}
+		assert.Equal(t, []string{"Цвет волшебства", "Безумная звезда", "Троллев мост", "Мор, ученик Смерти"}, order)
+		assert.Equal(t, 4, got.BookCount)
+		assert.Equal(t, "/books/"+bids["Цвет волшебства"], got.Books[0].URL)
+	})
+
+	t.Run("book info has previous and next", func(t *testing.T) {
+		w := do(http.MethodGet, "/books/"+bids["Цвет волшебства"], nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var book models.Book
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &book))
+		if assert.Len(t, book.Series, 1) {
+			assert.Nil(t, book.Series[0].Prev)
+			assert.Equal(t, bids["Безумная звезда"], book.Series[0].Next.BID)
+			assert.Equal(t, "Плоский мир", book.Series[0].Name)
+		}
+	})
+
+	t.Run("invalid position", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, place("Троллев мост", -1).Code)
+		assert.Equal(t, http.StatusBadRequest, place("Троллев мост", 2.555).Code)
+	})
+
+	t.Run("unknown series", func(t *testing.T) {
+		w := do(http.MethodPut, "/books/"+bids["Троллев мост"]+"/series",
+			[]models.BookSeries{{SeriesID: "missing", Position: 1}})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = do(http.MethodGet, "/series/missing", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("rename and delete", func(t *testing.T) {
+		w := do(http.MethodPut, "/series/"+discworld.ID, models.Series{Name: "Discworld"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"book_count":4`)
+
+		w = do(http.MethodDelete, "/series/"+discworld.ID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = do(http.MethodGet, "/books/"+bids["Троллев мост"]+"/series", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"series": []}`, w.Body.String())
+	})
+}
@@ -0,0 +1,290 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/azaliaz/bookly/book-service/internal/cache"
+	"github.com/azaliaz/bookly/book-service/internal/config"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/server"
+	"github.com/azaliaz/bookly/book-service/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	lru := cache.NewLRU(2)
+
+	assert.NoError(t, lru.Set(ctx, "a", []byte("1"), 0))
+	assert.NoError(t, lru.Set(ctx, "b", []byte("2"), 0))
+	_, ok, _ := lru.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.NoError(t, lru.Set(ctx, "c", []byte("3"), 0))
+	assert.Equal(t, 2, lru.Len())
+	_, ok, _ = lru.Get(ctx, "b")
+	assert.False(t, ok, "least recently used entry must be evicted")
+	value, ok, _ := lru.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", string(value))
+
+	assert.NoError(t, lru.Set(ctx, "ttl", []byte("x"), time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	_, ok, _ = lru.Get(ctx, "ttl")
+	assert.False(t, ok, "expired entry must not be returned")
+
+	n, err := lru.Incr(ctx, "gen")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	n, _ = lru.Incr(ctx, "gen")
+	assert.Equal(t, int64(2), n)
+	value, ok, _ = lru.Get(ctx, "gen")
+	assert.True(t, ok)
+	assert.Equal(t, "2", string(value))
+}
+
+func testStorageCache(t *testing.T, backend cache.Backend) {
+	stor := storage.New()
+	cached := server.NewCachedStorage(stor, backend, time.Minute)
+
+	require.NoError(t, cached.SaveBook(models.Book{
+		Lable: "Обломов", Author: "Иван Гончаров", Age: 1859, ISBN: "9785170906307",
+	}, ""))
+	book, err := stor.GetBookByISBN("9785170906307")
+	require.NoError(t, err)
+
+	got, err := cached.GetBook(book.BID)
+	require.NoError(t, err)
+	assert.Equal(t, "Обломов", got.Lable)
+	assert.Equal(t, server.CacheStats{Misses: 1}, cached.CacheStats())
+
+	got, err = cached.GetBook(book.BID)
+	require.NoError(t, err)
+	assert.Equal(t, "Обломов", got.Lable)
+	assert.Equal(t, server.CacheStats{Hits: 1, Misses: 1}, cached.CacheStats())
+
+	_, err = cached.GetBook("missing")
+	assert.Error(t, err)
+	_, err = cached.GetBook("missing")
+	assert.Error(t, err, "errors must not be cached")
+	assert.Equal(t, uint64(3), cached.CacheStats().Misses)
+
+	page, err := cached.GetBooksWithFilters(models.BookFilter{Search: "обломов", Year: "1859"})
+	require.NoError(t, err)
+	again, err := cached.GetBooksWithFilters(models.BookFilter{Search: "ОБЛОМОВ", Year: " 1859 "})
+	require.NoError(t, err)
+	assert.Equal(t, page, again)
+	assert.Equal(t, uint64(2), cached.CacheStats().Hits, "equivalent filters must share a cache entry")
+
+	list, err := cached.GetBooks(models.Page{})
+	require.NoError(t, err)
+	assert.Len(t, list.Books, 1)
+
+	require.NoError(t, cached.SaveBook(models.Book{Lable: "Обрыв", Author: "Иван Гончаров", Age: 1869}, ""))
+	list, err = cached.GetBooks(models.Page{})
+	require.NoError(t, err)
+	assert.Len(t, list.Books, 2, "SaveBook must invalidate the cache")
+
+	require.NoError(t, cached.DeleteBook(book.BID, "admin1"))
+	_, err = cached.GetBook(book.BID)
+	assert.Error(t, err, "DeleteBook must invalidate the cache")
+	assert.Zero(t, cached.CacheStats().Errors)
+}
+
+func TestCachedStorage_memory(t *testing.T) {
+	testStorageCache(t, cache.NewLRU(100))
+}
+
+func TestCachedStorage_redis(t *testing.T) {
+	addr := startRESPServer(t, "secret")
+	redis, err := cache.NewRedis("redis://:secret@" + addr + "/2")
+	require.NoError(t, err)
+	t.Cleanup(func() { redis.Close() })
+	require.NoError(t, redis.Ping(context.Background()))
+
+	testStorageCache(t, redis)
+
+	ctx := context.Background()
+	require.NoError(t, redis.Set(ctx, "short", []byte("value"), time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	_, ok, err := redis.Get(ctx, "short")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	wrong, err := cache.NewRedis("redis://:wrong@" + addr)
+	require.NoError(t, err)
+	assert.Error(t, wrong.Ping(ctx))
+}
+
+func TestCachedStorage_unavailable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+	redis, err := cache.NewRedis("redis://" + addr)
+	require.NoError(t, err)
+
+	stor := storage.New()
+	cached := server.NewCachedStorage(stor, redis, time.Minute)
+	require.NoError(t, cached.SaveBook(models.Book{
+		Lable: "Обломов", Author: "Иван Гончаров", Age: 1859, ISBN: "9785170906307",
+	}, ""))
+	book, err := stor.GetBookByISBN("9785170906307")
+	require.NoError(t, err)
+
+	got, err := cached.GetBook(book.BID)
+	assert.NoError(t, err, "reads must fall through to the storage")
+	assert.Equal(t, "Обломов", got.Lable)
+	assert.NotZero(t, cached.CacheStats().Errors)
+}
+
+func TestServer_StorageCacheStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	admin := "Bearer " + testToken(t, "admin1", "admin")
+	stats := func(s *server.Server) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.GET("/cache/stats", s.JWTAuthRoleMiddleware("admin"), s.StorageCacheStats)
+		req := httptest.NewRequest(http.MethodGet, "/cache/stats", nil)
+		req.Header.Set("Authorization", admin)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := stats(server.New(config.Config{BlobDir: t.TempDir()}, storage.New()))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	cached := server.NewCachedStorage(storage.New(), cache.NewLRU(10), time.Minute)
+	require.NoError(t, cached.SaveBook(models.Book{Lable: "Обрыв", Author: "Иван Гончаров", Age: 1869}, ""))
+	_, _ = cached.GetBooks(models.Page{})
+	_, _ = cached.GetBooks(models.Page{})
+	w = stats(server.New(config.Config{BlobDir: t.TempDir()}, cached))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got server.CacheStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, server.CacheStats{Hits: 1, Misses: 1}, got)
+}
+
+// startRESPServer запускает заглушку Redis с командами, которые нужны кешу.
+func startRESPServer(t *testing.T, password string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	type entry struct {
+		value   string
+		expires time.Time
+	}
+	var mu sync.Mutex
+	data := map[string]entry{}
+
+	handle := func(args []string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			return "+PONG\r\n"
+		case "SELECT":
+			return "+OK\r\n"
+		case "GET":
+			e, ok := data[args[1]]
+			if !ok || (!e.expires.IsZero() && time.Now().After(e.expires)) {
+				return "$-1\r\n"
+			}
+			return fmt.Sprintf("$%d\r\n%s\r\n", len(e.value), e.value)
+		case "SET":
+			e := entry{value: args[2]}
+			if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+				ms, _ := strconv.Atoi(args[4])
+				e.expires = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			}
+			data[args[1]] = e
+			return "+OK\r\n"
+		case "INCR":
+			n, _ := strconv.ParseInt(data[args[1]].value, 10, 64)
+			data[args[1]] = entry{value: strconv.FormatInt(n+1, 10)}
+			return fmt.Sprintf(":%d\r\n", n+1)
+		}
+		return "-ERR unknown command\r\n"
+	}
+
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		authed := password == ""
+		for {
+			args, err := readRESPCommand(r)
+			if err != nil {
+				return
+			}
+			var reply string
+			switch {
+			case strings.ToUpper(args[0]) == "AUTH":
+				authed = len(args) == 2 && args[1] == password
+				reply = "+OK\r\n"
+				if !authed {
+					reply = "-WRONGPASS invalid password\r\n"
+				}
+			case !authed:
+				reply = "-NOAUTH Authentication required.\r\n"
+			default:
+				reply = handle(args)
+			}
+			if _, err := io.WriteString(conn, reply); err != nil {
+				return
+			}
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid command %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
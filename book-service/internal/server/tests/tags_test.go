@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/azaliaz/bookly/book-service/internal/config"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/server"
+	"github.com/azaliaz/bookly/book-service/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_tags(t *testing.T) {
+	stor := storage.New()
+	s := server.New(config.Config{BlobDir: t.TempDir()}, stor)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/books/search", s.AllBooksWithSearch)
+	router.GET("/books/:id", s.BookInfo)
+	router.PUT("/books/:id/tags", s.JWTAuthRoleMiddleware("admin"), s.SetBookTags)
+	router.POST("/books/:id/tags", s.JWTAuthRoleMiddleware(), s.SuggestBookTag)
+	router.PUT("/books/:id/tags/:tag_id", s.JWTAuthRoleMiddleware("admin"), s.ApproveBookTag)
+	router.DELETE("/books/:id/tags/:tag_id", s.JWTAuthRoleMiddleware("admin"), s.RemoveBookTag)
+	router.GET("/tags", s.TagCloud)
+	router.GET("/tags/suggestions", s.JWTAuthRoleMiddleware("admin"), s.TagSuggestions)
+	router.DELETE("/tags/:id", s.JWTAuthRoleMiddleware("admin"), s.RemoveTag)
+	admin := "Bearer " + testToken(t, "admin1", "admin")
+	user := "Bearer " + testToken(t, "user1", "user")
+
+	do := func(token, method, target string, v interface{}) *httptest.ResponseRecorder {
+		body := new(bytes.Buffer)
+		if v != nil {
+			assert.NoError(t, json.NewEncoder(body).Encode(v))
+		}
+		req := httptest.NewRequest(method, target, body)
+		req.Header.Set("Authorization", token)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	search := func(query string) []string {
+		w := do(user, http.MethodGet, "/books/search?sort_by=lable&"+query, nil)
+		if w.Code == http.StatusNotFound {
+			return nil
+		}
+		assert.Equal(t, http.StatusOK, w.Code)
+		var page models.BooksPage
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		var lables []string
+		for _, book := range page.Books {
+			lables = append(lables, book.Lable)
+		}
+		return lables
+	}
+	cloud := func() []models.Tag {
+		w := do(user, http.MethodGet, "/tags", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var res struct {
+			Tags []models.Tag `json:"tags"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return res.Tags
+	}
+
+	statuses, err := stor.SaveBooks([]models.Book{
+		{Lable: "Сто лет одиночества", Author: "Габриэль Гарсиа Маркес", Age: 1967},
+		{Lable: "Старик и море", Author: "Эрнест Хемингуэй", Age: 1952},
+		{Lable: "Семь мужей Эвелин Хьюго", Author: "Тейлор Дженкинс Рид", Age: 2017},
+	}, "")
+	assert.NoError(t, err)
+	marquez, hemingway, hugo := statuses[0].BID, statuses[1].BID, statuses[2].BID
+
+	t.Run("admin tags are approved at once", func(t *testing.T) {
+		w := do(admin, http.MethodPut, "/books/"+marquez+"/tags", []string{" Нобелевские  лауреаты ", "классика", "КЛАССИКА"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var book models.Book
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &book))
+		assert.Len(t, book.Tags, 2)
+		assert.Equal(t, "классика", book.Tags[0].Name)
+		assert.Equal(t, "Нобелевские лауреаты", book.Tags[1].Name)
+
+		w = do(admin, http.MethodPut, "/books/"+hemingway+"/tags", []string{"нобелевские лауреаты"})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = do(admin, http.MethodPut, "/books/"+hugo+"/tags", []string{"x"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = do(admin, http.MethodPut, "/books/missing/tags", []string{"классика"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = do(user, http.MethodPut, "/books/"+hugo+"/tags", []string{"booktok"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("user tags wait for moderation", func(t *testing.T) {
+		w := do(user, http.MethodPost, "/books/"+hugo+"/tags", gin.H{"name": "booktok"})
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		var suggestion models.TagSuggestion
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &suggestion))
+		assert.Equal(t, "user1", suggestion.SuggestedBy)
+
+		w = do(user, http.MethodPost, "/books/"+hugo+"/tags", gin.H{"name": "BookTok"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		w = do(user, http.MethodPost, "/books/"+hemingway+"/tags", gin.H{"name": "booktok"})
+		assert.Equal(t, http.StatusAccepted, w.Code)
+
+		// до одобрения тег не виден ни в карточке, ни в фильтре, ни в облаке
+		assert.Nil(t, search("tag=booktok"))
+		assert.Len(t, cloud(), 2)
+
+		w = do(admin, http.MethodGet, "/tags/suggestions", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var res struct {
+			Suggestions []models.TagSuggestion `json:"suggestions"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Len(t, res.Suggestions, 2)
+		assert.Equal(t, "Семь мужей Эвелин Хьюго", res.Suggestions[0].Lable)
+
+		w = do(admin, http.MethodPut, "/books/"+hugo+"/tags/"+suggestion.TagID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = do(admin, http.MethodDelete, "/books/"+hemingway+"/tags/"+suggestion.TagID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = do(admin, http.MethodDelete, "/books/"+hemingway+"/tags/"+suggestion.TagID, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		assert.Equal(t, []string{"Семь мужей Эвелин Хьюго"}, search("tag=booktok"))
+	})
+
+	t.Run("filter by any or all tags", func(t *testing.T) {
+		assert.Equal(t, []string{"Старик и море", "Сто лет одиночества"}, search("tag=нобелевские+лауреаты"))
+		assert.Equal(t, []string{"Старик и море", "Сто лет одиночества"}, search("tag=классика&tag=Нобелевские+лауреаты"))
+		assert.Equal(t, []string{"Сто лет одиночества"}, search("tag=классика&tag=Нобелевские+лауреаты&tag_mode=all"))
+		assert.Equal(t, []string{"Сто лет одиночества"}, search("tag=классика&tag=КЛАССИКА&tag_mode=all"))
+		assert.Nil(t, search("tag=booktok&tag=классика&tag_mode=all"))
+	})
+
+	t.Run("tag cloud counts approved books", func(t *testing.T) {
+		tags := cloud()
+		assert.Len(t, tags, 3)
+		assert.Equal(t, "Нобелевские лауреаты", tags[0].Name)
+		assert.Equal(t, 2, tags[0].BookCount)
+
+		w := do(user, http.MethodGet, "/tags?limit=1", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Нобелевские лауреаты")
+		assert.NotContains(t, w.Body.String(), "классика")
+
+		w = do(admin, http.MethodDelete, "/tags/"+tags[0].ID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, cloud(), 2)
+		assert.Nil(t, search("tag=нобелевские+лауреаты"))
+		w = do(admin, http.MethodDelete, "/tags/"+tags[0].ID, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/azaliaz/bookly/book-service/internal/config"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/server"
+	"github.com/azaliaz/bookly/book-service/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_trash(t *testing.T) {
+	stor := storage.New()
+	blobDir := t.TempDir()
+	s := server.New(config.Config{BlobDir: blobDir, TrashRetention: time.Hour}, stor)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/books/search", s.AllBooksWithSearch)
+	router.GET("/books/trash", s.JWTAuthRoleMiddleware("admin"), s.ListTrash)
+	router.GET("/books/:id", s.BookInfo)
+	router.DELETE("/books/remove/:id", s.JWTAuthRoleMiddleware("admin"), s.RemoveBook)
+	router.POST("/books/trash/:id/restore", s.JWTAuthRoleMiddleware("admin"), s.RestoreBook)
+	admin := "Bearer " + testToken(t, "admin1", "admin")
+
+	do := func(method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", admin)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	trash := func() []models.Book {
+		w := do(http.MethodGet, "/books/trash")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var res struct {
+			Books []models.Book `json:"books"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return res.Books
+	}
+
+	pdf := []byte("%PDF-1.4 test")
+	assert.NoError(t, s.Blobs.Put(context.Background(), "pdf/book.pdf", bytes.NewReader(pdf), int64(len(pdf)), "application/pdf"))
+	statuses, err := stor.SaveBooks([]models.Book{
+		{Lable: "Мастер и Маргарита", Author: "Михаил Булгаков", Age: 1967, ISBN: "9785170906307",
+			PDFKey: "pdf/book.pdf", PDFHash: "hash1"},
+		{Lable: "Белая гвардия", Author: "Михаил Булгаков", Age: 1925},
+	}, "")
+	assert.NoError(t, err)
+	master := statuses[0].BID
+
+	t.Run("deleted book goes to trash", func(t *testing.T) {
+		w := do(http.MethodDelete, "/books/remove/"+master)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/books/"+master).Code)
+		w = do(http.MethodGet, "/books/search?search=Булгаков")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "Мастер и Маргарита")
+		assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/books/remove/"+master).Code)
+
+		books := trash()
+		assert.Len(t, books, 1)
+		assert.Equal(t, master, books[0].BID)
+		assert.Equal(t, "admin1", books[0].DeletedBy)
+		assert.NotNil(t, books[0].DeletedAt)
+		assert.Contains(t, do(http.MethodGet, "/books/trash").Body.String(), `"purge_at"`)
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		// пока книга в корзине, то же издание можно загрузить заново
+		assert.NoError(t, stor.SaveBook(models.Book{Lable: "Мастер и Маргарита", Author: "М. Булгаков", ISBN: "9785170906307"}, ""))
+		assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/books/trash/"+master+"/restore").Code)
+
+		other, err := stor.GetBookByISBN("9785170906307")
+		assert.NoError(t, err)
+		assert.NoError(t, stor.DeleteBook(other.BID, "admin1"))
+
+		w := do(http.MethodPost, "/books/trash/"+master+"/restore")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/books/"+master).Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/books/trash/"+master+"/restore").Code)
+		assert.Len(t, trash(), 1)
+	})
+
+	t.Run("purge after retention", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/books/remove/"+master).Code)
+
+		purged, err := s.PurgeTrash()
+		assert.NoError(t, err)
+		assert.Zero(t, purged)
+		assert.Len(t, trash(), 2)
+
+		purger := server.New(config.Config{BlobDir: blobDir, TrashRetention: time.Nanosecond}, stor)
+		purged, err = purger.PurgeTrash()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, purged)
+		assert.Empty(t, trash())
+
+		_, err = s.Blobs.Get(context.Background(), "pdf/book.pdf")
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/books/trash/"+master+"/restore").Code)
+		w := do(http.MethodGet, "/books/search?search=Булгаков")
+		assert.Contains(t, w.Body.String(), "Белая гвардия")
+	})
+}
@@ -0,0 +1,232 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/azaliaz/bookly/book-service/internal/blob"
+	"github.com/azaliaz/bookly/book-service/internal/config"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/server"
+	"github.com/azaliaz/bookly/book-service/internal/server/mocks"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_updateBook(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	s := server.New(config.Config{}, mockStorage)
+	s.Blobs = blob.NewLocal(t.TempDir())
+
+	current := models.Book{
+		BID:      "book123",
+		Lable:    "War and Peas",
+		Author:   "Leo Tolstoy",
+		Desc:     "A novel about war and peace",
+		Age:      1869,
+		Genre:    "Роман",
+		CoverKey: "covers/old.jpg",
+		Version:  3,
+	}
+
+	createCtx := func(method, body, ifMatch string) (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(method, "/books/book123", strings.NewReader(body))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			ctx.Request.Header.Set("If-Match", ifMatch)
+		}
+		ctx.Set("uid", "admin1")
+		ctx.Params = gin.Params{{Key: "id", Value: "book123"}}
+		return ctx, w
+	}
+
+	t.Run("patch success", func(t *testing.T) {
+		fixed := current
+		fixed.Lable = "War and Peace"
+		mockStorage.EXPECT().GetBook("book123").Return(current, nil)
+		mockStorage.EXPECT().UpdateBook(fixed, 3, gomock.Any()).DoAndReturn(func(b models.Book, _ int, _ string) (models.Book, error) {
+			b.Version = 4
+			return b, nil
+		})
+
+		ctx, w := createCtx(http.MethodPatch, `{"lable":"War and Peace"}`, `"3"`)
+		s.PatchBook(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, strings.HasPrefix(w.Header().Get("ETag"), `"4-`), w.Header().Get("ETag"))
+		assert.Contains(t, w.Body.String(), "War and Peace")
+	})
+
+	t.Run("missing if-match", func(t *testing.T) {
+		ctx, w := createCtx(http.MethodPatch, `{"lable":"War and Peace"}`, "")
+		s.PatchBook(ctx)
+
+		assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	})
+
+	t.Run("stale version", func(t *testing.T) {
+		mockStorage.EXPECT().GetBook("book123").Return(current, nil)
+
+		ctx, w := createCtx(http.MethodPatch, `{"lable":"War and Peace"}`, `"2"`)
+		s.PatchBook(ctx)
+
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+		assert.Contains(t, w.Body.String(), storerrros.ErrVersionConflict.Error())
+	})
+
+	t.Run("concurrent update", func(t *testing.T) {
+		mockStorage.EXPECT().GetBook("book123").Return(current, nil)
+		mockStorage.EXPECT().UpdateBook(gomock.Any(), 3, gomock.Any()).Return(models.Book{}, storerrros.ErrVersionConflict)
+
+		ctx, w := createCtx(http.MethodPatch, `{"rating":5}`, `"3"`)
+		s.PatchBook(ctx)
+
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockStorage.EXPECT().GetBook("book123").Return(models.Book{}, storerrros.ErrBookNoExist)
+
+		ctx, w := createCtx(http.MethodPatch, `{"rating":5}`, `"3"`)
+		s.PatchBook(ctx)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("patch fails validation", func(t *testing.T) {
+		mockStorage.EXPECT().GetBook("book123").Return(current, nil)
+
+		ctx, w := createCtx(http.MethodPatch, `{"lable":"W"}`, `"3"`)
+		s.PatchBook(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Lable")
+	})
+
+	t.Run("put requires all fields", func(t *testing.T) {
+		ctx, w := createCtx(http.MethodPut, `{"lable":"War and Peace"}`, `"3"`)
+		s.ReplaceBook(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "missing or empty field: author")
+	})
+
+	t.Run("put resets rating", func(t *testing.T) {
+		rated := current
+		rated.Rating = 4
+		mockStorage.EXPECT().GetBook("book123").Return(rated, nil)
+		mockStorage.EXPECT().UpdateBook(gomock.Any(), 3, gomock.Any()).DoAndReturn(func(b models.Book, _ int, _ string) (models.Book, error) {
+			assert.Equal(t, 0, b.Rating)
+			assert.Equal(t, "Новое описание книги", b.Desc)
+			return b, nil
+		})
+
+		body := `{"lable":"War and Peace","author":"Leo Tolstoy","desc":"Новое описание книги","genre":"Роман","age":1869}`
+		ctx, w := createCtx(http.MethodPut, body, `"3"`)
+		s.ReplaceBook(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("replace cover", func(t *testing.T) {
+		mockStorage.EXPECT().GetBook("book123").Return(current, nil)
+		mockStorage.EXPECT().UpdateBook(gomock.Any(), 3, gomock.Any()).DoAndReturn(func(b models.Book, _ int, _ string) (models.Book, error) {
+			assert.NotEqual(t, current.CoverKey, b.CoverKey)
+			assert.True(t, strings.HasPrefix(b.CoverKey, "covers/"))
+			assert.True(t, strings.HasSuffix(b.CoverKey, "/full.jpg"))
+			return b, nil
+		})
+
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		assert.NoError(t, writer.WriteField("genre", "Эпопея"))
+		part, err := writer.CreateFormFile("cover", "cover.jpg")
+		assert.NoError(t, err)
+		_, err = part.Write(testCover(t, 300, 450))
+		assert.NoError(t, err)
+		assert.NoError(t, writer.Close())
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodPatch, "/books/book123", body)
+		ctx.Request.Header.Set("Content-Type", writer.FormDataContentType())
+		ctx.Request.Header.Set("If-Match", `"3"`)
+		ctx.Set("uid", "admin1")
+		ctx.Params = gin.Params{{Key: "id", Value: "book123"}}
+
+		s.PatchBook(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Эпопея")
+		assert.Contains(t, w.Body.String(), `"cover_urls":{`)
+	})
+
+	t.Run("replace pdf", func(t *testing.T) {
+		pdfRequest := func(pdf []byte) (*gin.Context, *httptest.ResponseRecorder) {
+			body := new(bytes.Buffer)
+			writer := multipart.NewWriter(body)
+			part, err := writer.CreateFormFile("pdf", "book.pdf")
+			assert.NoError(t, err)
+			_, err = part.Write(pdf)
+			assert.NoError(t, err)
+			assert.NoError(t, writer.Close())
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = httptest.NewRequest(http.MethodPatch, "/books/book123", body)
+			ctx.Request.Header.Set("Content-Type", writer.FormDataContentType())
+			ctx.Request.Header.Set("If-Match", `"3"`)
+			ctx.Set("uid", "admin1")
+			ctx.Params = gin.Params{{Key: "id", Value: "book123"}}
+			return ctx, w
+		}
+
+		mockStorage.EXPECT().GetBook("book123").Return(current, nil)
+		mockStorage.EXPECT().GetBookByFileHash(gomock.Any()).Return(models.Book{}, storerrros.ErrBookNoExist)
+		mockStorage.EXPECT().UpdateBook(gomock.Any(), 3, gomock.Any()).DoAndReturn(func(b models.Book, _ int, _ string) (models.Book, error) {
+			assert.True(t, strings.HasPrefix(b.PDFKey, "pdfs/"))
+			assert.Equal(t, 5, b.PDFPages)
+			assert.Equal(t, "War and Peace", b.PDFTitle)
+			assert.Len(t, b.PDFHash, 64)
+			return b, nil
+		})
+		ctx, w := pdfRequest(testPDF("War and Peace", "Leo Tolstoy", 5))
+		s.PatchBook(ctx)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"pdf_pages":5`)
+
+		mockStorage.EXPECT().GetBook("book123").Return(current, nil)
+		mockStorage.EXPECT().GetBookByFileHash(gomock.Any()).Return(models.Book{BID: "other"}, nil)
+		ctx, w = pdfRequest(testPDF("War and Peace", "Leo Tolstoy", 5))
+		s.PatchBook(ctx)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), `"bid":"other"`)
+
+		mockStorage.EXPECT().GetBook("book123").Return(current, nil)
+		ctx, w = pdfRequest([]byte("not a pdf"))
+		s.PatchBook(ctx)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("internal error", func(t *testing.T) {
+		mockStorage.EXPECT().GetBook("book123").Return(current, nil)
+		mockStorage.EXPECT().UpdateBook(gomock.Any(), 3, gomock.Any()).Return(models.Book{}, errors.New("db error"))
+
+		ctx, w := createCtx(http.MethodPatch, `{"rating":5}`, `"3"`)
+		s.PatchBook(ctx)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/azaliaz/bookly/book-service/internal/config"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/server"
+	"github.com/azaliaz/bookly/book-service/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_works(t *testing.T) {
+	stor := storage.New()
+	s := server.New(config.Config{BlobDir: t.TempDir()}, stor)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/books/search", s.AllBooksWithSearch)
+	router.GET("/books/:id", s.BookInfo)
+	router.PUT("/books/:id/work", s.JWTAuthRoleMiddleware("admin"), s.SetBookWork)
+	router.GET("/works/:id", s.WorkInfo)
+	admin := "Bearer " + testToken(t, "admin1", "admin")
+
+	do := func(method, target string, v interface{}) *httptest.ResponseRecorder {
+		body := new(bytes.Buffer)
+		if v != nil {
+			assert.NoError(t, json.NewEncoder(body).Encode(v))
+		}
+		req := httptest.NewRequest(method, target, body)
+		req.Header.Set("Authorization", admin)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	bookInfo := func(bid string) models.Book {
+		w := do(http.MethodGet, "/books/"+bid, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var book models.Book
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &book))
+		return book
+	}
+
+	statuses, err := stor.SaveBooks([]models.Book{
+		{Lable: "Война и мир", Author: "Лев Толстой", Age: 1869, Language: "ru", Publisher: "Русский вестник", ISBN: "9785170906307"},
+		{Lable: "Война и мир", Author: "Лев Толстой", Age: 2012, Language: "ru", Publisher: "АСТ", ISBN: "9785171183066"},
+		{Lable: "Анна Каренина", Author: "Лев Толстой", Age: 1878, Language: "ru"},
+	}, "")
+	assert.NoError(t, err)
+	original, reprint, anna := statuses[0].BID, statuses[1].BID, statuses[2].BID
+
+	// перевод с другим названием попадает в произведение, только если оно задано явно
+	translation := bookInfo(original)
+	assert.NoError(t, stor.SaveBook(models.Book{
+		Lable: "War and Peace", Author: "Leo Tolstoy", Age: 2007, Language: "en",
+		Publisher: "Penguin Classics", ISBN: "9780140447934", WorkID: translation.WorkID,
+	}, ""))
+	english, err := stor.GetBookByISBN("9780140447934")
+	assert.NoError(t, err)
+
+	t.Run("book shows sibling editions", func(t *testing.T) {
+		book := bookInfo(reprint)
+
+		assert.NotEmpty(t, book.WorkID)
+		assert.Equal(t, "АСТ", book.Publisher)
+		assert.Equal(t, []models.Edition{
+			{BID: original, Lable: "Война и мир", Language: "ru", Publisher: "Русский вестник", Year: 1869,
+				ISBN: "9785170906307", URL: "/books/" + original},
+			{BID: english.BID, Lable: "War and Peace", Language: "en", Publisher: "Penguin Classics", Year: 2007,
+				ISBN: "9780140447934", URL: "/books/" + english.BID},
+		}, book.Editions)
+
+		assert.Empty(t, bookInfo(anna).Editions)
+	})
+
+	t.Run("popularity aggregates downloads of all editions", func(t *testing.T) {
+		for _, bid := range []string{original, english.BID, english.BID} {
+			assert.NoError(t, stor.LogDownload(models.Download{BID: bid, UID: "u1", Format: "pdf", DownloadedAt: time.Now()}))
+		}
+		assert.NoError(t, stor.LogDownload(models.Download{BID: anna, UID: "u1", Format: "pdf", DownloadedAt: time.Now()}))
+
+		assert.Equal(t, 3, bookInfo(reprint).Popularity)
+		assert.Equal(t, 1, bookInfo(anna).Popularity)
+
+		w := do(http.MethodGet, "/works/"+english.WorkID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var work models.Work
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &work))
+		assert.Equal(t, "Война и мир", work.Title)
+		assert.Equal(t, 3, work.Popularity)
+		assert.Len(t, work.Editions, 3)
+
+		w = do(http.MethodGet, "/books/search?sort_by=popularity&ascending=false&limit=2", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var page models.BooksPage
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		for _, book := range page.Books {
+			assert.Equal(t, english.WorkID, book.WorkID)
+		}
+		assert.NotEmpty(t, page.NextCursor)
+	})
+
+	t.Run("move edition between works", func(t *testing.T) {
+		// перевод выделяется в отдельное произведение, затем возвращается обратно
+		w := do(http.MethodPut, "/books/"+english.BID+"/work", gin.H{"work_id": ""})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var moved models.Book
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &moved))
+		assert.NotEqual(t, english.WorkID, moved.WorkID)
+		assert.Empty(t, moved.Editions)
+		assert.Len(t, bookInfo(original).Editions, 1)
+
+		w = do(http.MethodPut, "/books/"+english.BID+"/work", gin.H{"work_id": english.WorkID})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, bookInfo(original).Editions, 2)
+
+		// произведение без изданий удаляется
+		w = do(http.MethodGet, "/works/"+moved.WorkID, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = do(http.MethodPut, "/books/"+english.BID+"/work", gin.H{"work_id": "missing"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+// ListTrash (GET /books/trash) возвращает книги в корзине, начиная с удаленных
+// последними, и время, когда каждая будет удалена окончательно.
+func (s *Server) ListTrash(ctx *gin.Context) {
+	books, err := s.Storage.GetTrash()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	type trashBook struct {
+		models.Book
+		PurgeAt time.Time `json:"purge_at"`
+	}
+	trash := make([]trashBook, 0, len(books))
+	for _, book := range books {
+		trash = append(trash, trashBook{Book: withURLs(book), PurgeAt: book.DeletedAt.Add(s.trashRetention)})
+	}
+	ctx.JSON(http.StatusOK, gin.H{"books": trash})
+}
+
+// RestoreBook (POST /books/trash/:id/restore) возвращает книгу из корзины в каталог.
+// Если за это время загрузили то же издание или тот же файл, отвечает 409.
+func (s *Server) RestoreBook(ctx *gin.Context) {
+	log := logger.Get()
+
+	bid := ctx.Param("id")
+	if err := s.Storage.RestoreBook(bid, ctx.GetString("uid")); err != nil {
+		switch {
+		case errors.Is(err, storerrros.ErrBookNoExist):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "book not found in trash"})
+		case errors.Is(err, storerrros.ErrDuplicateFile), errors.Is(err, storerrros.ErrDuplicateISBN):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Error().Err(err).Msg("failed to restore book")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore book"})
+		}
+		return
+	}
+
+	book, err := s.Storage.GetBook(bid)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, withURLs(book))
+}
+
+// PurgeTrash окончательно удаляет книги, пролежавшие в корзине дольше срока
+// хранения, и их файлы, включая замененные файлы из истории книги. Возвращает
+// число удаленных книг.
+func (s *Server) PurgeTrash() (int, error) {
+	log := logger.Get()
+
+	books, err := s.Storage.PurgeBooks(time.Now().Add(-s.trashRetention))
+	if err != nil {
+		return 0, err
+	}
+	for _, book := range books {
+		for _, key := range s.bookFileKeys(book) {
+			s.removeUpload(key)
+		}
+		log.Info().Str("bid", book.BID).Str("lable", book.Lable).Msg("book purged from trash")
+	}
+	return len(books), nil
+}
+
+// bookFileKeys возвращает ключи всех файлов, которые когда-либо были у книги:
+// текущих и упомянутых в снимках ее ревизий.
+func (s *Server) bookFileKeys(book models.Book) []string {
+	log := logger.Get()
+
+	keys := []string{book.CoverKey, book.PDFKey, book.EPUBKey}
+	history, err := s.Storage.GetBookHistory(book.BID)
+	if err != nil && !errors.Is(err, storerrros.ErrBookNoExist) {
+		log.Error().Err(err).Str("bid", book.BID).Msg("failed to get book history for purge")
+	}
+	for _, rev := range history {
+		keys = append(keys, rev.Snapshot.CoverKey, rev.Snapshot.PDFKey, rev.Snapshot.EPUBKey)
+	}
+	slices.Sort(keys)
+	return slices.DeleteFunc(slices.Compact(keys), func(key string) bool { return key == "" })
+}
+
+// deleter очищает корзину раз в TrashPurgeInterval и после каждого удаления книги
+// (сигнал в delChan), пока не отменен ctx.
+func (s *Server) deleter(ctx context.Context) {
+	log := logger.Get()
+	ticker := time.NewTicker(consts.TrashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debug().Msg("delete listener stopped")
+			return
+		case <-ticker.C:
+		case <-s.delChan:
+		}
+		if _, err := s.PurgeTrash(); err != nil {
+			log.Error().Err(err).Msg("failed to purge trash")
+		}
+	}
+}
+
+// wakeDeleter просит deleter проверить корзину; если сигнал уже ждет в очереди,
+// новый не нужен.
+func (s *Server) wakeDeleter() {
+	select {
+	case s.delChan <- struct{}{}:
+	default:
+	}
+}
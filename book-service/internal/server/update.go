@@ -0,0 +1,240 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/epubmeta"
+	"github.com/azaliaz/bookly/book-service/internal/isbn"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+var errIfMatchRequired = errors.New("If-Match header with book version is required")
+
+// ifMatchVersion достает версию книги из If-Match: ETag карточки (см. bookETag)
+// или просто номер версии в кавычках.
+func ifMatchVersion(header string) (int, error) {
+	tag, _, _ := strings.Cut(strings.Trim(strings.TrimSpace(header), `"`), "-")
+	version, err := strconv.Atoi(tag)
+	if err != nil || version < 1 {
+		return 0, errIfMatchRequired
+	}
+	return version, nil
+}
+
+// ReplaceBook (PUT /books/:id) заменяет все метаданные книги.
+func (s *Server) ReplaceBook(ctx *gin.Context) {
+	s.updateBook(ctx, true)
+}
+
+// PatchBook (PATCH /books/:id) меняет только переданные поля.
+func (s *Server) PatchBook(ctx *gin.Context) {
+	s.updateBook(ctx, false)
+}
+
+// updateBook принимает JSON или multipart-форму; в форме можно заменить файлы cover,
+// pdf и epub. Версия книги передается в If-Match, при несовпадении возвращается 412.
+func (s *Server) updateBook(ctx *gin.Context, replace bool) {
+	log := logger.Get()
+
+	if _, exist := ctx.Get("uid"); !exist {
+		log.Error().Msg("user ID not found")
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	id := ctx.Param("id")
+	version, err := ifMatchVersion(ctx.GetHeader("If-Match"))
+	if err != nil {
+		ctx.JSON(http.StatusPreconditionRequired, gin.H{"error": err.Error()})
+		return
+	}
+
+	patch, err := bookPatchFromRequest(ctx, replace)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	current, err := s.Storage.GetBook(id)
+	if err != nil {
+		if errors.Is(err, storerrros.ErrBookNoExist) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if current.Version != version {
+		ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": storerrros.ErrVersionConflict.Error()})
+		return
+	}
+
+	book := patch.Apply(current)
+	if err := s.valid.Struct(book); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if hasUpload(ctx, "pdf") {
+		pdf, err := inspectPDF(ctx)
+		if err == nil {
+			err = s.checkDuplicateFile(pdf.SHA256, book.BID)
+		}
+		if err != nil {
+			writeUploadError(ctx, err)
+			return
+		}
+		applyPDFInfo(&book, pdf)
+	}
+	var epub epubmeta.Info
+	if hasUpload(ctx, "epub") {
+		epub, err = inspectEPUB(ctx)
+		if err == nil {
+			err = s.checkDuplicateFile(epub.SHA256, book.BID)
+		}
+		if err != nil {
+			writeUploadError(ctx, err)
+			return
+		}
+		applyEPUBInfo(&book, epub)
+	}
+	if err := s.checkDuplicateISBN(book.ISBN, book.BID); err != nil {
+		writeUploadError(ctx, err)
+		return
+	}
+
+	var uploaded []string
+	removeUploaded := func() {
+		for _, key := range uploaded {
+			s.removeUpload(key)
+		}
+	}
+	switch {
+	case hasUpload(ctx, "cover"):
+		book.CoverKey, err = s.saveImage(ctx, "cover", coverPrefix)
+	case book.CoverKey == "" && len(epub.Cover) > 0:
+		// у книги без обложки появляется обложка из загруженного EPUB
+		book.CoverKey, err = s.storeImage(ctx.Request.Context(), bytes.NewReader(epub.Cover), "cover", coverPrefix)
+	}
+	if err != nil {
+		writeUploadError(ctx, err)
+		return
+	}
+	if book.CoverKey != current.CoverKey {
+		uploaded = append(uploaded, book.CoverKey)
+	}
+	if hasUpload(ctx, "pdf") {
+		if book.PDFKey, err = s.saveFile(ctx, consts.FormatPDF); err != nil {
+			removeUploaded()
+			writeUploadError(ctx, err)
+			return
+		}
+		uploaded = append(uploaded, book.PDFKey)
+	}
+	if hasUpload(ctx, "epub") {
+		if book.EPUBKey, err = s.saveFile(ctx, consts.FormatEPUB); err != nil {
+			removeUploaded()
+			writeUploadError(ctx, err)
+			return
+		}
+		uploaded = append(uploaded, book.EPUBKey)
+	}
+
+	updated, err := s.Storage.UpdateBook(book, version, ctx.GetString("uid"))
+	if err != nil {
+		removeUploaded()
+		switch {
+		case errors.Is(err, storerrros.ErrBookNoExist):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, storerrros.ErrVersionConflict):
+			ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		case errors.Is(err, storerrros.ErrDuplicateFile), errors.Is(err, storerrros.ErrDuplicateISBN):
+			writeUploadError(ctx, err)
+		default:
+			log.Error().Err(err).Msg("update book failed")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// замененные файлы остаются в хранилище: на них ссылается история книги, и
+	// откат к прежней ревизии возвращает их. Удаляются они вместе с книгой, см. PurgeTrash.
+
+	writeBook(ctx, updated)
+}
+
+// bookPatchFromRequest читает изменения из multipart-формы или JSON. Для PUT
+// обязательны те же поля, что и при добавлении книги.
+func bookPatchFromRequest(ctx *gin.Context, replace bool) (models.BookPatch, error) {
+	var patch models.BookPatch
+
+	if ctx.ContentType() == gin.MIMEMultipartPOSTForm {
+		if err := ctx.Request.ParseMultipartForm(10 << 20); err != nil {
+			return models.BookPatch{}, errors.New("failed to parse multipart form")
+		}
+		values := ctx.Request.MultipartForm.Value
+		value := func(field string) *string {
+			if len(values[field]) == 0 {
+				return nil
+			}
+			return &values[field][0]
+		}
+		patch.Lable, patch.Author, patch.Desc, patch.Genre = value("lable"), value("author"), value("desc"), value("genre")
+		patch.Language, patch.ISBN, patch.Publisher = value("language"), value("isbn"), value("publisher")
+		for _, f := range []struct {
+			name string
+			dst  **int
+		}{{"age", &patch.Age}, {"rating", &patch.Rating}} {
+			v := value(f.name)
+			if v == nil || *v == "" {
+				continue
+			}
+			n, err := strconv.Atoi(*v)
+			if err != nil {
+				return models.BookPatch{}, errors.New("invalid " + f.name + " value")
+			}
+			*f.dst = &n
+		}
+	} else if err := ctx.ShouldBindJSON(&patch); err != nil {
+		return models.BookPatch{}, errors.New("invalid request body")
+	}
+
+	if replace {
+		required := []struct {
+			name    string
+			missing bool
+		}{
+			{"lable", patch.Lable == nil || *patch.Lable == ""},
+			{"author", patch.Author == nil || *patch.Author == ""},
+			{"desc", patch.Desc == nil || *patch.Desc == ""},
+			{"genre", patch.Genre == nil || *patch.Genre == ""},
+			{"age", patch.Age == nil},
+		}
+		for _, field := range required {
+			if field.missing {
+				return models.BookPatch{}, errors.New("missing or empty field: " + field.name)
+			}
+		}
+		if patch.Rating == nil {
+			zero := 0
+			patch.Rating = &zero
+		}
+	}
+	if patch.ISBN != nil {
+		normalized, err := isbn.Normalize(*patch.ISBN)
+		if err != nil {
+			return models.BookPatch{}, err
+		}
+		patch.ISBN = &normalized
+	}
+	return patch, nil
+}
@@ -0,0 +1,369 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/azaliaz/bookly/book-service/internal/blob"
+	"github.com/azaliaz/bookly/book-service/internal/covers"
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/epubmeta"
+	"github.com/azaliaz/bookly/book-service/internal/isbn"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	"github.com/azaliaz/bookly/book-service/internal/pdfmeta"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+const (
+	coverPrefix = "covers/"
+	photoPrefix = "photos/"
+	pdfPrefix   = "pdfs/"
+	epubPrefix  = "epubs/"
+)
+
+// uploadError - ошибка сохранения загруженного файла с HTTP-статусом для ответа.
+type uploadError struct {
+	status int
+	msg    string
+}
+
+func (e *uploadError) Error() string {
+	return e.msg
+}
+
+// saveFile сохраняет файл книги из одноименного формату поля формы в BlobStore под
+// ключом <префикс формата><uuid>.<формат> и возвращает ключ.
+func (s *Server) saveFile(ctx *gin.Context, format string) (string, error) {
+	log := logger.Get()
+
+	file, header, err := ctx.Request.FormFile(format)
+	if err != nil {
+		log.Error().Err(err).Msgf("failed to get %s file", format)
+		return "", &uploadError{http.StatusBadRequest, "failed to get " + format + " file"}
+	}
+	defer file.Close()
+
+	bf := fileFormat(format)
+	key := bf.prefix + uuid.New().String() + "." + format
+	if err := s.Blobs.Put(ctx.Request.Context(), key, file, header.Size, bf.contentType); err != nil {
+		log.Error().Err(err).Str("key", key).Msgf("failed to save %s file", format)
+		return "", &uploadError{http.StatusInternalServerError, "failed to save " + format + " file"}
+	}
+	return key, nil
+}
+
+// saveImage нормализует изображение из поля формы field (см. пакет covers) и сохраняет
+// все его варианты под префиксом prefix. Возвращает ключ полного варианта.
+func (s *Server) saveImage(ctx *gin.Context, field, prefix string) (string, error) {
+	log := logger.Get()
+
+	file, _, err := ctx.Request.FormFile(field)
+	if err != nil {
+		log.Error().Err(err).Msgf("failed to get %s file", field)
+		return "", &uploadError{http.StatusBadRequest, "failed to get " + field + " file"}
+	}
+	defer file.Close()
+	return s.storeImage(ctx.Request.Context(), file, field, prefix)
+}
+
+// storeImage нормализует изображение из r и сохраняет его варианты; ошибки переводятся
+// в uploadError с именем поля field.
+func (s *Server) storeImage(ctx context.Context, r io.Reader, field, prefix string) (string, error) {
+	log := logger.Get()
+
+	images, err := covers.Process(r)
+	switch {
+	case errors.Is(err, covers.ErrNotImage):
+		return "", &uploadError{http.StatusBadRequest, err.Error()}
+	case errors.Is(err, covers.ErrTooLarge):
+		return "", &uploadError{http.StatusRequestEntityTooLarge, err.Error()}
+	case err != nil:
+		log.Error().Err(err).Msgf("failed to process %s file", field)
+		return "", &uploadError{http.StatusInternalServerError, "failed to process " + field + " file"}
+	}
+
+	key, err := s.putImages(ctx, prefix, images)
+	if err != nil {
+		log.Error().Err(err).Msgf("failed to save %s file", field)
+		return "", &uploadError{http.StatusInternalServerError, "failed to save " + field + " file"}
+	}
+	return key, nil
+}
+
+// putImages сохраняет варианты изображения под ключами <prefix><uuid>/<вариант>.jpg и
+// возвращает ключ полного варианта. Если какой-то вариант не сохранился, уже
+// записанные удаляются.
+func (s *Server) putImages(ctx context.Context, prefix string, images []covers.Image) (string, error) {
+	base := prefix + uuid.New().String()
+	for i, img := range images {
+		key := covers.Key(base, img.Variant)
+		if err := s.Blobs.Put(ctx, key, bytes.NewReader(img.Data), int64(len(img.Data)), blob.ContentType(key)); err != nil {
+			for _, done := range images[:i] {
+				s.removeUpload(covers.Key(base, done.Variant))
+			}
+			return "", err
+		}
+	}
+	return covers.Key(base, covers.Full), nil
+}
+
+// inspectPDF проверяет PDF из поля формы pdf и возвращает его сведения (см. пакет pdfmeta).
+func inspectPDF(ctx *gin.Context) (pdfmeta.Info, error) {
+	log := logger.Get()
+
+	file, header, err := ctx.Request.FormFile("pdf")
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get pdf file")
+		return pdfmeta.Info{}, &uploadError{http.StatusBadRequest, "failed to get pdf file"}
+	}
+	defer file.Close()
+
+	info, err := pdfmeta.Inspect(file, header.Size)
+	switch {
+	case errors.Is(err, pdfmeta.ErrNotPDF), errors.Is(err, pdfmeta.ErrMalformed):
+		return pdfmeta.Info{}, &uploadError{http.StatusBadRequest, err.Error()}
+	case errors.Is(err, pdfmeta.ErrTooLarge):
+		return pdfmeta.Info{}, &uploadError{http.StatusRequestEntityTooLarge, err.Error()}
+	case err != nil:
+		log.Error().Err(err).Msg("failed to read pdf file")
+		return pdfmeta.Info{}, &uploadError{http.StatusInternalServerError, "failed to read pdf file"}
+	}
+	return info, nil
+}
+
+// applyPDFInfo сохраняет сведения о PDF в книге.
+func applyPDFInfo(book *models.Book, info pdfmeta.Info) {
+	book.PDFPages = info.Pages
+	book.PDFTitle = info.Title
+	book.PDFAuthor = info.Author
+	book.PDFSize = info.Size
+	book.PDFHash = info.SHA256
+}
+
+// inspectEPUB проверяет EPUB из поля формы epub и возвращает его сведения (см. пакет epubmeta).
+func inspectEPUB(ctx *gin.Context) (epubmeta.Info, error) {
+	log := logger.Get()
+
+	file, header, err := ctx.Request.FormFile("epub")
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get epub file")
+		return epubmeta.Info{}, &uploadError{http.StatusBadRequest, "failed to get epub file"}
+	}
+	defer file.Close()
+
+	info, err := epubmeta.Inspect(file, header.Size)
+	switch {
+	case errors.Is(err, epubmeta.ErrNotEPUB), errors.Is(err, epubmeta.ErrMalformed):
+		return epubmeta.Info{}, &uploadError{http.StatusBadRequest, err.Error()}
+	case errors.Is(err, epubmeta.ErrTooLarge):
+		return epubmeta.Info{}, &uploadError{http.StatusRequestEntityTooLarge, err.Error()}
+	case err != nil:
+		log.Error().Err(err).Msg("failed to read epub file")
+		return epubmeta.Info{}, &uploadError{http.StatusInternalServerError, "failed to read epub file"}
+	}
+	return info, nil
+}
+
+// applyEPUBInfo сохраняет сведения об EPUB в книге. Язык и ISBN из OPF заполняют
+// только пустые поля: заданные администратором значения важнее.
+func applyEPUBInfo(book *models.Book, info epubmeta.Info) {
+	book.EPUBSize = info.Size
+	book.EPUBHash = info.SHA256
+	book.Language = cmp.Or(book.Language, info.Language)
+	book.ISBN = cmp.Or(book.ISBN, info.ISBN)
+}
+
+// checkDuplicateFile возвращает 409 с bid книги, если такой же PDF или EPUB уже
+// загружен к другой книге, чем bid.
+func (s *Server) checkDuplicateFile(hash, bid string) error {
+	other, err := s.Storage.GetBookByFileHash(hash)
+	switch {
+	case errors.Is(err, storerrros.ErrBookNoExist):
+		return nil
+	case err != nil:
+		log := logger.Get()
+		log.Error().Err(err).Msg("failed to check file duplicate")
+		return err
+	case other.BID == bid:
+		return nil
+	}
+	return &duplicateError{err: storerrros.ErrDuplicateFile, bid: other.BID}
+}
+
+// checkDuplicateISBN возвращает 409 с bid книги, если ISBN уже принадлежит другой
+// книге, чем bid: одно издание - одна книга в каталоге.
+func (s *Server) checkDuplicateISBN(isbn13, bid string) error {
+	if isbn13 == "" {
+		return nil
+	}
+	other, err := s.Storage.GetBookByISBN(isbn13)
+	switch {
+	case errors.Is(err, storerrros.ErrBookNoExist):
+		return nil
+	case err != nil:
+		log := logger.Get()
+		log.Error().Err(err).Msg("failed to check isbn duplicate")
+		return err
+	case other.BID == bid:
+		return nil
+	}
+	return &duplicateError{err: storerrros.ErrDuplicateISBN, bid: other.BID}
+}
+
+// duplicateError - файл или ISBN уже принадлежит книге bid.
+type duplicateError struct {
+	err error
+	bid string
+}
+
+func (e *duplicateError) Error() string {
+	return e.err.Error()
+}
+
+func (e *duplicateError) Unwrap() error {
+	return e.err
+}
+
+// hasUpload сообщает, пришел ли в multipart-форме файл в поле field.
+func hasUpload(ctx *gin.Context, field string) bool {
+	form := ctx.Request.MultipartForm
+	return form != nil && len(form.File[field]) > 0
+}
+
+// removeUpload удаляет ранее сохраненный файл по ключу, а для обложки - все ее
+// варианты; ошибки только логируются.
+func (s *Server) removeUpload(key string) {
+	if key == "" {
+		return
+	}
+	keys := []string{key}
+	if variants := covers.Keys(key); variants != nil {
+		keys = slices.Collect(maps.Values(variants))
+	}
+	for _, k := range keys {
+		if err := s.Blobs.Delete(context.Background(), k); err != nil {
+			log := logger.Get()
+			log.Error().Err(err).Str("key", k).Msg("failed to remove upload")
+		}
+	}
+}
+
+func writeUploadError(ctx *gin.Context, err error) {
+	var uerr *uploadError
+	if errors.As(err, &uerr) {
+		ctx.JSON(uerr.status, gin.H{"error": uerr.msg})
+		return
+	}
+	var derr *duplicateError
+	if errors.As(err, &derr) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": derr.Error(), "bid": derr.bid})
+		return
+	}
+	if errors.Is(err, storerrros.ErrDuplicateFile) || errors.Is(err, storerrros.ErrDuplicateISBN) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// DownloadBlob (GET /uploads/*key) отдает обложки из BlobStore. PDF и EPUB отсюда
+// не отдаются: их можно скачать только по подписанной ссылке (см. PDFLink).
+func (s *Server) DownloadBlob(ctx *gin.Context) {
+	key := strings.TrimPrefix(ctx.Param("key"), "/")
+	for _, f := range bookFiles {
+		if strings.HasPrefix(key, f.prefix) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": blob.ErrNotFound.Error()})
+			return
+		}
+	}
+	s.serveBlob(ctx, key, nil)
+}
+
+// serveBlob отдает объект через http.ServeContent, который сам обрабатывает Range,
+// If-Modified-Since и HEAD. headers добавляются к ответу, если объект найден.
+func (s *Server) serveBlob(ctx *gin.Context, key string, headers map[string]string) {
+	log := logger.Get()
+
+	obj, err := s.Blobs.Get(ctx.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) || errors.Is(err, blob.ErrInvalidKey) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": blob.ErrNotFound.Error()})
+			return
+		}
+		log.Error().Err(err).Str("key", key).Msg("failed to get blob")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer obj.Body.Close()
+
+	ctx.Header("Content-Type", cmp.Or(obj.ContentType, blob.ContentType(key)))
+	for name, value := range headers {
+		ctx.Header(name, value)
+	}
+	http.ServeContent(ctx.Writer, ctx.Request, path.Base(key), obj.ModTime, obj.Body)
+}
+
+// withURLs заполняет ссылки на обложку, ее варианты и файлы книги. Обложки отдаются
+// напрямую из BlobStore, а pdf_url, epub_url и formats ведут на PDFLink и EPUBLink,
+// которые выдают авторизованному пользователю подписанную ссылку. Соседние книги
+// серий получают ссылки на свои карточки.
+func withURLs(book models.Book) models.Book {
+	book.CoverURL, book.CoverURLs = imageURLs(book.CoverKey)
+	book.PDFURL, book.EPUBURL, book.Formats = "", "", nil
+	if book.PDFKey != "" {
+		book.PDFURL = "/books/" + url.PathEscape(book.BID) + "/pdf"
+		book.Formats = append(book.Formats, models.BookFormat{Format: consts.FormatPDF, URL: book.PDFURL, Size: book.PDFSize})
+	}
+	if book.EPUBKey != "" {
+		book.EPUBURL = "/books/" + url.PathEscape(book.BID) + "/epub"
+		book.Formats = append(book.Formats, models.BookFormat{Format: consts.FormatEPUB, URL: book.EPUBURL, Size: book.EPUBSize})
+	}
+	if book.Series != nil {
+		book.Series = seriesWithURLs(book.Series)
+	}
+	if book.Editions != nil {
+		book.Editions = editionsWithURLs(book.Editions)
+	}
+	book.ISBN10 = isbn.To10(book.ISBN)
+	return book
+}
+
+// withAuthorURLs заполняет ссылки на фотографию автора и ее варианты.
+func withAuthorURLs(author models.Author) models.Author {
+	author.PhotoURL, author.PhotoURLs = imageURLs(author.PhotoKey)
+	return author
+}
+
+// imageURLs возвращает ссылку на изображение и ссылки на его варианты, если
+// изображение сохранено пакетом covers.
+func imageURLs(key string) (string, map[string]string) {
+	keys := covers.Keys(key)
+	if keys == nil {
+		return blob.URL(key), nil
+	}
+	urls := make(map[string]string, len(keys))
+	for variant, k := range keys {
+		urls[variant] = blob.URL(k)
+	}
+	return blob.URL(key), urls
+}
+
+func withPageURLs(page models.BooksPage) models.BooksPage {
+	for i := range page.Books {
+		page.Books[i] = withURLs(page.Books[i])
+	}
+	return page
+}
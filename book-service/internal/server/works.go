@@ -0,0 +1,73 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+// WorkInfo (GET /works/:id) возвращает произведение со всеми изданиями и
+// популярностью, посчитанной по скачиваниям всех изданий.
+func (s *Server) WorkInfo(ctx *gin.Context) {
+	work, err := s.Storage.GetWork(ctx.Param("id"))
+	if err != nil {
+		if errors.Is(err, storerrros.ErrWorkNoExist) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	work.Editions = editionsWithURLs(work.Editions)
+	ctx.JSON(http.StatusOK, work)
+}
+
+// SetBookWork (PUT /books/:id/work) переносит издание в другое произведение:
+// {"work_id": "..."}. Пустой work_id выделяет издание в отдельное произведение.
+func (s *Server) SetBookWork(ctx *gin.Context) {
+	log := logger.Get()
+
+	var req struct {
+		WorkID string `json:"work_id"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	bid := ctx.Param("id")
+	if err := s.Storage.SetBookWork(bid, strings.TrimSpace(req.WorkID)); err != nil {
+		switch {
+		case errors.Is(err, storerrros.ErrBookNoExist), errors.Is(err, storerrros.ErrWorkNoExist):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			log.Error().Err(err).Msg("failed to set book work")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	book, err := s.Storage.GetBook(bid)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, withURLs(book))
+}
+
+// editionsWithURLs заполняет ссылки на карточки изданий.
+func editionsWithURLs(editions []models.Edition) []models.Edition {
+	if editions == nil {
+		return []models.Edition{}
+	}
+	for i := range editions {
+		editions[i].URL = bookURL(editions[i].BID)
+	}
+	return editions
+}
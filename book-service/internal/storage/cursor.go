@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+// bookCursor - содержимое непрозрачного курсора: значение ключа сортировки и bid
+// последней выданной книги. Курсор привязан к сортировке, для которой он выдан.
+type bookCursor struct {
+	SortBy    string  `json:"s"`
+	Ascending bool    `json:"a"`
+	Str       string  `json:"k,omitempty"`
+	Num       int     `json:"n,omitempty"`
+	Rank      float64 `json:"r,omitempty"`
+	Time      int64   `json:"t,omitempty"`
+	BID       string  `json:"b"`
+}
+
+// sortColumn приводит sort_by к колонке таблицы books; неизвестные значения сортируют по bid.
+func sortColumn(sortBy string) string {
+	switch strings.ToLower(sortBy) {
+	case "lable", "label":
+		return "lable"
+	case "author":
+		return "author"
+	case "rating":
+		return "rating"
+	case "popularity", "popular":
+		return "popularity"
+	case "genre":
+		return "genre"
+	case "age":
+		return "age"
+	case "relevance":
+		return "relevance"
+	case "created", "created_at":
+		return "created_at"
+	default:
+		return "bid"
+	}
+}
+
+// resolveSort определяет колонку и направление сортировки. Релевантность всегда
+// сортируется по убыванию, а без поисковой строки заменяется сортировкой по рейтингу.
+func resolveSort(filter models.BookFilter) (string, bool) {
+	column := sortColumn(filter.SortBy)
+	if column == "relevance" {
+		if filter.Search == "" {
+			return "rating", false
+		}
+		return column, false
+	}
+	return column, filter.Ascending
+}
+
+func isNumericColumn(column string) bool {
+	return column == "rating" || column == "age" || column == "popularity"
+}
+
+func newCursor(column string, ascending bool, book models.Book) bookCursor {
+	cur := bookCursor{SortBy: column, Ascending: ascending, BID: book.BID}
+	switch column {
+	case "lable":
+		cur.Str = book.Lable
+	case "author":
+		cur.Str = book.Author
+	case "genre":
+		cur.Str = book.Genre
+	case "rating":
+		cur.Num = book.Rating
+	case "age":
+		cur.Num = book.Age
+	case "popularity":
+		cur.Num = book.Popularity
+	case "relevance":
+		cur.Rank = book.Rank
+	case "created_at":
+		cur.Time = book.CreatedAt.UnixMicro()
+	}
+	return cur
+}
+
+func (c bookCursor) encode() string {
+	raw, _ := json.Marshal(c) //nolint:errchkjson // плоская структура без каналов и функций
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor разбирает курсор и проверяет, что он выдан для той же сортировки.
+func decodeCursor(value string, column string, ascending bool) (bookCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return bookCursor{}, storerrros.ErrInvalidCursor
+	}
+	var cur bookCursor
+	if err := json.Unmarshal(raw, &cur); err != nil {
+		return bookCursor{}, storerrros.ErrInvalidCursor
+	}
+	if cur.SortBy != column || cur.Ascending != ascending || cur.BID == "" {
+		return bookCursor{}, storerrros.ErrInvalidCursor
+	}
+	return cur, nil
+}
+
+// compareBooks сравнивает книги по колонке сортировки, при равенстве - по bid,
+// так же как ORDER BY <column>, bid в DBStorage.
+func compareBooks(a, b models.Book, column string) int {
+	ka, kb := newCursor(column, true, a), newCursor(column, true, b)
+	var c int
+	switch {
+	case column == "relevance":
+		c = cmp.Compare(ka.Rank, kb.Rank)
+	case column == "created_at":
+		c = cmp.Compare(ka.Time, kb.Time)
+	case isNumericColumn(column):
+		c = cmp.Compare(ka.Num, kb.Num)
+	default:
+		c = strings.Compare(ka.Str, kb.Str)
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(a.BID, b.BID)
+}
+
+// afterCursor сообщает, идет ли книга после курсора в порядке выдачи.
+func afterCursor(book models.Book, cur bookCursor) bool {
+	c := compareBooks(book, models.Book{
+		BID:        cur.BID,
+		Lable:      cur.Str,
+		Author:     cur.Str,
+		Genre:      cur.Str,
+		Rating:     cur.Num,
+		Age:        cur.Num,
+		Popularity: cur.Num,
+		Rank:       cur.Rank,
+		CreatedAt:  time.UnixMicro(cur.Time),
+	}, cur.SortBy)
+	if cur.Ascending {
+		return c > 0
+	}
+	return c < 0
+}
+
+// authorCursor - имя и id последнего автора на странице; авторы выдаются по (name, id).
+type authorCursor struct {
+	Name string `json:"n"`
+	ID   string `json:"i"`
+}
+
+func (c authorCursor) encode() string {
+	raw, _ := json.Marshal(c) //nolint:errchkjson // плоская структура без каналов и функций
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeAuthorCursor(value string) (authorCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return authorCursor{}, storerrros.ErrInvalidCursor
+	}
+	var cur authorCursor
+	if err := json.Unmarshal(raw, &cur); err != nil || cur.ID == "" {
+		return authorCursor{}, storerrros.ErrInvalidCursor
+	}
+	return cur, nil
+}
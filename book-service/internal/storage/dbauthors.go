@@ -0,0 +1,274 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+// authorColumns - колонки authors в порядке полей authorFields; последняя считает книги автора.
+const authorColumns = `id, name, alt_names, bio, birth_year, death_year, photo_key, created_at,
+	(SELECT count(*) FROM book_authors ba JOIN books b ON b.bid = ba.bid
+		WHERE ba.author_id = authors.id AND b.deleted_at IS NULL)`
+
+func authorFields(author *models.Author) []interface{} {
+	return []interface{}{&author.ID, &author.Name, &author.AltNames, &author.Bio, &author.BirthYear,
+		&author.DeathYear, &author.PhotoKey, &author.CreatedAt, &author.BookCount}
+}
+
+// querier - общее у pgxpool.Pool и pgx.Tx, чтобы связывать авторов в транзакции и без нее.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// altNames не дает записать NULL в alt_names.
+func altNames(author models.Author) []string {
+	if author.AltNames == nil {
+		return []string{}
+	}
+	return author.AltNames
+}
+
+func (dbs *DBStorage) SaveAuthor(author models.Author) (models.Author, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	author.ID = uuid.New().String()
+	err := dbs.pool.QueryRow(ctx,
+		`INSERT INTO authors (id, name, alt_names, bio, birth_year, death_year, photo_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at`,
+		author.ID, author.Name, altNames(author), author.Bio, author.BirthYear, author.DeathYear, author.PhotoKey,
+	).Scan(&author.CreatedAt)
+	if err != nil {
+		log.Error().Err(err).Msg("save author failed")
+		return models.Author{}, err
+	}
+	return author, nil
+}
+
+func (dbs *DBStorage) GetAuthor(id string) (models.Author, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	var author models.Author
+	err := dbs.pool.QueryRow(ctx, `SELECT `+authorColumns+` FROM authors WHERE id = $1`, id).Scan(authorFields(&author)...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Author{}, storerrros.ErrAuthorNoExist
+		}
+		log.Error().Err(err).Msg("failed to get author")
+		return models.Author{}, err
+	}
+	return author, nil
+}
+
+// ListAuthors возвращает страницу авторов по имени. query ищется как подстрока
+// в имени и других написаниях; страницы выбираются keyset-пагинацией по (name, id).
+func (dbs *DBStorage) ListAuthors(query string, page models.Page) (models.AuthorsPage, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	limit := page.Limit
+	if limit <= 0 {
+		limit = consts.DefaultPageLimit
+	}
+
+	q := &bookQuery{}
+	if query != "" {
+		like := q.arg("%" + query + "%")
+		q.where("(name ILIKE " + like + " OR EXISTS (SELECT 1 FROM unnest(alt_names) n WHERE n ILIKE " + like + "))")
+	}
+	var total int
+	if err := dbs.pool.QueryRow(ctx, `SELECT count(*) FROM authors`+q.whereClause(), q.args...).Scan(&total); err != nil {
+		log.Error().Err(err).Msg("failed to count authors")
+		return models.AuthorsPage{}, err
+	}
+	if page.Cursor != "" {
+		cur, err := decodeAuthorCursor(page.Cursor)
+		if err != nil {
+			return models.AuthorsPage{}, err
+		}
+		q.where("(name, id) > (" + q.arg(cur.Name) + ", " + q.arg(cur.ID) + ")")
+	}
+
+	rows, err := dbs.pool.Query(ctx,
+		`SELECT `+authorColumns+` FROM authors`+q.whereClause()+` ORDER BY name, id LIMIT `+q.arg(limit+1), q.args...)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get authors")
+		return models.AuthorsPage{}, err
+	}
+	defer rows.Close()
+
+	authors := make([]models.Author, 0, limit+1)
+	for rows.Next() {
+		var author models.Author
+		if err := rows.Scan(authorFields(&author)...); err != nil {
+			log.Error().Err(err).Msg("failed to scan author")
+			return models.AuthorsPage{}, err
+		}
+		authors = append(authors, author)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error().Err(err).Msg("failed to read authors")
+		return models.AuthorsPage{}, err
+	}
+
+	result := models.AuthorsPage{Total: total}
+	if len(authors) > limit {
+		authors = authors[:limit]
+		last := authors[limit-1]
+		result.NextCursor = authorCursor{Name: last.Name, ID: last.ID}.encode()
+	}
+	result.Authors = authors
+	return result, nil
+}
+
+func (dbs *DBStorage) UpdateAuthor(author models.Author) (models.Author, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	err := dbs.pool.QueryRow(ctx,
+		`UPDATE authors SET name = $1, alt_names = $2, bio = $3, birth_year = $4, death_year = $5, photo_key = $6
+		WHERE id = $7
+		RETURNING created_at, (SELECT count(*) FROM book_authors ba JOIN books b ON b.bid = ba.bid
+		WHERE ba.author_id = authors.id AND b.deleted_at IS NULL)`,
+		author.Name, altNames(author), author.Bio, author.BirthYear, author.DeathYear, author.PhotoKey, author.ID,
+	).Scan(&author.CreatedAt, &author.BookCount)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Author{}, storerrros.ErrAuthorNoExist
+		}
+		log.Error().Err(err).Msg("failed to update author")
+		return models.Author{}, err
+	}
+	return author, nil
+}
+
+// DeleteAuthor удаляет автора вместе со связями с книгами; сами книги остаются.
+func (dbs *DBStorage) DeleteAuthor(id string) error {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	tag, err := dbs.pool.Exec(ctx, `DELETE FROM authors WHERE id = $1`, id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to delete author")
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storerrros.ErrAuthorNoExist
+	}
+	return nil
+}
+
+// SetBookAuthors заменяет всех авторов книги; порядок в authors сохраняется.
+func (dbs *DBStorage) SetBookAuthors(bid string, authors []models.BookAuthor) error {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	tx, err := dbs.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // после Commit ничего не делает
+	}()
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM books WHERE bid = $1 AND deleted_at IS NULL)`, bid).Scan(&exists); err != nil {
+		log.Error().Err(err).Msg("failed to check book")
+		return err
+	}
+	if !exists {
+		return storerrros.ErrBookNoExist
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM book_authors WHERE bid = $1`, bid); err != nil {
+		log.Error().Err(err).Msg("failed to clear book authors")
+		return err
+	}
+	for i, a := range authors {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO book_authors (bid, author_id, role, position) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`,
+			bid, a.AuthorID, a.Role, i)
+		if isForeignKeyViolation(err) {
+			return storerrros.ErrAuthorNoExist
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("failed to link book author")
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// bookAuthors возвращает авторов книги в порядке, заданном в SetBookAuthors.
+func bookAuthors(ctx context.Context, q querier, bid string) ([]models.BookAuthor, error) {
+	rows, err := q.Query(ctx,
+		`SELECT a.id, a.name, ba.role FROM book_authors ba JOIN authors a ON a.id = ba.author_id
+		WHERE ba.bid = $1 ORDER BY ba.position, a.name`, bid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var authors []models.BookAuthor
+	for rows.Next() {
+		var a models.BookAuthor
+		if err := rows.Scan(&a.AuthorID, &a.Name, &a.Role); err != nil {
+			return nil, err
+		}
+		authors = append(authors, a)
+	}
+	return authors, rows.Err()
+}
+
+// relinkAuthor заменяет основного автора книги автором из строки author: после правки
+// или отката поля author книга должна числиться за новым автором. Переводчики и другие
+// роли, заданные в SetBookAuthors, остаются.
+func relinkAuthor(ctx context.Context, q querier, bid, author string) error {
+	if _, err := q.Exec(ctx, `DELETE FROM book_authors WHERE bid = $1 AND role = 'author'`, bid); err != nil {
+		return err
+	}
+	return linkAuthor(ctx, q, bid, author)
+}
+
+// linkAuthor связывает новую книгу с автором из строки author: автор ищется по имени
+// и другим написаниям без учета регистра, а если не найден - создается.
+func linkAuthor(ctx context.Context, q querier, bid, author string) error {
+	name := strings.Join(strings.Fields(author), " ")
+	if name == "" {
+		return nil
+	}
+	_, err := q.Exec(ctx,
+		`WITH found AS (
+			SELECT id FROM authors
+			WHERE lower(name) = lower($2) OR lower($2) IN (SELECT lower(n) FROM unnest(alt_names) n)
+			ORDER BY created_at, id
+			LIMIT 1
+		), created AS (
+			INSERT INTO authors (id, name) SELECT $3, $2 WHERE NOT EXISTS (SELECT 1 FROM found)
+			RETURNING id
+		)
+		INSERT INTO book_authors (bid, author_id, role)
+		SELECT $1, id, 'author' FROM (SELECT id FROM found UNION ALL SELECT id FROM created) a
+		ON CONFLICT DO NOTHING`,
+		bid, name, uuid.New().String())
+	return err
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+)
+
+// GetBookFacets считает книги по жанрам, десятилетиям и самым частым авторам
+// с учетом остальных активных фильтров.
+func (dbs *DBStorage) GetBookFacets(filter models.BookFilter) (models.BookFacets, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	genreFilter, yearFilter, authorFilter := facetFilters(filter)
+	var facets models.BookFacets
+	var err error
+
+	if facets.Genres, err = dbs.facetCounts(ctx, genreFilter,
+		`COALESCE(genre, '')`, `count(*) DESC, value`, 0); err != nil {
+		log.Error().Err(err).Msg("failed to count genre facet")
+		return models.BookFacets{}, err
+	}
+	if facets.Years, err = dbs.facetCounts(ctx, yearFilter,
+		fmt.Sprintf(`((age / %[1]d) * %[1]d)::text || '-' || ((age / %[1]d) * %[1]d + %[2]d)::text`,
+			consts.FacetYearBucket, consts.FacetYearBucket-1), `min(age)`, 0); err != nil {
+		log.Error().Err(err).Msg("failed to count year facet")
+		return models.BookFacets{}, err
+	}
+	if facets.Authors, err = dbs.facetCounts(ctx, authorFilter,
+		`author`, `count(*) DESC, value`, consts.FacetTopAuthors); err != nil {
+		log.Error().Err(err).Msg("failed to count author facet")
+		return models.BookFacets{}, err
+	}
+	return facets, nil
+}
+
+// GetAuthors возвращает всех авторов каталога по алфавиту с числом их книг.
+func (dbs *DBStorage) GetAuthors() ([]models.FacetCount, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	authors, err := dbs.facetCounts(ctx, models.BookFilter{}, `author`, `value`, 0)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to list authors")
+		return nil, err
+	}
+	return authors, nil
+}
+
+func (dbs *DBStorage) facetCounts(ctx context.Context, filter models.BookFilter, expr, order string, limit int) ([]models.FacetCount, error) {
+	q := newBookQuery(filter)
+	query := `SELECT ` + expr + ` AS value, count(*) FROM books` + q.whereClause() + ` GROUP BY value ORDER BY ` + order
+	if limit > 0 {
+		query += " LIMIT " + q.arg(limit)
+	}
+	rows, err := dbs.pool.Query(ctx, query, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.FacetCount{}
+	for rows.Next() {
+		var c models.FacetCount
+		if err := rows.Scan(&c.Value, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,281 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+// genreColumns - колонки genres в порядке полей genreFields; последняя считает книги жанра.
+const genreColumns = `id, COALESCE(parent_id, ''), name_ru, name_en, created_at,
+	(SELECT count(*) FROM book_genres bg JOIN books b ON b.bid = bg.bid
+		WHERE bg.genre_id = genres.id AND b.deleted_at IS NULL)`
+
+func genreFields(genre *models.Genre) []interface{} {
+	return []interface{}{&genre.ID, &genre.ParentID, &genre.NameRU, &genre.NameEN, &genre.CreatedAt, &genre.BookCount}
+}
+
+// genreTreeQuery выбирает id жанров, заданных id или названием в массиве %[1]s, вместе
+// со всеми их поджанрами.
+const genreTreeQuery = `WITH RECURSIVE tree AS (
+		SELECT id FROM genres WHERE id = ANY(%[1]s) OR lower(name_ru) = ANY(%[1]s) OR lower(name_en) = ANY(%[1]s)
+		UNION
+		SELECT g.id FROM genres g JOIN tree ON g.parent_id = tree.id
+	) SELECT id FROM tree`
+
+var genreSeparator = regexp.MustCompile(`\s*[,;]\s*`)
+
+// splitGenres разбирает строку genre книги на отдельные жанры, как миграция 11_genres.
+func splitGenres(genre string) []string {
+	var names []string
+	for _, name := range genreSeparator.Split(genre, -1) {
+		name = strings.Join(strings.Fields(name), " ")
+		if name != "" && name != consts.DefaultGenre {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// genreValues приводит значения фильтра Genres к виду, в котором они сравниваются с
+// id и названиями жанров.
+func genreValues(genres []string) []string {
+	values := make([]string, 0, len(genres))
+	for _, g := range genres {
+		if g = strings.ToLower(strings.Join(strings.Fields(g), " ")); g != "" {
+			values = append(values, g)
+		}
+	}
+	return values
+}
+
+func (dbs *DBStorage) SaveGenre(genre models.Genre) (models.Genre, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	genre.ID = uuid.New().String()
+	err := dbs.pool.QueryRow(ctx,
+		`INSERT INTO genres (id, parent_id, name_ru, name_en) VALUES ($1, NULLIF($2, ''), $3, $4)
+		RETURNING created_at`,
+		genre.ID, genre.ParentID, genre.NameRU, genre.NameEN,
+	).Scan(&genre.CreatedAt)
+	if isForeignKeyViolation(err) {
+		return models.Genre{}, storerrros.ErrGenreNoExist
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("save genre failed")
+		return models.Genre{}, err
+	}
+	return genre, nil
+}
+
+// GetGenres возвращает все жанры списком, отсортированным по русскому названию;
+// дерево из него строит сервер.
+func (dbs *DBStorage) GetGenres() ([]models.Genre, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	rows, err := dbs.pool.Query(ctx, `SELECT `+genreColumns+` FROM genres ORDER BY name_ru, id`)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get genres")
+		return nil, err
+	}
+	defer rows.Close()
+
+	genres := []models.Genre{}
+	for rows.Next() {
+		var genre models.Genre
+		if err := rows.Scan(genreFields(&genre)...); err != nil {
+			log.Error().Err(err).Msg("failed to scan genre")
+			return nil, err
+		}
+		genres = append(genres, genre)
+	}
+	return genres, rows.Err()
+}
+
+// UpdateGenre меняет названия и родителя жанра. Жанр нельзя перенести в него самого
+// или в его поджанр, иначе дерево превратится в цикл.
+func (dbs *DBStorage) UpdateGenre(genre models.Genre) (models.Genre, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	tx, err := dbs.pool.Begin(ctx)
+	if err != nil {
+		return models.Genre{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // после Commit ничего не делает
+	}()
+
+	if genre.ParentID != "" {
+		var cycle bool
+		err := tx.QueryRow(ctx,
+			`WITH RECURSIVE ancestors AS (
+				SELECT id, parent_id FROM genres WHERE id = $1
+				UNION
+				SELECT g.id, g.parent_id FROM genres g JOIN ancestors a ON g.id = a.parent_id
+			) SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2)`,
+			genre.ParentID, genre.ID).Scan(&cycle)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to check genre ancestors")
+			return models.Genre{}, err
+		}
+		if cycle {
+			return models.Genre{}, storerrros.ErrGenreCycle
+		}
+	}
+
+	err = tx.QueryRow(ctx,
+		`UPDATE genres SET parent_id = NULLIF($1, ''), name_ru = $2, name_en = $3 WHERE id = $4
+		RETURNING created_at, (SELECT count(*) FROM book_genres bg JOIN books b ON b.bid = bg.bid
+		WHERE bg.genre_id = genres.id AND b.deleted_at IS NULL)`,
+		genre.ParentID, genre.NameRU, genre.NameEN, genre.ID,
+	).Scan(&genre.CreatedAt, &genre.BookCount)
+	if errors.Is(err, pgx.ErrNoRows) || isForeignKeyViolation(err) {
+		return models.Genre{}, storerrros.ErrGenreNoExist
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to update genre")
+		return models.Genre{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return models.Genre{}, err
+	}
+	return genre, nil
+}
+
+// DeleteGenre удаляет жанр без поджанров; связи с книгами удаляются вместе с ним.
+func (dbs *DBStorage) DeleteGenre(id string) error {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	tag, err := dbs.pool.Exec(ctx, `DELETE FROM genres WHERE id = $1`, id)
+	if isForeignKeyViolation(err) {
+		return storerrros.ErrGenreHasChildren
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to delete genre")
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storerrros.ErrGenreNoExist
+	}
+	return nil
+}
+
+// SetBookGenres заменяет жанры книги; порядок ids сохраняется.
+func (dbs *DBStorage) SetBookGenres(bid string, ids []string) error {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	tx, err := dbs.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // после Commit ничего не делает
+	}()
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM books WHERE bid = $1 AND deleted_at IS NULL)`, bid).Scan(&exists); err != nil {
+		log.Error().Err(err).Msg("failed to check book")
+		return err
+	}
+	if !exists {
+		return storerrros.ErrBookNoExist
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM book_genres WHERE bid = $1`, bid); err != nil {
+		log.Error().Err(err).Msg("failed to clear book genres")
+		return err
+	}
+	for i, id := range ids {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO book_genres (bid, genre_id, position) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`, bid, id, i)
+		if isForeignKeyViolation(err) {
+			return storerrros.ErrGenreNoExist
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("failed to link book genre")
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// isForeignKeyViolation сообщает, что запрос сослался на несуществующую строку или
+// удаляет строку, на которую еще ссылаются.
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+// bookGenres возвращает жанры книги в порядке, заданном в SetBookGenres.
+func bookGenres(ctx context.Context, q querier, bid string) ([]models.BookGenre, error) {
+	rows, err := q.Query(ctx,
+		`SELECT g.id, g.name_ru, g.name_en FROM book_genres bg JOIN genres g ON g.id = bg.genre_id
+		WHERE bg.bid = $1 ORDER BY bg.position, g.name_ru`, bid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var genres []models.BookGenre
+	for rows.Next() {
+		var g models.BookGenre
+		if err := rows.Scan(&g.GenreID, &g.NameRU, &g.NameEN); err != nil {
+			return nil, err
+		}
+		genres = append(genres, g)
+	}
+	return genres, rows.Err()
+}
+
+// relinkGenres заменяет жанры книги жанрами из строки genre: после правки или отката
+// поля genre фильтр по жанрам должен находить книгу по новому значению.
+func relinkGenres(ctx context.Context, q querier, bid, genre string) error {
+	if _, err := q.Exec(ctx, `DELETE FROM book_genres WHERE bid = $1`, bid); err != nil {
+		return err
+	}
+	return linkGenres(ctx, q, bid, genre)
+}
+
+// linkGenres связывает новую книгу с жанрами из строки genre: жанр ищется по русскому
+// или английскому названию без учета регистра, а если не найден - создается корневым.
+func linkGenres(ctx context.Context, q querier, bid, genre string) error {
+	for i, name := range splitGenres(genre) {
+		_, err := q.Exec(ctx,
+			`WITH found AS (
+				SELECT id FROM genres
+				WHERE lower(name_ru) = lower($2) OR lower(name_en) = lower($2)
+				ORDER BY created_at, id
+				LIMIT 1
+			), created AS (
+				INSERT INTO genres (id, name_ru) SELECT $3, $2 WHERE NOT EXISTS (SELECT 1 FROM found)
+				RETURNING id
+			)
+			INSERT INTO book_genres (bid, genre_id, position)
+			SELECT $1, id, $4 FROM (SELECT id FROM found UNION ALL SELECT id FROM created) g
+			ON CONFLICT DO NOTHING`,
+			bid, name, uuid.New().String(), i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+// snapshotColumns - колонки books в порядке полей snapshotFields.
+const snapshotColumns = `lable, author, "desc", age, genre, rating, language, isbn, publisher,
+	cover_key, pdf_key, pdf_pages, pdf_title, pdf_author, pdf_size, pdf_hash, epub_key, epub_size, epub_hash`
+
+func snapshotFields(s *models.BookSnapshot) []interface{} {
+	return []interface{}{&s.Lable, &s.Author, &s.Desc, &s.Age, &s.Genre, &s.Rating, &s.Language, &s.ISBN, &s.Publisher,
+		&s.CoverKey, &s.PDFKey, &s.PDFPages, &s.PDFTitle, &s.PDFAuthor, &s.PDFSize, &s.PDFHash,
+		&s.EPUBKey, &s.EPUBSize, &s.EPUBHash}
+}
+
+// newRevision собирает ревизию с изменениями от снимка before к снимку after.
+func newRevision(bid, action, actor string, before, after models.BookSnapshot) models.BookRevision {
+	return models.BookRevision{BID: bid, Action: action, Actor: actor, Changes: before.Diff(after), Snapshot: after}
+}
+
+// GetBookHistory возвращает ревизии книги, начиная с последней. История есть и у
+// книг в корзине, и у окончательно удаленных книг.
+func (dbs *DBStorage) GetBookHistory(bid string) ([]models.BookRevision, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	rows, err := dbs.pool.Query(ctx,
+		`SELECT bid, revision, action, actor, reverted_to, changes, snapshot, changed_at
+		FROM book_history WHERE bid = $1 ORDER BY revision DESC`, bid)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get book history")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []models.BookRevision
+	for rows.Next() {
+		var rev models.BookRevision
+		if err := rows.Scan(&rev.BID, &rev.Revision, &rev.Action, &rev.Actor, &rev.RevertedTo,
+			&rev.Changes, &rev.Snapshot, &rev.ChangedAt); err != nil {
+			log.Error().Err(err).Msg("failed to scan book revision")
+			return nil, err
+		}
+		history = append(history, rev)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error().Err(err).Msg("failed to read book history")
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, storerrros.ErrBookNoExist
+	}
+	return history, nil
+}
+
+// RevertBook возвращает поля книги к снимку ревизии revision и записывает это в
+// историю действием revert от имени actor. Из связей книги заново строятся только
+// основной автор и жанры, если откат меняет строки author и genre.
+func (dbs *DBStorage) RevertBook(bid string, revision int, actor string) (models.Book, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	tx, err := dbs.pool.Begin(ctx)
+	if err != nil {
+		return models.Book{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // после Commit ничего не делает
+	}()
+
+	current, err := lockSnapshot(ctx, tx, bid)
+	if err != nil {
+		return models.Book{}, err
+	}
+	var target models.BookSnapshot
+	err = tx.QueryRow(ctx, `SELECT snapshot FROM book_history WHERE bid = $1 AND revision = $2`, bid, revision).Scan(&target)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Book{}, storerrros.ErrRevisionNoExist
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get book revision")
+		return models.Book{}, err
+	}
+	if _, _, err := updateBookRow(ctx, tx, bid, target); err != nil {
+		return models.Book{}, err
+	}
+	if target.Author != current.Author {
+		if err := relinkAuthor(ctx, tx, bid, target.Author); err != nil {
+			log.Error().Err(err).Str("bid", bid).Msg("relink book author failed")
+			return models.Book{}, err
+		}
+	}
+	if target.Genre != current.Genre {
+		if err := relinkGenres(ctx, tx, bid, target.Genre); err != nil {
+			log.Error().Err(err).Str("bid", bid).Msg("relink book genres failed")
+			return models.Book{}, err
+		}
+	}
+	rev := newRevision(bid, models.HistoryRevert, actor, current, target)
+	rev.RevertedTo = revision
+	if err := addRevision(ctx, tx, rev); err != nil {
+		log.Error().Err(err).Str("bid", bid).Msg("failed to record book revision")
+		return models.Book{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return models.Book{}, err
+	}
+	return dbs.GetBook(bid)
+}
+
+// lockSnapshot блокирует строку книги не из корзины до конца транзакции и
+// возвращает ее снимок.
+func lockSnapshot(ctx context.Context, q querier, bid string) (models.BookSnapshot, error) {
+	var s models.BookSnapshot
+	err := q.QueryRow(ctx, `SELECT `+snapshotColumns+` FROM books WHERE bid = $1 AND deleted_at IS NULL FOR UPDATE`, bid).
+		Scan(snapshotFields(&s)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.BookSnapshot{}, storerrros.ErrBookNoExist
+	}
+	return s, err
+}
+
+// updateBookRow записывает поля снимка в книгу, увеличивает версию и возвращает ее
+// вместе с новым updated_at.
+func updateBookRow(ctx context.Context, q querier, bid string, s models.BookSnapshot) (int, time.Time, error) {
+	var version int
+	var updatedAt time.Time
+	err := q.QueryRow(ctx,
+		`UPDATE books SET lable = $1, author = $2, "desc" = $3, age = $4, genre = $5, rating = $6,
+			cover_key = $7, pdf_key = $8, pdf_pages = $9, pdf_title = $10, pdf_author = $11,
+			pdf_size = $12, pdf_hash = $13, epub_key = $14, epub_size = $15, epub_hash = $16,
+			language = $17, isbn = $18, publisher = $19, version = version + 1
+		WHERE bid = $20
+		RETURNING version, updated_at`,
+		s.Lable, s.Author, s.Desc, s.Age, s.Genre, s.Rating, s.CoverKey, s.PDFKey,
+		s.PDFPages, s.PDFTitle, s.PDFAuthor, s.PDFSize, s.PDFHash,
+		s.EPUBKey, s.EPUBSize, s.EPUBHash, s.Language, s.ISBN, s.Publisher, bid).Scan(&version, &updatedAt)
+	if isDuplicateFile(err) {
+		return 0, time.Time{}, storerrros.ErrDuplicateFile
+	}
+	if isUniqueViolation(err, isbnKey) {
+		return 0, time.Time{}, storerrros.ErrDuplicateISBN
+	}
+	return version, updatedAt, err
+}
+
+// addRevision дописывает ревизию в историю книги под следующим номером.
+func addRevision(ctx context.Context, q querier, rev models.BookRevision) error {
+	if rev.Changes == nil {
+		rev.Changes = map[string]models.FieldChange{}
+	}
+	_, err := q.Exec(ctx,
+		`INSERT INTO book_history (bid, revision, action, actor, reverted_to, changes, snapshot)
+		SELECT $1, COALESCE(max(revision), 0) + 1, $2, $3, $4, $5, $6 FROM book_history WHERE bid = $1`,
+		rev.BID, rev.Action, rev.Actor, rev.RevertedTo, rev.Changes, rev.Snapshot)
+	return err
+}
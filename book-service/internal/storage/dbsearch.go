@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+)
+
+// headlineOptions - параметры ts_headline для описания; в названии и авторе подсвечивается все поле.
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=25, MinWords=10"
+
+// bookQuery накапливает условия WHERE и аргументы запроса к books.
+type bookQuery struct {
+	conditions []string
+	args       []interface{}
+	tsQuery    string
+}
+
+// arg добавляет аргумент и возвращает его плейсхолдер.
+func (q *bookQuery) arg(v interface{}) string {
+	q.args = append(q.args, v)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+func (q *bookQuery) where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+func (q *bookQuery) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// newBookQuery собирает условия для фильтров поиска. Поисковая строка ищется
+// по search_vector (название, автор и описание с русской и английской морфологией),
+// а ILIKE по названию и автору сохранен для поиска по части слова.
+func newBookQuery(filter models.BookFilter) *bookQuery {
+	q := &bookQuery{}
+	q.where("deleted_at IS NULL")
+
+	if filter.Search != "" {
+		p := q.arg(filter.Search)
+		q.tsQuery = fmt.Sprintf("(websearch_to_tsquery('russian', %s) || websearch_to_tsquery('english', %s))", p, p)
+		like := q.arg("%" + filter.Search + "%")
+		q.where(fmt.Sprintf("(search_vector @@ %s OR lable ILIKE %s OR author ILIKE %s)", q.tsQuery, like, like))
+	}
+
+	// жанр сравнивается с деревом жанров целиком, а не по подстроке: фильтр по
+	// родительскому жанру включает книги всех его поджанров
+	if genres := genreValues(filter.Genres); len(genres) > 0 {
+		tree := fmt.Sprintf(genreTreeQuery, q.arg(genres))
+		q.where("bid IN (SELECT bid FROM book_genres WHERE genre_id IN (" + tree + "))")
+	}
+
+	if filter.Author != "" {
+		q.where("author = " + q.arg(filter.Author))
+	}
+
+	if filter.AuthorID != "" {
+		cond := "author_id = " + q.arg(filter.AuthorID)
+		if filter.AuthorRole != "" {
+			cond += " AND role = " + q.arg(filter.AuthorRole)
+		}
+		q.where("bid IN (SELECT bid FROM book_authors WHERE " + cond + ")")
+	}
+
+	// учитываются только одобренные теги; для "всех тегов" книга должна совпасть
+	// с каждым из разных названий
+	if tags := tagValues(filter.Tags); len(tags) > 0 {
+		sub := fmt.Sprintf(tagBooksQuery, q.arg(tags))
+		if filter.TagsAll {
+			sub += fmt.Sprintf(tagsAllClause, q.arg(len(tags)))
+		}
+		q.where("bid IN (" + sub + ")")
+	}
+
+	if filter.Year != "" {
+		if from, to, ok := parseYearRange(filter.Year); ok {
+			q.where(fmt.Sprintf("age BETWEEN %s AND %s", q.arg(from), q.arg(to)))
+		} else {
+			q.where("age = " + q.arg(filter.Year))
+		}
+	}
+	return q
+}
+
+// sortExpr возвращает SQL-выражение для колонки сортировки.
+func (q *bookQuery) sortExpr(column string) string {
+	switch column {
+	case "relevance":
+		return fmt.Sprintf("ts_rank_cd(search_vector, %s)::float8", q.tsQuery)
+	case "popularity":
+		return workPopularity
+	}
+	return column
+}
+
+// listBooks выбирает одну страницу книг keyset-пагинацией: ORDER BY <колонка>, bid
+// и условие (<колонка>, bid) > (значения из курсора), поэтому страницы стабильны
+// при вставке новых книг и не требуют OFFSET.
+func (dbs *DBStorage) listBooks(filter models.BookFilter) (models.BooksPage, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = consts.DefaultPageLimit
+	}
+	column, ascending := resolveSort(filter)
+	orderDirection, cmpOp := "ASC", ">"
+	if !ascending {
+		orderDirection, cmpOp = "DESC", "<"
+	}
+
+	q := newBookQuery(filter)
+
+	var total int
+	if err := dbs.pool.QueryRow(ctx, `SELECT count(*) FROM books`+q.whereClause(), q.args...).Scan(&total); err != nil {
+		log.Error().Err(err).Msg("failed to count books")
+		return models.BooksPage{}, err
+	}
+
+	sortExpr := q.sortExpr(column)
+	if filter.Cursor != "" {
+		cur, err := decodeCursor(filter.Cursor, column, ascending)
+		if err != nil {
+			return models.BooksPage{}, err
+		}
+		if column == "bid" {
+			q.where(fmt.Sprintf("bid %s %s", cmpOp, q.arg(cur.BID)))
+		} else {
+			var key interface{} = cur.Str
+			switch {
+			case column == "relevance":
+				key = cur.Rank
+			case column == "created_at":
+				key = time.UnixMicro(cur.Time)
+			case isNumericColumn(column):
+				key = cur.Num
+			}
+			q.where(fmt.Sprintf("(%s, bid) %s (%s, %s)", sortExpr, cmpOp, q.arg(key), q.arg(cur.BID)))
+		}
+	}
+
+	selectList := bookColumns
+	if q.tsQuery != "" {
+		selectList += fmt.Sprintf(`, ts_rank_cd(search_vector, %[1]s)::float8,
+			ts_headline('russian', lable, %[1]s, 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>'),
+			ts_headline('russian', author, %[1]s, 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>'),
+			ts_headline('russian', "desc", %[1]s, '%[2]s')`, q.tsQuery, headlineOptions)
+	}
+	sortQuery := fmt.Sprintf(" ORDER BY %s %s", sortExpr, orderDirection)
+	if column != "bid" {
+		sortQuery += ", bid " + orderDirection
+	}
+	fullQuery := `SELECT ` + selectList + ` FROM books` + q.whereClause() + sortQuery + " LIMIT " + q.arg(limit+1)
+
+	rows, err := dbs.pool.Query(ctx, fullQuery, q.args...)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get books from db")
+		return models.BooksPage{}, err
+	}
+	defer rows.Close()
+
+	books := make([]models.Book, 0, limit+1)
+	for rows.Next() {
+		var book models.Book
+		dest := bookFields(&book)
+		var hlLable, hlAuthor, hlDesc string
+		if q.tsQuery != "" {
+			dest = append(dest, &book.Rank, &hlLable, &hlAuthor, &hlDesc)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			log.Error().Err(err).Msg("failed to scan data from db")
+			return models.BooksPage{}, err
+		}
+		if q.tsQuery != "" {
+			book.Highlights = highlights(book, hlLable, hlAuthor, hlDesc)
+		}
+		books = append(books, book)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error().Err(err).Msg("failed to read books from db")
+		return models.BooksPage{}, err
+	}
+
+	page := models.BooksPage{Total: total}
+	if len(books) > limit {
+		books = books[:limit]
+		page.NextCursor = newCursor(column, ascending, books[limit-1]).encode()
+	}
+	page.Books = books
+	return page, nil
+}
+
+// highlights оставляет только поля, в которых действительно что-то подсвечено.
+func highlights(book models.Book, lable, author, desc string) map[string]string {
+	res := make(map[string]string)
+	if lable != book.Lable {
+		res["lable"] = lable
+	}
+	if author != book.Author {
+		res["author"] = author
+	}
+	if strings.Contains(desc, "<mark>") {
+		res["desc"] = desc
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
+// StreamBooks передает в fn все книги, подходящие под фильтр, в порядке сортировки фильтра.
+// Строки читаются из курсора по одной, поэтому каталог не загружается в память целиком.
+func (dbs *DBStorage) StreamBooks(filter models.BookFilter, fn func(models.Book) error) error {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBExportCtxTimeout)
+	defer cancel()
+
+	column, ascending := resolveSort(filter)
+	orderDirection := "ASC"
+	if !ascending {
+		orderDirection = "DESC"
+	}
+	q := newBookQuery(filter)
+	query := `SELECT ` + bookColumns + ` FROM books` +
+		q.whereClause() + fmt.Sprintf(" ORDER BY %s %s, bid %s", q.sortExpr(column), orderDirection, orderDirection)
+
+	rows, err := dbs.pool.Query(ctx, query, q.args...)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to stream books from db")
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var book models.Book
+		if err := rows.Scan(bookFields(&book)...); err != nil {
+			log.Error().Err(err).Msg("failed to scan data from db")
+			return err
+		}
+		if err := fn(book); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+// seriesOrder - порядок чтения книг серии; при равных позициях - по названию.
+const seriesOrder = `bs.position, b.lable, b.bid`
+
+func (dbs *DBStorage) SaveSeries(series models.Series) (models.Series, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	series.ID = uuid.New().String()
+	err := dbs.pool.QueryRow(ctx,
+		`INSERT INTO series (id, name, "desc") VALUES ($1, $2, $3) RETURNING created_at`,
+		series.ID, series.Name, series.Desc,
+	).Scan(&series.CreatedAt)
+	if err != nil {
+		log.Error().Err(err).Msg("save series failed")
+		return models.Series{}, err
+	}
+	return series, nil
+}
+
+// GetSeries возвращает серию с ее книгами в порядке чтения.
+func (dbs *DBStorage) GetSeries(id string) (models.Series, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	var series models.Series
+	err := dbs.pool.QueryRow(ctx, `SELECT id, name, "desc", created_at FROM series WHERE id = $1`, id).
+		Scan(&series.ID, &series.Name, &series.Desc, &series.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Series{}, storerrros.ErrSeriesNoExist
+		}
+		log.Error().Err(err).Msg("failed to get series")
+		return models.Series{}, err
+	}
+
+	rows, err := dbs.pool.Query(ctx,
+		`SELECT b.bid, b.lable, b.author, bs.position::float8
+		FROM book_series bs JOIN books b ON b.bid = bs.bid
+		WHERE bs.series_id = $1 AND b.deleted_at IS NULL ORDER BY `+seriesOrder, id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get series books")
+		return models.Series{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var book models.SeriesBook
+		if err := rows.Scan(&book.BID, &book.Lable, &book.Author, &book.Position); err != nil {
+			log.Error().Err(err).Msg("failed to scan series book")
+			return models.Series{}, err
+		}
+		series.Books = append(series.Books, book)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error().Err(err).Msg("failed to read series books")
+		return models.Series{}, err
+	}
+	series.BookCount = len(series.Books)
+	return series, nil
+}
+
+func (dbs *DBStorage) UpdateSeries(series models.Series) (models.Series, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	err := dbs.pool.QueryRow(ctx,
+		`UPDATE series SET name = $1, "desc" = $2 WHERE id = $3
+		RETURNING created_at, (SELECT count(*) FROM book_series bs JOIN books b ON b.bid = bs.bid
+		WHERE bs.series_id = series.id AND b.deleted_at IS NULL)`,
+		series.Name, series.Desc, series.ID,
+	).Scan(&series.CreatedAt, &series.BookCount)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Series{}, storerrros.ErrSeriesNoExist
+		}
+		log.Error().Err(err).Msg("failed to update series")
+		return models.Series{}, err
+	}
+	return series, nil
+}
+
+// DeleteSeries удаляет серию; книги остаются в каталоге.
+func (dbs *DBStorage) DeleteSeries(id string) error {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	tag, err := dbs.pool.Exec(ctx, `DELETE FROM series WHERE id = $1`, id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to delete series")
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storerrros.ErrSeriesNoExist
+	}
+	return nil
+}
+
+// SetBookSeries заменяет серии книги и ее места в них.
+func (dbs *DBStorage) SetBookSeries(bid string, entries []models.BookSeries) error {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	tx, err := dbs.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // после Commit ничего не делает
+	}()
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM books WHERE bid = $1 AND deleted_at IS NULL)`, bid).Scan(&exists); err != nil {
+		log.Error().Err(err).Msg("failed to check book")
+		return err
+	}
+	if !exists {
+		return storerrros.ErrBookNoExist
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM book_series WHERE bid = $1`, bid); err != nil {
+		log.Error().Err(err).Msg("failed to clear book series")
+		return err
+	}
+	for _, entry := range entries {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO book_series (bid, series_id, position) VALUES ($1, $2, $3)
+			ON CONFLICT (bid, series_id) DO UPDATE SET position = EXCLUDED.position`,
+			bid, entry.SeriesID, entry.Position)
+		if isForeignKeyViolation(err) {
+			return storerrros.ErrSeriesNoExist
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("failed to link book series")
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// bookSeries возвращает серии книги с соседними книгами в порядке чтения.
+func bookSeries(ctx context.Context, q querier, bid string) ([]models.BookSeries, error) {
+	rows, err := q.Query(ctx,
+		`SELECT s.id, s.name, o.position,
+			o.prev_bid, o.prev_lable, o.prev_position, o.next_bid, o.next_lable, o.next_position
+		FROM (
+			SELECT bs.series_id, bs.bid, bs.position::float8 AS position,
+				lag(b.bid) OVER w AS prev_bid, lag(b.lable) OVER w AS prev_lable,
+				lag(bs.position::float8) OVER w AS prev_position,
+				lead(b.bid) OVER w AS next_bid, lead(b.lable) OVER w AS next_lable,
+				lead(bs.position::float8) OVER w AS next_position
+			FROM book_series bs JOIN books b ON b.bid = bs.bid
+			WHERE bs.series_id IN (SELECT series_id FROM book_series WHERE bid = $1) AND b.deleted_at IS NULL
+			WINDOW w AS (PARTITION BY bs.series_id ORDER BY `+seriesOrder+`)
+		) o JOIN series s ON s.id = o.series_id
+		WHERE o.bid = $1
+		ORDER BY s.name, s.id`, bid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.BookSeries
+	for rows.Next() {
+		var entry models.BookSeries
+		var prevBID, prevLable, nextBID, nextLable *string
+		var prevPosition, nextPosition *float64
+		if err := rows.Scan(&entry.SeriesID, &entry.Name, &entry.Position,
+			&prevBID, &prevLable, &prevPosition, &nextBID, &nextLable, &nextPosition); err != nil {
+			return nil, err
+		}
+		if prevBID != nil {
+			entry.Prev = &models.SeriesBook{BID: *prevBID, Lable: *prevLable, Position: *prevPosition}
+		}
+		if nextBID != nil {
+			entry.Next = &models.SeriesBook{BID: *nextBID, Lable: *nextLable, Position: *nextPosition}
+		}
+		result = append(result, entry)
+	}
+	return result, rows.Err()
+}
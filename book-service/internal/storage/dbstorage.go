@@ -11,11 +11,62 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"strings"
 	"time"
 )
 
+// bookColumns - колонки books в порядке полей bookFields.
+const bookColumns = `bid, lable, author, "desc", age, genre, rating, cover_key, pdf_key, version, created_at, updated_at,
+	pdf_pages, pdf_title, pdf_author, pdf_size, pdf_hash, epub_key, epub_size, epub_hash, language, isbn,
+	work_id, publisher, deleted_at, deleted_by, ` + workPopularity
+
+// Уникальные индексы по хешам файлов книги и по ISBN.
+const (
+	pdfHashKey  = "books_pdf_hash_key"
+	epubHashKey = "books_epub_hash_key"
+	isbnKey     = "books_isbn_key"
+)
+
+// bookFields возвращает указатели на поля книги для Scan в порядке bookColumns.
+func bookFields(book *models.Book) []interface{} {
+	return []interface{}{&book.BID, &book.Lable, &book.Author, &book.Desc, &book.Age, &book.Genre, &book.Rating,
+		&book.CoverKey, &book.PDFKey, &book.Version, &book.CreatedAt, &book.UpdatedAt,
+		&book.PDFPages, &book.PDFTitle, &book.PDFAuthor, &book.PDFSize, &book.PDFHash,
+		&book.EPUBKey, &book.EPUBSize, &book.EPUBHash, &book.Language, &book.ISBN,
+		&book.WorkID, &book.Publisher, &book.DeletedAt, &book.DeletedBy, &book.Popularity}
+}
+
+// isUniqueViolation сообщает, что запрос нарушил уникальный индекс constraint.
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == constraint
+}
+
+// isDuplicateFile сообщает, что такой же PDF или EPUB уже загружен к другой книге.
+func isDuplicateFile(err error) bool {
+	return isUniqueViolation(err, pdfHashKey) || isUniqueViolation(err, epubHashKey)
+}
+
+// findDuplicate ищет в каталоге ту же книгу. Книга с ISBN совпадает только с тем же
+// изданием, поэтому разные издания одного романа не склеиваются. Книга без ISBN
+// совпадает по названию и автору без учета регистра и пунктуации.
+func findDuplicate(ctx context.Context, q querier, book models.Book) (string, error) {
+	var bid string
+	var err error
+	if book.ISBN != "" {
+		err = q.QueryRow(ctx, `SELECT bid FROM books WHERE isbn = $1 AND deleted_at IS NULL`, book.ISBN).Scan(&bid)
+	} else {
+		err = q.QueryRow(ctx,
+			`SELECT bid FROM books
+			WHERE book_title_key(lable) = book_title_key($1) AND book_title_key(author) = book_title_key($2)
+				AND deleted_at IS NULL
+			ORDER BY created_at, bid LIMIT 1`,
+			book.Lable, book.Author).Scan(&bid)
+	}
+	return bid, err
+}
+
 type DBStorage struct {
 	pool *pgxpool.Pool
 }
@@ -37,126 +88,317 @@ func NewDB(ctx context.Context, addr string) (*DBStorage, error) {
 	return &DBStorage{pool: pool}, nil
 }
 
-func (dbs *DBStorage) SaveBook(book models.Book) error {
+func (dbs *DBStorage) SaveBook(book models.Book, actor string) error {
 	log := logger.Get()
 	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
 	defer cancel()
 
-	var bid string
-	err := dbs.pool.QueryRow(ctx, `SELECT bid FROM books WHERE lable=$1 AND author=$2`, book.Lable, book.Author).Scan(&bid)
+	// книга, ее связи с автором и жанрами и ревизия create записываются вместе: иначе
+	// при ошибке в каталоге осталась бы книга без истории или с файлами, которые
+	// AddBook уже удалил
+	tx, err := dbs.pool.Begin(ctx)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			bid := uuid.New().String()
-			_, err := dbs.pool.Exec(ctx,
-				`INSERT INTO books (bid, lable, author, "desc", age, genre, rating, cover_url, pdf_url) 
-                VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
-				bid, book.Lable, book.Author, book.Desc, book.Age, book.Genre, book.Rating, book.CoverURL, book.PDFURL)
-			if err != nil {
-				log.Error().Err(err).Msg("save book failed")
-				return err
-			}
-			return nil
-		}
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // после Commit ничего не делает
+	}()
+
+	bid, err := findDuplicate(ctx, tx, book)
+	if err == nil {
+		log.Debug().Str("bid", bid).Msg("book already exists")
+		return nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
 		log.Error().Err(err).Msg("get book failed")
 		return err
 	}
-	log.Debug().Str("bid", bid).Msg("book already exists")
+	bid = uuid.New().String()
+	workID, err := bookWork(ctx, tx, book)
+	if err != nil {
+		log.Error().Err(err).Msg("find book work failed")
+		return err
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO books (bid, lable, author, "desc", age, genre, rating, cover_key, pdf_key,
+			pdf_pages, pdf_title, pdf_author, pdf_size, pdf_hash, epub_key, epub_size, epub_hash, language, isbn,
+			work_id, publisher) 
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)`,
+		bid, book.Lable, book.Author, book.Desc, book.Age, book.Genre, book.Rating, book.CoverKey, book.PDFKey,
+		book.PDFPages, book.PDFTitle, book.PDFAuthor, book.PDFSize, book.PDFHash,
+		book.EPUBKey, book.EPUBSize, book.EPUBHash, book.Language, book.ISBN, workID, book.Publisher)
+	if isDuplicateFile(err) {
+		return storerrros.ErrDuplicateFile
+	}
+	if isUniqueViolation(err, isbnKey) {
+		return storerrros.ErrDuplicateISBN
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("save book failed")
+		return err
+	}
+	if err := linkAuthor(ctx, tx, bid, book.Author); err != nil {
+		log.Error().Err(err).Str("bid", bid).Msg("link book author failed")
+		return err
+	}
+	if err := linkGenres(ctx, tx, bid, book.Genre); err != nil {
+		log.Error().Err(err).Str("bid", bid).Msg("link book genres failed")
+		return err
+	}
+	rev := newRevision(bid, models.HistoryCreate, actor, models.BookSnapshot{}, models.NewBookSnapshot(book))
+	if err := addRevision(ctx, tx, rev); err != nil {
+		log.Error().Err(err).Str("bid", bid).Msg("failed to record book revision")
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Error().Err(err).Msg("commit book failed")
+		return err
+	}
 	return nil
 }
 
-func (dbs *DBStorage) SaveBooks(books []models.Book) error {
+// SaveBooks сохраняет книги в одной транзакции. Для каждой книги возвращается ее bid
+// и признак того, что такая книга уже была в каталоге: с тем же ISBN, а для книг без
+// ISBN - с тем же названием и автором. Новые книги записываются в историю от
+// имени actor.
+func (dbs *DBStorage) SaveBooks(books []models.Book, actor string) ([]models.SaveStatus, error) {
 	log := logger.Get()
-	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBBulkCtxTimeout)
 	defer cancel()
 
 	tx, err := dbs.pool.Begin(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer func() {
-		if err != nil {
-			_ = tx.Rollback(ctx)
-		} else {
-			_ = tx.Commit(ctx)
-		}
+		_ = tx.Rollback(ctx) // после Commit ничего не делает
 	}()
 
+	statuses := make([]models.SaveStatus, 0, len(books))
 	for _, book := range books {
 		var bid string
-		err = tx.QueryRow(ctx, `SELECT bid FROM books WHERE lable = $1 AND author = $2`, book.Lable, book.Author).Scan(&bid)
-		if err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				bid := uuid.New().String()
-				_, err = tx.Exec(ctx,
-					`INSERT INTO books (bid, lable, author, "desc", age, genre, rating, cover_url, pdf_url) 
-                    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
-					bid, book.Lable, book.Author, book.Desc, book.Age, book.Genre, book.Rating, book.CoverURL, book.PDFURL)
-				if err != nil {
-					log.Error().Err(err).Msg("insert book failed")
-					return err
-				}
-				continue
-			}
+		bid, err = findDuplicate(ctx, tx, book)
+		if err == nil {
+			log.Debug().Str("bid", bid).Msg("book already exists")
+			statuses = append(statuses, models.SaveStatus{BID: bid, Duplicate: true})
+			continue
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
 			log.Error().Err(err).Msg("check book failed")
-			return err
+			return nil, err
 		}
-		log.Debug().Str("bid", bid).Msg("book already exists")
+		bid = uuid.New().String()
+		var workID string
+		if workID, err = bookWork(ctx, tx, book); err != nil {
+			log.Error().Err(err).Msg("find book work failed")
+			return nil, err
+		}
+		_, err = tx.Exec(ctx,
+			`INSERT INTO books (bid, lable, author, "desc", age, genre, rating, cover_key, pdf_key,
+				pdf_pages, pdf_title, pdf_author, pdf_size, pdf_hash, epub_key, epub_size, epub_hash, language, isbn,
+				work_id, publisher) 
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)`,
+			bid, book.Lable, book.Author, book.Desc, book.Age, book.Genre, book.Rating, book.CoverKey, book.PDFKey,
+			book.PDFPages, book.PDFTitle, book.PDFAuthor, book.PDFSize, book.PDFHash,
+			book.EPUBKey, book.EPUBSize, book.EPUBHash, book.Language, book.ISBN, workID, book.Publisher)
+		if isDuplicateFile(err) {
+			return nil, storerrros.ErrDuplicateFile
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("insert book failed")
+			return nil, err
+		}
+		if err = linkAuthor(ctx, tx, bid, book.Author); err != nil {
+			log.Error().Err(err).Str("bid", bid).Msg("link book author failed")
+			return nil, err
+		}
+		if err = linkGenres(ctx, tx, bid, book.Genre); err != nil {
+			log.Error().Err(err).Str("bid", bid).Msg("link book genres failed")
+			return nil, err
+		}
+		rev := newRevision(bid, models.HistoryCreate, actor, models.BookSnapshot{}, models.NewBookSnapshot(book))
+		if err = addRevision(ctx, tx, rev); err != nil {
+			log.Error().Err(err).Str("bid", bid).Msg("failed to record book revision")
+			return nil, err
+		}
+		statuses = append(statuses, models.SaveStatus{BID: bid})
 	}
-	return nil
+	if err = tx.Commit(ctx); err != nil {
+		log.Error().Err(err).Msg("commit books failed")
+		return nil, err
+	}
+	return statuses, nil
 }
 
-func (dbs *DBStorage) GetBooks() ([]models.Book, error) {
+// GetBooks возвращает страницу каталога в порядке bid.
+func (dbs *DBStorage) GetBooks(page models.Page) (models.BooksPage, error) {
+	return dbs.listBooks(models.BookFilter{Ascending: true, Page: page})
+}
+
+func (dbs *DBStorage) GetBook(bid string) (models.Book, error) {
 	log := logger.Get()
 	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
 	defer cancel()
-	rows, err := dbs.pool.Query(ctx, `SELECT bid, lable, author, "desc", age, genre, rating, cover_url, pdf_url FROM books`)
+	row := dbs.pool.QueryRow(ctx, `SELECT `+bookColumns+` FROM books WHERE bid = $1 AND deleted_at IS NULL`, bid)
+
+	var book models.Book
+	if err := row.Scan(bookFields(&book)...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Book{}, storerrros.ErrBookNoExist
+		}
+		log.Error().Err(err).Msg("failed to scan data from db")
+		return models.Book{}, err
+	}
+	authors, err := bookAuthors(ctx, dbs.pool, bid)
 	if err != nil {
-		log.Error().Err(err).Msg("failed get all books from db")
-		return nil, err
+		log.Error().Err(err).Str("bid", bid).Msg("failed to get book authors")
+		return models.Book{}, err
+	}
+	book.Authors = authors
+	genres, err := bookGenres(ctx, dbs.pool, bid)
+	if err != nil {
+		log.Error().Err(err).Str("bid", bid).Msg("failed to get book genres")
+		return models.Book{}, err
+	}
+	book.Genres = genres
+	series, err := bookSeries(ctx, dbs.pool, bid)
+	if err != nil {
+		log.Error().Err(err).Str("bid", bid).Msg("failed to get book series")
+		return models.Book{}, err
 	}
-	defer rows.Close()
+	book.Series = series
+	tags, err := bookTags(ctx, dbs.pool, bid)
+	if err != nil {
+		log.Error().Err(err).Str("bid", bid).Msg("failed to get book tags")
+		return models.Book{}, err
+	}
+	book.Tags = tags
+	editions, err := workEditions(ctx, dbs.pool, book.WorkID)
+	if err != nil {
+		log.Error().Err(err).Str("bid", bid).Msg("failed to get book editions")
+		return models.Book{}, err
+	}
+	book.Editions = otherEditions(editions, bid)
+	return book, nil
+}
 
-	var books []models.Book
-	for rows.Next() {
-		var book models.Book
-		if err := rows.Scan(&book.BID, &book.Lable, &book.Author, &book.Desc, &book.Age, &book.Genre, &book.Rating, &book.CoverURL, &book.PDFURL); err != nil {
-			log.Error().Err(err).Msg("failed to scan data from db")
-			return nil, err
+// GetBookByFileHash возвращает книгу, к которой загружен PDF или EPUB с хешем hash.
+func (dbs *DBStorage) GetBookByFileHash(hash string) (models.Book, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+	if hash == "" {
+		return models.Book{}, storerrros.ErrBookNoExist
+	}
+
+	var book models.Book
+	err := dbs.pool.QueryRow(ctx, `SELECT `+bookColumns+` FROM books
+		WHERE (pdf_hash = $1 OR epub_hash = $1) AND deleted_at IS NULL LIMIT 1`, hash).Scan(bookFields(&book)...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Book{}, storerrros.ErrBookNoExist
 		}
-		books = append(books, book)
+		log.Error().Err(err).Msg("failed to find book by file hash")
+		return models.Book{}, err
 	}
-	return books, nil
+	return book, nil
 }
 
-func (dbs *DBStorage) GetBook(bid string) (models.Book, error) {
+// GetBookByISBN возвращает книгу по нормализованному ISBN-13.
+func (dbs *DBStorage) GetBookByISBN(isbn string) (models.Book, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+	if isbn == "" {
+		return models.Book{}, storerrros.ErrBookNoExist
+	}
+
+	var bid string
+	if err := dbs.pool.QueryRow(ctx, `SELECT bid FROM books WHERE isbn = $1 AND deleted_at IS NULL`, isbn).Scan(&bid); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Book{}, storerrros.ErrBookNoExist
+		}
+		log.Error().Err(err).Msg("failed to find book by isbn")
+		return models.Book{}, err
+	}
+	return dbs.GetBook(bid)
+}
+
+// UpdateBook перезаписывает книгу, только если ее версия в базе равна version,
+// и увеличивает версию. Так два администратора не затрут правки друг друга.
+// Если изменились строки author или genre, автор и жанры книги связываются заново.
+// Изменение записывается в историю книги от имени actor.
+func (dbs *DBStorage) UpdateBook(book models.Book, version int, actor string) (models.Book, error) {
 	log := logger.Get()
 	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
 	defer cancel()
-	row := dbs.pool.QueryRow(ctx, `SELECT bid, lable, author, "desc", age, genre, rating, cover_url, pdf_url FROM books WHERE bid = $1`, bid)
 
-	var book models.Book
-	if err := row.Scan(&book.BID, &book.Lable, &book.Author, &book.Desc, &book.Age, &book.Genre, &book.Rating, &book.CoverURL, &book.PDFURL); err != nil {
-		log.Error().Err(err).Msg("failed to scan data from db")
+	tx, err := dbs.pool.Begin(ctx)
+	if err != nil {
+		return models.Book{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // после Commit ничего не делает
+	}()
+
+	var stored int
+	var current models.BookSnapshot
+	err = tx.QueryRow(ctx,
+		`SELECT version, `+snapshotColumns+` FROM books WHERE bid = $1 AND deleted_at IS NULL FOR UPDATE`, book.BID).
+		Scan(append([]interface{}{&stored}, snapshotFields(&current)...)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Book{}, storerrros.ErrBookNoExist
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get book")
+		return models.Book{}, err
+	}
+	if stored != version {
+		log.Warn().Str("bid", book.BID).Int("version", version).Msg("book version conflict")
+		return models.Book{}, storerrros.ErrVersionConflict
+	}
+
+	snapshot := models.NewBookSnapshot(book)
+	if book.Version, book.UpdatedAt, err = updateBookRow(ctx, tx, book.BID, snapshot); err != nil {
+		if !errors.Is(err, storerrros.ErrDuplicateFile) && !errors.Is(err, storerrros.ErrDuplicateISBN) {
+			log.Error().Err(err).Msg("failed to update book")
+		}
+		return models.Book{}, err
+	}
+	if snapshot.Author != current.Author {
+		if err := relinkAuthor(ctx, tx, book.BID, snapshot.Author); err != nil {
+			log.Error().Err(err).Str("bid", book.BID).Msg("relink book author failed")
+			return models.Book{}, err
+		}
+	}
+	if snapshot.Genre != current.Genre {
+		if err := relinkGenres(ctx, tx, book.BID, snapshot.Genre); err != nil {
+			log.Error().Err(err).Str("bid", book.BID).Msg("relink book genres failed")
+			return models.Book{}, err
+		}
+	}
+	if err := addRevision(ctx, tx, newRevision(book.BID, models.HistoryUpdate, actor, current, snapshot)); err != nil {
+		log.Error().Err(err).Str("bid", book.BID).Msg("failed to record book revision")
+		return models.Book{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
 		return models.Book{}, err
 	}
 	return book, nil
 }
 
-func (dbs *DBStorage) DeleteBook(bid string) error {
+// LogDownload записывает скачивание файла книги пользователем.
+func (dbs *DBStorage) LogDownload(download models.Download) error {
 	log := logger.Get()
 	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
 	defer cancel()
 
-	res, err := dbs.pool.Exec(ctx, "DELETE FROM books WHERE bid = $1", bid)
+	_, err := dbs.pool.Exec(ctx, `INSERT INTO book_downloads (bid, uid, format, downloaded_at) VALUES ($1, $2, $3, $4)`,
+		download.BID, download.UID, download.Format, download.DownloadedAt)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to delete book")
+		log.Error().Err(err).Str("bid", download.BID).Msg("failed to log download")
 		return err
 	}
-	if res.RowsAffected() == 0 {
-		log.Warn().Str("bid", bid).Msg("book not found")
-		return errors.New("book not found")
-	}
-	log.Info().Str("bid", bid).Msg("book deleted successfully")
 	return nil
 }
 
@@ -214,90 +456,15 @@ func (dbs *DBStorage) DeleteBook(bid string) error {
 //		return books, nil
 //	}
 
-func (dbs *DBStorage) GetBooksWithFilters(searchTerm string, genres []string, year string, sortBy string, ascending bool) ([]models.Book, error) {
-	log := logger.Get()
-	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
-	defer cancel()
-
-	var orderDirection string
-	if ascending {
-		orderDirection = "ASC"
-	} else {
-		orderDirection = "DESC"
-	}
-
-	baseQuery := `SELECT bid, lable, author, "desc", age, genre, rating, cover_url, pdf_url FROM books`
-	var conditions []string
-	var args []interface{}
-	argPos := 1
-
-	if searchTerm != "" {
-		conditions = append(conditions, fmt.Sprintf("(lable ILIKE $%d OR author ILIKE $%d)", argPos, argPos+1))
-		args = append(args, "%"+searchTerm+"%", "%"+searchTerm+"%")
-		argPos += 2
-	}
-
-	if len(genres) > 0 {
-		var genreConds []string
-		for _, g := range genres {
-			genreConds = append(genreConds, fmt.Sprintf("genre ILIKE $%d", argPos))
-			args = append(args, "%"+g+"%")
-			argPos++
-		}
-		conditions = append(conditions, "("+strings.Join(genreConds, " OR ")+")")
-	}
-
-	if year != "" {
-		conditions = append(conditions, fmt.Sprintf("age = $%d", argPos))
-		args = append(args, year)
-		argPos++
-	}
-
-	whereClause := ""
-	if len(conditions) > 0 {
-		whereClause = " WHERE " + strings.Join(conditions, " AND ")
-	}
-
-	var sortQuery string
-	switch sortBy {
-	case "lable":
-		sortQuery = ` ORDER BY lable ` + orderDirection
-	case "author":
-		sortQuery = ` ORDER BY author ` + orderDirection
-	case "rating":
-		sortQuery = ` ORDER BY rating ` + orderDirection
-	case "genre":
-		sortQuery = ` ORDER BY genre ` + orderDirection
-	case "age":
-		sortQuery = ` ORDER BY age ` + orderDirection
-	default:
-		sortQuery = ` ORDER BY bid ` + orderDirection
-	}
-
-	fullQuery := baseQuery + whereClause + sortQuery
-
-	rows, err := dbs.pool.Query(ctx, fullQuery, args...)
+func (dbs *DBStorage) GetBooksWithFilters(filter models.BookFilter) (models.BooksPage, error) {
+	page, err := dbs.listBooks(filter)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to get books from db")
-		return nil, err
-	}
-	defer rows.Close()
-
-	var books []models.Book
-	for rows.Next() {
-		var book models.Book
-		if err := rows.Scan(&book.BID, &book.Lable, &book.Author, &book.Desc, &book.Age, &book.Genre, &book.Rating, &book.CoverURL, &book.PDFURL); err != nil {
-			log.Error().Err(err).Msg("failed to scan data from db")
-			return nil, err
-		}
-		books = append(books, book)
+		return models.BooksPage{}, err
 	}
-
-	if len(books) == 0 {
-		return nil, storerrros.ErrEmptyBooksList
+	if page.Total == 0 {
+		return models.BooksPage{}, storerrros.ErrEmptyBooksList
 	}
-
-	return books, nil
+	return page, nil
 }
 
 func Migrations(dbDsn string, migrationsPath string) error {
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"strings"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	"github.com/azaliaz/bookly/book-service/internal/translit"
+)
+
+// likeEscaper экранирует спецсимволы шаблона LIKE в пользовательском вводе.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`) //nolint:gochecknoglobals // неизменяемый
+
+// prefixPatterns строит шаблоны ILIKE для совпадения с началом поля или любого слова в нем.
+func prefixPatterns(variants []string) []string {
+	patterns := make([]string, 0, 2*len(variants))
+	for _, v := range variants {
+		v = likeEscaper.Replace(v)
+		patterns = append(patterns, v+"%", "% "+v+"%")
+	}
+	return patterns
+}
+
+// SuggestBooks ищет названия и авторов по префиксу и по триграммному сходству pg_trgm
+// с запросом и его транслитерациями. Совпадение по префиксу всегда выше нечеткого.
+func (dbs *DBStorage) SuggestBooks(query string, limit int) ([]models.Suggestion, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	variants := translit.Variants(query)
+	rows, err := dbs.pool.Query(ctx, `
+		WITH q AS (SELECT unnest($1::text[]) AS v)
+		SELECT kind, text, bid, score FROM (
+			SELECT $3::text AS kind, lable AS text, bid,
+				(SELECT max(similarity(lable, q.v)) FROM q)::float8 +
+				CASE WHEN lable ILIKE ANY($2::text[]) THEN 1 ELSE 0 END AS score
+			FROM books
+			WHERE (lable ILIKE ANY($2::text[]) OR lable % ANY($1::text[])) AND deleted_at IS NULL
+			UNION ALL
+			SELECT $4::text AS kind, author AS text, '' AS bid,
+				(SELECT max(similarity(author, q.v)) FROM q)::float8 +
+				CASE WHEN author ILIKE ANY($2::text[]) THEN 1 ELSE 0 END AS score
+			FROM books
+			WHERE (author ILIKE ANY($2::text[]) OR author % ANY($1::text[])) AND deleted_at IS NULL
+			GROUP BY author
+		) s
+		ORDER BY score DESC, text
+		LIMIT $5`,
+		variants, prefixPatterns(variants), consts.SuggestKindTitle, consts.SuggestKindAuthor, limit)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get suggestions from db")
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := make([]models.Suggestion, 0, limit)
+	for rows.Next() {
+		var s models.Suggestion
+		if err := rows.Scan(&s.Kind, &s.Text, &s.BID, &s.Score); err != nil {
+			log.Error().Err(err).Msg("failed to scan data from db")
+			return nil, err
+		}
+		suggestions = append(suggestions, s)
+	}
+	return suggestions, rows.Err()
+}
@@ -0,0 +1,277 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+// tagBooksQuery выбирает книги с одобренными тегами из массива названий %[1]s.
+// Для фильтра "все теги" к нему добавляется tagsAllClause с числом тегов.
+const tagBooksQuery = `SELECT bt.bid FROM book_tags bt JOIN tags t ON t.id = bt.tag_id
+	WHERE bt.status = 'approved' AND lower(t.name) = ANY(%[1]s)`
+
+const tagsAllClause = ` GROUP BY bt.bid HAVING count(DISTINCT t.id) = %s`
+
+// tagValues приводит названия тегов из фильтра к нижнему регистру и убирает повторы,
+// чтобы фильтр "все теги" сравнивал число совпавших тегов с числом разных названий.
+func tagValues(tags []string) []string {
+	var values []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag != "" && !slices.Contains(values, tag) {
+			values = append(values, tag)
+		}
+	}
+	return values
+}
+
+// GetTags возвращает облако тегов: теги с одобренными книгами по убыванию числа
+// книг. limit <= 0 возвращает все теги.
+func (dbs *DBStorage) GetTags(limit int) ([]models.Tag, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	query := `SELECT t.id, t.name, count(*)::int AS books FROM tags t
+		JOIN book_tags bt ON bt.tag_id = t.id AND bt.status = 'approved'
+		JOIN books b ON b.bid = bt.bid AND b.deleted_at IS NULL
+		GROUP BY t.id ORDER BY books DESC, lower(t.name)`
+	args := []interface{}{}
+	if limit > 0 {
+		query += ` LIMIT $1`
+		args = append(args, limit)
+	}
+	rows, err := dbs.pool.Query(ctx, query, args...)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get tags")
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.BookCount); err != nil {
+			log.Error().Err(err).Msg("failed to scan tag")
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// DeleteTag удаляет тег из каталога вместе со всеми его связями с книгами.
+func (dbs *DBStorage) DeleteTag(id string) error {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	tag, err := dbs.pool.Exec(ctx, `DELETE FROM tags WHERE id = $1`, id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to delete tag")
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storerrros.ErrTagNoExist
+	}
+	return nil
+}
+
+// SetBookTags заменяет одобренные теги книги. Недостающие теги создаются, а
+// предложенные пользователями теги из names считаются одобренными; остальные
+// предложения остаются на модерации.
+func (dbs *DBStorage) SetBookTags(bid string, names []string) error {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	tx, err := dbs.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // после Commit ничего не делает
+	}()
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM books WHERE bid = $1 AND deleted_at IS NULL)`, bid).Scan(&exists); err != nil {
+		log.Error().Err(err).Msg("failed to check book")
+		return err
+	}
+	if !exists {
+		return storerrros.ErrBookNoExist
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM book_tags WHERE bid = $1 AND status = 'approved'`, bid); err != nil {
+		log.Error().Err(err).Msg("failed to clear book tags")
+		return err
+	}
+	for _, name := range names {
+		id, err := tagID(ctx, tx, name)
+		if err != nil {
+			log.Error().Err(err).Str("tag", name).Msg("failed to create tag")
+			return err
+		}
+		_, err = tx.Exec(ctx,
+			`INSERT INTO book_tags (bid, tag_id, status) VALUES ($1, $2, 'approved')
+			ON CONFLICT (bid, tag_id) DO UPDATE SET status = 'approved'`, bid, id)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to link book tag")
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// SuggestBookTag сохраняет тег, предложенный пользователем uid, на модерацию.
+// Если у книги уже есть этот тег, одобренный или предложенный, возвращается ErrTagExists.
+func (dbs *DBStorage) SuggestBookTag(bid, uid, name string) (models.TagSuggestion, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	tx, err := dbs.pool.Begin(ctx)
+	if err != nil {
+		return models.TagSuggestion{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // после Commit ничего не делает
+	}()
+
+	suggestion := models.TagSuggestion{BID: bid, SuggestedBy: uid}
+	err = tx.QueryRow(ctx, `SELECT lable FROM books WHERE bid = $1 AND deleted_at IS NULL`, bid).Scan(&suggestion.Lable)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.TagSuggestion{}, storerrros.ErrBookNoExist
+		}
+		log.Error().Err(err).Msg("failed to check book")
+		return models.TagSuggestion{}, err
+	}
+	if suggestion.TagID, err = tagID(ctx, tx, name); err != nil {
+		log.Error().Err(err).Str("tag", name).Msg("failed to create tag")
+		return models.TagSuggestion{}, err
+	}
+	err = tx.QueryRow(ctx,
+		`INSERT INTO book_tags (bid, tag_id, status, suggested_by) VALUES ($1, $2, 'pending', $3)
+		ON CONFLICT (bid, tag_id) DO NOTHING
+		RETURNING created_at, (SELECT name FROM tags WHERE id = $2)`,
+		bid, suggestion.TagID, uid).Scan(&suggestion.CreatedAt, &suggestion.Name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.TagSuggestion{}, storerrros.ErrTagExists
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to suggest book tag")
+		return models.TagSuggestion{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return models.TagSuggestion{}, err
+	}
+	return suggestion, nil
+}
+
+// GetTagSuggestions возвращает очередь модерации, начиная с самых старых предложений.
+func (dbs *DBStorage) GetTagSuggestions() ([]models.TagSuggestion, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	rows, err := dbs.pool.Query(ctx,
+		`SELECT bt.bid, b.lable, t.id, t.name, bt.suggested_by, bt.created_at
+		FROM book_tags bt JOIN tags t ON t.id = bt.tag_id JOIN books b ON b.bid = bt.bid
+		WHERE bt.status = 'pending' AND b.deleted_at IS NULL ORDER BY bt.created_at, bt.bid, t.id`)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get tag suggestions")
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []models.TagSuggestion{}
+	for rows.Next() {
+		var s models.TagSuggestion
+		if err := rows.Scan(&s.BID, &s.Lable, &s.TagID, &s.Name, &s.SuggestedBy, &s.CreatedAt); err != nil {
+			log.Error().Err(err).Msg("failed to scan tag suggestion")
+			return nil, err
+		}
+		suggestions = append(suggestions, s)
+	}
+	return suggestions, rows.Err()
+}
+
+// ApproveBookTag одобряет предложенный тег книги; одобренный тег не меняется.
+func (dbs *DBStorage) ApproveBookTag(bid, tagID string) error {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	tag, err := dbs.pool.Exec(ctx, `UPDATE book_tags SET status = 'approved' WHERE bid = $1 AND tag_id = $2`, bid, tagID)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to approve book tag")
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storerrros.ErrTagNoExist
+	}
+	return nil
+}
+
+// RemoveBookTag снимает тег с книги или отклоняет предложенный тег.
+func (dbs *DBStorage) RemoveBookTag(bid, tagID string) error {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	tag, err := dbs.pool.Exec(ctx, `DELETE FROM book_tags WHERE bid = $1 AND tag_id = $2`, bid, tagID)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to remove book tag")
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storerrros.ErrTagNoExist
+	}
+	return nil
+}
+
+// tagID возвращает id тега с названием name без учета регистра, создавая тег при
+// необходимости.
+func tagID(ctx context.Context, q querier, name string) (string, error) {
+	var id string
+	err := q.QueryRow(ctx,
+		`WITH created AS (
+			INSERT INTO tags (id, name) VALUES ($1, $2) ON CONFLICT ((lower(name))) DO NOTHING
+			RETURNING id
+		)
+		SELECT id FROM created UNION ALL SELECT id FROM tags WHERE lower(name) = lower($2)
+		LIMIT 1`,
+		uuid.New().String(), name).Scan(&id)
+	return id, err
+}
+
+// bookTags возвращает одобренные теги книги по названию.
+func bookTags(ctx context.Context, q querier, bid string) ([]models.BookTag, error) {
+	rows, err := q.Query(ctx,
+		`SELECT t.id, t.name FROM book_tags bt JOIN tags t ON t.id = bt.tag_id
+		WHERE bt.bid = $1 AND bt.status = 'approved' ORDER BY lower(t.name)`, bid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []models.BookTag
+	for rows.Next() {
+		var t models.BookTag
+		if err := rows.Scan(&t.TagID, &t.Name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+// DeleteBook переносит книгу в корзину от имени администратора uid. Связи, отзывы
+// и файлы книги сохраняются до PurgeBooks.
+func (dbs *DBStorage) DeleteBook(bid string, uid string) error {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	err := dbs.setDeleted(ctx, bid, uid, true)
+	if errors.Is(err, storerrros.ErrBookNoExist) {
+		log.Warn().Str("bid", bid).Msg("book not found")
+		return err
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to delete book")
+		return err
+	}
+	log.Info().Str("bid", bid).Str("uid", uid).Msg("book moved to trash")
+	return nil
+}
+
+// GetTrash возвращает книги в корзине, начиная с удаленных последними.
+func (dbs *DBStorage) GetTrash() ([]models.Book, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	rows, err := dbs.pool.Query(ctx,
+		`SELECT `+bookColumns+` FROM books WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, bid`)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get trash")
+		return nil, err
+	}
+	defer rows.Close()
+
+	books := []models.Book{}
+	for rows.Next() {
+		var book models.Book
+		if err := rows.Scan(bookFields(&book)...); err != nil {
+			log.Error().Err(err).Msg("failed to scan data from db")
+			return nil, err
+		}
+		books = append(books, book)
+	}
+	return books, rows.Err()
+}
+
+// RestoreBook возвращает книгу из корзины в каталог от имени администратора uid.
+// Если за это время в каталог добавили то же издание или тот же файл, возвращается
+// ErrDuplicateISBN или ErrDuplicateFile.
+func (dbs *DBStorage) RestoreBook(bid string, uid string) error {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	err := dbs.setDeleted(ctx, bid, uid, false)
+	if isDuplicateFile(err) {
+		return storerrros.ErrDuplicateFile
+	}
+	if isUniqueViolation(err, isbnKey) {
+		return storerrros.ErrDuplicateISBN
+	}
+	if errors.Is(err, storerrros.ErrBookNoExist) {
+		return err
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to restore book")
+		return err
+	}
+	log.Info().Str("bid", bid).Str("uid", uid).Msg("book restored from trash")
+	return nil
+}
+
+// setDeleted переносит книгу в корзину или возвращает из нее и записывает это в
+// историю книги.
+func (dbs *DBStorage) setDeleted(ctx context.Context, bid, uid string, deleted bool) error {
+	tx, err := dbs.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // после Commit ничего не делает
+	}()
+
+	query := `UPDATE books SET deleted_at = now(), deleted_by = $2, version = version + 1
+		WHERE bid = $1 AND deleted_at IS NULL RETURNING ` + snapshotColumns
+	args, action := []interface{}{bid, uid}, models.HistoryDelete
+	if !deleted {
+		query = `UPDATE books SET deleted_at = NULL, deleted_by = '', version = version + 1
+			WHERE bid = $1 AND deleted_at IS NOT NULL RETURNING ` + snapshotColumns
+		args, action = args[:1], models.HistoryRestore
+	}
+	var snapshot models.BookSnapshot
+	err = tx.QueryRow(ctx, query, args...).Scan(snapshotFields(&snapshot)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storerrros.ErrBookNoExist
+	}
+	if err != nil {
+		return err
+	}
+	if err := addRevision(ctx, tx, newRevision(bid, action, uid, snapshot, snapshot)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// PurgeBooks окончательно удаляет книги, попавшие в корзину раньше before, вместе
+// с отзывами и позициями корзин покупателей, и возвращает их, чтобы сервер удалил
+// файлы. В истории книги остается запись purge. Произведения, у которых не
+// осталось изданий, удаляются.
+func (dbs *DBStorage) PurgeBooks(before time.Time) ([]models.Book, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBBulkCtxTimeout)
+	defer cancel()
+
+	tx, err := dbs.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // после Commit ничего не делает
+	}()
+
+	rows, err := tx.Query(ctx,
+		`DELETE FROM books WHERE deleted_at < $1
+		RETURNING bid, work_id, deleted_at, `+snapshotColumns, before)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to purge books")
+		return nil, err
+	}
+	var books []models.Book
+	for rows.Next() {
+		var book models.Book
+		var snapshot models.BookSnapshot
+		dest := append([]interface{}{&book.BID, &book.WorkID, &book.DeletedAt}, snapshotFields(&snapshot)...)
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			log.Error().Err(err).Msg("failed to scan purged book")
+			return nil, err
+		}
+		books = append(books, snapshot.Apply(book))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Error().Err(err).Msg("failed to purge books")
+		return nil, err
+	}
+	for _, book := range books {
+		if err := dropEmptyWork(ctx, tx, book.WorkID); err != nil {
+			log.Error().Err(err).Str("work_id", book.WorkID).Msg("failed to delete empty work")
+			return nil, err
+		}
+		snapshot := models.NewBookSnapshot(book)
+		if err := addRevision(ctx, tx, newRevision(book.BID, models.HistoryPurge, "", snapshot, snapshot)); err != nil {
+			log.Error().Err(err).Str("bid", book.BID).Msg("failed to record book revision")
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return books, nil
+}
@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+// workPopularity - скачивания всех изданий произведения книги; выражение
+// используется в bookColumns и для сортировки по популярности.
+const workPopularity = `(SELECT count(*) FROM book_downloads d JOIN books e ON e.bid = d.bid
+	WHERE e.work_id = books.work_id)::int`
+
+// editionOrder - порядок изданий произведения: по году, затем по названию.
+const editionOrder = `age, lable, bid`
+
+// GetWork возвращает произведение со всеми его изданиями.
+func (dbs *DBStorage) GetWork(id string) (models.Work, error) {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	var work models.Work
+	err := dbs.pool.QueryRow(ctx,
+		`SELECT id, title, author, created_at,
+			(SELECT count(*) FROM book_downloads d JOIN books b ON b.bid = d.bid WHERE b.work_id = works.id)::int
+		FROM works WHERE id = $1`, id,
+	).Scan(&work.ID, &work.Title, &work.Author, &work.CreatedAt, &work.Popularity)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Work{}, storerrros.ErrWorkNoExist
+		}
+		log.Error().Err(err).Msg("failed to get work")
+		return models.Work{}, err
+	}
+	if work.Editions, err = workEditions(ctx, dbs.pool, id); err != nil {
+		log.Error().Err(err).Str("work_id", id).Msg("failed to get work editions")
+		return models.Work{}, err
+	}
+	return work, nil
+}
+
+// SetBookWork переносит издание в произведение workID. Пустой workID выделяет
+// издание в новое произведение. Произведение, у которого не осталось изданий,
+// удаляется.
+func (dbs *DBStorage) SetBookWork(bid string, workID string) error {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
+	defer cancel()
+
+	tx, err := dbs.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // после Commit ничего не делает
+	}()
+
+	var book models.Book
+	err = tx.QueryRow(ctx, `SELECT lable, author, work_id FROM books WHERE bid = $1 AND deleted_at IS NULL FOR UPDATE`, bid).
+		Scan(&book.Lable, &book.Author, &book.WorkID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storerrros.ErrBookNoExist
+		}
+		log.Error().Err(err).Msg("failed to get book work")
+		return err
+	}
+	previous := book.WorkID
+	if workID == "" {
+		workID, err = createWork(ctx, tx, book)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to create work")
+			return err
+		}
+	}
+	_, err = tx.Exec(ctx, `UPDATE books SET work_id = $1 WHERE bid = $2`, workID, bid)
+	if isForeignKeyViolation(err) {
+		return storerrros.ErrWorkNoExist
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to set book work")
+		return err
+	}
+	if err := dropEmptyWork(ctx, tx, previous); err != nil {
+		log.Error().Err(err).Str("work_id", previous).Msg("failed to delete empty work")
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// bookWork возвращает произведение для нового издания: заданное в книге, иначе
+// произведение изданий с тем же названием и автором без учета пунктуации, иначе
+// новое произведение.
+func bookWork(ctx context.Context, q querier, book models.Book) (string, error) {
+	if book.WorkID != "" {
+		var exists bool
+		if err := q.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM works WHERE id = $1)`, book.WorkID).Scan(&exists); err != nil {
+			return "", err
+		}
+		if !exists {
+			return "", storerrros.ErrWorkNoExist
+		}
+		return book.WorkID, nil
+	}
+
+	var workID string
+	err := q.QueryRow(ctx,
+		`SELECT work_id FROM books
+		WHERE book_title_key(lable) = book_title_key($1) AND book_title_key(author) = book_title_key($2)
+		ORDER BY created_at, bid LIMIT 1`,
+		book.Lable, book.Author).Scan(&workID)
+	if err == nil {
+		return workID, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return "", err
+	}
+	return createWork(ctx, q, book)
+}
+
+func createWork(ctx context.Context, q querier, book models.Book) (string, error) {
+	id := uuid.New().String()
+	_, err := q.Exec(ctx, `INSERT INTO works (id, title, author) VALUES ($1, $2, $3)`, id, book.Lable, book.Author)
+	return id, err
+}
+
+// dropEmptyWork удаляет произведение, если у него не осталось изданий.
+func dropEmptyWork(ctx context.Context, q querier, id string) error {
+	_, err := q.Exec(ctx,
+		`DELETE FROM works WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM books WHERE work_id = $1)`, id)
+	return err
+}
+
+// workEditions возвращает издания произведения в порядке editionOrder.
+func workEditions(ctx context.Context, q querier, workID string) ([]models.Edition, error) {
+	rows, err := q.Query(ctx,
+		`SELECT bid, lable, language, publisher, age, isbn FROM books
+		WHERE work_id = $1 AND deleted_at IS NULL ORDER BY `+editionOrder, workID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var editions []models.Edition
+	for rows.Next() {
+		var e models.Edition
+		if err := rows.Scan(&e.BID, &e.Lable, &e.Language, &e.Publisher, &e.Year, &e.ISBN); err != nil {
+			return nil, err
+		}
+		editions = append(editions, e)
+	}
+	return editions, rows.Err()
+}
+
+// otherEditions убирает из изданий произведения саму книгу bid.
+func otherEditions(editions []models.Edition, bid string) []models.Edition {
+	var others []models.Edition
+	for _, e := range editions {
+		if e.BID != bid {
+			others = append(others, e)
+		}
+	}
+	return others
+}
@@ -3,6 +3,19 @@ package storerrros
 import "errors"
 
 var (
-	ErrBookNoExist    = errors.New("book does not exists")
-	ErrEmptyBooksList = errors.New("empty books list")
+	ErrBookNoExist      = errors.New("book does not exists")
+	ErrEmptyBooksList   = errors.New("empty books list")
+	ErrInvalidCursor    = errors.New("invalid cursor")
+	ErrVersionConflict  = errors.New("book was modified by someone else")
+	ErrDuplicateFile    = errors.New("book with the same file already exists")
+	ErrDuplicateISBN    = errors.New("book with the same ISBN already exists")
+	ErrAuthorNoExist    = errors.New("author does not exists")
+	ErrGenreNoExist     = errors.New("genre does not exists")
+	ErrGenreCycle       = errors.New("genre cannot be moved under itself or its subgenre")
+	ErrGenreHasChildren = errors.New("genre has subgenres")
+	ErrSeriesNoExist    = errors.New("series does not exists")
+	ErrWorkNoExist      = errors.New("work does not exists")
+	ErrTagNoExist       = errors.New("tag does not exists")
+	ErrTagExists        = errors.New("book already has this tag")
+	ErrRevisionNoExist  = errors.New("book revision does not exists")
 )
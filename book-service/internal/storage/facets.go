@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+)
+
+// parseYearRange разбирает фильтр года вида "1990-1999", который выдается в фасетах.
+func parseYearRange(year string) (int, int, bool) {
+	fromStr, toStr, found := strings.Cut(year, "-")
+	if !found {
+		return 0, 0, false
+	}
+	from, err := strconv.Atoi(strings.TrimSpace(fromStr))
+	if err != nil {
+		return 0, 0, false
+	}
+	to, err := strconv.Atoi(strings.TrimSpace(toStr))
+	if err != nil || to < from {
+		return 0, 0, false
+	}
+	return from, to, true
+}
+
+// decadeBucket возвращает интервал десятилетия для года в формате фильтра year.
+func decadeBucket(year int) string {
+	from := year - year%consts.FacetYearBucket
+	return fmt.Sprintf("%d-%d", from, from+consts.FacetYearBucket-1)
+}
+
+// facetFilters возвращает фильтры для подсчета каждого фасета: счетчик измерения
+// учитывает все активные фильтры, кроме фильтра по самому этому измерению.
+func facetFilters(filter models.BookFilter) (genre, year, author models.BookFilter) {
+	filter.Page = models.Page{}
+	genre, year, author = filter, filter, filter
+	genre.Genres = nil
+	year.Year = ""
+	author.Author = ""
+	return genre, year, author
+}
+
+// GetBookFacets - in-process аналог DBStorage.GetBookFacets.
+func (ms *MemStorage) GetBookFacets(filter models.BookFilter) (models.BookFacets, error) {
+	genreFilter, yearFilter, authorFilter := facetFilters(filter)
+
+	genres := make(map[string]int)
+	for _, book := range ms.filterBooks(genreFilter) {
+		genres[book.Genre]++
+	}
+	years := make(map[string]int)
+	for _, book := range ms.filterBooks(yearFilter) {
+		years[decadeBucket(book.Age)]++
+	}
+	authors := make(map[string]int)
+	for _, book := range ms.filterBooks(authorFilter) {
+		authors[book.Author]++
+	}
+
+	facets := models.BookFacets{
+		Genres:  sortedCounts(genres, byCount),
+		Years:   sortedCounts(years, byValue),
+		Authors: sortedCounts(authors, byCount),
+	}
+	if len(facets.Authors) > consts.FacetTopAuthors {
+		facets.Authors = facets.Authors[:consts.FacetTopAuthors]
+	}
+	return facets, nil
+}
+
+// GetAuthors - in-process аналог DBStorage.GetAuthors.
+func (ms *MemStorage) GetAuthors() ([]models.FacetCount, error) {
+	authors := make(map[string]int)
+	for _, book := range ms.bookStor {
+		authors[book.Author]++
+	}
+	return sortedCounts(authors, byName), nil
+}
+
+func byCount(a, b models.FacetCount) int {
+	return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Value, b.Value))
+}
+
+func byName(a, b models.FacetCount) int {
+	return strings.Compare(a.Value, b.Value)
+}
+
+func byValue(a, b models.FacetCount) int {
+	af, _, _ := parseYearRange(a.Value)
+	bf, _, _ := parseYearRange(b.Value)
+	return cmp.Compare(af, bf)
+}
+
+func sortedCounts(counts map[string]int, order func(a, b models.FacetCount) int) []models.FacetCount {
+	res := make([]models.FacetCount, 0, len(counts))
+	for value, count := range counts {
+		res = append(res, models.FacetCount{Value: value, Count: count})
+	}
+	slices.SortFunc(res, order)
+	return res
+}
@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+func (ms *MemStorage) SaveAuthor(author models.Author) (models.Author, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.saveAuthor(author)
+}
+
+func (ms *MemStorage) saveAuthor(author models.Author) (models.Author, error) {
+	author.ID = uuid.New().String()
+	author.CreatedAt = createdNow()
+	ms.authors[author.ID] = author
+	return author, nil
+}
+
+func (ms *MemStorage) GetAuthor(id string) (models.Author, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return ms.getAuthor(id)
+}
+
+func (ms *MemStorage) getAuthor(id string) (models.Author, error) {
+	author, ok := ms.authors[id]
+	if !ok {
+		return models.Author{}, storerrros.ErrAuthorNoExist
+	}
+	author.BookCount = ms.authorBookCount(id)
+	return author, nil
+}
+
+// ListAuthors возвращает страницу авторов по (name, id), как DBStorage.
+func (ms *MemStorage) ListAuthors(query string, page models.Page) (models.AuthorsPage, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	limit := page.Limit
+	if limit <= 0 {
+		limit = consts.DefaultPageLimit
+	}
+
+	authors := make([]models.Author, 0, len(ms.authors))
+	for id := range ms.authors {
+		author, _ := ms.getAuthor(id)
+		if query == "" || authorMatches(author, query) {
+			authors = append(authors, author)
+		}
+	}
+	sort.Slice(authors, func(i, j int) bool { return authorLess(authors[i], authors[j]) })
+
+	result := models.AuthorsPage{Total: len(authors)}
+	if page.Cursor != "" {
+		cur, err := decodeAuthorCursor(page.Cursor)
+		if err != nil {
+			return models.AuthorsPage{}, err
+		}
+		last := models.Author{Name: cur.Name, ID: cur.ID}
+		start := sort.Search(len(authors), func(i int) bool { return authorLess(last, authors[i]) })
+		authors = authors[start:]
+	}
+	if len(authors) > limit {
+		authors = authors[:limit]
+		last := authors[limit-1]
+		result.NextCursor = authorCursor{Name: last.Name, ID: last.ID}.encode()
+	}
+	result.Authors = authors
+	return result, nil
+}
+
+func authorLess(a, b models.Author) bool {
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return a.ID < b.ID
+}
+
+func authorMatches(author models.Author, query string) bool {
+	query = strings.ToLower(query)
+	for _, name := range append([]string{author.Name}, author.AltNames...) {
+		if strings.Contains(strings.ToLower(name), query) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ms *MemStorage) UpdateAuthor(author models.Author) (models.Author, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	stored, ok := ms.authors[author.ID]
+	if !ok {
+		return models.Author{}, storerrros.ErrAuthorNoExist
+	}
+	author.CreatedAt = stored.CreatedAt
+	ms.authors[author.ID] = author
+	if author.Name != stored.Name {
+		for bid := range ms.bookAuthors {
+			if ms.hasAuthor(bid, author.ID, "") {
+				ms.touch(bid)
+			}
+		}
+	}
+	author.BookCount = ms.authorBookCount(author.ID)
+	return author, nil
+}
+
+func (ms *MemStorage) DeleteAuthor(id string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.authors[id]; !ok {
+		return storerrros.ErrAuthorNoExist
+	}
+	delete(ms.authors, id)
+	for bid, links := range ms.bookAuthors {
+		if kept := slices.DeleteFunc(links, func(a models.BookAuthor) bool { return a.AuthorID == id }); len(kept) != len(links) {
+			ms.bookAuthors[bid] = kept
+			ms.touch(bid)
+		}
+	}
+	return nil
+}
+
+func (ms *MemStorage) SetBookAuthors(bid string, authors []models.BookAuthor) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.liveBook(bid); !ok {
+		return storerrros.ErrBookNoExist
+	}
+	links := make([]models.BookAuthor, 0, len(authors))
+	for _, a := range authors {
+		if _, ok := ms.authors[a.AuthorID]; !ok {
+			return storerrros.ErrAuthorNoExist
+		}
+		link := models.BookAuthor{AuthorID: a.AuthorID, Role: a.Role}
+		if !slices.Contains(links, link) {
+			links = append(links, link)
+		}
+	}
+	ms.bookAuthors[bid] = links
+	ms.touch(bid)
+	return nil
+}
+
+// linkedAuthors возвращает авторов книги с именами, как bookAuthors в DBStorage.
+func (ms *MemStorage) linkedAuthors(bid string) []models.BookAuthor {
+	var authors []models.BookAuthor
+	for _, link := range ms.bookAuthors[bid] {
+		link.Name = ms.authors[link.AuthorID].Name
+		authors = append(authors, link)
+	}
+	return authors
+}
+
+// hasAuthor сообщает, связана ли книга с автором id (в роли role, если она задана).
+func (ms *MemStorage) hasAuthor(bid, id, role string) bool {
+	return slices.ContainsFunc(ms.bookAuthors[bid], func(a models.BookAuthor) bool {
+		return a.AuthorID == id && (role == "" || a.Role == role)
+	})
+}
+
+func (ms *MemStorage) authorBookCount(id string) int {
+	count := 0
+	for bid := range ms.bookAuthors {
+		if !ms.inTrash(bid) && ms.hasAuthor(bid, id, "") {
+			count++
+		}
+	}
+	return count
+}
+
+// relinkAuthor заменяет основного автора книги, как relinkAuthor в DBStorage.
+func (ms *MemStorage) relinkAuthor(bid, author string) {
+	ms.bookAuthors[bid] = slices.DeleteFunc(ms.bookAuthors[bid], func(a models.BookAuthor) bool {
+		return a.Role == consts.RoleAuthor
+	})
+	ms.linkAuthor(bid, author)
+}
+
+// linkAuthor связывает новую книгу с автором по имени или другому написанию, создавая
+// автора при необходимости, как linkAuthor в DBStorage.
+func (ms *MemStorage) linkAuthor(bid, author string) {
+	name := strings.Join(strings.Fields(author), " ")
+	if name == "" {
+		return
+	}
+	var found *models.Author
+	for _, a := range ms.authors {
+		if strings.EqualFold(a.Name, name) || slices.ContainsFunc(a.AltNames, func(n string) bool { return strings.EqualFold(n, name) }) {
+			if found == nil || a.CreatedAt.Before(found.CreatedAt) {
+				found = &a
+			}
+		}
+	}
+	if found == nil {
+		created, _ := ms.saveAuthor(models.Author{Name: name})
+		found = &created
+	}
+	ms.bookAuthors[bid] = append(ms.bookAuthors[bid], models.BookAuthor{AuthorID: found.ID, Role: consts.RoleAuthor})
+}
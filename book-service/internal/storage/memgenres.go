@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+func (ms *MemStorage) SaveGenre(genre models.Genre) (models.Genre, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.saveGenre(genre)
+}
+
+func (ms *MemStorage) saveGenre(genre models.Genre) (models.Genre, error) {
+	if _, ok := ms.genres[genre.ParentID]; genre.ParentID != "" && !ok {
+		return models.Genre{}, storerrros.ErrGenreNoExist
+	}
+	genre.ID = uuid.New().String()
+	genre.CreatedAt = createdNow()
+	genre.Children = nil
+	ms.genres[genre.ID] = genre
+	return genre, nil
+}
+
+// GetGenres возвращает все жанры по русскому названию, как DBStorage.
+func (ms *MemStorage) GetGenres() ([]models.Genre, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	genres := make([]models.Genre, 0, len(ms.genres))
+	for _, genre := range ms.genres {
+		for bid, ids := range ms.bookGenres {
+			if !ms.inTrash(bid) && slices.Contains(ids, genre.ID) {
+				genre.BookCount++
+			}
+		}
+		genres = append(genres, genre)
+	}
+	sort.Slice(genres, func(i, j int) bool {
+		if genres[i].NameRU != genres[j].NameRU {
+			return genres[i].NameRU < genres[j].NameRU
+		}
+		return genres[i].ID < genres[j].ID
+	})
+	return genres, nil
+}
+
+func (ms *MemStorage) UpdateGenre(genre models.Genre) (models.Genre, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	stored, ok := ms.genres[genre.ID]
+	if !ok {
+		return models.Genre{}, storerrros.ErrGenreNoExist
+	}
+	for parent := genre.ParentID; parent != ""; parent = ms.genres[parent].ParentID {
+		if parent == genre.ID {
+			return models.Genre{}, storerrros.ErrGenreCycle
+		}
+		if _, ok := ms.genres[parent]; !ok {
+			return models.Genre{}, storerrros.ErrGenreNoExist
+		}
+	}
+	genre.CreatedAt = stored.CreatedAt
+	genre.BookCount = 0
+	genre.Children = nil
+	ms.genres[genre.ID] = genre
+	renamed := genre.NameRU != stored.NameRU || genre.NameEN != stored.NameEN
+	for bid, ids := range ms.bookGenres {
+		if !slices.Contains(ids, genre.ID) {
+			continue
+		}
+		if renamed {
+			ms.touch(bid)
+		}
+		if !ms.inTrash(bid) {
+			genre.BookCount++
+		}
+	}
+	return genre, nil
+}
+
+func (ms *MemStorage) DeleteGenre(id string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.genres[id]; !ok {
+		return storerrros.ErrGenreNoExist
+	}
+	for _, genre := range ms.genres {
+		if genre.ParentID == id {
+			return storerrros.ErrGenreHasChildren
+		}
+	}
+	delete(ms.genres, id)
+	for bid, ids := range ms.bookGenres {
+		if kept := slices.DeleteFunc(ids, func(g string) bool { return g == id }); len(kept) != len(ids) {
+			ms.bookGenres[bid] = kept
+			ms.touch(bid)
+		}
+	}
+	return nil
+}
+
+func (ms *MemStorage) SetBookGenres(bid string, ids []string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.liveBook(bid); !ok {
+		return storerrros.ErrBookNoExist
+	}
+	links := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := ms.genres[id]; !ok {
+			return storerrros.ErrGenreNoExist
+		}
+		if !slices.Contains(links, id) {
+			links = append(links, id)
+		}
+	}
+	ms.bookGenres[bid] = links
+	ms.touch(bid)
+	return nil
+}
+
+// linkedGenres возвращает жанры книги, как bookGenres в DBStorage.
+func (ms *MemStorage) linkedGenres(bid string) []models.BookGenre {
+	var genres []models.BookGenre
+	for _, id := range ms.bookGenres[bid] {
+		g := ms.genres[id]
+		genres = append(genres, models.BookGenre{GenreID: id, NameRU: g.NameRU, NameEN: g.NameEN})
+	}
+	return genres
+}
+
+// genreTree возвращает id жанров, заданных id или названием, вместе с их поджанрами.
+func (ms *MemStorage) genreTree(values []string) map[string]bool {
+	tree := make(map[string]bool)
+	for id, g := range ms.genres {
+		if slices.Contains(values, id) || slices.Contains(values, strings.ToLower(g.NameRU)) ||
+			slices.Contains(values, strings.ToLower(g.NameEN)) {
+			tree[id] = true
+		}
+	}
+	for added := true; added; {
+		added = false
+		for id, g := range ms.genres {
+			if !tree[id] && tree[g.ParentID] {
+				tree[id] = true
+				added = true
+			}
+		}
+	}
+	return tree
+}
+
+// relinkGenres заменяет жанры книги жанрами из строки genre, как relinkGenres в DBStorage.
+func (ms *MemStorage) relinkGenres(bid, genre string) {
+	delete(ms.bookGenres, bid)
+	ms.linkGenres(bid, genre)
+}
+
+// linkGenres связывает новую книгу с жанрами из строки genre, создавая недостающие,
+// как linkGenres в DBStorage.
+func (ms *MemStorage) linkGenres(bid, genre string) {
+	for _, name := range splitGenres(genre) {
+		var found *models.Genre
+		for _, g := range ms.genres {
+			if strings.EqualFold(g.NameRU, name) || strings.EqualFold(g.NameEN, name) {
+				if found == nil || g.CreatedAt.Before(found.CreatedAt) {
+					found = &g
+				}
+			}
+		}
+		if found == nil {
+			created, _ := ms.saveGenre(models.Genre{NameRU: name})
+			found = &created
+		}
+		if !slices.Contains(ms.bookGenres[bid], found.ID) {
+			ms.bookGenres[bid] = append(ms.bookGenres[bid], found.ID)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"slices"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+// GetBookHistory возвращает ревизии книги, начиная с последней, как DBStorage.
+func (ms *MemStorage) GetBookHistory(bid string) ([]models.BookRevision, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	history := slices.Clone(ms.history[bid])
+	if len(history) == 0 {
+		return nil, storerrros.ErrBookNoExist
+	}
+	slices.Reverse(history)
+	return history, nil
+}
+
+func (ms *MemStorage) RevertBook(bid string, revision int, actor string) (models.Book, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	book, ok := ms.liveBook(bid)
+	if !ok {
+		return models.Book{}, storerrros.ErrBookNoExist
+	}
+	i := slices.IndexFunc(ms.history[bid], func(rev models.BookRevision) bool { return rev.Revision == revision })
+	if i < 0 {
+		return models.Book{}, storerrros.ErrRevisionNoExist
+	}
+	target := ms.history[bid][i].Snapshot
+	reverted := target.Apply(book)
+	if ms.fileTaken(reverted, bid) {
+		return models.Book{}, storerrros.ErrDuplicateFile
+	}
+	if other, err := ms.getBookByISBN(reverted.ISBN); err == nil && other.BID != bid {
+		return models.Book{}, storerrros.ErrDuplicateISBN
+	}
+	reverted.Version++
+	reverted.UpdatedAt = createdNow()
+	ms.bookStor[bid] = reverted
+	if reverted.Author != book.Author {
+		ms.relinkAuthor(bid, reverted.Author)
+	}
+	if reverted.Genre != book.Genre {
+		ms.relinkGenres(bid, reverted.Genre)
+	}
+	ms.touchRelated(bid)
+	rev := newRevision(bid, models.HistoryRevert, actor, models.NewBookSnapshot(book), target)
+	rev.RevertedTo = revision
+	ms.addRevision(rev)
+	return ms.getBook(bid)
+}
+
+// addRevision дописывает ревизию в историю книги под следующим номером, как
+// addRevision в DBStorage.
+func (ms *MemStorage) addRevision(rev models.BookRevision) {
+	rev.Revision = len(ms.history[rev.BID]) + 1
+	rev.ChangedAt = createdNow()
+	ms.history[rev.BID] = append(ms.history[rev.BID], rev)
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"strings"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+)
+
+// веса полей повторяют веса A, B и C, которые ts_rank_cd дает по умолчанию.
+const (
+	weightLable  = 1.0
+	weightAuthor = 0.4
+	weightDesc   = 0.2
+
+	snippetWordsBefore = 10
+	snippetWordsAfter  = 15
+)
+
+// matchSearch - упрощенный аналог поиска DBStorage: книга подходит, если строка
+// целиком входит в название или автора, либо каждое слово запроса встречается
+// в названии, авторе или описании. Вторым значением возвращается релевантность.
+func matchSearch(book models.Book, search string) (float64, bool) {
+	search = strings.ToLower(search)
+	lable, author, desc := strings.ToLower(book.Lable), strings.ToLower(book.Author), strings.ToLower(book.Desc)
+	substring := strings.Contains(lable, search) || strings.Contains(author, search)
+
+	var rank float64
+	for _, term := range strings.Fields(search) {
+		hits := weightLable*float64(strings.Count(lable, term)) +
+			weightAuthor*float64(strings.Count(author, term)) +
+			weightDesc*float64(strings.Count(desc, term))
+		if hits == 0 && !substring {
+			return 0, false
+		}
+		rank += hits
+	}
+	return rank, true
+}
+
+// searchHighlights размечает совпавшие слова тегом <mark>, как ts_headline в DBStorage.
+func searchHighlights(book models.Book, search string) map[string]string {
+	terms := strings.Fields(strings.ToLower(search))
+	res := make(map[string]string)
+	if s, ok := markWords(strings.Fields(book.Lable), terms); ok {
+		res["lable"] = s
+	}
+	if s, ok := markWords(strings.Fields(book.Author), terms); ok {
+		res["author"] = s
+	}
+	words := strings.Fields(book.Desc)
+	for i, w := range words {
+		if containsAny(strings.ToLower(w), terms) {
+			from, to := max(0, i-snippetWordsBefore), min(len(words), i+snippetWordsAfter)
+			s, _ := markWords(words[from:to], terms)
+			res["desc"] = s
+			break
+		}
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
+func markWords(words []string, terms []string) (string, bool) {
+	marked := false
+	out := make([]string, len(words))
+	for i, w := range words {
+		if containsAny(strings.ToLower(w), terms) {
+			w = "<mark>" + w + "</mark>"
+			marked = true
+		}
+		out[i] = w
+	}
+	return strings.Join(out, " "), marked
+}
+
+func containsAny(s string, terms []string) bool {
+	for _, t := range terms {
+		if strings.Contains(s, t) {
+			return true
+		}
+	}
+	return false
+}
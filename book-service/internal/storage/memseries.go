@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+func (ms *MemStorage) SaveSeries(series models.Series) (models.Series, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	series.ID = uuid.New().String()
+	series.CreatedAt = createdNow()
+	series.Books, series.BookCount = nil, 0
+	ms.series[series.ID] = series
+	return series, nil
+}
+
+func (ms *MemStorage) GetSeries(id string) (models.Series, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	series, ok := ms.series[id]
+	if !ok {
+		return models.Series{}, storerrros.ErrSeriesNoExist
+	}
+	series.Books = ms.seriesBooks(id)
+	series.BookCount = len(series.Books)
+	return series, nil
+}
+
+func (ms *MemStorage) UpdateSeries(series models.Series) (models.Series, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	stored, ok := ms.series[series.ID]
+	if !ok {
+		return models.Series{}, storerrros.ErrSeriesNoExist
+	}
+	series.CreatedAt = stored.CreatedAt
+	series.Books = nil
+	ms.series[series.ID] = series
+	if series.Name != stored.Name {
+		ms.touchSeries(series.ID)
+	}
+	series.BookCount = len(ms.seriesBooks(series.ID))
+	return series, nil
+}
+
+func (ms *MemStorage) DeleteSeries(id string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.series[id]; !ok {
+		return storerrros.ErrSeriesNoExist
+	}
+	delete(ms.series, id)
+	for bid, entries := range ms.bookSeries {
+		if kept := slices.DeleteFunc(entries, func(e models.BookSeries) bool { return e.SeriesID == id }); len(kept) != len(entries) {
+			ms.bookSeries[bid] = kept
+			ms.touch(bid)
+		}
+	}
+	return nil
+}
+
+func (ms *MemStorage) SetBookSeries(bid string, entries []models.BookSeries) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.liveBook(bid); !ok {
+		return storerrros.ErrBookNoExist
+	}
+	links := make([]models.BookSeries, 0, len(entries))
+	for _, entry := range entries {
+		if _, ok := ms.series[entry.SeriesID]; !ok {
+			return storerrros.ErrSeriesNoExist
+		}
+		links = slices.DeleteFunc(links, func(e models.BookSeries) bool { return e.SeriesID == entry.SeriesID })
+		links = append(links, models.BookSeries{SeriesID: entry.SeriesID, Position: entry.Position})
+	}
+	for _, e := range ms.bookSeries[bid] {
+		ms.touchSeries(e.SeriesID)
+	}
+	ms.bookSeries[bid] = links
+	ms.touch(bid)
+	for _, e := range links {
+		ms.touchSeries(e.SeriesID)
+	}
+	return nil
+}
+
+// seriesBooks возвращает книги серии в порядке чтения, как seriesOrder в DBStorage.
+func (ms *MemStorage) seriesBooks(id string) []models.SeriesBook {
+	var books []models.SeriesBook
+	for bid, entries := range ms.bookSeries {
+		for _, e := range entries {
+			if book, ok := ms.liveBook(bid); ok && e.SeriesID == id {
+				books = append(books, models.SeriesBook{BID: bid, Lable: book.Lable, Author: book.Author, Position: e.Position})
+			}
+		}
+	}
+	slices.SortFunc(books, func(a, b models.SeriesBook) int {
+		return cmp.Or(cmp.Compare(a.Position, b.Position), cmp.Compare(a.Lable, b.Lable), cmp.Compare(a.BID, b.BID))
+	})
+	return books
+}
+
+// linkedSeries возвращает серии книги с соседними книгами, как bookSeries в DBStorage.
+func (ms *MemStorage) linkedSeries(bid string) []models.BookSeries {
+	var result []models.BookSeries
+	for _, e := range ms.bookSeries[bid] {
+		entry := models.BookSeries{SeriesID: e.SeriesID, Name: ms.series[e.SeriesID].Name, Position: e.Position}
+		books := ms.seriesBooks(e.SeriesID)
+		i := slices.IndexFunc(books, func(b models.SeriesBook) bool { return b.BID == bid })
+		if i > 0 {
+			prev := books[i-1]
+			entry.Prev = &models.SeriesBook{BID: prev.BID, Lable: prev.Lable, Position: prev.Position}
+		}
+		if i >= 0 && i < len(books)-1 {
+			next := books[i+1]
+			entry.Next = &models.SeriesBook{BID: next.BID, Lable: next.Lable, Position: next.Position}
+		}
+		result = append(result, entry)
+	}
+	slices.SortFunc(result, func(a, b models.BookSeries) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.SeriesID, b.SeriesID))
+	})
+	return result
+}
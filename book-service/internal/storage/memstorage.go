@@ -3,136 +3,445 @@ package storage
 import (
 	// "golang.org/x/crypto/bcrypt"
 	"github.com/google/uuid"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
 	"github.com/azaliaz/bookly/book-service/internal/domain/models"
 	"github.com/azaliaz/bookly/book-service/internal/logger"
 	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
 )
 
+// MemStorage хранит каталог в памяти процесса. Все экспортируемые методы берут
+// mu, поэтому хранилище можно использовать из параллельных запросов; внутренние
+// методы рассчитывают, что mu уже взят.
 type MemStorage struct {
-	bookStor map[string]models.Book
+	mu          sync.RWMutex
+	bookStor    map[string]models.Book
+	downloads   []models.Download
+	authors     map[string]models.Author
+	bookAuthors map[string][]models.BookAuthor
+	genres      map[string]models.Genre
+	bookGenres  map[string][]string
+	series      map[string]models.Series
+	bookSeries  map[string][]models.BookSeries
+	works       map[string]models.Work
+	tags        map[string]models.Tag
+	bookTags    map[string][]memBookTag
+	history     map[string][]models.BookRevision
 }
 
 func New() *MemStorage {
 	return &MemStorage{
-		bookStor: make(map[string]models.Book),
+		bookStor:    make(map[string]models.Book),
+		authors:     make(map[string]models.Author),
+		bookAuthors: make(map[string][]models.BookAuthor),
+		genres:      make(map[string]models.Genre),
+		bookGenres:  make(map[string][]string),
+		series:      make(map[string]models.Series),
+		bookSeries:  make(map[string][]models.BookSeries),
+		works:       make(map[string]models.Work),
+		tags:        make(map[string]models.Tag),
+		bookTags:    make(map[string][]memBookTag),
+		history:     make(map[string][]models.BookRevision),
 	}
 }
 
-func (ms *MemStorage) SaveBook(book models.Book) error {
-	memBook, err := ms.findBook(book)
-	if err == nil {
-		ms.bookStor[memBook.BID] = memBook
+func (ms *MemStorage) SaveBook(book models.Book, actor string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.findBID(book); ok {
 		return nil
 	}
+	if ms.fileTaken(book, "") {
+		return storerrros.ErrDuplicateFile
+	}
+	workID, err := ms.bookWork(book)
+	if err != nil {
+		return err
+	}
 	bid := uuid.New().String()
+	book.BID = bid
+	book.WorkID = workID
+	book.Version = 1
+	book.CreatedAt = createdNow()
+	book.UpdatedAt = book.CreatedAt
 	ms.bookStor[bid] = book
+	ms.linkAuthor(bid, book.Author)
+	ms.linkGenres(bid, book.Genre)
+	ms.touchRelated(bid)
+	ms.addRevision(newRevision(bid, models.HistoryCreate, actor, models.BookSnapshot{}, models.NewBookSnapshot(book)))
 	return nil
 }
 
-func (ms *MemStorage) SaveBooks(_ []models.Book) error {
-	return nil
+func (ms *MemStorage) SaveBooks(books []models.Book, actor string) ([]models.SaveStatus, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	statuses := make([]models.SaveStatus, 0, len(books))
+	for _, book := range books {
+		if bid, ok := ms.findBID(book); ok {
+			statuses = append(statuses, models.SaveStatus{BID: bid, Duplicate: true})
+			continue
+		}
+		if ms.fileTaken(book, "") {
+			return nil, storerrros.ErrDuplicateFile
+		}
+		workID, err := ms.bookWork(book)
+		if err != nil {
+			return nil, err
+		}
+		bid := uuid.New().String()
+		book.BID = bid
+		book.WorkID = workID
+		book.Version = 1
+		book.CreatedAt = createdNow()
+		book.UpdatedAt = book.CreatedAt
+		ms.bookStor[bid] = book
+		ms.linkAuthor(bid, book.Author)
+		ms.linkGenres(bid, book.Genre)
+		ms.touchRelated(bid)
+		ms.addRevision(newRevision(bid, models.HistoryCreate, actor, models.BookSnapshot{}, models.NewBookSnapshot(book)))
+		statuses = append(statuses, models.SaveStatus{BID: bid})
+	}
+	return statuses, nil
 }
 
-func (ms *MemStorage) GetBooks() ([]models.Book, error) {
-	var books []models.Book
-	for _, book := range ms.bookStor {
+// createdNow возвращает время добавления книги с точностью timestamptz,
+// чтобы курсор по created_at вел себя так же, как в DBStorage.
+func createdNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// touch сдвигает UpdatedAt книги bid, как триггеры на books и таблицах связей
+// в DBStorage.
+func (ms *MemStorage) touch(bid string) {
+	if book, ok := ms.bookStor[bid]; ok {
+		book.UpdatedAt = createdNow()
+		ms.bookStor[bid] = book
+	}
+}
+
+// touchRelated сдвигает UpdatedAt книг, в карточках которых видна книга bid: других
+// изданий ее произведения и соседей по ее сериям, как триггер books_touch_related.
+func (ms *MemStorage) touchRelated(bid string) {
+	ms.touchWork(ms.bookStor[bid].WorkID)
+	for _, e := range ms.bookSeries[bid] {
+		ms.touchSeries(e.SeriesID)
+	}
+}
+
+// touchWork сдвигает UpdatedAt всех изданий произведения workID.
+func (ms *MemStorage) touchWork(workID string) {
+	for bid, book := range ms.bookStor {
+		if book.WorkID == workID {
+			ms.touch(bid)
+		}
+	}
+}
+
+// touchSeries сдвигает UpdatedAt всех книг серии id.
+func (ms *MemStorage) touchSeries(id string) {
+	for bid, entries := range ms.bookSeries {
+		if slices.ContainsFunc(entries, func(e models.BookSeries) bool { return e.SeriesID == id }) {
+			ms.touch(bid)
+		}
+	}
+}
+
+// findBID ищет ту же книгу по правилам findDuplicate из DBStorage и возвращает ее
+// ключ в хранилище.
+func (ms *MemStorage) findBID(value models.Book) (string, bool) {
+	for bid, book := range ms.bookStor {
+		if book.DeletedAt == nil && sameBook(book, value) {
+			return bid, true
+		}
+	}
+	return "", false
+}
+
+// sameBook сравнивает книги по ISBN, а книги без ISBN - по названию и автору.
+func sameBook(book, value models.Book) bool {
+	if value.ISBN != "" {
+		return book.ISBN == value.ISBN
+	}
+	return titleKey(book.Lable) == titleKey(value.Lable) && titleKey(book.Author) == titleKey(value.Author)
+}
+
+// titleKey повторяет SQL-функцию book_title_key: нижний регистр, пунктуация и
+// пробелы схлопываются в один пробел.
+func titleKey(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// GetBooks возвращает страницу каталога; пустой каталог - пустая страница, как в DBStorage.
+func (ms *MemStorage) GetBooks(page models.Page) (models.BooksPage, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return ms.paginate(ms.books(), models.BookFilter{Ascending: true, Page: page})
+}
+
+// liveBook возвращает книгу bid с заполненным BID, если она есть в каталоге и не
+// в корзине.
+func (ms *MemStorage) liveBook(bid string) (models.Book, bool) {
+	book, ok := ms.bookStor[bid]
+	if !ok || book.DeletedAt != nil {
+		return models.Book{}, false
+	}
+	book.BID = bid
+	return book, true
+}
+
+// inTrash сообщает, что книга bid лежит в корзине.
+func (ms *MemStorage) inTrash(bid string) bool {
+	return ms.bookStor[bid].DeletedAt != nil
+}
+
+// books возвращает копию книг каталога без корзины с заполненным BID.
+func (ms *MemStorage) books() []models.Book {
+	books := make([]models.Book, 0, len(ms.bookStor))
+	for bid, book := range ms.bookStor {
+		if book.DeletedAt != nil {
+			continue
+		}
+		book.BID = bid
+		book.Popularity = ms.workPopularity(book.WorkID)
 		books = append(books, book)
 	}
-	if len(books) < 1 {
-		return nil, storerrros.ErrEmptyBooksList
+	return books
+}
+
+// paginate сортирует книги так же, как ORDER BY <колонка>, bid в DBStorage,
+// и вырезает страницу после курсора.
+func (ms *MemStorage) paginate(books []models.Book, filter models.BookFilter) (models.BooksPage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = consts.DefaultPageLimit
+	}
+	column, ascending := resolveSort(filter)
+
+	sort.Slice(books, func(i, j int) bool {
+		c := compareBooks(books[i], books[j], column)
+		if ascending {
+			return c < 0
+		}
+		return c > 0
+	})
+
+	page := models.BooksPage{Total: len(books)}
+	if filter.Cursor != "" {
+		cur, err := decodeCursor(filter.Cursor, column, ascending)
+		if err != nil {
+			return models.BooksPage{}, err
+		}
+		start := sort.Search(len(books), func(i int) bool { return afterCursor(books[i], cur) })
+		books = books[start:]
 	}
-	return books, nil
+	if len(books) > limit {
+		books = books[:limit]
+		page.NextCursor = newCursor(column, ascending, books[limit-1]).encode()
+	}
+	page.Books = books
+	return page, nil
 }
 
 func (ms *MemStorage) GetBook(bid string) (models.Book, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return ms.getBook(bid)
+}
+
+func (ms *MemStorage) getBook(bid string) (models.Book, error) {
 	log := logger.Get()
-	book, ok := ms.bookStor[bid]
+	book, ok := ms.liveBook(bid)
 	if !ok {
 		log.Error().Str("bid", bid).Msg("user not found")
 		return models.Book{}, storerrros.ErrBookNoExist
 	}
+	book.BID = bid
+	book.Authors = ms.linkedAuthors(bid)
+	book.Genres = ms.linkedGenres(bid)
+	book.Series = ms.linkedSeries(bid)
+	book.Tags = ms.linkedTags(bid)
+	book.Popularity = ms.workPopularity(book.WorkID)
+	book.Editions = otherEditions(ms.workEditions(book.WorkID), bid)
+	return book, nil
+}
+
+func (ms *MemStorage) UpdateBook(book models.Book, version int, actor string) (models.Book, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	stored, ok := ms.liveBook(book.BID)
+	if !ok {
+		return models.Book{}, storerrros.ErrBookNoExist
+	}
+	if stored.Version != version {
+		return models.Book{}, storerrros.ErrVersionConflict
+	}
+	if ms.fileTaken(book, book.BID) {
+		return models.Book{}, storerrros.ErrDuplicateFile
+	}
+	if other, err := ms.getBookByISBN(book.ISBN); err == nil && other.BID != book.BID {
+		return models.Book{}, storerrros.ErrDuplicateISBN
+	}
+	book.Version = version + 1
+	book.WorkID = stored.WorkID
+	book.CreatedAt, book.UpdatedAt = stored.CreatedAt, createdNow()
+	// связи хранятся в bookAuthors, bookGenres, bookSeries и bookTags, издания и
+	// популярность считаются по произведению
+	book.Authors, book.Genres, book.Series, book.Tags = nil, nil, nil, nil
+	book.Editions, book.Popularity = nil, 0
+	ms.bookStor[book.BID] = book
+	if book.Author != stored.Author {
+		ms.relinkAuthor(book.BID, book.Author)
+	}
+	if book.Genre != stored.Genre {
+		ms.relinkGenres(book.BID, book.Genre)
+	}
+	ms.touchRelated(book.BID)
+	ms.addRevision(newRevision(book.BID, models.HistoryUpdate, actor,
+		models.NewBookSnapshot(stored), models.NewBookSnapshot(book)))
 	return book, nil
 }
 
-func (ms *MemStorage) findBook(value models.Book) (models.Book, error) {
-	for _, book := range ms.bookStor {
-		if book.Lable == value.Lable && book.Author == value.Author {
+// GetBookByFileHash возвращает книгу, к которой загружен PDF или EPUB с хешем hash.
+func (ms *MemStorage) GetBookByFileHash(hash string) (models.Book, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return ms.getBookByFileHash(hash)
+}
+
+func (ms *MemStorage) getBookByFileHash(hash string) (models.Book, error) {
+	for bid, book := range ms.bookStor {
+		if hash != "" && book.DeletedAt == nil && (book.PDFHash == hash || book.EPUBHash == hash) {
+			book.BID = bid
 			return book, nil
 		}
 	}
 	return models.Book{}, storerrros.ErrBookNoExist
 }
 
-func (ms *MemStorage) DeleteBook(bid string) error {
-	log := logger.Get()
-	if _, exists := ms.bookStor[bid]; !exists {
-		log.Warn().Str("bid", bid).Msg("book not found")
-		return storerrros.ErrBookNoExist
+// GetBookByISBN возвращает книгу по нормализованному ISBN-13.
+func (ms *MemStorage) GetBookByISBN(isbn string) (models.Book, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return ms.getBookByISBN(isbn)
+}
+
+func (ms *MemStorage) getBookByISBN(isbn string) (models.Book, error) {
+	for bid, book := range ms.bookStor {
+		if isbn != "" && book.DeletedAt == nil && book.ISBN == isbn {
+			return ms.getBook(bid)
+		}
+	}
+	return models.Book{}, storerrros.ErrBookNoExist
+}
+
+// fileTaken сообщает, что PDF или EPUB книги уже загружен к книге, отличной от bid.
+func (ms *MemStorage) fileTaken(book models.Book, bid string) bool {
+	for _, hash := range []string{book.PDFHash, book.EPUBHash} {
+		if other, err := ms.getBookByFileHash(hash); err == nil && other.BID != bid {
+			return true
+		}
 	}
-	delete(ms.bookStor, bid)
-	log.Info().Str("bid", bid).Msg("book deleted successfully")
+	return false
+}
 
+func (ms *MemStorage) LogDownload(download models.Download) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.downloads = append(ms.downloads, download)
+	if book, ok := ms.bookStor[download.BID]; ok {
+		ms.touchWork(book.WorkID)
+	}
 	return nil
 }
-func (ms *MemStorage) GetBooksWithFilters(search string, genres []string, year string, sortBy string, ascending bool) ([]models.Book, error) {
-	search = strings.ToLower(search)
+
+func (ms *MemStorage) GetBooksWithFilters(filter models.BookFilter) (models.BooksPage, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	result := ms.filterBooks(filter)
+	if len(result) == 0 {
+		return models.BooksPage{}, storerrros.ErrEmptyBooksList
+	}
+
+	return ms.paginate(result, filter)
+}
+
+// filterBooks отбирает книги по условиям фильтра без учета пагинации.
+func (ms *MemStorage) filterBooks(filter models.BookFilter) []models.Book {
 	var result []models.Book
+	var genres map[string]bool
+	if values := genreValues(filter.Genres); len(values) > 0 {
+		genres = ms.genreTree(values)
+	}
 
-	for _, book := range ms.bookStor {
-		if search != "" && !(strings.Contains(strings.ToLower(book.Author), search) || strings.Contains(strings.ToLower(book.Lable), search)) {
+	for _, book := range ms.books() {
+		if filter.Search != "" {
+			rank, ok := matchSearch(book, filter.Search)
+			if !ok {
+				continue
+			}
+			book.Rank = rank
+			book.Highlights = searchHighlights(book, filter.Search)
+		}
+
+		if genres != nil && !slices.ContainsFunc(ms.bookGenres[book.BID], func(id string) bool { return genres[id] }) {
 			continue
 		}
 
-		if len(genres) > 0 {
-			matched := false
-			for _, g := range genres {
-				if strings.EqualFold(book.Genre, g) {
-					matched = true
-					break
-				}
-			}
-			if !matched {
-				continue
-			}
+		if filter.Author != "" && book.Author != filter.Author {
+			continue
 		}
 
-		if year != "" && strconv.Itoa(book.Age) != year {
+		if filter.AuthorID != "" && !ms.hasAuthor(book.BID, filter.AuthorID, filter.AuthorRole) {
 			continue
 		}
 
-		result = append(result, book)
-	}
+		if tags := tagValues(filter.Tags); len(tags) > 0 && !ms.hasTags(book.BID, tags, filter.TagsAll) {
+			continue
+		}
 
-	if len(result) == 0 {
-		return nil, storerrros.ErrEmptyBooksList
-	}
-
-	sort.Slice(result, func(i, j int) bool {
-		var less bool
-		switch sortBy {
-		case "author":
-			less = result[i].Author < result[j].Author
-		case "lable", "label":
-			less = result[i].Lable < result[j].Lable
-		case "rating":
-			less = result[i].Rating < result[j].Rating
-		case "age":
-			less = result[i].Age < result[j].Age
-		case "genre":
-			less = result[i].Genre < result[j].Genre
-		default:
-			return true
+		if filter.Year != "" {
+			if from, to, ok := parseYearRange(filter.Year); ok {
+				if book.Age < from || book.Age > to {
+					continue
+				}
+			} else if strconv.Itoa(book.Age) != filter.Year {
+				continue
+			}
 		}
 
+		result = append(result, book)
+	}
+	return result
+}
+
+// StreamBooks передает в fn отфильтрованные книги в порядке сортировки фильтра.
+// Книги отбираются под mu, а fn вызывается уже без него, чтобы медленный
+// получатель не задерживал запись.
+func (ms *MemStorage) StreamBooks(filter models.BookFilter, fn func(models.Book) error) error {
+	ms.mu.RLock()
+	books := ms.filterBooks(filter)
+	ms.mu.RUnlock()
+	column, ascending := resolveSort(filter)
+	sort.Slice(books, func(i, j int) bool {
+		c := compareBooks(books[i], books[j], column)
 		if ascending {
-			return less
+			return c < 0
 		}
-		return !less
+		return c > 0
 	})
-
-	return result, nil
+	for _, book := range books {
+		if err := fn(book); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/translit"
+)
+
+// trigramThreshold совпадает с pg_trgm.similarity_threshold по умолчанию.
+const trigramThreshold = 0.3
+
+// SuggestBooks - in-process аналог DBStorage.SuggestBooks.
+func (ms *MemStorage) SuggestBooks(query string, limit int) ([]models.Suggestion, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	variants := translit.Variants(query)
+	authors := make(map[string]float64)
+	var suggestions []models.Suggestion
+
+	for bid, book := range ms.bookStor {
+		if book.DeletedAt != nil {
+			continue
+		}
+		if score, ok := suggestScore(book.Lable, variants); ok {
+			suggestions = append(suggestions, models.Suggestion{Kind: consts.SuggestKindTitle, Text: book.Lable, BID: bid, Score: score})
+		}
+		if score, ok := suggestScore(book.Author, variants); ok {
+			authors[book.Author] = max(authors[book.Author], score)
+		}
+	}
+	for author, score := range authors {
+		suggestions = append(suggestions, models.Suggestion{Kind: consts.SuggestKindAuthor, Text: author, Score: score})
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Text < suggestions[j].Text
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// suggestScore считает оценку так же, как запрос DBStorage: лучшее триграммное
+// сходство с вариантами запроса плюс единица за совпадение по префиксу слова.
+func suggestScore(text string, variants []string) (float64, bool) {
+	lower := strings.ToLower(text)
+	var best float64
+	prefix := false
+	for _, v := range variants {
+		best = max(best, similarity(lower, v))
+		if strings.HasPrefix(lower, v) || strings.Contains(lower, " "+v) {
+			prefix = true
+		}
+	}
+	if !prefix && best < trigramThreshold {
+		return 0, false
+	}
+	if prefix {
+		best++
+	}
+	return best, true
+}
+
+// similarity повторяет similarity() из pg_trgm: доля общих триграмм слов,
+// дополненных двумя пробелами в начале и одним в конце.
+func similarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	common := 0
+	for t := range ta {
+		if _, ok := tb[t]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(ta)+len(tb)-common)
+}
+
+func trigrams(s string) map[string]struct{} {
+	set := make(map[string]struct{})
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		r := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			set[string(r[i:i+3])] = struct{}{}
+		}
+	}
+	return set
+}
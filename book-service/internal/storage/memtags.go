@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+// memBookTag - связь книги с тегом, как строка book_tags.
+type memBookTag struct {
+	TagID       string
+	Status      string
+	SuggestedBy string
+	CreatedAt   time.Time
+}
+
+// GetTags возвращает облако тегов, как DBStorage.
+func (ms *MemStorage) GetTags(limit int) ([]models.Tag, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	counts := make(map[string]int)
+	for bid, links := range ms.bookTags {
+		for _, link := range links {
+			if link.Status == models.TagApproved && !ms.inTrash(bid) {
+				counts[link.TagID]++
+			}
+		}
+	}
+	tags := []models.Tag{}
+	for id, count := range counts {
+		tag := ms.tags[id]
+		tag.BookCount = count
+		tags = append(tags, tag)
+	}
+	slices.SortFunc(tags, func(a, b models.Tag) int {
+		return cmp.Or(cmp.Compare(b.BookCount, a.BookCount), strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)))
+	})
+	if limit > 0 && len(tags) > limit {
+		tags = tags[:limit]
+	}
+	return tags, nil
+}
+
+func (ms *MemStorage) DeleteTag(id string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.tags[id]; !ok {
+		return storerrros.ErrTagNoExist
+	}
+	delete(ms.tags, id)
+	for bid, links := range ms.bookTags {
+		if kept := slices.DeleteFunc(links, func(link memBookTag) bool { return link.TagID == id }); len(kept) != len(links) {
+			ms.bookTags[bid] = kept
+			ms.touch(bid)
+		}
+	}
+	return nil
+}
+
+func (ms *MemStorage) SetBookTags(bid string, names []string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.liveBook(bid); !ok {
+		return storerrros.ErrBookNoExist
+	}
+	links := slices.DeleteFunc(ms.bookTags[bid], func(link memBookTag) bool { return link.Status == models.TagApproved })
+	for _, name := range names {
+		id := ms.tagID(name)
+		if i := slices.IndexFunc(links, func(link memBookTag) bool { return link.TagID == id }); i >= 0 {
+			links[i].Status = models.TagApproved
+			continue
+		}
+		links = append(links, memBookTag{TagID: id, Status: models.TagApproved, CreatedAt: createdNow()})
+	}
+	ms.bookTags[bid] = links
+	ms.touch(bid)
+	return nil
+}
+
+func (ms *MemStorage) SuggestBookTag(bid, uid, name string) (models.TagSuggestion, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	book, ok := ms.liveBook(bid)
+	if !ok {
+		return models.TagSuggestion{}, storerrros.ErrBookNoExist
+	}
+	id := ms.tagID(name)
+	if slices.ContainsFunc(ms.bookTags[bid], func(link memBookTag) bool { return link.TagID == id }) {
+		return models.TagSuggestion{}, storerrros.ErrTagExists
+	}
+	link := memBookTag{TagID: id, Status: models.TagPending, SuggestedBy: uid, CreatedAt: createdNow()}
+	ms.bookTags[bid] = append(ms.bookTags[bid], link)
+	ms.touch(bid)
+	return models.TagSuggestion{
+		BID: bid, Lable: book.Lable, TagID: id, Name: ms.tags[id].Name, SuggestedBy: uid, CreatedAt: link.CreatedAt,
+	}, nil
+}
+
+// GetTagSuggestions возвращает очередь модерации, как DBStorage.
+func (ms *MemStorage) GetTagSuggestions() ([]models.TagSuggestion, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	suggestions := []models.TagSuggestion{}
+	for bid, links := range ms.bookTags {
+		for _, link := range links {
+			if link.Status != models.TagPending || ms.inTrash(bid) {
+				continue
+			}
+			suggestions = append(suggestions, models.TagSuggestion{
+				BID: bid, Lable: ms.bookStor[bid].Lable, TagID: link.TagID, Name: ms.tags[link.TagID].Name,
+				SuggestedBy: link.SuggestedBy, CreatedAt: link.CreatedAt,
+			})
+		}
+	}
+	slices.SortFunc(suggestions, func(a, b models.TagSuggestion) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.BID, b.BID), strings.Compare(a.TagID, b.TagID))
+	})
+	return suggestions, nil
+}
+
+func (ms *MemStorage) ApproveBookTag(bid, tagID string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	links := ms.bookTags[bid]
+	i := slices.IndexFunc(links, func(link memBookTag) bool { return link.TagID == tagID })
+	if i < 0 {
+		return storerrros.ErrTagNoExist
+	}
+	links[i].Status = models.TagApproved
+	ms.touch(bid)
+	return nil
+}
+
+func (ms *MemStorage) RemoveBookTag(bid, tagID string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	links := ms.bookTags[bid]
+	i := slices.IndexFunc(links, func(link memBookTag) bool { return link.TagID == tagID })
+	if i < 0 {
+		return storerrros.ErrTagNoExist
+	}
+	ms.bookTags[bid] = slices.Delete(links, i, i+1)
+	ms.touch(bid)
+	return nil
+}
+
+// tagID возвращает id тега с названием name без учета регистра, создавая тег при
+// необходимости, как tagID в DBStorage.
+func (ms *MemStorage) tagID(name string) string {
+	for id, tag := range ms.tags {
+		if strings.EqualFold(tag.Name, name) {
+			return id
+		}
+	}
+	tag := models.Tag{ID: uuid.New().String(), Name: name}
+	ms.tags[tag.ID] = tag
+	return tag.ID
+}
+
+// linkedTags возвращает одобренные теги книги по названию, как bookTags в DBStorage.
+func (ms *MemStorage) linkedTags(bid string) []models.BookTag {
+	var tags []models.BookTag
+	for _, link := range ms.bookTags[bid] {
+		if link.Status == models.TagApproved {
+			tags = append(tags, models.BookTag{TagID: link.TagID, Name: ms.tags[link.TagID].Name})
+		}
+	}
+	slices.SortFunc(tags, func(a, b models.BookTag) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	return tags
+}
+
+// hasTags проверяет одобренные теги книги по значениям из tagValues: все, если
+// all, иначе хотя бы один.
+func (ms *MemStorage) hasTags(bid string, values []string, all bool) bool {
+	matched := 0
+	for _, tag := range ms.linkedTags(bid) {
+		if slices.Contains(values, strings.ToLower(tag.Name)) {
+			matched++
+		}
+	}
+	if all {
+		return matched == len(values)
+	}
+	return matched > 0
+}
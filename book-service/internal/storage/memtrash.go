@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	"github.com/azaliaz/bookly/book-service/internal/logger"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+func (ms *MemStorage) DeleteBook(bid string, uid string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	log := logger.Get()
+	book, ok := ms.liveBook(bid)
+	if !ok {
+		log.Warn().Str("bid", bid).Msg("book not found")
+		return storerrros.ErrBookNoExist
+	}
+	deletedAt := createdNow()
+	book.DeletedAt, book.DeletedBy = &deletedAt, uid
+	book.UpdatedAt = deletedAt
+	book.Version++
+	ms.bookStor[bid] = book
+	ms.touchRelated(bid)
+	snapshot := models.NewBookSnapshot(book)
+	ms.addRevision(newRevision(bid, models.HistoryDelete, uid, snapshot, snapshot))
+	log.Info().Str("bid", bid).Str("uid", uid).Msg("book moved to trash")
+	return nil
+}
+
+// GetTrash возвращает книги в корзине, начиная с удаленных последними, как DBStorage.
+func (ms *MemStorage) GetTrash() ([]models.Book, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	books := []models.Book{}
+	for bid, book := range ms.bookStor {
+		if book.DeletedAt != nil {
+			book.BID = bid
+			books = append(books, book)
+		}
+	}
+	slices.SortFunc(books, func(a, b models.Book) int {
+		return cmp.Or(b.DeletedAt.Compare(*a.DeletedAt), strings.Compare(a.BID, b.BID))
+	})
+	return books, nil
+}
+
+func (ms *MemStorage) RestoreBook(bid string, uid string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	book, ok := ms.bookStor[bid]
+	if !ok || book.DeletedAt == nil {
+		return storerrros.ErrBookNoExist
+	}
+	if ms.fileTaken(book, bid) {
+		return storerrros.ErrDuplicateFile
+	}
+	if _, err := ms.getBookByISBN(book.ISBN); err == nil {
+		return storerrros.ErrDuplicateISBN
+	}
+	book.DeletedAt, book.DeletedBy = nil, ""
+	book.UpdatedAt = createdNow()
+	book.Version++
+	ms.bookStor[bid] = book
+	ms.touchRelated(bid)
+	snapshot := models.NewBookSnapshot(book)
+	ms.addRevision(newRevision(bid, models.HistoryRestore, uid, snapshot, snapshot))
+	return nil
+}
+
+// PurgeBooks окончательно удаляет книги, попавшие в корзину раньше before, как DBStorage.
+func (ms *MemStorage) PurgeBooks(before time.Time) ([]models.Book, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	var purged []models.Book
+	for bid, book := range ms.bookStor {
+		if book.DeletedAt == nil || !book.DeletedAt.Before(before) {
+			continue
+		}
+		delete(ms.bookStor, bid)
+		delete(ms.bookAuthors, bid)
+		delete(ms.bookGenres, bid)
+		delete(ms.bookSeries, bid)
+		delete(ms.bookTags, bid)
+		// вместе с книгой удаляются ее скачивания, и популярность произведения падает
+		ms.touchWork(book.WorkID)
+		ms.dropEmptyWork(book.WorkID)
+		snapshot := models.NewBookSnapshot(book)
+		ms.addRevision(newRevision(bid, models.HistoryPurge, "", snapshot, snapshot))
+		book.BID = bid
+		purged = append(purged, book)
+	}
+	return purged, nil
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/models"
+	storerrros "github.com/azaliaz/bookly/book-service/internal/storage/errors"
+)
+
+func (ms *MemStorage) GetWork(id string) (models.Work, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	work, ok := ms.works[id]
+	if !ok {
+		return models.Work{}, storerrros.ErrWorkNoExist
+	}
+	work.Popularity = ms.workPopularity(id)
+	work.Editions = ms.workEditions(id)
+	return work, nil
+}
+
+func (ms *MemStorage) SetBookWork(bid string, workID string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	book, ok := ms.liveBook(bid)
+	if !ok {
+		return storerrros.ErrBookNoExist
+	}
+	if workID == "" {
+		workID = ms.createWork(book)
+	} else if _, ok := ms.works[workID]; !ok {
+		return storerrros.ErrWorkNoExist
+	}
+	previous := book.WorkID
+	book.WorkID = workID
+	book.UpdatedAt = createdNow()
+	ms.bookStor[bid] = book
+	ms.touchWork(previous)
+	ms.touchRelated(bid)
+	ms.dropEmptyWork(previous)
+	return nil
+}
+
+// bookWork повторяет bookWork из DBStorage: заданное произведение, произведение
+// изданий с тем же названием и автором или новое.
+func (ms *MemStorage) bookWork(book models.Book) (string, error) {
+	if book.WorkID != "" {
+		if _, ok := ms.works[book.WorkID]; !ok {
+			return "", storerrros.ErrWorkNoExist
+		}
+		return book.WorkID, nil
+	}
+	var first *models.Book
+	for _, other := range ms.bookStor {
+		if titleKey(other.Lable) != titleKey(book.Lable) || titleKey(other.Author) != titleKey(book.Author) {
+			continue
+		}
+		if first == nil || other.CreatedAt.Before(first.CreatedAt) {
+			first = &other
+		}
+	}
+	if first != nil {
+		return first.WorkID, nil
+	}
+	return ms.createWork(book), nil
+}
+
+func (ms *MemStorage) createWork(book models.Book) string {
+	work := models.Work{ID: uuid.New().String(), Title: book.Lable, Author: book.Author, CreatedAt: createdNow()}
+	ms.works[work.ID] = work
+	return work.ID
+}
+
+func (ms *MemStorage) dropEmptyWork(id string) {
+	for _, book := range ms.bookStor {
+		if book.WorkID == id {
+			return
+		}
+	}
+	delete(ms.works, id)
+}
+
+// workEditions возвращает издания произведения по году, затем по названию.
+func (ms *MemStorage) workEditions(workID string) []models.Edition {
+	var editions []models.Edition
+	for bid, book := range ms.bookStor {
+		if book.WorkID != workID || book.DeletedAt != nil {
+			continue
+		}
+		editions = append(editions, models.Edition{
+			BID: bid, Lable: book.Lable, Language: book.Language, Publisher: book.Publisher,
+			Year: book.Age, ISBN: book.ISBN,
+		})
+	}
+	slices.SortFunc(editions, func(a, b models.Edition) int {
+		return cmp.Or(cmp.Compare(a.Year, b.Year), strings.Compare(a.Lable, b.Lable), strings.Compare(a.BID, b.BID))
+	})
+	return editions
+}
+
+// workPopularity считает скачивания всех изданий произведения.
+func (ms *MemStorage) workPopularity(workID string) int {
+	count := 0
+	for _, download := range ms.downloads {
+		if book, ok := ms.bookStor[download.BID]; ok && book.WorkID == workID {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,110 @@
+// Package translit переводит строки между кириллицей и латиницей, чтобы поиск
+// находил «Dostoevsky» по запросу «Достоевский» и наоборот.
+package translit
+
+import (
+	"strings"
+	"unicode"
+)
+
+var cyrToLat = map[rune]string{ //nolint:gochecknoglobals // таблица транслитерации
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "",
+	'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// latToCyr перебирается по порядку, поэтому длинные сочетания идут раньше коротких.
+var latToCyr = []struct{ lat, cyr string }{ //nolint:gochecknoglobals // таблица транслитерации
+	{"shch", "щ"}, {"sch", "щ"}, {"iy", "ий"}, {"yy", "ый"}, {"zh", "ж"}, {"kh", "х"}, {"ts", "ц"},
+	{"ch", "ч"}, {"sh", "ш"}, {"yu", "ю"}, {"ya", "я"}, {"yo", "ё"}, {"ye", "е"},
+	{"a", "а"}, {"b", "б"}, {"c", "к"}, {"d", "д"}, {"e", "е"}, {"f", "ф"},
+	{"g", "г"}, {"h", "х"}, {"i", "и"}, {"j", "й"}, {"k", "к"}, {"l", "л"},
+	{"m", "м"}, {"n", "н"}, {"o", "о"}, {"p", "п"}, {"q", "к"}, {"r", "р"},
+	{"s", "с"}, {"t", "т"}, {"u", "у"}, {"v", "в"}, {"w", "в"}, {"x", "кс"},
+	{"y", "й"}, {"z", "з"},
+}
+
+// ToLatin транслитерирует кириллицу в латиницу; остальные символы не меняются.
+func ToLatin(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if lat, ok := cyrToLat[r]; ok {
+			b.WriteString(lat)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ToCyrillic транслитерирует латиницу в кириллицу; остальные символы не меняются.
+func ToCyrillic(s string) string {
+	s = strings.ToLower(s)
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		// окончание -sky/-ky пишется как «-ский»/«-кий»: Dostoevsky -> достоевский
+		if s[i] == 'y' && i > 0 && strings.IndexByte(vowels, s[i-1]) < 0 && wordEnds(s, i+1) {
+			b.WriteString("ий")
+			i++
+			continue
+		}
+		matched := false
+		for _, p := range latToCyr {
+			if strings.HasPrefix(s[i:], p.lat) {
+				b.WriteString(p.cyr)
+				i += len(p.lat)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			r := []rune(s[i:])[0]
+			b.WriteRune(r)
+			i += len(string(r))
+		}
+	}
+	return b.String()
+}
+
+const vowels = "aeiouy"
+
+func wordEnds(s string, i int) bool {
+	if i >= len(s) {
+		return true
+	}
+	r := []rune(s[i:])[0]
+	return !unicode.IsLetter(r)
+}
+
+// Variants возвращает запрос в нижнем регистре и его транслитерации без повторов.
+func Variants(s string) []string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	variants := []string{s}
+	if hasScript(s, unicode.Cyrillic) {
+		variants = appendUnique(variants, ToLatin(s))
+	}
+	if hasScript(s, unicode.Latin) {
+		variants = appendUnique(variants, ToCyrillic(s))
+	}
+	return variants
+}
+
+func hasScript(s string, table *unicode.RangeTable) bool {
+	for _, r := range s {
+		if unicode.Is(table, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
@@ -0,0 +1,112 @@
+package users
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/azaliaz/bookly/book-service/internal/domain/consts"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid login or password")
+	ErrUnavailable        = errors.New("user-service unavailable")
+)
+
+type cachedToken struct {
+	token   string
+	expires time.Time
+}
+
+// Client проверяет логин и пароль через POST /users/login в user-service и
+// возвращает выданный там JWT. Удачные проверки кэшируются на consts.BasicAuthCacheTTL:
+// OPDS-клиент присылает Basic на каждый запрос, а user-service проверяет пароль через bcrypt.
+type Client struct {
+	addr string
+	http *http.Client
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]cachedToken
+}
+
+func New(addr string) *Client {
+	return &Client{
+		addr:  strings.TrimRight(addr, "/"),
+		http:  &http.Client{Timeout: consts.UsersCtxTimeout},
+		cache: make(map[[sha256.Size]byte]cachedToken),
+	}
+}
+
+func (c *Client) Login(email, pass string) (string, error) {
+	key := sha256.Sum256([]byte(email + "\x00" + pass))
+	now := time.Now()
+
+	c.mu.Lock()
+	cached, ok := c.cache[key]
+	c.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.token, nil
+	}
+
+	token, err := c.login(email, pass)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, v := range c.cache {
+		if now.After(v.expires) {
+			delete(c.cache, k)
+		}
+	}
+	c.cache[key] = cachedToken{token: token, expires: now.Add(consts.BasicAuthCacheTTL)}
+	return token, nil
+}
+
+func (c *Client) login(email, pass string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), consts.UsersCtxTimeout)
+	defer cancel()
+
+	body, err := json.Marshal(map[string]string{"email": email, "pass": pass})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.addr+"/users/login", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound:
+		return "", ErrInvalidCredentials
+	default:
+		return "", fmt.Errorf("%w: status %d", ErrUnavailable, resp.StatusCode)
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 8<<10))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	token := strings.TrimSpace(string(raw))
+	if token == "" {
+		return "", fmt.Errorf("%w: empty token", ErrUnavailable)
+	}
+	return token, nil
+}
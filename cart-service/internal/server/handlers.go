@@ -35,6 +35,10 @@ func (s *Server) addBookToCart(ctx *gin.Context) {
 	}
 
 	if err := s.storage.AddBookToCart(cartID, bookID); err != nil {
+		if errors.Is(err, storerrros.ErrBookNoExist) || errors.Is(err, storerrros.ErrCartNotExist) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		log.Error().Err(err).Msg("failed to add book to cart")
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -79,7 +83,7 @@ func (s *Server) removeBookFromCart(ctx *gin.Context) {
 	}
 
 	if err := s.storage.RemoveBookFromCart(itemID); err != nil {
-		if err.Error() == "book not found" {
+		if errors.Is(err, storerrros.ErrBookNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "book not found in cart"})
 			return
 		}
@@ -102,9 +106,18 @@ func (s *Server) clearCart(ctx *gin.Context) {
 	cartID := ctx.Param("cart_id")
 
 	if err := s.storage.ClearCart(cartID); err != nil {
+		if errors.Is(err, storerrros.ErrCartNotExist) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Cart not found"})
+			return
+		}
 		log.Error().Err(err).Msg("failed to clear cart")
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to clear cart"})
 		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{"message": "cart cleared"})
 }
+
+// Status (GET /status) сообщает, на каком хранилище работает сервис.
+func (s *Server) Status(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok", "storage": s.storageBackend})
+}
@@ -39,6 +39,9 @@ type Server struct {
 	storage Storage
 	delChan chan struct{}
 	ErrChan chan error
+
+	// storageBackend - выбранный в конфиге бэкенд хранилища, см. Status
+	storageBackend string
 }
 
 func New(cfg config.Config, stor Storage) *Server {
@@ -52,6 +55,8 @@ func New(cfg config.Config, stor Storage) *Server {
 		storage: stor,
 		delChan: make(chan struct{}, 10), //nolint:mnd //todo
 		ErrChan: make(chan error),
+
+		storageBackend: cfg.StorageBackend,
 	}
 }
 
@@ -70,6 +75,7 @@ func (s *Server) Run(ctx context.Context) error {
 		AllowCredentials: true,
 		MaxAge:           12 * 3600,
 	}))
+	router.GET("/status", s.Status)
 	cart := router.Group("/cart")
 	{
 		cart.GET("/:cart_id", s.JWTAuthMiddleware(), s.getCartItems)
@@ -10,6 +10,8 @@ import (
 	"github.com/google/uuid"
 	// "github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
+
+	storerrros "github.com/azaliaz/bookly/cart-service/internal/storage/errors"
 )
 
 type DBStorage struct {
@@ -35,7 +37,10 @@ func (s *DBStorage) AddBookToCart(cartID, BID string) error {
 		return fmt.Errorf("failed to check if book exists: %v", err)
 	}
 	if !exists {
-		return fmt.Errorf("book with ID %s does not exist", BID)
+		return storerrros.ErrBookNoExist
+	}
+	if err := s.checkCart(ctx, cartID); err != nil {
+		return err
 	}
 
 	err = s.conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM cart_items WHERE cart_id = $1 AND book_id = $2)", cartID, BID).Scan(&exists)
@@ -79,7 +84,7 @@ func (s *DBStorage) GetCartItems(cartID string) ([]models.CartItem, error) {
 	}
 	defer rows.Close()
 
-	var items []models.CartItem
+	items := []models.CartItem{}
 	for rows.Next() {
 		var item models.CartItem
 		if err := rows.Scan(&item.ItemID, &item.CartID, &item.BID); err != nil {
@@ -87,6 +92,14 @@ func (s *DBStorage) GetCartItems(cartID string) ([]models.CartItem, error) {
 		}
 		items = append(items, item)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		if err := s.checkCart(context.Background(), cartID); err != nil {
+			return nil, err
+		}
+	}
 	return items, nil
 }
 
@@ -97,6 +110,9 @@ func (s *DBStorage) RemoveBookFromCart(itemID string) error {
 	err := s.conn.QueryRow(ctx, `
 		SELECT book_id FROM cart_items WHERE item_id = $1
 	`, itemID).Scan(&bookID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storerrros.ErrBookNotFound
+	}
 	if err != nil {
 		return fmt.Errorf("failed to find book_id for cart item: %v", err)
 	}
@@ -119,8 +135,28 @@ func (s *DBStorage) RemoveBookFromCart(itemID string) error {
 }
 
 func (s *DBStorage) ClearCart(cartID string) error {
-	_, err := s.conn.Exec(context.Background(), "DELETE FROM cart_items WHERE cart_id = $1", cartID)
-	return err
+	ctx := context.Background()
+	res, err := s.conn.Exec(ctx, "DELETE FROM cart_items WHERE cart_id = $1", cartID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return s.checkCart(ctx, cartID)
+	}
+	return nil
+}
+
+// checkCart возвращает ErrCartNotExist, если корзины cartID нет.
+func (s *DBStorage) checkCart(ctx context.Context, cartID string) error {
+	var exists bool
+	err := s.conn.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM cart WHERE cart_id::text = $1)`, cartID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check if cart exists: %v", err)
+	}
+	if !exists {
+		return storerrros.ErrCartNotExist
+	}
+	return nil
 }
 func Migrations(dbDsn string, migrationsPath string) error {
 	log := logger.Get()
@@ -1,7 +1,9 @@
 package storage
 
 import (
-	"fmt"
+	"slices"
+	"sync"
+
 	// "golang.org/x/crypto/bcrypt"
 	"github.com/google/uuid"
 
@@ -10,7 +12,11 @@ import (
 	storerrros "github.com/azaliaz/bookly/cart-service/internal/storage/errors"
 )
 
+// MemStorage хранит корзины в памяти процесса; методы можно вызывать из
+// параллельных запросов. Корзины и книги в DBStorage заводят user-service и
+// book-service, здесь для этого есть CreateCart и SaveBook.
 type MemStorage struct {
+	mu        sync.RWMutex
 	carts     map[string]models.Cart
 	cartItems map[string][]models.CartItem
 	books     map[string]models.Book
@@ -24,19 +30,34 @@ func New() *MemStorage {
 	}
 }
 func (ms *MemStorage) CreateCart(UID string) (string, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
 	cartID := uuid.New().String()
 	ms.carts[cartID] = models.Cart{CartID: cartID, UID: UID}
 	return cartID, nil
 }
+
+// SaveBook добавляет книгу, которую можно положить в корзину.
+func (ms *MemStorage) SaveBook(book models.Book) (string, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if book.BID == "" {
+		book.BID = uuid.New().String()
+	}
+	ms.books[book.BID] = book
+	return book.BID, nil
+}
+
 func (ms *MemStorage) AddBookToCart(cartID, BID string) error {
-	// Проверяем, существует ли книга
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	// Проверяем, существуют ли книга и корзина
 	if _, exists := ms.books[BID]; !exists {
-		return fmt.Errorf("book not found")
+		return storerrros.ErrBookNoExist
 	}
-
-	// Создаем корзину, если ее нет
 	if _, exists := ms.carts[cartID]; !exists {
-		ms.carts[cartID] = models.Cart{}
+		return storerrros.ErrCartNotExist
 	}
 
 	// Проверяем, есть ли уже такая книга в корзине — если есть, ничего не делаем
@@ -53,31 +74,51 @@ func (ms *MemStorage) AddBookToCart(cartID, BID string) error {
 		BID:    BID,
 	})
 
+	// рейтинг книги - число пользователей, у которых она в корзине, как в DBStorage
+	users := make(map[string]bool)
+	for id, items := range ms.cartItems {
+		if slices.ContainsFunc(items, func(item models.CartItem) bool { return item.BID == BID }) {
+			users[ms.carts[id].UID] = true
+		}
+	}
+	book := ms.books[BID]
+	book.Rating = len(users)
+	ms.books[BID] = book
+
 	return nil
 }
 
 func (ms *MemStorage) GetCartItems(cartID string) ([]models.CartItem, error) {
-	items, exists := ms.cartItems[cartID]
-	if !exists {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	if _, exists := ms.carts[cartID]; !exists {
 		return nil, storerrros.ErrCartNotExist
 	}
-	return items, nil
+	return append([]models.CartItem{}, ms.cartItems[cartID]...), nil
 }
 func (ms *MemStorage) RemoveBookFromCart(itemID string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
 	for cartID, items := range ms.cartItems {
 		for i, item := range items {
 			if item.ItemID == itemID {
 				// Просто удаляем книгу из корзины
-				ms.cartItems[cartID] = append(items[:i], items[i+1:]...)
+				ms.cartItems[cartID] = slices.Delete(items, i, i+1)
+				if book, ok := ms.books[item.BID]; ok && book.Rating > 0 {
+					book.Rating--
+					ms.books[item.BID] = book
+				}
 				return nil
 			}
 		}
 	}
 
-	return storerrros.ErrBookNoExist
+	return storerrros.ErrBookNotFound
 }
 
 func (ms *MemStorage) ClearCart(cartID string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
 	if _, exists := ms.carts[cartID]; !exists {
 		return storerrros.ErrCartNotExist
 	}
@@ -0,0 +1,211 @@
+package storage_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/azaliaz/bookly/cart-service/internal/domain/models"
+	"github.com/azaliaz/bookly/cart-service/internal/server"
+	"github.com/azaliaz/bookly/cart-service/internal/storage"
+	storerrros "github.com/azaliaz/bookly/cart-service/internal/storage/errors"
+)
+
+// fixtures заводит корзины и книги, которыми в DBStorage владеют user-service и
+// book-service.
+type fixtures interface {
+	cart(t *testing.T) string
+	book(t *testing.T) string
+}
+
+type memFixtures struct{ *storage.MemStorage }
+
+func (f memFixtures) cart(t *testing.T) string {
+	cartID, err := f.CreateCart(uuid.New().String())
+	require.NoError(t, err)
+	return cartID
+}
+
+func (f memFixtures) book(t *testing.T) string {
+	bid, err := f.SaveBook(models.Book{Lable: "Обломов", Author: "Иван Гончаров", Desc: "роман", Age: 1859})
+	require.NoError(t, err)
+	return bid
+}
+
+type dbFixtures struct{ conn *pgx.Conn }
+
+func (f dbFixtures) cart(t *testing.T) string {
+	ctx := context.Background()
+	uid, cartID := uuid.New().String(), uuid.New().String()
+	_, err := f.conn.Exec(ctx, `INSERT INTO users (uid, cart_id, email, name, lastname, pass)
+		VALUES ($1, $2, $1 || '@example.com', 'Иван', 'Гончаров', '')`, uid, cartID)
+	require.NoError(t, err)
+	_, err = f.conn.Exec(ctx, `INSERT INTO cart (cart_id, user_id) VALUES ($1, $2)`, cartID, uid)
+	require.NoError(t, err)
+	return cartID
+}
+
+func (f dbFixtures) book(t *testing.T) string {
+	ctx := context.Background()
+	bid := uuid.New().String()
+	_, err := f.conn.Exec(ctx, `INSERT INTO works (id, title, author) VALUES ($1, 'Обломов', 'Иван Гончаров')`, bid)
+	require.NoError(t, err)
+	_, err = f.conn.Exec(ctx, `INSERT INTO books (bid, lable, author, "desc", age, work_id)
+		VALUES ($1, 'Обломов', 'Иван Гончаров', 'роман', 1859, $1)`, bid)
+	require.NoError(t, err)
+	return bid
+}
+
+type testStorage struct {
+	server.Storage
+	fixtures
+}
+
+// storages возвращает реализации Storage, на которых прогоняются одни и те же
+// проверки: MemStorage всегда, DBStorage - если TEST_DB_DSN указывает на тестовую базу.
+func storages(t *testing.T) map[string]testStorage {
+	mem := storage.New()
+	result := map[string]testStorage{"mem": {mem, memFixtures{mem}}}
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Log("TEST_DB_DSN is not set, DBStorage is skipped")
+		return result
+	}
+	require.NoError(t, storage.Migrations(dsn, "../../../migrations"))
+	db, err := storage.NewDB(context.Background(), dsn)
+	require.NoError(t, err)
+	conn, err := pgx.Connect(context.Background(), dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close(context.Background()) })
+	result["db"] = testStorage{db, dbFixtures{conn}}
+	return result
+}
+
+func TestStorage_conformance(t *testing.T) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T, s testStorage)
+	}{
+		{
+			name: "add and list items",
+			run: func(t *testing.T, s testStorage) {
+				cartID, first, second := s.cart(t), s.book(t), s.book(t)
+				require.NoError(t, s.AddBookToCart(cartID, first))
+				require.NoError(t, s.AddBookToCart(cartID, second))
+				require.NoError(t, s.AddBookToCart(cartID, first), "adding the same book again is a no-op")
+
+				items, err := s.GetCartItems(cartID)
+				require.NoError(t, err)
+				require.Len(t, items, 2)
+				var books []string
+				for _, item := range items {
+					assert.NotEmpty(t, item.ItemID)
+					assert.Equal(t, cartID, item.CartID)
+					books = append(books, item.BID)
+				}
+				assert.ElementsMatch(t, []string{first, second}, books)
+			},
+		},
+		{
+			name: "empty cart",
+			run: func(t *testing.T, s testStorage) {
+				items, err := s.GetCartItems(s.cart(t))
+				require.NoError(t, err)
+				assert.NotNil(t, items)
+				assert.Empty(t, items)
+			},
+		},
+		{
+			name: "missing cart or book",
+			run: func(t *testing.T, s testStorage) {
+				cartID, bid := s.cart(t), s.book(t)
+				assert.ErrorIs(t, s.AddBookToCart(cartID, uuid.New().String()), storerrros.ErrBookNoExist)
+				assert.ErrorIs(t, s.AddBookToCart(uuid.New().String(), bid), storerrros.ErrCartNotExist)
+				_, err := s.GetCartItems(uuid.New().String())
+				assert.ErrorIs(t, err, storerrros.ErrCartNotExist)
+				assert.ErrorIs(t, s.ClearCart(uuid.New().String()), storerrros.ErrCartNotExist)
+			},
+		},
+		{
+			name: "remove item",
+			run: func(t *testing.T, s testStorage) {
+				cartID, first, second := s.cart(t), s.book(t), s.book(t)
+				require.NoError(t, s.AddBookToCart(cartID, first))
+				require.NoError(t, s.AddBookToCart(cartID, second))
+				items, err := s.GetCartItems(cartID)
+				require.NoError(t, err)
+				require.Len(t, items, 2)
+
+				require.NoError(t, s.RemoveBookFromCart(items[0].ItemID))
+				left, err := s.GetCartItems(cartID)
+				require.NoError(t, err)
+				assert.Equal(t, []models.CartItem{items[1]}, left)
+
+				assert.ErrorIs(t, s.RemoveBookFromCart(items[0].ItemID), storerrros.ErrBookNotFound)
+			},
+		},
+		{
+			name: "clear cart",
+			run: func(t *testing.T, s testStorage) {
+				cartID := s.cart(t)
+				require.NoError(t, s.AddBookToCart(cartID, s.book(t)))
+				require.NoError(t, s.ClearCart(cartID))
+				items, err := s.GetCartItems(cartID)
+				require.NoError(t, err)
+				assert.Empty(t, items)
+				require.NoError(t, s.ClearCart(cartID), "clearing an empty cart is not an error")
+			},
+		},
+	}
+
+	for name, s := range storages(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) { tc.run(t, s) })
+			}
+		})
+	}
+}
+
+// TestMemStorage_concurrent рассчитан на запуск с -race: запросы gin идут
+// параллельно, и резервное хранилище не должно терять записи.
+func TestMemStorage_concurrent(t *testing.T) {
+	s := storage.New()
+	cartID := memFixtures{s}.cart(t)
+	const workers = 8
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bid, _ := s.SaveBook(models.Book{Lable: "Обломов", Author: "Иван Гончаров", Desc: "роман", Age: 1859})
+			if err := s.AddBookToCart(cartID, bid); err != nil {
+				t.Error(err)
+			}
+			if _, err := s.GetCartItems(cartID); err != nil {
+				t.Error(err)
+			}
+			other, _ := s.CreateCart(uuid.New().String())
+			if err := s.AddBookToCart(other, bid); err != nil {
+				t.Error(err)
+			}
+			if err := s.ClearCart(other); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	items, err := s.GetCartItems(cartID)
+	require.NoError(t, err)
+	assert.Len(t, items, workers)
+}
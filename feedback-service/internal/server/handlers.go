@@ -76,3 +76,8 @@ func (s *Server) deleteFeedback(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "feedback deleted successfully"})
 }
+
+// Status (GET /status) сообщает, на каком хранилище работает сервис.
+func (s *Server) Status(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok", "storage": s.storageBackend})
+}
@@ -43,6 +43,9 @@ type Server struct {
 	storage Storage
 	delChan chan struct{}
 	ErrChan chan error
+
+	// storageBackend - выбранный в конфиге бэкенд хранилища, см. Status
+	storageBackend string
 }
 
 func New(cfg config.Config, stor Storage) *Server {
@@ -56,6 +59,8 @@ func New(cfg config.Config, stor Storage) *Server {
 		storage: stor,
 		delChan: make(chan struct{}, 10), //nolint:mnd //todo
 		ErrChan: make(chan error),
+
+		storageBackend: cfg.StorageBackend,
 	}
 }
 
@@ -76,6 +81,7 @@ func (s *Server) Run(ctx context.Context) error {
 		MaxAge:           12 * 3600,
 	}))
 	router.GET("/", func(ctx *gin.Context) { ctx.String(http.StatusOK, "Hello") })
+	router.GET("/status", s.Status)
 	feedback := router.Group("/feedback")
 	{
 		feedback.POST("/", s.JWTAuthMiddleware(), s.saveFeedback)
@@ -11,7 +11,10 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"time"
+
+	storerrros "github.com/azaliaz/bookly/feedback-service/internal/storage/errors"
 )
 
 type DBStorage struct {
@@ -43,6 +46,15 @@ func (dbs *DBStorage) SaveFeedback(feedback models.Feedback) error {
 		VALUES ($1, $2, $3, $4, $5)
 	`, feedbackID, feedback.UserID, feedback.BookID, feedback.Text, createdAt)
 
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		switch pgErr.ConstraintName {
+		case "feedbacks_user_id_fkey":
+			return storerrros.ErrUserNoExist
+		case "feedbacks_book_id_fkey":
+			return storerrros.ErrBookNoExist
+		}
+	}
 	if err != nil {
 		log.Error().Err(err).Msg("failed to save feedback")
 		return err
@@ -52,23 +64,30 @@ func (dbs *DBStorage) SaveFeedback(feedback models.Feedback) error {
 	return nil
 }
 
+// Отзывы о книге собираются по всему произведению: отзыв к одному переводу или
+// изданию показывается и у остальных изданий того же произведения.
+const (
+	feedbacksByWork = `f.book_id IN (SELECT bid FROM books WHERE work_id = (SELECT work_id FROM books WHERE bid = $1))`
+	feedbacksByUser = `f.user_id = $1`
+)
+
 func (dbs *DBStorage) GetFeedbacksByBookAsc(bookID string) ([]models.Feedback, error) {
-	return dbs.getFeedbacks("book_id", bookID, utils.SortAsc)
+	return dbs.getFeedbacks(feedbacksByWork, bookID, utils.SortAsc)
 }
 
 func (dbs *DBStorage) GetFeedbacksByBookDesc(bookID string) ([]models.Feedback, error) {
-	return dbs.getFeedbacks("book_id", bookID, utils.SortDesc)
+	return dbs.getFeedbacks(feedbacksByWork, bookID, utils.SortDesc)
 }
 
 func (dbs *DBStorage) GetFeedbacksByUserAsc(userID string) ([]models.Feedback, error) {
-	return dbs.getFeedbacks("user_id", userID, utils.SortAsc)
+	return dbs.getFeedbacks(feedbacksByUser, userID, utils.SortAsc)
 }
 
 func (dbs *DBStorage) GetFeedbacksByUserDesc(userID string) ([]models.Feedback, error) {
-	return dbs.getFeedbacks("user_id", userID, utils.SortDesc)
+	return dbs.getFeedbacks(feedbacksByUser, userID, utils.SortDesc)
 }
 
-func (dbs *DBStorage) getFeedbacks(columnName, id string, sortOrder utils.SortOrder) ([]models.Feedback, error) {
+func (dbs *DBStorage) getFeedbacks(condition, id string, sortOrder utils.SortOrder) ([]models.Feedback, error) {
 	log := logger.Get()
 	ctx, cancel := context.WithTimeout(context.Background(), consts.DBCtxTimeout)
 	defer cancel()
@@ -78,8 +97,8 @@ func (dbs *DBStorage) getFeedbacks(columnName, id string, sortOrder utils.SortOr
 	       u.name, u.lastname
 	FROM feedbacks f
 	JOIN users u ON f.user_id = u.uid
-	WHERE f.%s = $1
-	ORDER BY f.create_at %s`, columnName, string(sortOrder))
+	WHERE %s
+	ORDER BY f.create_at %s`, condition, string(sortOrder))
 
 	rows, err := dbs.conn.Query(ctx, query, id)
 	if err != nil {
@@ -104,8 +123,8 @@ func (dbs *DBStorage) getFeedbacks(columnName, id string, sortOrder utils.SortOr
 	}
 
 	if len(feedbacks) == 0 {
-		log.Warn().Str(columnName, id).Msg("no feedbacks found")
-		return nil, errors.New("no feedbacks found")
+		log.Warn().Str("id", id).Msg("no feedbacks found")
+		return nil, storerrros.ErrFeedbackNoExist
 	}
 
 	return feedbacks, nil
@@ -123,7 +142,7 @@ func (dbs *DBStorage) DeleteFeedback(feedbackID string) error {
 	}
 	if res.RowsAffected() == 0 {
 		log.Warn().Str("fid", feedbackID).Msg("feedback not found")
-		return errors.New("feedback not found")
+		return storerrros.ErrFeedbackNoExist
 	}
 	log.Info().Str("fid", feedbackID).Msg("feedback deleted successfully")
 	return nil
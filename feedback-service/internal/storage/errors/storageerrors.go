@@ -4,4 +4,6 @@ import "errors"
 
 var (
 	ErrFeedbackNoExist = errors.New("feedback does not exist")
+	ErrUserNoExist     = errors.New("user does not exist")
+	ErrBookNoExist     = errors.New("book does not exist")
 )
@@ -7,39 +7,91 @@ import (
 	"github.com/azaliaz/bookly/feedback-service/internal/utils"
 	"github.com/google/uuid"
 	"sort"
+	"sync"
+	"time"
 )
 
+// MemStorage хранит отзывы в памяти процесса; методы можно вызывать из
+// параллельных запросов. Пользователей и книги в DBStorage заводят user-service и
+// book-service, здесь для этого есть SaveUser и SaveBook. bookWorks повторяет
+// books.work_id: по нему отзывы к книге собираются со всех изданий произведения,
+// как в DBStorage. Запущенный сервис SaveUser и SaveBook не вызывает, поэтому с
+// бэкендом memory отзывы принимаются только от заведенных в тестах пользователей
+// к заведенным там же книгам.
 type MemStorage struct {
+	mu           sync.RWMutex
 	feedbackStor map[string]models.Feedback
+	users        map[string]memUser
+	bookWorks    map[string]string // bid -> id произведения
+}
+
+type memUser struct {
+	name, lastname string
 }
 
 func New() *MemStorage {
 	return &MemStorage{
 		feedbackStor: make(map[string]models.Feedback),
+		users:        make(map[string]memUser),
+		bookWorks:    make(map[string]string),
 	}
 }
 
+// SaveUser добавляет автора отзывов.
+func (ms *MemStorage) SaveUser(uid, name, lastname string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.users[uid] = memUser{name: name, lastname: lastname}
+}
+
+// SaveBook добавляет книгу - издание произведения workID.
+func (ms *MemStorage) SaveBook(bid, workID string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.bookWorks[bid] = workID
+}
+
 // Сохранить отзыв
 func (ms *MemStorage) SaveFeedback(feedback models.Feedback) error {
-	if _, exists := ms.feedbackStor[feedback.FeedbackID]; exists {
-
-		ms.feedbackStor[feedback.FeedbackID] = feedback
-		return nil
-	}
-	feedback.FeedbackID = uuid.New().String()
-	ms.feedbackStor[feedback.FeedbackID] = feedback
-	return nil
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.saveFeedback(feedback)
 }
 
 func (ms *MemStorage) SaveFeedbacks(feedbacks []models.Feedback) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
 	for _, feedback := range feedbacks {
-		if err := ms.SaveFeedback(feedback); err != nil {
+		if err := ms.saveFeedback(feedback); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// saveFeedback, как и DBStorage, всегда добавляет новый отзыв и проверяет, что
+// пользователь и книга существуют.
+func (ms *MemStorage) saveFeedback(feedback models.Feedback) error {
+	if _, ok := ms.users[feedback.UserID]; !ok {
+		return storerrros.ErrUserNoExist
+	}
+	if _, ok := ms.bookWorks[feedback.BookID]; !ok {
+		return storerrros.ErrBookNoExist
+	}
+	feedback.FeedbackID = uuid.New().String()
+	if feedback.CreatedAt.IsZero() {
+		feedback.CreatedAt = time.Now()
+	}
+	// create_at в базе - timestamp без часового пояса: из него читается время по
+	// часам с пометкой UTC и точностью до микросекунд
+	t := feedback.CreatedAt
+	feedback.CreatedAt = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC).
+		Truncate(time.Microsecond)
+	feedback.Name, feedback.Lastname = "", ""
+	ms.feedbackStor[feedback.FeedbackID] = feedback
+	return nil
+}
+
 func (ms *MemStorage) GetFeedbacksByBookAsc(bookID string) ([]models.Feedback, error) {
 	return ms.getFeedbacks("bid", bookID, utils.SortAsc)
 }
@@ -55,10 +107,18 @@ func (ms *MemStorage) GetFeedbacksByUserDesc(userID string) ([]models.Feedback,
 	return ms.getFeedbacks("uid", userID, utils.SortDesc)
 }
 
+// getFeedbacks отбирает отзывы пользователя или, как DBStorage, отзывы ко всем
+// изданиям произведения книги и подставляет имя автора отзыва.
 func (ms *MemStorage) getFeedbacks(filterBy string, id string, sortOrder utils.SortOrder) ([]models.Feedback, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	workID, known := ms.bookWorks[id]
 	var feedbacks []models.Feedback
 	for _, feedback := range ms.feedbackStor {
-		if (filterBy == "bid" && feedback.BookID == id) || (filterBy == "uid" && feedback.UserID == id) {
+		if (filterBy == "bid" && known && ms.bookWorks[feedback.BookID] == workID) || (filterBy == "uid" && feedback.UserID == id) {
+			user := ms.users[feedback.UserID]
+			feedback.Name, feedback.Lastname = user.name, user.lastname
 			feedbacks = append(feedbacks, feedback)
 		}
 	}
@@ -79,6 +139,8 @@ func (ms *MemStorage) getFeedbacks(filterBy string, id string, sortOrder utils.S
 
 func (ms *MemStorage) DeleteFeedback(feedbackID string) error {
 	log := logger.Get()
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
 	if _, exists := ms.feedbackStor[feedbackID]; !exists {
 		log.Warn().Str("feedbackID", feedbackID).Msg("feedback not found")
 		return storerrros.ErrFeedbackNoExist
@@ -0,0 +1,258 @@
+package storage_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/azaliaz/bookly/feedback-service/internal/domain/models"
+	"github.com/azaliaz/bookly/feedback-service/internal/logger"
+	"github.com/azaliaz/bookly/feedback-service/internal/server"
+	"github.com/azaliaz/bookly/feedback-service/internal/storage"
+	storerrros "github.com/azaliaz/bookly/feedback-service/internal/storage/errors"
+)
+
+func TestMain(m *testing.M) {
+	logger.Get(false)
+	os.Exit(m.Run())
+}
+
+// fixtures заводит пользователей и книги, которыми в DBStorage владеют
+// user-service и book-service.
+type fixtures interface {
+	user(t *testing.T, name, lastname string) string
+	book(t *testing.T, workID string) string
+}
+
+type memFixtures struct{ *storage.MemStorage }
+
+func (f memFixtures) user(t *testing.T, name, lastname string) string {
+	uid := uuid.New().String()
+	f.SaveUser(uid, name, lastname)
+	return uid
+}
+
+func (f memFixtures) book(t *testing.T, workID string) string {
+	bid := uuid.New().String()
+	f.SaveBook(bid, workID)
+	return bid
+}
+
+type dbFixtures struct{ conn *pgx.Conn }
+
+func (f dbFixtures) user(t *testing.T, name, lastname string) string {
+	uid := uuid.New().String()
+	_, err := f.conn.Exec(context.Background(), `INSERT INTO users (uid, cart_id, email, name, lastname, pass)
+		VALUES ($1, $2, $1 || '@example.com', $3, $4, '')`, uid, uuid.New().String(), name, lastname)
+	require.NoError(t, err)
+	return uid
+}
+
+func (f dbFixtures) book(t *testing.T, workID string) string {
+	ctx := context.Background()
+	bid := uuid.New().String()
+	_, err := f.conn.Exec(ctx, `INSERT INTO works (id, title, author) VALUES ($1, 'Обломов', 'Иван Гончаров')
+		ON CONFLICT (id) DO NOTHING`, workID)
+	require.NoError(t, err)
+	_, err = f.conn.Exec(ctx, `INSERT INTO books (bid, lable, author, "desc", age, work_id)
+		VALUES ($1, 'Обломов', 'Иван Гончаров', 'роман', 1859, $2)`, bid, workID)
+	require.NoError(t, err)
+	return bid
+}
+
+type testStorage struct {
+	server.Storage
+	fixtures
+}
+
+// storages возвращает реализации Storage, на которых прогоняются одни и те же
+// проверки: MemStorage всегда, DBStorage - если TEST_DB_DSN указывает на тестовую базу.
+func storages(t *testing.T) map[string]testStorage {
+	mem := storage.New()
+	result := map[string]testStorage{"mem": {mem, memFixtures{mem}}}
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Log("TEST_DB_DSN is not set, DBStorage is skipped")
+		return result
+	}
+	require.NoError(t, storage.Migrations(dsn, "../../../migrations"))
+	db, err := storage.NewDB(context.Background(), dsn)
+	require.NoError(t, err)
+	conn, err := pgx.Connect(context.Background(), dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close(context.Background()) })
+	result["db"] = testStorage{db, dbFixtures{conn}}
+	return result
+}
+
+func texts(feedbacks []models.Feedback) []string {
+	result := make([]string, 0, len(feedbacks))
+	for _, feedback := range feedbacks {
+		result = append(result, feedback.Text)
+	}
+	return result
+}
+
+func TestStorage_conformance(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)
+
+	cases := []struct {
+		name string
+		run  func(t *testing.T, s testStorage)
+	}{
+		{
+			name: "user feedbacks are sorted by date",
+			run: func(t *testing.T, s testStorage) {
+				uid, bid := s.user(t, "Иван", "Гончаров"), s.book(t, uuid.New().String())
+				require.NoError(t, s.SaveFeedback(models.Feedback{UserID: uid, BookID: bid, Text: "второй", CreatedAt: created.Add(time.Hour)}))
+				require.NoError(t, s.SaveFeedback(models.Feedback{UserID: uid, BookID: bid, Text: "первый", CreatedAt: created}))
+
+				asc, err := s.GetFeedbacksByUserAsc(uid)
+				require.NoError(t, err)
+				assert.Equal(t, []string{"первый", "второй"}, texts(asc))
+				desc, err := s.GetFeedbacksByUserDesc(uid)
+				require.NoError(t, err)
+				assert.Equal(t, []string{"второй", "первый"}, texts(desc))
+
+				first := asc[0]
+				assert.NotEmpty(t, first.FeedbackID)
+				assert.Equal(t, uid, first.UserID)
+				assert.Equal(t, bid, first.BookID)
+				assert.Equal(t, "Иван", first.Name)
+				assert.Equal(t, "Гончаров", first.Lastname)
+				assert.True(t, created.Equal(first.CreatedAt), first.CreatedAt)
+			},
+		},
+		{
+			name: "book feedbacks cover all editions of the work",
+			run: func(t *testing.T, s testStorage) {
+				uid, workID := s.user(t, "Иван", "Гончаров"), uuid.New().String()
+				first, second, other := s.book(t, workID), s.book(t, workID), s.book(t, uuid.New().String())
+				require.NoError(t, s.SaveFeedback(models.Feedback{UserID: uid, BookID: first, Text: "перевод", CreatedAt: created}))
+				require.NoError(t, s.SaveFeedback(models.Feedback{UserID: uid, BookID: second, Text: "оригинал", CreatedAt: created.Add(time.Hour)}))
+				require.NoError(t, s.SaveFeedback(models.Feedback{UserID: uid, BookID: other, Text: "другая книга", CreatedAt: created}))
+
+				asc, err := s.GetFeedbacksByBookAsc(second)
+				require.NoError(t, err)
+				assert.Equal(t, []string{"перевод", "оригинал"}, texts(asc))
+				desc, err := s.GetFeedbacksByBookDesc(first)
+				require.NoError(t, err)
+				assert.Equal(t, []string{"оригинал", "перевод"}, texts(desc))
+
+				// отзыв к другому произведению того же автора в выборку не попадает
+				byOther, err := s.GetFeedbacksByBookAsc(other)
+				require.NoError(t, err)
+				assert.Equal(t, []string{"другая книга"}, texts(byOther))
+			},
+		},
+		{
+			name: "feedback id and date are assigned by the storage",
+			run: func(t *testing.T, s testStorage) {
+				uid, bid := s.user(t, "Иван", "Гончаров"), s.book(t, uuid.New().String())
+				given := uuid.New().String()
+				require.NoError(t, s.SaveFeedback(models.Feedback{FeedbackID: given, UserID: uid, BookID: bid, Text: "отзыв"}))
+				require.NoError(t, s.SaveFeedback(models.Feedback{FeedbackID: given, UserID: uid, BookID: bid, Text: "отзыв"}))
+
+				feedbacks, err := s.GetFeedbacksByUserAsc(uid)
+				require.NoError(t, err)
+				require.Len(t, feedbacks, 2)
+				for _, feedback := range feedbacks {
+					assert.NotEqual(t, given, feedback.FeedbackID)
+					assert.False(t, feedback.CreatedAt.IsZero())
+				}
+			},
+		},
+		{
+			name: "unknown user or book",
+			run: func(t *testing.T, s testStorage) {
+				uid, bid := s.user(t, "Иван", "Гончаров"), s.book(t, uuid.New().String())
+				err := s.SaveFeedback(models.Feedback{UserID: uuid.New().String(), BookID: bid, Text: "отзыв"})
+				assert.ErrorIs(t, err, storerrros.ErrUserNoExist)
+				err = s.SaveFeedback(models.Feedback{UserID: uid, BookID: uuid.New().String(), Text: "отзыв"})
+				assert.ErrorIs(t, err, storerrros.ErrBookNoExist)
+			},
+		},
+		{
+			name: "no feedbacks",
+			run: func(t *testing.T, s testStorage) {
+				_, err := s.GetFeedbacksByUserAsc(s.user(t, "Иван", "Гончаров"))
+				assert.ErrorIs(t, err, storerrros.ErrFeedbackNoExist)
+				_, err = s.GetFeedbacksByBookDesc(s.book(t, uuid.New().String()))
+				assert.ErrorIs(t, err, storerrros.ErrFeedbackNoExist)
+				_, err = s.GetFeedbacksByBookAsc(uuid.New().String())
+				assert.ErrorIs(t, err, storerrros.ErrFeedbackNoExist)
+			},
+		},
+		{
+			name: "delete feedback",
+			run: func(t *testing.T, s testStorage) {
+				uid, bid := s.user(t, "Иван", "Гончаров"), s.book(t, uuid.New().String())
+				require.NoError(t, s.SaveFeedback(models.Feedback{UserID: uid, BookID: bid, Text: "отзыв"}))
+				feedbacks, err := s.GetFeedbacksByUserAsc(uid)
+				require.NoError(t, err)
+				require.Len(t, feedbacks, 1)
+
+				require.NoError(t, s.DeleteFeedback(feedbacks[0].FeedbackID))
+				_, err = s.GetFeedbacksByUserAsc(uid)
+				assert.ErrorIs(t, err, storerrros.ErrFeedbackNoExist)
+				assert.ErrorIs(t, s.DeleteFeedback(feedbacks[0].FeedbackID), storerrros.ErrFeedbackNoExist)
+			},
+		},
+	}
+
+	for name, s := range storages(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) { tc.run(t, s) })
+			}
+		})
+	}
+}
+
+// TestMemStorage_concurrent рассчитан на запуск с -race: запросы gin идут
+// параллельно, и резервное хранилище не должно терять записи.
+func TestMemStorage_concurrent(t *testing.T) {
+	s := storage.New()
+	f := memFixtures{s}
+	bid := f.book(t, uuid.New().String())
+	const workers, feedbacks = 8, 20
+
+	var wg sync.WaitGroup
+	for range workers {
+		uid := f.user(t, "Иван", "Гончаров")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range feedbacks {
+				if err := s.SaveFeedback(models.Feedback{UserID: uid, BookID: bid, Text: "отзыв"}); err != nil {
+					t.Error(err)
+				}
+				if _, err := s.GetFeedbacksByBookDesc(bid); err != nil {
+					t.Error(err)
+				}
+			}
+			own, err := s.GetFeedbacksByUserAsc(uid)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if err := s.DeleteFeedback(own[0].FeedbackID); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	all, err := s.GetFeedbacksByBookAsc(bid)
+	require.NoError(t, err)
+	assert.Len(t, all, workers*(feedbacks-1))
+}
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS authors (
+    id varchar(36) NOT NULL PRIMARY KEY,
+    name text NOT NULL,
+    alt_names text[] NOT NULL DEFAULT '{}',
+    bio text NOT NULL DEFAULT '',
+    birth_year integer NOT NULL DEFAULT 0,
+    death_year integer NOT NULL DEFAULT 0,
+    photo_key text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS authors_name_idx ON authors (name, id);
+CREATE INDEX IF NOT EXISTS authors_lower_name_idx ON authors (lower(name));
+
+CREATE TABLE IF NOT EXISTS book_authors (
+    bid varchar(36) NOT NULL REFERENCES books(bid) ON DELETE CASCADE,
+    author_id varchar(36) NOT NULL REFERENCES authors(id) ON DELETE CASCADE,
+    role varchar(16) NOT NULL DEFAULT 'author' CHECK (role IN ('author', 'translator', 'illustrator')),
+    position integer NOT NULL DEFAULT 0,
+    PRIMARY KEY (bid, author_id, role)
+);
+
+CREATE INDEX IF NOT EXISTS book_authors_author_idx ON book_authors (author_id, role);
+
+-- Строки author, которые отличаются только регистром и пробелами, становятся одним
+-- автором: самое частое написание - имя, остальные - alt_names.
+WITH spellings AS (
+    SELECT regexp_replace(btrim(author), '\s+', ' ', 'g') AS name, count(*) AS books
+    FROM books
+    WHERE btrim(author) <> ''
+    GROUP BY 1
+)
+INSERT INTO authors (id, name, alt_names)
+SELECT gen_random_uuid()::text,
+       (array_agg(name ORDER BY books DESC, name))[1],
+       (array_agg(name ORDER BY books DESC, name))[2:]
+FROM spellings
+GROUP BY lower(name);
+
+INSERT INTO book_authors (bid, author_id, role)
+SELECT b.bid, a.id, 'author'
+FROM books b
+JOIN authors a ON lower(a.name) = lower(regexp_replace(btrim(b.author), '\s+', ' ', 'g'))
+ON CONFLICT DO NOTHING;
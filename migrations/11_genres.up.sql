@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS genres (
+    id varchar(36) NOT NULL PRIMARY KEY,
+    parent_id varchar(36) REFERENCES genres(id),
+    name_ru text NOT NULL,
+    name_en text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS genres_parent_idx ON genres (parent_id);
+CREATE INDEX IF NOT EXISTS genres_name_ru_idx ON genres (lower(name_ru));
+CREATE INDEX IF NOT EXISTS genres_name_en_idx ON genres (lower(name_en));
+
+CREATE TABLE IF NOT EXISTS book_genres (
+    bid varchar(36) NOT NULL REFERENCES books(bid) ON DELETE CASCADE,
+    genre_id varchar(36) NOT NULL REFERENCES genres(id) ON DELETE CASCADE,
+    position integer NOT NULL DEFAULT 0,
+    PRIMARY KEY (bid, genre_id)
+);
+
+CREATE INDEX IF NOT EXISTS book_genres_genre_idx ON book_genres (genre_id);
+
+-- Строка genre может содержать несколько жанров через запятую или точку с запятой.
+-- Каждый жанр, без учета регистра и лишних пробелов, становится корневым узлом дерева
+-- с самым частым написанием; "Без жанра" жанром не считается.
+CREATE TEMPORARY TABLE book_genre_names AS
+SELECT b.bid, g.position, regexp_replace(btrim(g.name), '\s+', ' ', 'g') AS name
+FROM books b,
+     LATERAL regexp_split_to_table(COALESCE(b.genre, ''), '\s*[,;]\s*') WITH ORDINALITY AS g(name, position)
+WHERE btrim(g.name) NOT IN ('', 'Без жанра');
+
+INSERT INTO genres (id, name_ru)
+SELECT gen_random_uuid()::text, (array_agg(name ORDER BY books DESC, name))[1]
+FROM (SELECT name, count(*) AS books FROM book_genre_names GROUP BY name) spellings
+GROUP BY lower(name);
+
+INSERT INTO book_genres (bid, genre_id, position)
+SELECT n.bid, g.id, min(n.position) - 1
+FROM book_genre_names n
+JOIN genres g ON lower(g.name_ru) = lower(n.name)
+GROUP BY n.bid, g.id
+ON CONFLICT DO NOTHING;
+
+DROP TABLE book_genre_names;
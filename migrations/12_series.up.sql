@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS series (
+    id varchar(36) NOT NULL PRIMARY KEY,
+    name text NOT NULL,
+    "desc" text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS series_name_idx ON series (name, id);
+
+-- position - место книги в порядке чтения; дробное у повестей между книгами (2.5).
+CREATE TABLE IF NOT EXISTS book_series (
+    bid varchar(36) NOT NULL REFERENCES books(bid) ON DELETE CASCADE,
+    series_id varchar(36) NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+    position numeric(8, 2) NOT NULL CHECK (position >= 0),
+    PRIMARY KEY (bid, series_id)
+);
+
+CREATE INDEX IF NOT EXISTS book_series_order_idx ON book_series (series_id, position);
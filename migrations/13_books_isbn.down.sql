@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS books_title_key_idx;
+DROP INDEX IF EXISTS books_isbn_key;
+DROP FUNCTION IF EXISTS book_title_key(text);
+DROP TABLE IF EXISTS books_legacy_isbn;
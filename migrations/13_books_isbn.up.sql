@@ -0,0 +1,73 @@
+-- book_title_key - название или автор без регистра и пунктуации: по нему ищутся
+-- дубликаты книг без ISBN.
+CREATE OR REPLACE FUNCTION book_title_key(text) RETURNS text
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE
+    AS $$ SELECT btrim(regexp_replace(lower($1), '[^[:alnum:]]+', ' ', 'g')) $$;
+
+-- Существующие ISBN приводятся к ISBN-13 из одних цифр, неверные очищаются.
+CREATE FUNCTION pg_temp.isbn13(raw text) RETURNS text
+    LANGUAGE plpgsql IMMUTABLE
+    AS $$
+DECLARE
+    s     text := regexp_replace(upper(regexp_replace(raw, '^\s*(urn:isbn:|isbn:?)', '', 'i')), '[- ]', '', 'g');
+    given text;
+    sum   int := 0;
+BEGIN
+    IF s ~ '^[0-9]{9}[0-9X]$' THEN
+        FOR i IN 1..10 LOOP
+            sum := sum + (11 - i) * CASE WHEN substr(s, i, 1) = 'X' THEN 10 ELSE substr(s, i, 1)::int END;
+        END LOOP;
+        IF sum % 11 <> 0 THEN
+            RETURN '';
+        END IF;
+        s := '978' || left(s, 9);
+        given := NULL;
+    ELSIF s ~ '^[0-9]{13}$' THEN
+        given := right(s, 1);
+        s := left(s, 12);
+    ELSE
+        RETURN '';
+    END IF;
+
+    sum := 0;
+    FOR i IN 1..12 LOOP
+        sum := sum + substr(s, i, 1)::int * CASE WHEN i % 2 = 0 THEN 3 ELSE 1 END;
+    END LOOP;
+    s := s || ((10 - sum % 10) % 10)::text;
+    IF given IS NOT NULL AND right(s, 1) <> given THEN
+        RETURN '';
+    END IF;
+    RETURN s;
+END
+$$;
+
+-- Очищенные ISBN не теряются: администраторы находят их здесь и исправляют книги
+-- вручную. reason - invalid (не ISBN) или duplicate (уже есть у более ранней книги).
+CREATE TABLE IF NOT EXISTS books_legacy_isbn (
+    bid varchar(36) NOT NULL PRIMARY KEY REFERENCES books(bid) ON DELETE CASCADE,
+    isbn text NOT NULL,
+    reason text NOT NULL CHECK (reason IN ('invalid', 'duplicate')),
+    cleared_at timestamptz NOT NULL DEFAULT now()
+);
+COMMENT ON TABLE books_legacy_isbn IS
+    'ISBN, очищенные миграцией 13: неверные и повторы; книги нужно проверить вручную';
+
+INSERT INTO books_legacy_isbn (bid, isbn, reason)
+SELECT bid, isbn, 'invalid' FROM books WHERE isbn <> '' AND pg_temp.isbn13(isbn) = '';
+
+UPDATE books SET isbn = pg_temp.isbn13(isbn) WHERE isbn <> '';
+
+-- Одно издание - одна книга: у повторов ISBN остается только у самой ранней.
+WITH duplicates AS (
+    SELECT bid, isbn FROM (
+        SELECT bid, isbn, row_number() OVER (PARTITION BY isbn ORDER BY created_at, bid) AS n
+        FROM books WHERE isbn <> ''
+    ) d WHERE n > 1
+), saved AS (
+    INSERT INTO books_legacy_isbn (bid, isbn, reason)
+    SELECT bid, isbn, 'duplicate' FROM duplicates
+)
+UPDATE books SET isbn = '' WHERE bid IN (SELECT bid FROM duplicates);
+
+CREATE UNIQUE INDEX IF NOT EXISTS books_isbn_key ON books (isbn) WHERE isbn <> '';
+CREATE INDEX IF NOT EXISTS books_title_key_idx ON books (book_title_key(lable), book_title_key(author));
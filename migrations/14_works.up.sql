@@ -0,0 +1,33 @@
+-- Произведение объединяет переводы и издания одной книги; каждая строка books -
+-- издание со своими файлами.
+CREATE TABLE IF NOT EXISTS works (
+    id varchar(36) NOT NULL PRIMARY KEY,
+    title text NOT NULL,
+    author text NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS work_id varchar(36) REFERENCES works(id),
+    ADD COLUMN IF NOT EXISTS publisher text NOT NULL DEFAULT '';
+
+-- Книги с одинаковым названием и автором без учета пунктуации - издания одного
+-- произведения. Произведение получает bid, название и автора самого раннего издания.
+CREATE TEMP TABLE book_works AS
+SELECT bid, first_value(bid) OVER (
+    PARTITION BY book_title_key(lable), book_title_key(author) ORDER BY created_at, bid
+) AS work_id
+FROM books;
+
+INSERT INTO works (id, title, author, created_at)
+SELECT b.bid, b.lable, b.author, b.created_at
+FROM books b JOIN book_works bw ON bw.bid = b.bid
+WHERE bw.work_id = b.bid;
+
+UPDATE books b SET work_id = bw.work_id FROM book_works bw WHERE bw.bid = b.bid;
+
+DROP TABLE book_works;
+
+ALTER TABLE books ALTER COLUMN work_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS books_work_id_idx ON books (work_id);
@@ -0,0 +1,22 @@
+-- Теги - свободные метки подборок ("booktok", "Нобелевские лауреаты"); название
+-- уникально без учета регистра.
+CREATE TABLE IF NOT EXISTS tags (
+    id varchar(36) NOT NULL PRIMARY KEY,
+    name text NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS tags_name_key ON tags (lower(name));
+
+-- Теги администратора сразу одобрены, теги пользователей ждут модерации.
+CREATE TABLE IF NOT EXISTS book_tags (
+    bid varchar(36) NOT NULL REFERENCES books(bid) ON DELETE CASCADE,
+    tag_id varchar(36) NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    status text NOT NULL DEFAULT 'approved' CHECK (status IN ('approved', 'pending')),
+    suggested_by varchar(36) NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (bid, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS book_tags_tag_id_idx ON book_tags (tag_id) WHERE status = 'approved';
+CREATE INDEX IF NOT EXISTS book_tags_pending_idx ON book_tags (created_at) WHERE status = 'pending';
@@ -0,0 +1,14 @@
+DELETE FROM books WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS books_pdf_hash_key;
+CREATE UNIQUE INDEX books_pdf_hash_key ON books (pdf_hash) WHERE pdf_hash <> '';
+DROP INDEX IF EXISTS books_epub_hash_key;
+CREATE UNIQUE INDEX books_epub_hash_key ON books (epub_hash) WHERE epub_hash <> '';
+DROP INDEX IF EXISTS books_isbn_key;
+CREATE UNIQUE INDEX books_isbn_key ON books (isbn) WHERE isbn <> '';
+
+DROP INDEX IF EXISTS books_deleted_at_idx;
+
+ALTER TABLE books
+    DROP COLUMN IF EXISTS deleted_by,
+    DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,15 @@
+-- Удаление книги переносит ее в корзину: строка остается вместе с отзывами,
+-- корзинами покупателей и файлами, пока ее не удалит очистка корзины.
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_by varchar(36) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS books_deleted_at_idx ON books (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Книга в корзине не мешает загрузить то же издание или тот же файл заново.
+DROP INDEX IF EXISTS books_pdf_hash_key;
+CREATE UNIQUE INDEX books_pdf_hash_key ON books (pdf_hash) WHERE pdf_hash <> '' AND deleted_at IS NULL;
+DROP INDEX IF EXISTS books_epub_hash_key;
+CREATE UNIQUE INDEX books_epub_hash_key ON books (epub_hash) WHERE epub_hash <> '' AND deleted_at IS NULL;
+DROP INDEX IF EXISTS books_isbn_key;
+CREATE UNIQUE INDEX books_isbn_key ON books (isbn) WHERE isbn <> '' AND deleted_at IS NULL;
@@ -0,0 +1,38 @@
+-- История изменений книг: каждая ревизия хранит автора изменения, изменившиеся
+-- поля и снимок книги после изменения. Ссылки на books нет, чтобы история
+-- пережила окончательное удаление книги.
+CREATE TABLE IF NOT EXISTS book_history (
+    id bigserial PRIMARY KEY,
+    bid varchar(36) NOT NULL,
+    revision int NOT NULL,
+    action text NOT NULL CHECK (action IN ('create', 'update', 'delete', 'restore', 'revert', 'purge')),
+    actor varchar(36) NOT NULL DEFAULT '',
+    reverted_to int NOT NULL DEFAULT 0,
+    changes jsonb NOT NULL DEFAULT '{}',
+    snapshot jsonb NOT NULL,
+    changed_at timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (bid, revision)
+);
+
+-- Записи истории нельзя изменить или удалить.
+CREATE OR REPLACE FUNCTION book_history_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'book_history is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS book_history_immutable ON book_history;
+CREATE TRIGGER book_history_immutable BEFORE UPDATE OR DELETE ON book_history
+    FOR EACH ROW EXECUTE FUNCTION book_history_immutable();
+
+-- Книги, добавленные до появления истории, получают ревизию create без автора.
+INSERT INTO book_history (bid, revision, action, changes, snapshot, changed_at)
+SELECT bid, 1, 'create', '{}', jsonb_build_object(
+        'lable', lable, 'author', author, 'desc', "desc", 'age', age, 'genre', genre, 'rating', rating,
+        'language', language, 'isbn', isbn, 'publisher', publisher,
+        'cover_key', cover_key, 'pdf_key', pdf_key, 'pdf_pages', pdf_pages, 'pdf_title', pdf_title,
+        'pdf_author', pdf_author, 'pdf_size', pdf_size, 'pdf_hash', pdf_hash,
+        'epub_key', epub_key, 'epub_size', epub_size, 'epub_hash', epub_hash),
+    created_at
+FROM books
+ON CONFLICT DO NOTHING;
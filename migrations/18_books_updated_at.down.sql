@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS book_tags_touch_book ON book_tags;
+DROP TRIGGER IF EXISTS book_series_touch_book ON book_series;
+DROP TRIGGER IF EXISTS book_genres_touch_book ON book_genres;
+DROP TRIGGER IF EXISTS book_authors_touch_book ON book_authors;
+DROP FUNCTION IF EXISTS book_links_touch_book();
+
+DROP TRIGGER IF EXISTS books_touch_updated_at ON books;
+DROP FUNCTION IF EXISTS books_touch_updated_at();
+
+DROP INDEX IF EXISTS books_updated_at_idx;
+ALTER TABLE books DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,49 @@
+-- Время последнего изменения книги для Last-Modified и условных запросов.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS updated_at timestamptz;
+UPDATE books SET updated_at = greatest(created_at, coalesce(deleted_at, created_at)) WHERE updated_at IS NULL;
+ALTER TABLE books ALTER COLUMN updated_at SET DEFAULT now();
+ALTER TABLE books ALTER COLUMN updated_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS books_updated_at_idx ON books (updated_at);
+
+-- Любое изменение строки книги сдвигает updated_at.
+CREATE OR REPLACE FUNCTION books_touch_updated_at() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at = now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS books_touch_updated_at ON books;
+CREATE TRIGGER books_touch_updated_at BEFORE UPDATE ON books
+    FOR EACH ROW EXECUTE FUNCTION books_touch_updated_at();
+
+-- Авторы, жанры, серии и теги входят в карточку книги, поэтому их изменение
+-- тоже сдвигает updated_at книги.
+CREATE OR REPLACE FUNCTION book_links_touch_book() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        UPDATE books SET updated_at = now() WHERE bid = OLD.bid;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        UPDATE books SET updated_at = now() WHERE bid = NEW.bid;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS book_authors_touch_book ON book_authors;
+CREATE TRIGGER book_authors_touch_book AFTER INSERT OR UPDATE OR DELETE ON book_authors
+    FOR EACH ROW EXECUTE FUNCTION book_links_touch_book();
+
+DROP TRIGGER IF EXISTS book_genres_touch_book ON book_genres;
+CREATE TRIGGER book_genres_touch_book AFTER INSERT OR UPDATE OR DELETE ON book_genres
+    FOR EACH ROW EXECUTE FUNCTION book_links_touch_book();
+
+DROP TRIGGER IF EXISTS book_series_touch_book ON book_series;
+CREATE TRIGGER book_series_touch_book AFTER INSERT OR UPDATE OR DELETE ON book_series
+    FOR EACH ROW EXECUTE FUNCTION book_links_touch_book();
+
+DROP TRIGGER IF EXISTS book_tags_touch_book ON book_tags;
+CREATE TRIGGER book_tags_touch_book AFTER INSERT OR UPDATE OR DELETE ON book_tags
+    FOR EACH ROW EXECUTE FUNCTION book_links_touch_book();
@@ -0,0 +1,14 @@
+DROP TRIGGER IF EXISTS series_touch_books ON series;
+DROP TRIGGER IF EXISTS genres_touch_books ON genres;
+DROP TRIGGER IF EXISTS authors_touch_books ON authors;
+DROP FUNCTION IF EXISTS names_touch_books();
+
+DROP TRIGGER IF EXISTS book_series_touch_series ON book_series;
+DROP FUNCTION IF EXISTS book_series_touch_series();
+
+DROP TRIGGER IF EXISTS book_downloads_touch_work ON book_downloads;
+DROP FUNCTION IF EXISTS book_downloads_touch_work();
+
+DROP TRIGGER IF EXISTS books_touch_related ON books;
+DROP FUNCTION IF EXISTS books_touch_related();
+DROP FUNCTION IF EXISTS touch_related_books(varchar, varchar);
@@ -0,0 +1,90 @@
+-- Карточка книги выводит также популярность и другие издания произведения,
+-- соседей по сериям и имена авторов, жанров и серий. Их изменения сдвигают
+-- updated_at всех книг, в карточках которых они видны.
+
+-- touch_related_books сдвигает updated_at других изданий произведения work и
+-- соседей книги book по ее сериям.
+CREATE OR REPLACE FUNCTION touch_related_books(book varchar, work varchar) RETURNS void AS $$
+    UPDATE books SET updated_at = now()
+    WHERE bid <> book AND (work_id = work OR bid IN (
+        SELECT s.bid FROM book_series s JOIN book_series own ON own.series_id = s.series_id
+        WHERE own.bid = book
+    ));
+$$ LANGUAGE sql;
+
+-- Издание и сосед по серии видны в чужих карточках названием, автором, языком,
+-- издательством, годом и ISBN; удаление в корзину убирает их оттуда. Сдвиг одного
+-- updated_at не входит в список столбцов, поэтому триггер не вызывает сам себя.
+CREATE OR REPLACE FUNCTION books_touch_related() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        PERFORM touch_related_books(OLD.bid, OLD.work_id);
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        PERFORM touch_related_books(NEW.bid, NEW.work_id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS books_touch_related ON books;
+CREATE TRIGGER books_touch_related
+    AFTER INSERT OR DELETE OR UPDATE OF lable, author, language, publisher, age, isbn, work_id, deleted_at ON books
+    FOR EACH ROW EXECUTE FUNCTION books_touch_related();
+
+-- Популярность считается по всем изданиям произведения.
+CREATE OR REPLACE FUNCTION book_downloads_touch_work() RETURNS trigger AS $$
+BEGIN
+    UPDATE books SET updated_at = now()
+    WHERE work_id = (SELECT work_id FROM books WHERE bid = NEW.bid);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS book_downloads_touch_work ON book_downloads;
+CREATE TRIGGER book_downloads_touch_work AFTER INSERT ON book_downloads
+    FOR EACH ROW EXECUTE FUNCTION book_downloads_touch_work();
+
+-- Книга, добавленная в серию или убранная из нее, меняет соседей остальных книг серии.
+CREATE OR REPLACE FUNCTION book_series_touch_series() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        UPDATE books SET updated_at = now()
+        WHERE bid IN (SELECT bid FROM book_series WHERE series_id = OLD.series_id);
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        UPDATE books SET updated_at = now()
+        WHERE bid IN (SELECT bid FROM book_series WHERE series_id = NEW.series_id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS book_series_touch_series ON book_series;
+CREATE TRIGGER book_series_touch_series AFTER INSERT OR UPDATE OR DELETE ON book_series
+    FOR EACH ROW EXECUTE FUNCTION book_series_touch_series();
+
+-- Переименование автора, жанра или серии сдвигает updated_at связанных книг.
+-- Аргументы триггера - таблица связей и ее столбец со ссылкой на строку.
+CREATE OR REPLACE FUNCTION names_touch_books() RETURNS trigger AS $$
+BEGIN
+    EXECUTE format('UPDATE books SET updated_at = now() WHERE bid IN (SELECT bid FROM %I WHERE %I = $1)',
+        TG_ARGV[0], TG_ARGV[1]) USING NEW.id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS authors_touch_books ON authors;
+CREATE TRIGGER authors_touch_books AFTER UPDATE OF name ON authors
+    FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+    EXECUTE FUNCTION names_touch_books('book_authors', 'author_id');
+
+DROP TRIGGER IF EXISTS genres_touch_books ON genres;
+CREATE TRIGGER genres_touch_books AFTER UPDATE OF name_ru, name_en ON genres
+    FOR EACH ROW WHEN (OLD.name_ru IS DISTINCT FROM NEW.name_ru OR OLD.name_en IS DISTINCT FROM NEW.name_en)
+    EXECUTE FUNCTION names_touch_books('book_genres', 'genre_id');
+
+DROP TRIGGER IF EXISTS series_touch_books ON series;
+CREATE TRIGGER series_touch_books AFTER UPDATE OF name ON series
+    FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+    EXECUTE FUNCTION names_touch_books('book_series', 'series_id');
@@ -0,0 +1,11 @@
+ALTER TABLE books ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', coalesce(lable, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(lable, '')), 'A') ||
+        setweight(to_tsvector('russian', coalesce(author, '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(author, '')), 'B') ||
+        setweight(to_tsvector('russian', coalesce("desc", '')), 'C') ||
+        setweight(to_tsvector('english', coalesce("desc", '')), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS books_search_vector_idx ON books USING GIN (search_vector);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS books_author_trgm_idx;
+DROP INDEX IF EXISTS books_lable_trgm_idx;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS books_created_at_idx;
+
+ALTER TABLE books DROP COLUMN IF EXISTS created_at;
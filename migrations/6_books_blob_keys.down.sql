@@ -0,0 +1,9 @@
+ALTER TABLE books ALTER COLUMN cover_key DROP NOT NULL, ALTER COLUMN cover_key DROP DEFAULT;
+ALTER TABLE books ALTER COLUMN pdf_key DROP NOT NULL, ALTER COLUMN pdf_key DROP DEFAULT;
+
+UPDATE books SET
+    cover_key = CASE WHEN cover_key = '' THEN cover_key ELSE '/uploads/' || cover_key END,
+    pdf_key = CASE WHEN pdf_key = '' THEN pdf_key ELSE '/uploads/' || pdf_key END;
+
+ALTER TABLE books RENAME COLUMN cover_key TO cover_url;
+ALTER TABLE books RENAME COLUMN pdf_key TO pdf_url;
@@ -0,0 +1,9 @@
+ALTER TABLE books RENAME COLUMN cover_url TO cover_key;
+ALTER TABLE books RENAME COLUMN pdf_url TO pdf_key;
+
+UPDATE books SET
+    cover_key = regexp_replace(COALESCE(cover_key, ''), '^/?uploads/', ''),
+    pdf_key = regexp_replace(COALESCE(pdf_key, ''), '^/?uploads/', '');
+
+ALTER TABLE books ALTER COLUMN cover_key SET DEFAULT '', ALTER COLUMN cover_key SET NOT NULL;
+ALTER TABLE books ALTER COLUMN pdf_key SET DEFAULT '', ALTER COLUMN pdf_key SET NOT NULL;
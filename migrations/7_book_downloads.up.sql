@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS book_downloads (
+    id bigserial PRIMARY KEY,
+    bid varchar(36) NOT NULL REFERENCES books(bid) ON DELETE CASCADE,
+    uid varchar(36) NOT NULL,
+    downloaded_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS book_downloads_uid_idx ON book_downloads (uid, downloaded_at);
+CREATE INDEX IF NOT EXISTS book_downloads_bid_idx ON book_downloads (bid, downloaded_at);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS books_pdf_hash_key;
+
+ALTER TABLE books
+    DROP COLUMN IF EXISTS pdf_pages,
+    DROP COLUMN IF EXISTS pdf_title,
+    DROP COLUMN IF EXISTS pdf_author,
+    DROP COLUMN IF EXISTS pdf_size,
+    DROP COLUMN IF EXISTS pdf_hash;
@@ -0,0 +1,8 @@
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS pdf_pages integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS pdf_title text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS pdf_author text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS pdf_size bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS pdf_hash varchar(64) NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS books_pdf_hash_key ON books (pdf_hash) WHERE pdf_hash <> '';
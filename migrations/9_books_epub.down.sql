@@ -0,0 +1,11 @@
+ALTER TABLE book_downloads
+    DROP COLUMN IF EXISTS format;
+
+DROP INDEX IF EXISTS books_epub_hash_key;
+
+ALTER TABLE books
+    DROP COLUMN IF EXISTS epub_key,
+    DROP COLUMN IF EXISTS epub_size,
+    DROP COLUMN IF EXISTS epub_hash,
+    DROP COLUMN IF EXISTS language,
+    DROP COLUMN IF EXISTS isbn;
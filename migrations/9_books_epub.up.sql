@@ -0,0 +1,11 @@
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS epub_key text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS epub_size bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS epub_hash varchar(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS language text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS isbn text NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS books_epub_hash_key ON books (epub_hash) WHERE epub_hash <> '';
+
+ALTER TABLE book_downloads
+    ADD COLUMN IF NOT EXISTS format varchar(8) NOT NULL DEFAULT 'pdf';
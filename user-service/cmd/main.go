@@ -37,15 +37,17 @@ func main() {
 
 	log.Debug().Any("cfg", cfg).Send()
 	var stor server.Storage
-
-	if err = storage.Migrations(cfg.DBDsn, cfg.MigratePath); err != nil {
-		log.Fatal().Err(err).Msg("migrations failed")
-	}
-	stor, err = storage.NewDB(context.TODO(), cfg.DBDsn)
-	if err != nil {
-		log.Error().Err(err).Msg("connecting to data base failed")
+	switch cfg.StorageBackend {
+	case storage.BackendPostgres:
+		stor, err = storage.Connect(ctx, cfg.DBDsn, cfg.MigratePath, cfg.DBConnectTimeout)
+		if err != nil {
+			log.Fatal().Err(err).Msg("connecting to data base failed")
+		}
+	case storage.BackendMemory:
+		log.Warn().Msg("in-memory storage is used, data will be lost on restart")
 		stor = storage.New()
 	}
+	log.Info().Str("backend", cfg.StorageBackend).Msg("storage ready")
 	serv := server.New(*cfg, stor)
 	group, gCtx := errgroup.WithContext(ctx)
 	group.Go(func() error {
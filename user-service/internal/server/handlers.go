@@ -160,3 +160,8 @@ func (s *Server) UserInfo(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, user)
 }
+
+// Status (GET /status) сообщает, на каком хранилище работает сервис.
+func (s *Server) Status(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok", "storage": s.storageBackend})
+}
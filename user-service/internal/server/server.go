@@ -42,6 +42,9 @@ type Server struct {
 	storage Storage
 	delChan chan struct{}
 	ErrChan chan error
+
+	// storageBackend - выбранный в конфиге бэкенд хранилища, см. Status
+	storageBackend string
 }
 
 func New(cfg config.Config, stor Storage) *Server {
@@ -55,6 +58,8 @@ func New(cfg config.Config, stor Storage) *Server {
 		storage: stor,
 		delChan: make(chan struct{}, 10), //nolint:mnd //todo
 		ErrChan: make(chan error),
+
+		storageBackend: cfg.StorageBackend,
 	}
 }
 
@@ -75,6 +80,7 @@ func (s *Server) Run(ctx context.Context) error {
 	}))
 
 	router.GET("/", func(ctx *gin.Context) { ctx.String(http.StatusOK, "Hello") })
+	router.GET("/status", s.Status)
 	users := router.Group("/users")
 	{
 		users.GET("/info", s.JWTAuthMiddleware(), s.UserInfo)
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/azaliaz/bookly/user-service/internal/logger"
+)
+
+// Бэкенды хранилища, которые выбираются в конфиге.
+const (
+	BackendPostgres = "postgres"
+	BackendMemory   = "memory"
+)
+
+// Пауза между попытками подключиться к базе растет от connectBackoffMin до connectBackoffMax.
+const (
+	connectBackoffMin = 500 * time.Millisecond
+	connectBackoffMax = 10 * time.Second
+)
+
+// Connect подключается к базе и применяет миграции. При общем старте база может
+// подняться позже сервиса, поэтому неудачные подключения повторяются с растущей
+// паузой, пока не пройдет timeout; при timeout = 0 делается одна попытка. Ошибки,
+// которые повтор не исправит, - неверный DSN и ошибки миграций - возвращаются сразу.
+func Connect(ctx context.Context, dsn, migratePath string, timeout time.Duration) (*DBStorage, error) {
+	if _, err := pgx.ParseConfig(dsn); err != nil {
+		return nil, err
+	}
+	dbs, err := connect(ctx, dsn, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := Migrations(dsn, migratePath); err != nil {
+		dbs.conn.Close(ctx)
+		return nil, fmt.Errorf("migrations failed: %w", err)
+	}
+	return dbs, nil
+}
+
+// connect подключается к базе, повторяя попытки до истечения timeout.
+func connect(ctx context.Context, dsn string, timeout time.Duration) (*DBStorage, error) {
+	log := logger.Get()
+	deadline := time.Now().Add(timeout)
+	backoff := connectBackoffMin
+	for attempt := 1; ; attempt++ {
+		dbs, err := NewDB(ctx, dsn)
+		if err == nil {
+			return dbs, nil
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return nil, fmt.Errorf("data base is unavailable after %d attempts: %w", attempt, err)
+		}
+		log.Warn().Err(err).Int("attempt", attempt).Dur("retry_in", backoff).Msg("connecting to data base failed")
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, connectBackoffMax)
+	}
+}
@@ -9,8 +9,8 @@ import (
 	"golang.org/x/crypto/bcrypt"
 	// "github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 
-	// "github.com/jackc/pgx/v5/pgconn"
 	"github.com/azaliaz/bookly/user-service/internal/domain/consts"
 	"github.com/azaliaz/bookly/user-service/internal/domain/models"
 	"github.com/azaliaz/bookly/user-service/internal/logger"
@@ -33,11 +33,14 @@ func NewDB(ctx context.Context, addr string) (*DBStorage, error) {
 
 func (dbs *DBStorage) UpdateUserCartID(uid, cartID string) error {
 	log := logger.Get()
-	_, err := dbs.conn.Exec(context.Background(), "UPDATE users SET cart_id = $1 WHERE uid = $2", cartID, uid)
+	res, err := dbs.conn.Exec(context.Background(), "UPDATE users SET cart_id = $1 WHERE uid = $2", cartID, uid)
 	if err != nil {
 		log.Error().Err(err).Msg("failed to update cart ID")
 		return err
 	}
+	if res.RowsAffected() == 0 {
+		return storerrros.ErrUserNotFound
+	}
 	return nil
 }
 func (dbs *DBStorage) SaveUser(user models.User, adminKey string) (string, error) {
@@ -69,6 +72,9 @@ func (dbs *DBStorage) SaveUser(user models.User, adminKey string) (string, error
 
 	_, err = dbs.conn.Exec(context.Background(), "INSERT INTO users (uid, cart_id, email, name, lastname, pass, age, role) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
 		userUUID, cartUUID, user.Email, user.Name, user.LastName, user.Pass, user.Age, user.Role)
+	if isUniqueViolation(err) {
+		return "", storerrros.ErrUserExists
+	}
 	if err != nil {
 		log.Error().Err(err).Msg("failed to insert user")
 		return "", err
@@ -91,6 +97,9 @@ func (dbs *DBStorage) ValidUser(user models.User) (string, error) {
 	var usr models.User
 	row := dbs.conn.QueryRow(ctx, "SELECT uid, cart_id, email, name, lastname, pass, age, role FROM users WHERE email = $1", user.Email)
 	if err := row.Scan(&usr.UID, &usr.CartID, &usr.Email, &usr.Name, &usr.LastName, &usr.Pass, &usr.Age, &usr.Role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", storerrros.ErrUserNoExist
+		}
 		log.Error().Err(err).Msg("failed scan db data")
 		return "", err
 	}
@@ -111,6 +120,10 @@ func (dbs *DBStorage) GetUser(uid string) (models.User, error) {
 	row := dbs.conn.QueryRow(ctx, "SELECT uid, cart_id, email, name, lastname, pass, age, role FROM users WHERE uid = $1", uid)
 	var usr models.User
 	if err := row.Scan(&usr.UID, &usr.CartID, &usr.Email, &usr.Name, &usr.LastName, &usr.Pass, &usr.Age, &usr.Role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Error().Str("uid", uid).Msg("user not found")
+			return models.User{}, storerrros.ErrUserNotFound
+		}
 		log.Error().Err(err).Msg("failed scan db data")
 		return models.User{}, err
 	}
@@ -119,6 +132,13 @@ func (dbs *DBStorage) GetUser(uid string) (models.User, error) {
 	return usr, nil
 }
 
+// isUniqueViolation сообщает, что запись нарушила уникальный индекс, например
+// email_id при одновременной регистрации с одним email.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
 func Migrations(dbDsn string, migrationsPath string) error {
 	log := logger.Get()
 	migratePath := fmt.Sprintf("file://%s", migrationsPath)
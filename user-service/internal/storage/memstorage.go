@@ -1,7 +1,7 @@
 package storage
 
 import (
-	"fmt"
+	"sync"
 
 	"golang.org/x/crypto/bcrypt"
 
@@ -12,7 +12,10 @@ import (
 	storerrros "github.com/azaliaz/bookly/user-service/internal/storage/errors"
 )
 
+// MemStorage хранит пользователей в памяти процесса; методы можно вызывать из
+// параллельных запросов.
 type MemStorage struct {
+	mu        sync.RWMutex
 	usersStor map[string]models.User
 }
 
@@ -22,6 +25,8 @@ func New() *MemStorage {
 	}
 }
 func (ms *MemStorage) UpdateUserCartID(uid, cartID string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
 	user, ok := ms.usersStor[uid]
 	if !ok {
 		return storerrros.ErrUserNotFound
@@ -33,12 +38,6 @@ func (ms *MemStorage) UpdateUserCartID(uid, cartID string) error {
 
 func (ms *MemStorage) SaveUser(user models.User, adminKey string) (string, error) {
 	log := logger.Get()
-	uuid := uuid.New().String()
-
-	if _, err := ms.findUser(user.Email); err == nil {
-		return "", storerrros.ErrUserExists
-	}
-
 	hash, err := bcrypt.GenerateFromPassword([]byte(user.Pass), bcrypt.DefaultCost)
 	if err != nil {
 		log.Error().Err(err).Msg("save user failed")
@@ -48,27 +47,33 @@ func (ms *MemStorage) SaveUser(user models.User, adminKey string) (string, error
 	// Логирование хеша
 	log.Debug().Str("hash", string(hash)).Send()
 
+	// как и DBStorage, вместе с пользователем заводится его корзина
 	user.Pass = string(hash)
-	user.UID = uuid
+	user.UID = uuid.New().String()
+	user.CartID = uuid.New().String()
 
 	if adminKey == "your-admin-secret-key" {
-		fmt.Println("admin")
 		user.Role = "admin"
 	} else if user.Role == "" {
 		user.Role = "user"
 	}
 
-	ms.usersStor[uuid] = user
-
-	log.Debug().Any("storage", ms.usersStor).Send()
+	// хеш считается до блокировки: bcrypt медленный, а проверка email и запись
+	// должны идти под одной блокировкой, как уникальный индекс в DBStorage
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, err := ms.findUser(user.Email); err == nil {
+		return "", storerrros.ErrUserExists
+	}
+	ms.usersStor[user.UID] = user
 
-	return uuid, nil
+	return user.UID, nil
 }
 
 func (ms *MemStorage) ValidUser(user models.User) (string, error) {
-	log := logger.Get()
-	log.Debug().Any("storage", ms.usersStor).Send()
+	ms.mu.RLock()
 	memUser, err := ms.findUser(user.Email)
+	ms.mu.RUnlock()
 	if err != nil {
 		return "", err
 	}
@@ -80,6 +85,8 @@ func (ms *MemStorage) ValidUser(user models.User) (string, error) {
 
 func (ms *MemStorage) GetUser(uid string) (models.User, error) {
 	log := logger.Get()
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
 	//поиск пользователя в хранилище по UID
 	//ms.usersStor представляет собой карту (map), где ключом является uid, а значением — объект models.User
 	user, ok := ms.usersStor[uid]
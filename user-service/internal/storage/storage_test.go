@@ -0,0 +1,176 @@
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/azaliaz/bookly/user-service/internal/domain/models"
+	"github.com/azaliaz/bookly/user-service/internal/server"
+	"github.com/azaliaz/bookly/user-service/internal/storage"
+	storerrros "github.com/azaliaz/bookly/user-service/internal/storage/errors"
+)
+
+// storages возвращает реализации Storage, на которых прогоняются одни и те же
+// проверки: MemStorage всегда, DBStorage - если TEST_DB_DSN указывает на тестовую базу.
+func storages(t *testing.T) map[string]server.Storage {
+	result := map[string]server.Storage{"mem": storage.New()}
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Log("TEST_DB_DSN is not set, DBStorage is skipped")
+		return result
+	}
+	require.NoError(t, storage.Migrations(dsn, "../../../migrations"))
+	db, err := storage.NewDB(context.Background(), dsn)
+	require.NoError(t, err)
+	result["db"] = db
+	return result
+}
+
+// testUser возвращает пользователя с email, которого еще нет ни в одном хранилище.
+func testUser() models.User {
+	return models.User{
+		Email:    uuid.New().String() + "@example.com",
+		Name:     "Иван",
+		LastName: "Гончаров",
+		Pass:     "password123",
+		Age:      30,
+	}
+}
+
+func TestStorage_conformance(t *testing.T) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T, s server.Storage)
+	}{
+		{
+			name: "save and get user",
+			run: func(t *testing.T, s server.Storage) {
+				user := testUser()
+				uid, err := s.SaveUser(user, "")
+				require.NoError(t, err)
+				require.NotEmpty(t, uid)
+
+				got, err := s.GetUser(uid)
+				require.NoError(t, err)
+				assert.Equal(t, uid, got.UID)
+				assert.Equal(t, user.Email, got.Email)
+				assert.Equal(t, user.Name, got.Name)
+				assert.Equal(t, user.LastName, got.LastName)
+				assert.Equal(t, user.Age, got.Age)
+				assert.Equal(t, "user", got.Role)
+				assert.NotEmpty(t, got.CartID)
+				assert.NotEqual(t, user.Pass, got.Pass, "password must be hashed")
+			},
+		},
+		{
+			name: "admin key grants admin role",
+			run: func(t *testing.T, s server.Storage) {
+				uid, err := s.SaveUser(testUser(), "your-admin-secret-key")
+				require.NoError(t, err)
+				got, err := s.GetUser(uid)
+				require.NoError(t, err)
+				assert.Equal(t, "admin", got.Role)
+			},
+		},
+		{
+			name: "duplicate email",
+			run: func(t *testing.T, s server.Storage) {
+				user := testUser()
+				_, err := s.SaveUser(user, "")
+				require.NoError(t, err)
+				_, err = s.SaveUser(user, "")
+				assert.ErrorIs(t, err, storerrros.ErrUserExists)
+			},
+		},
+		{
+			name: "validate user",
+			run: func(t *testing.T, s server.Storage) {
+				user := testUser()
+				uid, err := s.SaveUser(user, "")
+				require.NoError(t, err)
+
+				got, err := s.ValidUser(models.User{Email: user.Email, Pass: user.Pass})
+				require.NoError(t, err)
+				assert.Equal(t, uid, got)
+
+				_, err = s.ValidUser(models.User{Email: user.Email, Pass: "wrong-password"})
+				assert.ErrorIs(t, err, storerrros.ErrInvalidPassword)
+				_, err = s.ValidUser(testUser())
+				assert.ErrorIs(t, err, storerrros.ErrUserNoExist)
+			},
+		},
+		{
+			name: "missing user",
+			run: func(t *testing.T, s server.Storage) {
+				_, err := s.GetUser(uuid.New().String())
+				assert.ErrorIs(t, err, storerrros.ErrUserNotFound)
+				err = s.UpdateUserCartID(uuid.New().String(), uuid.New().String())
+				assert.ErrorIs(t, err, storerrros.ErrUserNotFound)
+			},
+		},
+		{
+			name: "update cart id",
+			run: func(t *testing.T, s server.Storage) {
+				uid, err := s.SaveUser(testUser(), "")
+				require.NoError(t, err)
+				cartID := uuid.New().String()
+				require.NoError(t, s.UpdateUserCartID(uid, cartID))
+				got, err := s.GetUser(uid)
+				require.NoError(t, err)
+				assert.Equal(t, cartID, got.CartID)
+			},
+		},
+	}
+
+	for name, s := range storages(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) { tc.run(t, s) })
+			}
+		})
+	}
+}
+
+// TestMemStorage_concurrent рассчитан на запуск с -race: регистрации с одним email
+// из параллельных запросов должны создать ровно одного пользователя.
+func TestMemStorage_concurrent(t *testing.T) {
+	s := storage.New()
+	user := testUser()
+
+	var wg sync.WaitGroup
+	uids := make(chan string, 8)
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if uid, err := s.SaveUser(user, ""); err == nil {
+				uids <- uid
+			}
+			other := testUser()
+			other.Email = fmt.Sprintf("%d-%s", i, other.Email)
+			uid, err := s.SaveUser(other, "")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if err := s.UpdateUserCartID(uid, uuid.New().String()); err != nil {
+				t.Error(err)
+			}
+			if _, err := s.GetUser(uid); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(uids)
+	assert.Len(t, uids, 1)
+}